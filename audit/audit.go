@@ -0,0 +1,117 @@
+// Package audit records every mutation performed on GitHub so that changes
+// made by the receiver can be explained later.
+package audit
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/stephen-soltesz/github-webhook-poc/internal/jsonl"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
+)
+
+// Record describes a single mutating GitHub API call.
+type Record struct {
+	// Time the call completed.
+	Time time.Time `json:"time"`
+	// DeliveryID of the webhook delivery that caused the call, if any.
+	DeliveryID string `json:"delivery_id,omitempty"`
+	// Rule that requested the call, if any.
+	Rule string `json:"rule,omitempty"`
+	// Repo is the full "owner/name" of the repository.
+	Repo string `json:"repo"`
+	// Issue number.
+	Issue int `json:"issue"`
+	// Operation names the call, e.g. "edit", "add-labels", "remove-label".
+	Operation string `json:"operation"`
+	// Detail describes the arguments to the operation.
+	Detail string `json:"detail,omitempty"`
+	// Before and After are the issue label sets around the call.
+	Before []string `json:"before"`
+	After  []string `json:"after"`
+	// Result is "ok", "dry-run" if the change was only logged, or the error
+	// message.
+	Result string `json:"result"`
+	// Status is the HTTP status of the API response, or zero if there was none.
+	Status int `json:"status,omitempty"`
+}
+
+// Recorder saves audit records.
+type Recorder interface {
+	Record(r *Record) error
+}
+
+// Query selects audit records. Zero valued fields match all records.
+type Query struct {
+	DeliveryID string
+	Rule       string
+	Repo       string
+	Issue      int
+	// Label matches records where the label was in the before or after set.
+	Label string
+	Since time.Time
+	Until time.Time
+	// Limit returns at most the last Limit matching records.
+	Limit int
+}
+
+// Match reports whether the record matches all query fields.
+func (q *Query) Match(r *Record) bool {
+	switch {
+	case q.DeliveryID != "" && q.DeliveryID != r.DeliveryID:
+		return false
+	case q.Rule != "" && q.Rule != r.Rule:
+		return false
+	case q.Repo != "" && !strings.EqualFold(q.Repo, r.Repo):
+		return false
+	case q.Issue != 0 && q.Issue != r.Issue:
+		return false
+	case q.Label != "" && !slice.ContainsString(r.Before, q.Label) && !slice.ContainsString(r.After, q.Label):
+		return false
+	case !q.Since.IsZero() && r.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && r.Time.After(q.Until):
+		return false
+	}
+	return true
+}
+
+// Store is a Recorder that appends records as JSON lines to a local file.
+type Store struct {
+	file *jsonl.File
+}
+
+// NewStore creates a new Store that saves records to the given file name. The
+// file is created on the first call to Record.
+func NewStore(path string) *Store {
+	return &Store{file: jsonl.New(path)}
+}
+
+// Record appends the record to the store file.
+func (s *Store) Record(r *Record) error {
+	return s.file.Append(r)
+}
+
+// Query returns all records matching q, oldest first. A missing store file
+// returns no records.
+func (s *Store) Query(q *Query) ([]*Record, error) {
+	records := []*Record{}
+	err := s.file.Read(func(line []byte) error {
+		r := &Record{}
+		if err := json.Unmarshal(line, r); err != nil {
+			return err
+		}
+		if q.Match(r) {
+			records = append(records, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[len(records)-q.Limit:]
+	}
+	return records, nil
+}
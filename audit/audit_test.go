@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := NewStore(filepath.Join(dir, "audit.jsonl"))
+
+	// Querying before any records exist returns nothing.
+	got, err := s.Query(&Query{})
+	if err != nil || len(got) != 0 {
+		t.Fatalf("Query() = %v, %v; want no records", got, err)
+	}
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []*Record{
+		{
+			Time: start, DeliveryID: "d1", Rule: "triage", Repo: "o/r", Issue: 1,
+			Operation: "add-labels", Before: []string{}, After: []string{"review/triage"}, Result: "ok", Status: 200,
+		},
+		{
+			Time: start.Add(time.Hour), DeliveryID: "d2", Rule: "current", Repo: "o/r", Issue: 1,
+			Operation: "remove-label", Before: []string{"backlog", "current"}, After: []string{"current"}, Result: "ok", Status: 200,
+		},
+		{
+			Time: start.Add(2 * time.Hour), DeliveryID: "d3", Rule: "current", Repo: "o/other", Issue: 2,
+			Operation: "remove-label", Before: []string{"current"}, After: []string{"current"}, Result: "404 Not Found", Status: 404,
+		},
+	}
+	for _, r := range records {
+		if err := s.Record(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{name: "all", query: Query{}, want: []string{"d1", "d2", "d3"}},
+		{name: "delivery", query: Query{DeliveryID: "d2"}, want: []string{"d2"}},
+		{name: "rule", query: Query{Rule: "current"}, want: []string{"d2", "d3"}},
+		{name: "repo", query: Query{Repo: "O/R"}, want: []string{"d1", "d2"}},
+		{name: "issue", query: Query{Issue: 2}, want: []string{"d3"}},
+		{name: "label", query: Query{Label: "backlog"}, want: []string{"d2"}},
+		{name: "since", query: Query{Since: start.Add(time.Minute)}, want: []string{"d2", "d3"}},
+		{name: "until", query: Query{Until: start.Add(time.Minute)}, want: []string{"d1"}},
+		{name: "limit", query: Query{Limit: 1}, want: []string{"d3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Query(&tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := []string{}
+			for _, r := range got {
+				ids = append(ids, r.DeliveryID)
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("Query() = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Errorf("Query() = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
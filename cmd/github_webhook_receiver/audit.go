@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stephen-soltesz/github-webhook-poc/audit"
+)
+
+const auditUsage = `
+USAGE:
+
+  github_webhook_receiver audit [flags]
+
+  Search the audit log written by the receiver with --audit-log. All flags
+  are optional and combine to narrow the results.
+
+FLAGS:
+
+`
+
+// auditMain implements the "audit" subcommand and returns the exit status.
+func auditMain(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, auditUsage)
+		fs.PrintDefaults()
+	}
+	var (
+		file   = fs.String("audit-log", os.Getenv("AUDIT_LOG"), "The audit log file name.")
+		q      audit.Query
+		since  time.Duration
+		asJSON bool
+	)
+	fs.StringVar(&q.DeliveryID, "delivery", "", "Only show records for the given webhook delivery ID.")
+	fs.StringVar(&q.Rule, "rule", "", "Only show records for the given rule name.")
+	fs.StringVar(&q.Repo, "repo", "", "Only show records for the given owner/repo.")
+	fs.IntVar(&q.Issue, "issue", 0, "Only show records for the given issue number.")
+	fs.StringVar(&q.Label, "label", "", "Only show records where the label was present before or after.")
+	fs.DurationVar(&since, "since", 0, "Only show records newer than this duration, e.g. 24h.")
+	fs.IntVar(&q.Limit, "limit", 0, "Only show the most recent N records.")
+	fs.BoolVar(&asJSON, "json", false, "Print records as JSON lines.")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "audit: --audit-log is required")
+		return 2
+	}
+	if since > 0 {
+		q.Since = time.Now().Add(-since)
+	}
+
+	records, err := audit.NewStore(*file).Query(&q)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "audit:", err)
+		return 1
+	}
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, r := range records {
+			enc.Encode(r)
+		}
+		return 0
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tDELIVERY\tRULE\tISSUE\tOPERATION\tBEFORE\tAFTER\tRESULT")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s#%d\t%s %s\t%s\t%s\t%s (%d)\n",
+			r.Time.Format(time.RFC3339), r.DeliveryID, r.Rule, r.Repo, r.Issue,
+			r.Operation, r.Detail, strings.Join(r.Before, ","),
+			strings.Join(r.After, ","), r.Result, r.Status)
+	}
+	w.Flush()
+	return 0
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+	"github.com/stephen-soltesz/github-webhook-poc/labels"
+	"github.com/stephen-soltesz/github-webhook-poc/local"
+	"github.com/stephen-soltesz/github-webhook-poc/repoconfig"
+)
+
+const labelsUsage = `
+USAGE:
+
+  github_webhook_receiver labels [flags]
+
+  Sync the label catalog to one repository (--repo) or to every repository
+  where the Github App is installed (--all). A label_catalog in a repo's
+  configuration file replaces the catalog for that repo.
+
+  With GITHUB_PRIVATE_KEY and GITHUB_APP_ID set, labels are changed by the
+  Github App. Otherwise, GITHUB_AUTH_TOKEN is used and --all is not supported.
+
+FLAGS:
+
+`
+
+// repoTarget is a repository and the client to use for it.
+type repoTarget struct {
+	client      *github.Client
+	owner, name string
+}
+
+// labelsMain implements the "labels" subcommand and returns the exit status.
+func labelsMain(args []string) int {
+	fs := flag.NewFlagSet("labels", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, labelsUsage)
+		fs.PrintDefaults()
+	}
+	var (
+		catalogFile   = fs.String("catalog", "", "The YAML or JSON label catalog. Defaults to built-in labels.")
+		repo          = fs.String("repo", "", "Sync the labels of this owner/repo.")
+		all           = fs.Bool("all", false, "Sync the labels of every repo where the Github App is installed.")
+		deleteUnknown = fs.Bool("delete-unknown", false, "Delete repo labels missing from the --catalog catalog.")
+		repoConfig    = fs.String("repo-config", repoconfig.DefaultPath, "Load per-repo configuration from this path in each repo. Empty disables.")
+		dryRun        = fs.Bool("dry-run", githubx.DryRun(), "Log intended changes to GitHub instead of making them.")
+	)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if (*repo == "") == !*all {
+		fmt.Fprintln(os.Stderr, "labels: exactly one of --repo or --all is required")
+		return 2
+	}
+	githubx.SetDryRun(*dryRun)
+
+	config := local.NewConfig(0)
+	if *catalogFile != "" {
+		catalog := &labels.Catalog{}
+		if err := configfile.Load(*catalogFile, catalog); err != nil {
+			fmt.Fprintln(os.Stderr, "labels:", err)
+			return 1
+		}
+		config.Labels = catalog
+	}
+	if *deleteUnknown {
+		config.Labels.DeleteUnknown = true
+	}
+	if *repoConfig != "" {
+		config.Repos = repoconfig.NewLoader()
+		config.Repos.Path = *repoConfig
+	}
+
+	ctx := context.Background()
+	targets, err := labelTargets(ctx, *repo, *all)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "labels:", err)
+		return 1
+	}
+	status := 0
+	for _, t := range targets {
+		result, err := config.SyncLabels(ctx, t.client, t.owner, t.name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "labels: %s/%s: %v\n", t.owner, t.name, err)
+			status = 1
+			continue
+		}
+		fmt.Printf("%s/%s: %s\n", t.owner, t.name, result)
+	}
+	return status
+}
+
+// labelTargets returns the repositories to sync, each with an authenticated
+// client.
+func labelTargets(ctx context.Context, repo string, all bool) ([]repoTarget, error) {
+	usingApp := os.Getenv("GITHUB_PRIVATE_KEY") != "" && os.Getenv("GITHUB_APP_ID") != ""
+	if !usingApp {
+		if all {
+			return nil, fmt.Errorf("--all requires Github App credentials")
+		}
+		owner, name, err := splitRepo(repo)
+		if err != nil {
+			return nil, err
+		}
+		client := githubx.NewClient(0)
+		if client == nil {
+			return nil, local.ErrNewClient
+		}
+		return []repoTarget{{client, owner, name}}, nil
+	}
+
+	apps := githubx.NewJWTClientFromEnv()
+	if apps == nil {
+		return nil, local.ErrNewClient
+	}
+	if !all {
+		owner, name, err := splitRepo(repo)
+		if err != nil {
+			return nil, err
+		}
+		install, err := githubx.FindInstallation(ctx, apps, owner, name)
+		if err != nil {
+			return nil, err
+		}
+		client := githubx.NewClient(install.GetID())
+		if client == nil {
+			return nil, local.ErrNewClient
+		}
+		return []repoTarget{{client, owner, name}}, nil
+	}
+
+	installs, err := githubx.ListInstallations(ctx, apps)
+	if err != nil {
+		return nil, err
+	}
+	targets := []repoTarget{}
+	for _, install := range installs {
+		client := githubx.NewClient(install.GetID())
+		if client == nil {
+			return nil, local.ErrNewClient
+		}
+		repos, err := githubx.ListInstallationRepos(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range repos {
+			targets = append(targets, repoTarget{client, r.GetOwner().GetLogin(), r.GetName()})
+		}
+	}
+	return targets, nil
+}
+
+func splitRepo(repo string) (string, string, error) {
+	f := strings.Split(repo, "/")
+	if len(f) != 2 || f[0] == "" || f[1] == "" {
+		return "", "", fmt.Errorf("--repo %q must be owner/repo", repo)
+	}
+	return f[0], f[1], nil
+}
@@ -3,15 +3,38 @@
 package main
 
 import (
+	"context"
+	"expvar"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"github.com/stephen-soltesz/github-webhook-poc/audit"
+	"github.com/stephen-soltesz/github-webhook-poc/digest"
+	"github.com/stephen-soltesz/github-webhook-poc/events/checks"
+	"github.com/stephen-soltesz/github-webhook-poc/events/projects"
+	"github.com/stephen-soltesz/github-webhook-poc/events/pulls"
+	"github.com/stephen-soltesz/github-webhook-poc/events/releases"
+	"github.com/stephen-soltesz/github-webhook-poc/events/statuses"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
 	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+	"github.com/stephen-soltesz/github-webhook-poc/iteration"
+	"github.com/stephen-soltesz/github-webhook-poc/labels"
 	"github.com/stephen-soltesz/github-webhook-poc/local"
+	"github.com/stephen-soltesz/github-webhook-poc/notify"
+	"github.com/stephen-soltesz/github-webhook-poc/relay"
+	"github.com/stephen-soltesz/github-webhook-poc/repoconfig"
+	"github.com/stephen-soltesz/github-webhook-poc/rules"
+	"github.com/stephen-soltesz/github-webhook-poc/scheduler"
+	"github.com/stephen-soltesz/github-webhook-poc/sink"
+	"github.com/stephen-soltesz/github-webhook-poc/sla"
+	"github.com/stephen-soltesz/github-webhook-poc/stale"
 
 	// "github.com/kr/pretty"
 
@@ -37,6 +60,37 @@ USAGE:
   For Let's Encrypt TLS certificate, you may provide a hostname:
   - WEBHOOK_HOSTNAME
 
+  To use a GitHub API server other than https://api.github.com:
+  - GITHUB_API_URL
+
+  To log intended changes instead of making them (same as --dry-run):
+  - DRY_RUN=1
+
+  To record every change made to issues (same as --audit-log):
+  - AUDIT_LOG - the path to the audit log file.
+
+  To report how long issues wait for triage (same as --sla-log):
+  - SLA_LOG - the path to the triage state file. The report is served at
+    /triage (add "?format=json" for JSON) and metrics at /debug/vars, on the
+    --admin-addr listener only, since the report lists private issues.
+
+  To forward every verified delivery to other services, list them in a
+  --relay configuration. Each subscriber gets its own secret and event filter.
+
+  To publish normalized events to a message broker (same as --sink):
+  - SINK_URL - nats://host:4222/<subject prefix>, redis://:<password>@host:6379/<stream>,
+    or file:///<queue directory>.
+
+  Scheduled jobs (iteration rollover, triage SLA, stale issues) run with
+  Github App authentication only. The --digest email job runs with any
+  authentication. With several replicas, set --lock-file to a path on a
+  shared volume so that only one replica runs them.
+
+SUBCOMMANDS:
+
+  audit - search the audit log. Run "github_webhook_receiver audit -help".
+  labels - sync the label catalog to repos. Run "github_webhook_receiver labels -help".
+
 PERSONAL ACCESS TOKENS:
 
   Allocate a "Personal Access Token" by visiting github.com:
@@ -57,7 +111,15 @@ REGISTER WEBHOOK:
    * Secret: value matching the environment variable GITHUB_WEBHOOK_SECRET
    * Select "Let me select individual events."
    * Check "Issues".
-   * Uncheck "Pushes".
+   * Check "Issue comments" (for slash commands like "/label bug" and stale issues).
+   * Check "Pull requests" (to label pull requests, request reviewers and run --checks).
+   * Check "Project cards" (to sync classic project board columns and labels).
+   * Check "Projects v2 item" (Github Apps only, to sync Projects v2 status and labels).
+   * Check "Check runs" (to post --notify chat messages, and re-run --checks with Github Apps).
+   * Check "Check suites" (Github Apps only, to re-run --checks).
+   * Check "Releases" (to post --notify chat messages and write --releases notes).
+   * Check "Pushes" (to reload per-repo configuration and set --statuses).
+   * Check "Installation repositories" (Github Apps only, to sync labels).
    * Click the green "Add Webhook" button.
 
   If the registration was successful, there should be a green checkmark. If
@@ -68,12 +130,44 @@ FLAGS:
 `
 )
 
+// shutdownTimeout limits how long queued deliveries are flushed on exit.
+const shutdownTimeout = 30 * time.Second
+
 var (
 	authToken     string
 	webhookSecret string
 	privateKey    string
 	hostname      string
 	fListenAddr   string
+	fAdminAddr    string
+	fDryRun       bool
+	fAuditLog     string
+	fRules        string
+	fRepoConfig   string
+	fLabels       string
+	fIterations   string
+	fStale        string
+	fPulls        string
+	fProjects     string
+
+	fScheduleTZ       string
+	fLockFile         string
+	fRolloverSchedule string
+	fTriageSchedule   string
+	fTriageSLA        time.Duration
+	fStaleSchedule    string
+	fSLALog           string
+	fRelay            string
+	fRelayLog         string
+	fSink             string
+	fChecks           bool
+	fStatuses         string
+	fStatusURL        string
+	fReleases         string
+	fNotify           string
+	fDigest           string
+	fDigestStore      string
+	fDigestSchedule   string
 )
 
 func init() {
@@ -82,6 +176,34 @@ func init() {
 	privateKey = os.Getenv("GITHUB_PRIVATE_KEY")
 	hostname = os.Getenv("WEBHOOK_HOSTNAME")
 	flag.StringVar(&fListenAddr, "addr", ":3000", "The github user or organization name.")
+	flag.StringVar(&fAdminAddr, "admin-addr", "localhost:3001", "Serve the --sla-log report and metrics on this address. Empty disables.")
+	flag.BoolVar(&fDryRun, "dry-run", githubx.DryRun(), "Log intended changes to GitHub instead of making them.")
+	flag.StringVar(&fRules, "rules", "", "Load issue label rules from this YAML or JSON file. Defaults to built-in rules.")
+	flag.StringVar(&fRepoConfig, "repo-config", repoconfig.DefaultPath, "Load per-repo configuration from this path in each repo. Empty disables.")
+	flag.StringVar(&fIterations, "iterations", "", "Load the iteration calendar from this YAML or JSON file. Defaults to ISO weeks.")
+	flag.StringVar(&fStale, "stale", "", "Load the stale issue configuration from this YAML or JSON file. Empty disables, unless set per repo.")
+	flag.StringVar(&fPulls, "pulls", "", "Load the pull request automation configuration from this YAML or JSON file. Defaults to size and draft labels.")
+	flag.StringVar(&fProjects, "projects", "", "Load the project board sync configuration from this YAML or JSON file. Empty disables, unless set per repo.")
+	flag.StringVar(&fLabels, "labels", "", "Sync this YAML or JSON label catalog on install. Defaults to built-in labels.")
+	flag.StringVar(&fScheduleTZ, "schedule-timezone", "UTC", "Evaluate job schedules in this timezone.")
+	flag.StringVar(&fLockFile, "lock-file", "", "Run scheduled jobs only while holding a lease on this file.")
+	flag.StringVar(&fRolloverSchedule, "rollover-schedule", "5 0 * * *", "Cron schedule to roll over \"current\" issues to the current iteration. Empty disables.")
+	flag.StringVar(&fTriageSchedule, "triage-schedule", "@hourly", "Cron schedule to check the triage SLA.")
+	flag.DurationVar(&fTriageSLA, "triage-sla", 0, "Comment on issues waiting for triage longer than this. Zero disables the comment, and the --sla-log report uses 72h.")
+	flag.StringVar(&fStaleSchedule, "stale-schedule", "@daily", "Cron schedule to mark and close stale issues. Empty disables.")
+	flag.StringVar(&fSLALog, "sla-log", os.Getenv("SLA_LOG"), "Track how long issues wait for triage in this file, and serve the report at /triage on --admin-addr.")
+	flag.StringVar(&fRelay, "relay", "", "Forward deliveries to the subscribers in this YAML or JSON file. Empty disables.")
+	flag.StringVar(&fRelayLog, "relay-log", "", "Record the status of every forwarded delivery in this file.")
+	flag.StringVar(&fSink, "sink", os.Getenv("SINK_URL"), "Publish normalized events to this nats://, redis:// or file:// URL. Empty disables.")
+	flag.BoolVar(&fChecks, "checks", false, "Gate pull requests with a check run that requires a reference to an issue labeled \"current\". Requires Github App authentication.")
+	flag.StringVar(&fStatuses, "statuses", "", "Set commit statuses from the validators in this YAML or JSON file. Empty disables, unless set per repo.")
+	flag.StringVar(&fStatusURL, "status-url", "", "Link commit statuses to this address of the /statuses page. Defaults to https://WEBHOOK_HOSTNAME/statuses.")
+	flag.StringVar(&fReleases, "releases", "", "Write release notes as configured in this YAML or JSON file. Empty disables, unless set per repo.")
+	flag.StringVar(&fNotify, "notify", "", "Post chat messages for the routes in this YAML or JSON file. Empty disables.")
+	flag.StringVar(&fDigest, "digest", "", "Email a digest of issue activity as configured in this YAML or JSON file. Empty disables.")
+	flag.StringVar(&fDigestStore, "digest-store", "digest.jsonl", "Save issue activity for the next digest in this file.")
+	flag.StringVar(&fDigestSchedule, "digest-schedule", "0 8 * * *", "Cron schedule to send the digest.")
+	flag.StringVar(&fAuditLog, "audit-log", os.Getenv("AUDIT_LOG"), "Record every change made to issues in this file.")
 
 	log.SetFlags(log.LstdFlags | log.LUTC | log.Lshortfile)
 
@@ -97,33 +219,274 @@ func usageHandler(w http.ResponseWriter, r *http.Request) {
 	flag.PrintDefaults()
 }
 
+// addAppJobs schedules the jobs that need Github App authentication.
+func addAppJobs(s *scheduler.Scheduler, config *local.Config) {
+	if fRolloverSchedule != "" {
+		if err := s.Add("rollover", fRolloverSchedule, config.RolloverJob); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if fTriageSLA > 0 {
+		config.TriageSLA = fTriageSLA
+		if err := s.Add("triage-sla", fTriageSchedule, config.TriageJob); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if fStaleSchedule != "" {
+		if err := s.Add("stale", fStaleSchedule, config.StaleJob); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// startScheduler runs the scheduled jobs until the process exits.
+func startScheduler(config *local.Config) {
+	loc, err := time.LoadLocation(fScheduleTZ)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := scheduler.New(loc)
+	if fLockFile != "" {
+		s.Locker = scheduler.NewFileLease(fLockFile, 5*time.Minute)
+	}
+	if config.Digest != nil {
+		if err := s.Add("digest", fDigestSchedule, config.DigestJob); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if privateKey != "" {
+		// These jobs list the repositories of the Github App.
+		addAppJobs(s, config)
+	}
+	s.Run(context.Background())
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		os.Exit(auditMain(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "labels" {
+		os.Exit(labelsMain(os.Args[2:]))
+	}
 	flag.Parse()
+	githubx.SetDryRun(fDryRun)
 	if (authToken == "" && privateKey == "") || webhookSecret == "" {
 		flag.Usage()
 		os.Exit(1)
 	}
 
 	config := local.NewConfig(time.Second)
+	if fAuditLog != "" {
+		config.Audit = audit.NewStore(fAuditLog)
+	}
+	if fRules != "" {
+		ruleConfig := &rules.Config{}
+		if err := configfile.Load(fRules, ruleConfig); err != nil {
+			log.Fatal(err)
+		}
+		config.Rules = rules.NewEngine(ruleConfig)
+	}
+	if fIterations != "" {
+		calendar := &iteration.Calendar{}
+		if err := configfile.Load(fIterations, calendar); err != nil {
+			log.Fatal(err)
+		}
+		config.Rules = config.Rules.WithCalendar(calendar)
+	}
+	if fStale != "" {
+		staleConfig := &stale.Config{}
+		if err := configfile.Load(fStale, staleConfig); err != nil {
+			log.Fatal(err)
+		}
+		config.Stale = staleConfig
+	}
+	if fPulls != "" {
+		pullsConfig := &pulls.Config{}
+		if err := configfile.Load(fPulls, pullsConfig); err != nil {
+			log.Fatal(err)
+		}
+		config.Pulls = pullsConfig
+	}
+	if fProjects != "" {
+		projectsConfig := &projects.Config{}
+		if err := configfile.Load(fProjects, projectsConfig); err != nil {
+			log.Fatal(err)
+		}
+		config.Projects = projectsConfig
+	}
+	if fChecks {
+		if privateKey == "" {
+			log.Fatal("--checks requires Github App authentication")
+		}
+		config.Checks = checks.NewRunner(&checks.CurrentIssue{Label: config.CurrentLabel})
+	}
+	if fStatuses != "" {
+		statusesConfig := &statuses.Config{}
+		if err := configfile.Load(fStatuses, statusesConfig); err != nil {
+			log.Fatal(err)
+		}
+		config.Statuses = statusesConfig
+	}
+	config.StatusURL = fStatusURL
+	if config.StatusURL == "" && hostname != "" {
+		config.StatusURL = "https://" + hostname + "/statuses"
+	}
+	if fReleases != "" {
+		releasesConfig := &releases.Config{}
+		if err := configfile.Load(fReleases, releasesConfig); err != nil {
+			log.Fatal(err)
+		}
+		config.Releases = releasesConfig
+	}
+	if fNotify != "" {
+		notifyConfig := &notify.Config{}
+		if err := configfile.Load(fNotify, notifyConfig); err != nil {
+			log.Fatal(err)
+		}
+		config.Notify = notify.New(notifyConfig)
+	}
+	if fDigest != "" {
+		digestConfig := &digest.Config{}
+		if err := configfile.Load(fDigest, digestConfig); err != nil {
+			log.Fatal(err)
+		}
+		config.Digest = digest.New(digestConfig, digest.NewStore(fDigestStore))
+	}
+	if fLabels != "" {
+		catalog := &labels.Catalog{}
+		if err := configfile.Load(fLabels, catalog); err != nil {
+			log.Fatal(err)
+		}
+		config.Labels = catalog
+	}
+	if fRepoConfig != "" {
+		config.Repos = repoconfig.NewLoader()
+		config.Repos.Path = fRepoConfig
+	}
+
+	if fSLALog != "" {
+		// Report breaches of the --triage-sla, even if the job is disabled.
+		d := fTriageSLA
+		if d <= 0 {
+			d = sla.DefaultSLA
+		}
+		tracker, err := sla.NewTracker(fSLALog, config.TriageLabel, d)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tracker.Publish("triage_sla")
+		config.SLA = tracker
+	}
+
+	if privateKey != "" || config.Digest != nil {
+		go startScheduler(config)
+	}
 
 	eventHandler := &webhook.Handler{
 		WebhookSecret:                 webhookSecret,
 		IssuesEvent:                   config.IssuesEvent,
-		InstallationEvent:             local.InstallationEvent,
-		InstallationRepositoriesEvent: local.InstallationRepositoriesEvent,
-		//ProjectCardEvent:              local.ProjectCardEvent,
+		IssueCommentEvent:             config.IssueCommentEvent,
+		PullRequestEvent:              config.PullRequestEvent,
+		InstallationEvent:             config.InstallationEvent,
+		InstallationRepositoriesEvent: config.InstallationRepositoriesEvent,
+		PushEvent:                     config.PushEvent,
+		ProjectCardEvent:              config.ProjectCardEvent,
+		ProjectV2ItemEvent:            config.ProjectV2ItemEvent,
+		CheckRunEvent:                 config.CheckRunEvent,
+		CheckSuiteEvent:               config.CheckSuiteEvent,
+		ReleaseEvent:                  config.ReleaseEvent,
 		//ProjectColumnEvent:            local.ProjectColumnEvent,
 		//ProjectEvent:                  local.ProjectEvent,
 	}
+	// closers flush the queued deliveries and stop the admin listener on
+	// shutdown.
+	var closers []func(ctx context.Context) error
+	if fRelay != "" {
+		relayConfig := &relay.Config{}
+		if err := configfile.Load(fRelay, relayConfig); err != nil {
+			log.Fatal(err)
+		}
+		r := relay.New(relayConfig)
+		if fRelayLog != "" {
+			r.Recorder = relay.NewStore(fRelayLog)
+		}
+		eventHandler.OnDelivery = append(eventHandler.OnDelivery, r.Deliver)
+		closers = append(closers, r.Close)
+	}
+	if fSink != "" {
+		s, err := sink.Open(fSink)
+		if err != nil {
+			log.Fatal(err)
+		}
+		a := sink.NewAsync(s, 1000)
+		eventHandler.OnDelivery = append(eventHandler.OnDelivery, a.Deliver)
+		closers = append(closers, func(ctx context.Context) error {
+			done := make(chan error, 1)
+			go func() { done <- a.Close() }()
+			select {
+			case err := <-done:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", usageHandler)
 	mux.Handle("/event_handler", eventHandler)
+	mux.HandleFunc("/statuses", statuses.Explain)
+	if config.SLA != nil && fAdminAddr != "" {
+		// The report lists issues of every installation, so it is not served
+		// on the public listener.
+		adminMux := http.NewServeMux()
+		adminMux.Handle("/triage", config.SLA)
+		adminMux.Handle("/debug/vars", expvar.Handler())
+		admin := &http.Server{Addr: fAdminAddr, Handler: adminMux}
+		closers = append(closers, admin.Shutdown)
+		go func() {
+			fmt.Println("Admin listening on ", fAdminAddr)
+			if err := admin.ListenAndServe(); err != http.ErrServerClosed {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	srv := &http.Server{Addr: fListenAddr, Handler: mux}
+	done := make(chan struct{})
+	go shutdown(srv, closers, done)
 
 	log.Println("Starting listeners")
+	var err error
 	if hostname != "" {
-		log.Fatal(http.Serve(autocert.NewListener(hostname), mux))
+		err = srv.Serve(autocert.NewListener(hostname))
 	} else {
 		fmt.Println("Listening on ", fListenAddr)
-		log.Fatal(http.ListenAndServe(fListenAddr, mux))
+		err = srv.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-done
+}
+
+// shutdown stops the server on SIGINT or SIGTERM, flushes the queued
+// deliveries with the closers, and then closes done.
+func shutdown(srv *http.Server, closers []func(ctx context.Context) error, done chan<- struct{}) {
+	defer close(done)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	<-sig
+	log.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	// Handlers finish before the closers run, so no delivery is queued after
+	// its queue is closed.
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Println("shutdown:", err)
+	}
+	for _, c := range closers {
+		if err := c(ctx); err != nil {
+			log.Println("shutdown:", err)
+		}
 	}
 }
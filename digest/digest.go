@@ -0,0 +1,389 @@
+// Package digest emails a periodic digest of issue activity.
+//
+// Issues events that add the triage label, add the current label or close an
+// issue are saved to a local store. Each time the digest is sent, the saved
+// entries are rendered with a text and an HTML template, emailed through an
+// SMTP server and removed from the store. For example:
+//
+//	smtp:
+//	  addr: smtp.example.com:587
+//	  username: receiver@example.com
+//	  password_env: SMTP_PASSWORD
+//	  timeout: 1m
+//	from: receiver@example.com
+//	to: [managers@example.com]
+//	subject: "Issue digest for {{.Date}}"
+package digest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/jsonl"
+)
+
+// Kinds of digest entries.
+const (
+	Triage  = "triage"
+	Current = "current"
+	Closed  = "closed"
+)
+
+// Entry is an issue event saved for the next digest.
+type Entry struct {
+	// Time the event was received.
+	Time time.Time `json:"time"`
+	// Kind is Triage, Current or Closed.
+	Kind string `json:"kind"`
+	// Repo is the full "owner/name" of the repository.
+	Repo string `json:"repo"`
+	// Issue number, title and URL.
+	Issue int    `json:"issue"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+	// Sender is the login of the user that caused the event.
+	Sender string `json:"sender,omitempty"`
+}
+
+// Store saves entries as JSON lines to a local file.
+type Store struct {
+	file *jsonl.File
+	// flushing serializes calls to Flush.
+	flushing sync.Mutex
+}
+
+// NewStore creates a new Store that saves entries to the given file name. The
+// file is created on the first call to Add.
+func NewStore(path string) *Store {
+	return &Store{file: jsonl.New(path)}
+}
+
+// Add appends the entry to the store file.
+func (s *Store) Add(e *Entry) error {
+	return s.file.Append(e)
+}
+
+// Flush calls fn with every saved entry, oldest first, and removes the
+// entries if fn succeeds. The entries are first moved to the file path+".flush",
+// so that Add never waits for fn, and entries added meanwhile are kept for the
+// next Flush. If fn fails, its entries are kept for the next Flush too.
+func (s *Store) Flush(fn func([]*Entry) error) error {
+	s.flushing.Lock()
+	defer s.flushing.Unlock()
+	pending := jsonl.New(s.file.Path() + ".flush")
+	if err := s.file.MoveTo(pending.Path()); err != nil {
+		return err
+	}
+	var entries []*Entry
+	err := pending.Read(func(line []byte) error {
+		e := &Entry{}
+		if err := json.Unmarshal(line, e); err != nil {
+			return err
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := fn(entries); err != nil {
+		return err
+	}
+	if err := os.Remove(pending.Path()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SMTP configures the mail server.
+type SMTP struct {
+	// Addr is the "host:port" of the server.
+	Addr string `yaml:"addr" json:"addr"`
+	// Username and Password authenticate with PLAIN auth. No authentication
+	// is used if Username is empty.
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	// PasswordEnv names the environment variable holding the password.
+	PasswordEnv string `yaml:"password_env,omitempty" json:"password_env,omitempty"`
+	// Timeout limits the whole exchange with the server. Defaults to one
+	// minute.
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// sendMail is smtp.SendMail, with a deadline of Timeout on the exchange.
+func (s SMTP) sendMail(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	conn, err := net.DialTimeout("tcp", addr, s.Timeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(s.Timeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(a); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Config is the digest configuration.
+type Config struct {
+	SMTP SMTP     `yaml:"smtp" json:"smtp"`
+	From string   `yaml:"from" json:"from"`
+	To   []string `yaml:"to" json:"to"`
+	// Subject is a text/template for the subject. Defaults to
+	// DefaultSubject.
+	Subject string `yaml:"subject,omitempty" json:"subject,omitempty"`
+	// Text and HTML are the text/template and html/template for the body.
+	// Default to DefaultText and DefaultHTML.
+	Text string `yaml:"text,omitempty" json:"text,omitempty"`
+	HTML string `yaml:"html,omitempty" json:"html,omitempty"`
+	// SendEmpty sends a digest even if there was no activity.
+	SendEmpty bool `yaml:"send_empty,omitempty" json:"send_empty,omitempty"`
+	// TriageLabel and CurrentLabel select the labeled events saved for the
+	// digest. Default to "review/triage" and "current".
+	TriageLabel  string `yaml:"triage_label,omitempty" json:"triage_label,omitempty"`
+	CurrentLabel string `yaml:"current_label,omitempty" json:"current_label,omitempty"`
+
+	templates *templates
+}
+
+// Validate checks the configuration, reads the password from the environment,
+// parses the templates and sets defaults.
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.SMTP.Addr); err != nil {
+		return fmt.Errorf("smtp: addr: %v", err)
+	}
+	if c.SMTP.PasswordEnv != "" {
+		if c.SMTP.Password != "" {
+			return fmt.Errorf("smtp: only one of password and password_env may be set")
+		}
+		c.SMTP.Password = os.Getenv(c.SMTP.PasswordEnv)
+	}
+	if c.SMTP.Timeout <= 0 {
+		c.SMTP.Timeout = time.Minute
+	}
+	if c.From == "" {
+		return fmt.Errorf("from must not be empty")
+	}
+	if len(c.To) == 0 {
+		return fmt.Errorf("to must not be empty")
+	}
+	if c.Subject == "" {
+		c.Subject = DefaultSubject
+	}
+	if c.Text == "" {
+		c.Text = DefaultText
+	}
+	if c.HTML == "" {
+		c.HTML = DefaultHTML
+	}
+	if c.TriageLabel == "" {
+		c.TriageLabel = "review/triage"
+	}
+	if c.CurrentLabel == "" {
+		c.CurrentLabel = "current"
+	}
+	t, err := parseTemplates(c)
+	if err != nil {
+		return err
+	}
+	c.templates = t
+	return nil
+}
+
+// Digest saves issue activity and emails it.
+type Digest struct {
+	*Config
+	Store *Store
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+
+	// sendMail sends the email. Tests replace it.
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// New creates a new Digest that saves activity in the store.
+func New(c *Config, store *Store) *Digest {
+	return &Digest{Config: c, Store: store, Now: time.Now, sendMail: c.SMTP.sendMail}
+}
+
+// IssuesEvent saves the event for the next digest if it adds the triage or
+// current label to an issue, or closes an issue.
+func (d *Digest) IssuesEvent(event *github.IssuesEvent) error {
+	var kind string
+	switch label := event.GetLabel().GetName(); {
+	case event.GetAction() == "closed":
+		kind = Closed
+	case event.GetAction() != "labeled":
+		return nil
+	case strings.EqualFold(label, d.TriageLabel):
+		kind = Triage
+	case strings.EqualFold(label, d.CurrentLabel):
+		kind = Current
+	default:
+		return nil
+	}
+	issue := event.GetIssue()
+	return d.Store.Add(&Entry{
+		Time:   d.Now(),
+		Kind:   kind,
+		Repo:   event.GetRepo().GetFullName(),
+		Issue:  issue.GetNumber(),
+		Title:  issue.GetTitle(),
+		URL:    issue.GetHTMLURL(),
+		Sender: event.GetSender().GetLogin(),
+	})
+}
+
+// Data is the input of the digest templates.
+type Data struct {
+	// Date the digest was sent, e.g. "2019-01-02".
+	Date string
+	// Triage, Current and Closed list the issues that entered triage, moved
+	// to current and closed, sorted by repository and number. An issue is
+	// listed once per kind.
+	Triage  []*Entry
+	Current []*Entry
+	Closed  []*Entry
+	// Total is the number of listed issues.
+	Total int
+}
+
+// NewData groups the entries for the templates.
+func NewData(date time.Time, entries []*Entry) *Data {
+	d := &Data{Date: date.Format("2006-01-02")}
+	seen := map[string]*Entry{}
+	for _, e := range entries {
+		k := fmt.Sprintf("%s %s#%d", e.Kind, strings.ToLower(e.Repo), e.Issue)
+		if prev, ok := seen[k]; ok {
+			*prev = *e
+			continue
+		}
+		e := *e
+		seen[k] = &e
+		switch e.Kind {
+		case Triage:
+			d.Triage = append(d.Triage, &e)
+		case Current:
+			d.Current = append(d.Current, &e)
+		case Closed:
+			d.Closed = append(d.Closed, &e)
+		default:
+			continue
+		}
+		d.Total++
+	}
+	for _, list := range [][]*Entry{d.Triage, d.Current, d.Closed} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Repo != list[j].Repo {
+				return list[i].Repo < list[j].Repo
+			}
+			return list[i].Issue < list[j].Issue
+		})
+	}
+	return d
+}
+
+// Send emails the saved activity and clears it. No email is sent if there was
+// no activity, unless SendEmpty is set.
+func (d *Digest) Send() error {
+	return d.Store.Flush(func(entries []*Entry) error {
+		data := NewData(d.Now(), entries)
+		if data.Total == 0 && !d.SendEmpty {
+			log.Println("digest: no activity")
+			return nil
+		}
+		msg, err := d.templates.message(d.Config, data)
+		if err != nil {
+			return err
+		}
+		var auth smtp.Auth
+		if d.SMTP.Username != "" {
+			host, _, _ := net.SplitHostPort(d.SMTP.Addr)
+			auth = smtp.PlainAuth("", d.SMTP.Username, d.SMTP.Password, host)
+		}
+		if err := d.sendMail(d.SMTP.Addr, auth, d.From, d.To, msg); err != nil {
+			return err
+		}
+		log.Printf("digest: sent %d issues to %q", data.Total, d.To)
+		return nil
+	})
+}
+
+// message returns the email with text and HTML parts.
+func (t *templates) message(c *Config, data *Data) ([]byte, error) {
+	var subject, text, html bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := t.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	const boundary = "digest-boundary-8a3f"
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", c.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", strings.TrimSpace(subject.String()))
+	fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", boundary, crlf(text.String()))
+	fmt.Fprintf(&b, "--%s\r\nContent-Type: text/html; charset=utf-8\r\n\r\n%s\r\n", boundary, crlf(html.String()))
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+// crlf converts line endings to CRLF, as SMTP requires.
+func crlf(s string) string {
+	return strings.Replace(strings.Replace(s, "\r\n", "\n", -1), "\n", "\r\n", -1)
+}
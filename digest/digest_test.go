@@ -0,0 +1,262 @@
+package digest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+// smtpServer is a local SMTP stand-in that accepts every message.
+type smtpServer struct {
+	net.Listener
+	mu       sync.Mutex
+	messages []string
+	rcpts    []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{Listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 localhost")
+		case "RCPT":
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, line)
+			s.mu.Unlock()
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 Go ahead")
+			b, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, string(b))
+			s.mu.Unlock()
+			tp.PrintfLine("250 OK")
+		case "QUIT":
+			tp.PrintfLine("221 Bye")
+			return
+		default:
+			tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func issuesEvent(action, label string, number int) *github.IssuesEvent {
+	return &github.IssuesEvent{
+		Action: github.String(action),
+		Label:  &github.Label{Name: github.String(label)},
+		Repo:   &github.Repository{FullName: github.String("o/r")},
+		Issue: &github.Issue{
+			Number:  github.Int(number),
+			Title:   github.String(fmt.Sprintf("Issue <%d>", number)),
+			HTMLURL: github.String(fmt.Sprintf("https://github.com/o/r/issues/%d", number)),
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	os.Setenv("DIGEST_TEST_PASSWORD", "secret")
+	defer os.Unsetenv("DIGEST_TEST_PASSWORD")
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "okay", config: "smtp: {addr: 'mail:25', username: u, password_env: DIGEST_TEST_PASSWORD}\nfrom: a@b\nto: [c@d]\n"},
+		{name: "bad-addr", config: "smtp: {addr: mail}\nfrom: a@b\nto: [c@d]\n", wantErr: true},
+		{name: "no-from", config: "smtp: {addr: 'mail:25'}\nto: [c@d]\n", wantErr: true},
+		{name: "no-to", config: "smtp: {addr: 'mail:25'}\nfrom: a@b\n", wantErr: true},
+		{name: "bad-template", config: "smtp: {addr: 'mail:25'}\nfrom: a@b\nto: [c@d]\nhtml: '{{.Date'\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := configfile.Parse([]byte(tt.config), c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (c.SMTP.Password != "secret" || c.Subject != DefaultSubject) {
+				t.Errorf("Parse() = %+v, want password from env and defaults", c)
+			}
+		})
+	}
+}
+
+func TestDigest_Send(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	srv := newSMTPServer(t)
+	defer srv.Close()
+
+	c := &Config{SMTP: SMTP{Addr: srv.Addr().String()}, From: "bot@example.com", To: []string{"a@example.com", "b@example.com"}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	d := New(c, NewStore(filepath.Join(dir, "digest.jsonl")))
+	d.Now = func() time.Time { return time.Date(2019, 1, 2, 8, 0, 0, 0, time.UTC) }
+
+	// Without activity, nothing is sent.
+	if err := d.Send(); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	for _, ev := range []*github.IssuesEvent{
+		issuesEvent("labeled", "review/triage", 2),
+		issuesEvent("labeled", "review/triage", 1),
+		issuesEvent("labeled", "review/triage", 1),
+		issuesEvent("labeled", "Current", 3),
+		issuesEvent("labeled", "bug", 4),
+		issuesEvent("edited", "", 5),
+		issuesEvent("closed", "", 6),
+	} {
+		if err := d.IssuesEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Send(); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if len(srv.messages) != 1 || len(srv.rcpts) != 2 {
+		t.Fatalf("Send() sent %d messages to %d recipients, want 1 to 2", len(srv.messages), len(srv.rcpts))
+	}
+	// The stand-in converts the CRLF line endings of the message to LF.
+	msg := srv.messages[0]
+	for _, want := range []string{
+		"Subject: Issue digest for 2019-01-02\n",
+		"Waiting for triage:\n- o/r#1 Issue <1>\n  https://github.com/o/r/issues/1\n- o/r#2 Issue <2>\n",
+		"Moved to current:\n- o/r#3 Issue <3>\n",
+		"Closed:\n- o/r#6 Issue <6>\n",
+		`<li><a href="https://github.com/o/r/issues/3">o/r#3</a> Issue &lt;3&gt;</li>`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Send() message = %q, want %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, "#4") || strings.Contains(msg, "#5") {
+		t.Errorf("Send() message = %q, want only triage, current and closed issues", msg)
+	}
+
+	// The sent entries are cleared.
+	if err := d.Send(); err != nil || len(srv.messages) != 1 {
+		t.Errorf("Send() again = %v, sent %d messages; want none", err, len(srv.messages)-1)
+	}
+}
+
+func TestDigest_Send_Timeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// The server accepts connections and never replies.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	c := &Config{SMTP: SMTP{Addr: l.Addr().String(), Timeout: 50 * time.Millisecond}, From: "bot@example.com", To: []string{"a@example.com"}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	d := New(c, NewStore(filepath.Join(dir, "digest.jsonl")))
+	d.IssuesEvent(issuesEvent("closed", "", 1))
+	done := make(chan error)
+	go func() { done <- d.Send() }()
+	// Issue events are saved while the digest is sent.
+	if err := d.IssuesEvent(issuesEvent("closed", "", 2)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Send() error = nil, want timeout")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Send() did not time out")
+	}
+	var got []*Entry
+	d.Store.Flush(func(entries []*Entry) error {
+		got = entries
+		return nil
+	})
+	if len(got) != 2 || got[0].Issue != 1 || got[1].Issue != 2 {
+		t.Errorf("Flush() = %v, want both entries in order", got)
+	}
+}
+
+func TestDigest_Send_Error(t *testing.T) {
+	dir, err := ioutil.TempDir("", "digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c := &Config{SMTP: SMTP{Addr: "localhost:25"}, From: "bot@example.com", To: []string{"a@example.com"}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	d := New(c, NewStore(filepath.Join(dir, "digest.jsonl")))
+	d.sendMail = func(string, smtp.Auth, string, []string, []byte) error { return fmt.Errorf("fake error") }
+	d.IssuesEvent(issuesEvent("closed", "", 1))
+	if err := d.Send(); err == nil {
+		t.Fatalf("Send() error = nil, want error")
+	}
+	// The entries are kept for the next attempt.
+	var got []*Entry
+	d.Store.Flush(func(entries []*Entry) error {
+		got = entries
+		return nil
+	})
+	if len(got) != 1 || got[0].Kind != Closed {
+		t.Errorf("Flush() = %v, want the closed entry", got)
+	}
+}
@@ -0,0 +1,65 @@
+package digest
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"text/template"
+)
+
+// DefaultSubject is the default subject template.
+const DefaultSubject = `Issue digest for {{.Date}}`
+
+// DefaultText is the default text body template.
+const DefaultText = `{{define "list"}}{{range .}}- {{.Repo}}#{{.Issue}} {{.Title}}
+  {{.URL}}
+{{else}}None.
+{{end}}{{end -}}
+Issue activity for {{.Date}}.
+
+Waiting for triage:
+{{template "list" .Triage}}
+Moved to current:
+{{template "list" .Current}}
+Closed:
+{{template "list" .Closed}}`
+
+// DefaultHTML is the default HTML body template.
+const DefaultHTML = `{{define "list"}}<ul>
+{{range .}}<li><a href="{{.URL}}">{{.Repo}}#{{.Issue}}</a> {{.Title}}</li>
+{{else}}<li>None.</li>
+{{end}}</ul>{{end -}}
+<html>
+<body>
+<p>Issue activity for {{.Date}}.</p>
+<h3>Waiting for triage</h3>
+{{template "list" .Triage}}
+<h3>Moved to current</h3>
+{{template "list" .Current}}
+<h3>Closed</h3>
+{{template "list" .Closed}}
+</body>
+</html>
+`
+
+// templates are the parsed templates of a Config.
+type templates struct {
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+func parseTemplates(c *Config) (*templates, error) {
+	subject, err := template.New("subject").Parse(c.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("subject: %v", err)
+	}
+	text, err := template.New("text").Parse(c.Text)
+	if err != nil {
+		return nil, fmt.Errorf("text: %v", err)
+	}
+	html, err := htmltemplate.New("html").Parse(c.HTML)
+	if err != nil {
+		return nil, fmt.Errorf("html: %v", err)
+	}
+	return &templates{subject: subject, text: text, html: html}, nil
+}
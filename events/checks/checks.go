@@ -0,0 +1,235 @@
+// Package checks gates pull requests with Github check runs.
+//
+// A Runner evaluates its rules on the head commit of a pull request and
+// reports each rule as a check run of the same name. The check runs are
+// created when a pull request is opened, edited or updated, and are evaluated
+// again when a user re-runs them from the Github UI. Marking a check run as
+// required in the branch protection settings blocks merges until it succeeds.
+//
+// The Checks API is only available to Github Apps.
+package checks
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/checks/iface"
+)
+
+// Conclusions of check runs.
+const (
+	Success        = "success"
+	Failure        = "failure"
+	Neutral        = "neutral"
+	ActionRequired = "action_required"
+)
+
+// MaxAnnotations is the number of annotations that Github accepts in one
+// check run update. Longer lists are sent in several updates.
+const MaxAnnotations = 50
+
+// Target is the pull request that a check run reports on.
+type Target struct {
+	Owner       string
+	Repo        string
+	PullRequest *github.PullRequest
+}
+
+// Result is the outcome of a rule.
+type Result struct {
+	// Conclusion is one of the conclusions above.
+	Conclusion string
+	// Title and Summary are shown on the pull request. Both are required.
+	Title   string
+	Summary string
+	// Text, if set, gives details in Markdown.
+	Text string
+	// Annotations point at lines of the changed files.
+	Annotations []*github.CheckRunAnnotation
+}
+
+// Rule evaluates a pull request.
+type Rule interface {
+	// Name is the name of the check run, and must be unique in a Runner.
+	Name() string
+	// Check evaluates the target. An error completes the check run with a
+	// failure, so that it can be re-run once the error is fixed.
+	Check(ctx context.Context, checks iface.Checks, t *Target) (*Result, error)
+}
+
+// Runner reports rules as check runs.
+type Runner struct {
+	Rules []Rule
+
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewRunner creates a Runner for the given rules.
+func NewRunner(rules ...Rule) *Runner {
+	return &Runner{Rules: rules, Now: time.Now}
+}
+
+// PullRequestEvent runs every rule when a pull request is opened, edited,
+// reopened or gets new commits.
+func (r *Runner) PullRequestEvent(ctx context.Context, checks iface.Checks, event *github.PullRequestEvent) error {
+	switch event.GetAction() {
+	case "opened", "edited", "reopened", "synchronize", "ready_for_review":
+	default:
+		return nil
+	}
+	t := &Target{
+		Owner:       event.GetRepo().GetOwner().GetLogin(),
+		Repo:        event.GetRepo().GetName(),
+		PullRequest: event.GetPullRequest(),
+	}
+	return r.Run(ctx, checks, t, r.Rules)
+}
+
+// CheckSuiteEvent runs every rule again when a user re-runs all checks of the
+// pull requests of a commit.
+func (r *Runner) CheckSuiteEvent(ctx context.Context, checks iface.Checks, event *github.CheckSuiteEvent) error {
+	if !isRerequested(event.GetAction()) {
+		return nil
+	}
+	return r.rerun(ctx, checks, event.GetRepo(), event.GetCheckSuite().PullRequests, r.Rules)
+}
+
+// CheckRunEvent runs a rule again when a user re-runs its check run. Check
+// runs of other names are ignored.
+func (r *Runner) CheckRunEvent(ctx context.Context, checks iface.Checks, event *github.CheckRunEvent) error {
+	if !isRerequested(event.GetAction()) {
+		return nil
+	}
+	rule := r.rule(event.GetCheckRun().GetName())
+	if rule == nil {
+		return nil
+	}
+	return r.rerun(ctx, checks, event.GetRepo(), event.GetCheckRun().PullRequests, []Rule{rule})
+}
+
+// rerun reads the current state of the pull requests, which webhook payloads
+// of checks only identify, and runs the rules.
+func (r *Runner) rerun(ctx context.Context, checks iface.Checks, repo *github.Repository, prs []*github.PullRequest, rules []Rule) error {
+	owner, name := repo.GetOwner().GetLogin(), repo.GetName()
+	for _, pr := range prs {
+		full, _, err := checks.GetPullRequest(ctx, owner, name, pr.GetNumber())
+		if err != nil {
+			return err
+		}
+		if err := r.Run(ctx, checks, &Target{Owner: owner, Repo: name, PullRequest: full}, rules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run reports the rules as check runs on the head commit of the target pull
+// request. Every rule runs, even if an earlier one fails to report; the first
+// error is returned.
+func (r *Runner) Run(ctx context.Context, checks iface.Checks, t *Target, rules []Rule) error {
+	var first error
+	for _, rule := range rules {
+		if err := r.run(ctx, checks, t, rule); err != nil {
+			log.Printf("checks: %s on %s/%s#%d: %v", rule.Name(), t.Owner, t.Repo, t.PullRequest.GetNumber(), err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// run creates an in progress check run, evaluates the rule and completes the
+// check run with the result.
+func (r *Runner) run(ctx context.Context, checks iface.Checks, t *Target, rule Rule) error {
+	head := t.PullRequest.GetHead()
+	started := github.Timestamp{Time: r.now()}
+	run, _, err := checks.CreateCheckRun(ctx, t.Owner, t.Repo, github.CreateCheckRunOptions{
+		Name:       rule.Name(),
+		HeadBranch: head.GetRef(),
+		HeadSHA:    head.GetSHA(),
+		Status:     github.String("in_progress"),
+		StartedAt:  &started,
+	})
+	if err != nil {
+		return err
+	}
+	res, err := rule.Check(ctx, checks, t)
+	if err != nil {
+		res = &Result{
+			Conclusion: Failure,
+			Title:      "Error",
+			Summary:    "The check could not be evaluated: " + err.Error() + "\n\nRe-run the check to try again.",
+		}
+	}
+	batch, rest := splitAnnotations(res.Annotations)
+	completed := github.Timestamp{Time: r.now()}
+	_, _, err = checks.UpdateCheckRun(ctx, t.Owner, t.Repo, run.GetID(), github.UpdateCheckRunOptions{
+		Name:        rule.Name(),
+		Status:      github.String("completed"),
+		Conclusion:  github.String(res.Conclusion),
+		CompletedAt: &completed,
+		Output:      output(res, batch),
+	})
+	if err != nil {
+		return err
+	}
+	// Github appends the annotations of every update to the check run.
+	for len(rest) > 0 {
+		batch, rest = splitAnnotations(rest)
+		_, _, err = checks.UpdateCheckRun(ctx, t.Owner, t.Repo, run.GetID(), github.UpdateCheckRunOptions{
+			Name:   rule.Name(),
+			Output: output(res, batch),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("checks: %s on %s/%s#%d: %s", rule.Name(), t.Owner, t.Repo, t.PullRequest.GetNumber(), res.Conclusion)
+	return nil
+}
+
+func (r *Runner) rule(name string) Rule {
+	for _, rule := range r.Rules {
+		if rule.Name() == name {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *Runner) now() time.Time {
+	if r.Now == nil {
+		return time.Now()
+	}
+	return r.Now()
+}
+
+func output(res *Result, annotations []*github.CheckRunAnnotation) *github.CheckRunOutput {
+	o := &github.CheckRunOutput{
+		Title:       github.String(res.Title),
+		Summary:     github.String(res.Summary),
+		Annotations: annotations,
+	}
+	if res.Text != "" {
+		o.Text = github.String(res.Text)
+	}
+	return o
+}
+
+// splitAnnotations returns the annotations of the next update, and the rest.
+func splitAnnotations(a []*github.CheckRunAnnotation) ([]*github.CheckRunAnnotation, []*github.CheckRunAnnotation) {
+	if len(a) > MaxAnnotations {
+		return a[:MaxAnnotations], a[MaxAnnotations:]
+	}
+	return a, nil
+}
+
+// isRerequested reports whether the action re-runs checks. Github sends
+// "rerequested"; older documentation spells it "re-requested".
+func isRerequested(action string) bool {
+	return action == "rerequested" || action == "re-requested"
+}
@@ -0,0 +1,252 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/checks/iface"
+)
+
+type fakeChecks struct {
+	pulls   map[int]*github.PullRequest
+	issues  map[string]*github.Issue
+	created []github.CreateCheckRunOptions
+	updated []github.UpdateCheckRunOptions
+	err     error
+}
+
+func (f *fakeChecks) CreateCheckRun(
+	ctx context.Context, owner string, repo string,
+	opt github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	f.created = append(f.created, opt)
+	id := int64(len(f.created))
+	return &github.CheckRun{ID: &id, Name: &opt.Name}, nil, f.err
+}
+
+func (f *fakeChecks) UpdateCheckRun(
+	ctx context.Context, owner string, repo string, id int64,
+	opt github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	f.updated = append(f.updated, opt)
+	return nil, nil, f.err
+}
+
+func (f *fakeChecks) GetPullRequest(
+	ctx context.Context, owner string, repo string, number int) (*github.PullRequest, *github.Response, error) {
+	pr, ok := f.pulls[number]
+	if !ok {
+		return nil, &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+			fmt.Errorf("not found")
+	}
+	return pr, nil, nil
+}
+
+func (f *fakeChecks) GetIssue(
+	ctx context.Context, owner string, repo string, number int) (*github.Issue, *github.Response, error) {
+	issue, ok := f.issues[fmt.Sprintf("%s/%s#%d", owner, repo, number)]
+	if !ok {
+		return nil, &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}},
+			fmt.Errorf("not found")
+	}
+	return issue, nil, f.err
+}
+
+var _ iface.Checks = &fakeChecks{}
+
+// fakeRule returns a fixed result or error.
+type fakeRule struct {
+	name   string
+	result *Result
+	err    error
+}
+
+func (r *fakeRule) Name() string { return r.name }
+
+func (r *fakeRule) Check(ctx context.Context, checks iface.Checks, t *Target) (*Result, error) {
+	return r.result, r.err
+}
+
+func newPullRequest(number int, sha, title, body string) *github.PullRequest {
+	return &github.PullRequest{
+		Number: &number,
+		Title:  &title,
+		Body:   &body,
+		Head:   &github.PullRequestBranch{Ref: github.String("feature"), SHA: &sha},
+	}
+}
+
+func newRepo() *github.Repository {
+	return &github.Repository{Name: github.String("r"), Owner: &github.User{Login: github.String("o")}}
+}
+
+func annotations(n int) []*github.CheckRunAnnotation {
+	var a []*github.CheckRunAnnotation
+	for i := 0; i < n; i++ {
+		a = append(a, &github.CheckRunAnnotation{FileName: github.String(fmt.Sprintf("f%d.go", i))})
+	}
+	return a
+}
+
+func TestRunner_PullRequestEvent(t *testing.T) {
+	pass := &fakeRule{name: "pass", result: &Result{Conclusion: Success, Title: "ok", Summary: "ok"}}
+	broken := &fakeRule{name: "broken", err: fmt.Errorf("boom")}
+	many := &fakeRule{name: "many", result: &Result{
+		Conclusion: Failure, Title: "lint", Summary: "lint", Annotations: annotations(120)}}
+	tests := []struct {
+		name          string
+		action        string
+		rules         []Rule
+		wantCreated   []string
+		wantConcluded []string
+		wantBatches   []int
+	}{
+		{
+			name:          "opened",
+			action:        "opened",
+			rules:         []Rule{pass, broken},
+			wantCreated:   []string{"pass", "broken"},
+			wantConcluded: []string{Success, Failure},
+			wantBatches:   []int{0, 0},
+		},
+		{
+			name:          "synchronize-annotations",
+			action:        "synchronize",
+			rules:         []Rule{many},
+			wantCreated:   []string{"many"},
+			wantConcluded: []string{Failure, "", ""},
+			wantBatches:   []int{50, 50, 20},
+		},
+		{
+			name:   "closed",
+			action: "closed",
+			rules:  []Rule{pass},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeChecks{}
+			now := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+			r := NewRunner(tt.rules...)
+			r.Now = func() time.Time { return now }
+			event := &github.PullRequestEvent{
+				Action:      &tt.action,
+				Repo:        newRepo(),
+				PullRequest: newPullRequest(1, "abc", "title", ""),
+			}
+			if err := r.PullRequestEvent(context.Background(), f, event); err != nil {
+				t.Fatalf("PullRequestEvent() error = %v", err)
+			}
+			var created, concluded []string
+			var batches []int
+			for _, c := range f.created {
+				if c.HeadSHA != "abc" || c.GetStatus() != "in_progress" || !c.GetStartedAt().Time.Equal(now) {
+					t.Errorf("CreateCheckRun(%+v), want in_progress on abc", c)
+				}
+				created = append(created, c.Name)
+			}
+			for _, u := range f.updated {
+				concluded = append(concluded, u.GetConclusion())
+				batches = append(batches, len(u.GetOutput().Annotations))
+			}
+			if !reflect.DeepEqual(created, tt.wantCreated) {
+				t.Errorf("created = %q, want %q", created, tt.wantCreated)
+			}
+			if !reflect.DeepEqual(concluded, tt.wantConcluded) {
+				t.Errorf("concluded = %q, want %q", concluded, tt.wantConcluded)
+			}
+			if !reflect.DeepEqual(batches, tt.wantBatches) {
+				t.Errorf("annotation batches = %v, want %v", batches, tt.wantBatches)
+			}
+		})
+	}
+}
+
+func TestRunner_Rerequested(t *testing.T) {
+	pass := &fakeRule{name: "pass", result: &Result{Conclusion: Success, Title: "ok", Summary: "ok"}}
+	other := &fakeRule{name: "other", result: &Result{Conclusion: Neutral, Title: "ok", Summary: "ok"}}
+	pulls := map[int]*github.PullRequest{2: newPullRequest(2, "def", "title", "")}
+	prs := []*github.PullRequest{{Number: github.Int(2)}}
+	tests := []struct {
+		name  string
+		event func(r *Runner, f *fakeChecks) error
+		want  []string
+	}{
+		{
+			name: "check-run",
+			event: func(r *Runner, f *fakeChecks) error {
+				return r.CheckRunEvent(context.Background(), f, &github.CheckRunEvent{
+					Action:   github.String("rerequested"),
+					Repo:     newRepo(),
+					CheckRun: &github.CheckRun{Name: github.String("other"), PullRequests: prs},
+				})
+			},
+			want: []string{"other"},
+		},
+		{
+			name: "check-run-unknown",
+			event: func(r *Runner, f *fakeChecks) error {
+				return r.CheckRunEvent(context.Background(), f, &github.CheckRunEvent{
+					Action:   github.String("rerequested"),
+					Repo:     newRepo(),
+					CheckRun: &github.CheckRun{Name: github.String("ci"), PullRequests: prs},
+				})
+			},
+		},
+		{
+			name: "check-run-created",
+			event: func(r *Runner, f *fakeChecks) error {
+				return r.CheckRunEvent(context.Background(), f, &github.CheckRunEvent{
+					Action:   github.String("created"),
+					Repo:     newRepo(),
+					CheckRun: &github.CheckRun{Name: github.String("pass"), PullRequests: prs},
+				})
+			},
+		},
+		{
+			name: "check-suite",
+			event: func(r *Runner, f *fakeChecks) error {
+				return r.CheckSuiteEvent(context.Background(), f, &github.CheckSuiteEvent{
+					Action:     github.String("rerequested"),
+					Repo:       newRepo(),
+					CheckSuite: &github.CheckSuite{PullRequests: prs},
+				})
+			},
+			want: []string{"pass", "other"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeChecks{pulls: pulls}
+			if err := tt.event(NewRunner(pass, other), f); err != nil {
+				t.Fatalf("event error = %v", err)
+			}
+			var got []string
+			for _, c := range f.created {
+				if c.HeadSHA != "def" {
+					t.Errorf("CreateCheckRun(%+v), want head def", c)
+				}
+				got = append(got, c.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("created = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunner_Run_error(t *testing.T) {
+	f := &fakeChecks{err: fmt.Errorf("forbidden")}
+	pass := &fakeRule{name: "pass", result: &Result{Conclusion: Success, Title: "ok", Summary: "ok"}}
+	r := NewRunner(pass, pass)
+	err := r.Run(context.Background(), f, &Target{Owner: "o", Repo: "r", PullRequest: newPullRequest(1, "abc", "", "")}, r.Rules)
+	if err == nil {
+		t.Errorf("Run() error = nil, want error")
+	}
+	if len(f.created) != 2 {
+		t.Errorf("created %d check runs, want every rule attempted", len(f.created))
+	}
+}
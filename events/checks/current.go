@@ -0,0 +1,88 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/stephen-soltesz/github-webhook-poc/events/checks/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
+)
+
+// DefaultCurrentIssueName is the check run name of the CurrentIssue rule.
+const DefaultCurrentIssueName = "current-issue"
+
+// CurrentIssue requires pull requests to reference an open or closed issue
+// with the current label, so that only work planned for the current
+// iteration is merged.
+type CurrentIssue struct {
+	// Label marks issues of the current iteration, e.g. "current".
+	Label string
+	// CheckName is the name of the check run. Defaults to
+	// DefaultCurrentIssueName.
+	CheckName string
+}
+
+// Name returns the check run name.
+func (c *CurrentIssue) Name() string {
+	if c.CheckName == "" {
+		return DefaultCurrentIssueName
+	}
+	return c.CheckName
+}
+
+// Check succeeds when the title or body of the pull request references an
+// issue with the label. References to pull requests and to missing issues are
+// ignored.
+func (c *CurrentIssue) Check(ctx context.Context, checks iface.Checks, t *Target) (*Result, error) {
+	pr := t.PullRequest
+	refs := issues.Refs(t.Owner, t.Repo, pr.GetTitle()+"\n"+pr.GetBody())
+	var text bytes.Buffer
+	var found *issues.Ref
+	for i, ref := range refs {
+		if ref.Owner == t.Owner && ref.Repo == t.Repo && ref.Number == pr.GetNumber() {
+			continue
+		}
+		issue, resp, err := checks.GetIssue(ctx, ref.Owner, ref.Repo, ref.Number)
+		if resp != nil && resp.Response != nil && resp.StatusCode == http.StatusNotFound {
+			fmt.Fprintf(&text, "- %s: not found\n", ref)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if issue.IsPullRequest() {
+			fmt.Fprintf(&text, "- %s: is a pull request\n", ref)
+			continue
+		}
+		var labels []string
+		for _, l := range issue.Labels {
+			labels = append(labels, l.GetName())
+		}
+		fmt.Fprintf(&text, "- %s %q: labels %q\n", ref, issue.GetTitle(), labels)
+		if found == nil && slice.ContainsFold(labels, c.Label) {
+			found = &refs[i]
+		}
+	}
+	if found != nil {
+		return &Result{
+			Conclusion: Success,
+			Title:      fmt.Sprintf("References %s", found),
+			Summary:    fmt.Sprintf("This pull request references %s, which is labeled %q.", found, c.Label),
+			Text:       text.String(),
+		}, nil
+	}
+	res := &Result{
+		Conclusion: Failure,
+		Title:      fmt.Sprintf("No %q issue", c.Label),
+		Summary: fmt.Sprintf("Reference an issue labeled %q in the title or description of this pull request, "+
+			"e.g. \"Fixes #123\". If the issue is labeled later, re-run this check.", c.Label),
+		Text: text.String(),
+	}
+	if text.Len() == 0 {
+		res.Text = "No issue references were found."
+	}
+	return res, nil
+}
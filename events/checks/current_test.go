@@ -0,0 +1,86 @@
+package checks
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func newIssue(title string, pr bool, labels ...string) *github.Issue {
+	issue := &github.Issue{Title: &title}
+	for _, l := range labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: github.String(l)})
+	}
+	if pr {
+		issue.PullRequestLinks = &github.PullRequestLinks{}
+	}
+	return issue
+}
+
+func TestCurrentIssue_Check(t *testing.T) {
+	issues := map[string]*github.Issue{
+		"o/r#1":     newIssue("backlog", false, "review/triage"),
+		"o/r#2":     newIssue("planned", false, "Current"),
+		"o/r#3":     newIssue("other pr", true, "current"),
+		"o/other#4": newIssue("elsewhere", false, "current"),
+	}
+	tests := []struct {
+		name      string
+		title     string
+		body      string
+		want      string
+		wantTitle string
+		wantText  string
+	}{
+		{
+			name:      "current",
+			title:     "Add feature (#1)",
+			body:      "Fixes #2",
+			want:      Success,
+			wantTitle: "References o/r#2",
+			wantText:  `- o/r#1 "backlog": labels ["review/triage"]`,
+		},
+		{
+			name:      "cross-repo",
+			body:      "Part of o/other#4",
+			want:      Success,
+			wantTitle: "References o/other#4",
+		},
+		{
+			name:      "not-current",
+			body:      "Fixes #1, see #3 and #99. This is #10.",
+			want:      Failure,
+			wantTitle: `No "current" issue`,
+			wantText:  "- o/r#3: is a pull request\n- o/r#99: not found\n",
+		},
+		{
+			name:      "no-refs",
+			title:     "Fix typo",
+			want:      Failure,
+			wantTitle: `No "current" issue`,
+			wantText:  "No issue references were found.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeChecks{issues: issues}
+			rule := &CurrentIssue{Label: "current"}
+			target := &Target{Owner: "o", Repo: "r", PullRequest: newPullRequest(10, "abc", tt.title, tt.body)}
+			res, err := rule.Check(context.Background(), f, target)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if res.Conclusion != tt.want || res.Title != tt.wantTitle || res.Summary == "" {
+				t.Errorf("Check() = %+v, want %s %q", res, tt.want, tt.wantTitle)
+			}
+			if !strings.Contains(res.Text, tt.wantText) {
+				t.Errorf("Check() text = %q, want %q", res.Text, tt.wantText)
+			}
+		})
+	}
+	if got := (&CurrentIssue{}).Name(); got != DefaultCurrentIssueName {
+		t.Errorf("Name() = %q, want %q", got, DefaultCurrentIssueName)
+	}
+}
@@ -0,0 +1,58 @@
+package iface
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// Checks defines the interface used by the check run logic. Pull requests and
+// the issues they reference are read to evaluate the checks.
+type Checks interface {
+	CreateCheckRun(ctx context.Context, owner string, repo string, opt github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error)
+	UpdateCheckRun(ctx context.Context, owner string, repo string, id int64, opt github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error)
+	GetPullRequest(ctx context.Context, owner string, repo string, number int) (*github.PullRequest, *github.Response, error)
+	GetIssue(ctx context.Context, owner string, repo string, number int) (*github.Issue, *github.Response, error)
+}
+
+// ChecksImpl implements the Checks interface.
+type ChecksImpl struct {
+	checks *github.ChecksService
+	pulls  *github.PullRequestsService
+	issues *github.IssuesService
+}
+
+// NewChecks creates a new Checks instance using the services of client.
+func NewChecks(client *github.Client) *ChecksImpl {
+	return &ChecksImpl{
+		checks: client.Checks,
+		pulls:  client.PullRequests,
+		issues: client.Issues,
+	}
+}
+
+// CreateCheckRun creates a check run in the owner repo.
+func (c *ChecksImpl) CreateCheckRun(
+	ctx context.Context, owner string, repo string,
+	opt github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	return c.checks.CreateCheckRun(ctx, owner, repo, opt)
+}
+
+// UpdateCheckRun updates the status, conclusion or output of a check run.
+func (c *ChecksImpl) UpdateCheckRun(
+	ctx context.Context, owner string, repo string, id int64,
+	opt github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	return c.checks.UpdateCheckRun(ctx, owner, repo, id, opt)
+}
+
+// GetPullRequest reads the repo pull request.
+func (c *ChecksImpl) GetPullRequest(
+	ctx context.Context, owner string, repo string, number int) (*github.PullRequest, *github.Response, error) {
+	return c.pulls.Get(ctx, owner, repo, number)
+}
+
+// GetIssue reads the repo issue.
+func (c *ChecksImpl) GetIssue(
+	ctx context.Context, owner string, repo string, number int) (*github.Issue, *github.Response, error) {
+	return c.issues.Get(ctx, owner, repo, number)
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Default returns a Registry with the built-in commands:
+//
+//	/label <label>...      add labels
+//	/unlabel <label>...    remove labels
+//	/assign [@user...]     assign users, or the commenter
+//	/close                 close the issue
+//	/current               schedule for the current iteration
+//	/backlog               move to the backlog
+//	/help                  list commands
+//
+// The /current and /backlog commands move issues between the given current
+// and backlog labels.
+func Default(currentLabel, backlogLabel string) *Registry {
+	r := NewRegistry()
+	for _, spec := range []Spec{
+		{Name: "label", Usage: "<label>... - add labels", Permission: Triage, Run: runLabel},
+		{Name: "unlabel", Usage: "<label>... - remove labels", Permission: Triage, Run: runUnlabel},
+		{Name: "assign", Usage: "[@user...] - assign users, or yourself", Permission: Triage, Run: runAssign},
+		{Name: "close", Usage: "- close the issue", Permission: Triage, Run: runClose},
+		{Name: "current", Usage: "- schedule for the current iteration", Permission: Triage, Run: move(currentLabel, backlogLabel)},
+		{Name: "backlog", Usage: "- move to the backlog", Permission: Triage, Run: move(backlogLabel, currentLabel)},
+		{Name: "help", Usage: "- list commands", Permission: Read, Run: r.runHelp},
+	} {
+		if err := r.Register(spec); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+func runLabel(ctx context.Context, ev *Event, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: /label <label>...")
+	}
+	_, _, err := ev.AddIssueLabels(ctx, args)
+	return "", err
+}
+
+func runUnlabel(ctx context.Context, ev *Event, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("usage: /unlabel <label>...")
+	}
+	_, err := ev.RemoveIssueLabels(ctx, args)
+	return "", err
+}
+
+func runAssign(ctx context.Context, ev *Event, args []string) (string, error) {
+	var users []string
+	for _, a := range args {
+		users = append(users, strings.TrimPrefix(a, "@"))
+	}
+	if len(users) == 0 {
+		users = []string{ev.GetSender().GetLogin()}
+	}
+	_, _, err := ev.AddAssignees(ctx, users)
+	return "", err
+}
+
+func runClose(ctx context.Context, ev *Event, args []string) (string, error) {
+	_, _, err := ev.CloseIssue(ctx, nil)
+	return "", err
+}
+
+// move returns a Handler that adds the label add and removes the label remove.
+func move(add, remove string) Handler {
+	return func(ctx context.Context, ev *Event, args []string) (string, error) {
+		_, err := ev.ReconcileLabels(ctx, []string{add}, []string{remove})
+		return "", err
+	}
+}
+
+func (r *Registry) runHelp(ctx context.Context, ev *Event, args []string) (string, error) {
+	lines := []string{"Available commands:", ""}
+	for _, spec := range r.Specs() {
+		lines = append(lines, fmt.Sprintf("* `/%s` %s (%s)", spec.Name, spec.Usage, spec.Permission))
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,306 @@
+// Package commands runs slash commands found in issue and pull request
+// comments, such as "/label bug" or "/close".
+//
+// A command is a comment line that starts with "/" followed by the command
+// name and optional arguments. Arguments are separated by spaces and may be
+// double quoted, e.g. `/label "good first issue"`. Lines in code blocks or
+// quotes are ignored, as are unknown commands, so comments may mention paths
+// like /usr/bin without effect.
+//
+// Every command requires a minimum repository permission of the commenter. The
+// permission API reports the triage and maintain roles as read and write, so
+// in practice Triage commands need write access. The bot acknowledges the
+// comment with a reaction: "+1" when every command succeeds, "-1" when one was
+// denied and "confused" when one failed, with a reply comment explaining
+// denials and failures.
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+)
+
+// Reactions used to acknowledge commands.
+const (
+	ReactionOK     = "+1"
+	ReactionDenied = "-1"
+	ReactionFailed = "confused"
+)
+
+// Permission is a repository permission level. Higher levels include lower ones.
+type Permission int
+
+// Permission levels, in increasing order.
+const (
+	None Permission = iota
+	Read
+	Triage
+	Write
+	Maintain
+	Admin
+)
+
+var permissionNames = []string{"none", "read", "triage", "write", "maintain", "admin"}
+
+// ParsePermission returns the named permission level, or None if the name is
+// unknown.
+func ParsePermission(name string) Permission {
+	for i, n := range permissionNames {
+		if strings.EqualFold(n, name) {
+			return Permission(i)
+		}
+	}
+	return None
+}
+
+func (p Permission) String() string {
+	if p < None || int(p) >= len(permissionNames) {
+		return fmt.Sprintf("Permission(%d)", int(p))
+	}
+	return permissionNames[p]
+}
+
+// Command is a single slash command parsed from a comment.
+type Command struct {
+	// Name is the lower case command name, without the leading "/".
+	Name string
+	// Args are the command arguments, with quotes removed.
+	Args []string
+}
+
+func (c Command) String() string {
+	return strings.Join(append([]string{"/" + c.Name}, c.Args...), " ")
+}
+
+// Parse returns the commands in a comment body, in order.
+func Parse(body string) []Command {
+	var cmds []Command
+	fenced := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced || len(line) < 2 || line[0] != '/' || !isLetter(line[1]) {
+			continue
+		}
+		fields := splitArgs(line[1:])
+		if !validName(fields[0]) {
+			continue
+		}
+		cmds = append(cmds, Command{Name: strings.ToLower(fields[0]), Args: fields[1:]})
+	}
+	return cmds
+}
+
+func isLetter(b byte) bool {
+	return ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// validName reports whether name is a letter followed by letters, digits,
+// "-" or "_".
+func validName(name string) bool {
+	if name == "" || !isLetter(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		c := name[i]
+		if !isLetter(c) && !('0' <= c && c <= '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// splitArgs splits s on spaces, keeping double quoted strings together.
+func splitArgs(s string) []string {
+	var args []string
+	var cur strings.Builder
+	quoted, started := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if started {
+				args = append(args, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// Event is an issue comment that may contain commands.
+type Event struct {
+	// Event performs changes to the commented issue or pull request.
+	*issues.Event
+	// Comment is the comment containing the commands.
+	Comment *github.IssueComment
+
+	Repos     Repos
+	Reactions Reactions
+}
+
+// NewEvent creates a new Event for the comment on the issue of ev.
+func NewEvent(ev *issues.Event, comment *github.IssueComment, repos Repos, reactions Reactions) *Event {
+	return &Event{
+		Event:     ev,
+		Comment:   comment,
+		Repos:     repos,
+		Reactions: reactions,
+	}
+}
+
+// permission returns the repository permission of the commenter.
+func (ev *Event) permission(ctx context.Context) (Permission, error) {
+	level, _, err := ev.Repos.GetPermissionLevel(ctx,
+		ev.GetRepo().GetOwner().GetLogin(), ev.GetRepo().GetName(), ev.GetSender().GetLogin())
+	if err != nil {
+		return None, err
+	}
+	return ParsePermission(level.GetPermission()), nil
+}
+
+// react adds a reaction to the comment.
+func (ev *Event) react(ctx context.Context, content string) error {
+	_, _, err := ev.Reactions.CreateIssueCommentReaction(ctx,
+		ev.GetRepo().GetOwner().GetLogin(), ev.GetRepo().GetName(), ev.Comment.GetID(), content)
+	return err
+}
+
+// Handler runs a command with the given arguments. A non-empty reply is
+// posted as a comment.
+type Handler func(ctx context.Context, ev *Event, args []string) (reply string, err error)
+
+// Spec describes a command.
+type Spec struct {
+	// Name is the command name, without the leading "/".
+	Name string
+	// Usage describes the arguments and effect, e.g. "<label>... - add labels".
+	Usage string
+	// Permission is the minimum repository permission needed to run the command.
+	Permission Permission
+	// Run runs the command.
+	Run Handler
+}
+
+// Registry is a set of commands.
+type Registry struct {
+	specs map[string]Spec
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{specs: map[string]Spec{}}
+}
+
+// Register adds a command to the registry. Names are case insensitive and
+// must be unique.
+func (r *Registry) Register(spec Spec) error {
+	spec.Name = strings.ToLower(spec.Name)
+	if !validName(spec.Name) {
+		return fmt.Errorf("invalid command name %q", spec.Name)
+	}
+	if spec.Run == nil {
+		return fmt.Errorf("command %q: missing handler", spec.Name)
+	}
+	if _, ok := r.specs[spec.Name]; ok {
+		return fmt.Errorf("command %q already registered", spec.Name)
+	}
+	r.specs[spec.Name] = spec
+	return nil
+}
+
+// Lookup returns the named command.
+func (r *Registry) Lookup(name string) (Spec, bool) {
+	spec, ok := r.specs[strings.ToLower(name)]
+	return spec, ok
+}
+
+// Specs returns all registered commands, sorted by name.
+func (r *Registry) Specs() []Spec {
+	specs := make([]Spec, 0, len(r.specs))
+	for _, s := range r.specs {
+		specs = append(specs, s)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// Handle runs the registered commands in the event comment and acknowledges
+// them. Comments from bots are ignored. Handle returns the number of commands
+// found and the last error.
+func (r *Registry) Handle(ctx context.Context, ev *Event) (int, error) {
+	if ev.GetSender().GetType() == "Bot" {
+		return 0, nil
+	}
+	var cmds []Command
+	for _, cmd := range Parse(ev.Comment.GetBody()) {
+		if _, ok := r.Lookup(cmd.Name); ok {
+			cmds = append(cmds, cmd)
+		}
+	}
+	if len(cmds) == 0 {
+		return 0, nil
+	}
+	perm, err := ev.permission(ctx)
+	if err != nil {
+		return len(cmds), err
+	}
+
+	var replies []string
+	var lastErr error
+	reaction := ReactionOK
+	for _, cmd := range cmds {
+		spec, _ := r.Lookup(cmd.Name)
+		if perm < spec.Permission {
+			log.Printf("commands: %s denied to %s (%s)", cmd, ev.GetSender().GetLogin(), perm)
+			replies = append(replies, fmt.Sprintf("`%s` requires %s permission.", cmd, spec.Permission))
+			if reaction == ReactionOK {
+				reaction = ReactionDenied
+			}
+			continue
+		}
+		log.Printf("commands: %s by %s on %s", cmd, ev.GetSender().GetLogin(), ev.GetIssue().GetHTMLURL())
+		ev.Rule = "command:" + spec.Name
+		reply, err := spec.Run(ctx, ev, cmd.Args)
+		if err != nil {
+			log.Printf("commands: %s: %v", cmd, err)
+			replies = append(replies, fmt.Sprintf("`%s` failed: %v", cmd, err))
+			reaction = ReactionFailed
+			lastErr = err
+			continue
+		}
+		if reply != "" {
+			replies = append(replies, reply)
+		}
+	}
+	ev.Rule = ""
+
+	if err := ev.react(ctx, reaction); err != nil {
+		lastErr = err
+	}
+	if len(replies) > 0 {
+		body := "@" + ev.GetSender().GetLogin() + ": " + strings.Join(replies, "\n\n")
+		if _, _, err := ev.CreateComment(ctx, body); err != nil {
+			lastErr = err
+		}
+	}
+	return len(cmds), lastErr
+}
@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/fakegithub"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Command
+	}{
+		{
+			name: "none",
+			body: "LGTM",
+		},
+		{
+			name: "commands",
+			body: "Thanks!\n/label bug \"good first issue\"\r\n  /Assign @alice\n/close",
+			want: []Command{
+				{Name: "label", Args: []string{"bug", "good first issue"}},
+				{Name: "assign", Args: []string{"@alice"}},
+				{Name: "close", Args: []string{}},
+			},
+		},
+		{
+			name: "skip-code-quotes-and-paths",
+			body: "```\n/close\n```\n> /close\n/usr/bin is missing\n//close\n/ close",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermission(t *testing.T) {
+	if got := ParsePermission("Write"); got != Write {
+		t.Errorf("ParsePermission(Write) = %v, want write", got)
+	}
+	if got := ParsePermission("owner"); got != None {
+		t.Errorf("ParsePermission(owner) = %v, want none", got)
+	}
+	if !(Read < Triage && Triage < Write && Write < Admin) {
+		t.Errorf("permissions are not ordered")
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	run := func(ctx context.Context, ev *Event, args []string) (string, error) { return "", nil }
+	r := Default("current", "backlog")
+	if err := r.Register(Spec{Name: "Deploy", Permission: Admin, Run: run}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if _, ok := r.Lookup("deploy"); !ok {
+		t.Errorf("Lookup(deploy) = false, want true")
+	}
+	for _, spec := range []Spec{
+		{Name: "label", Run: run},
+		{Name: "", Run: run},
+		{Name: "two words", Run: run},
+		{Name: "norun"},
+	} {
+		if err := r.Register(spec); err == nil {
+			t.Errorf("Register(%q) error = nil", spec.Name)
+		}
+	}
+	if got := len(r.Specs()); got != 8 {
+		t.Errorf("len(Specs()) = %d, want 8", got)
+	}
+}
+
+func TestRegistry_Handle(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		sender       string
+		senderType   string
+		wantCount    int
+		wantErr      bool
+		wantLabels   []string
+		wantState    string
+		wantReaction []string
+		wantReply    string
+	}{
+		{
+			name:         "label-and-current",
+			body:         "/label bug\n/current",
+			sender:       "writer",
+			wantCount:    2,
+			wantLabels:   []string{"bug", "sprint/current"},
+			wantState:    "open",
+			wantReaction: []string{ReactionOK},
+		},
+		{
+			name:         "unlabel-and-close",
+			body:         "/unlabel backlog\n/close",
+			sender:       "writer",
+			wantCount:    2,
+			wantLabels:   []string{},
+			wantState:    "closed",
+			wantReaction: []string{ReactionOK},
+		},
+		{
+			name:         "denied",
+			body:         "/close",
+			sender:       "reader",
+			wantCount:    1,
+			wantLabels:   []string{"backlog"},
+			wantState:    "open",
+			wantReaction: []string{ReactionDenied},
+			wantReply:    "`/close` requires triage permission.",
+		},
+		{
+			name:         "failed",
+			body:         "/label",
+			sender:       "writer",
+			wantCount:    1,
+			wantErr:      true,
+			wantLabels:   []string{"backlog"},
+			wantState:    "open",
+			wantReaction: []string{ReactionFailed},
+			wantReply:    "usage: /label",
+		},
+		{
+			name:         "help",
+			body:         "/help",
+			sender:       "reader",
+			wantCount:    1,
+			wantLabels:   []string{"backlog"},
+			wantState:    "open",
+			wantReaction: []string{ReactionOK},
+			wantReply:    "`/label` <label>...",
+		},
+		{
+			name:         "unknown",
+			body:         "/lgtm",
+			sender:       "writer",
+			wantLabels:   []string{"backlog"},
+			wantState:    "open",
+			wantReaction: []string{},
+		},
+		{
+			name:         "bot",
+			body:         "/close",
+			sender:       "writer",
+			senderType:   "Bot",
+			wantLabels:   []string{"backlog"},
+			wantState:    "open",
+			wantReaction: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fakegithub.NewServer()
+			defer s.Close()
+			s.SetPermission("owner", "repo", "writer", "write")
+			issue := &github.Issue{
+				Number: github.Int(1),
+				Labels: []github.Label{{Name: github.String("backlog")}},
+			}
+			s.AddIssue("owner", "repo", issue)
+			comment := s.AddComment("owner", "repo", 1, &github.IssueComment{Body: &tt.body})
+			client := s.Client()
+			ev := NewEvent(issues.NewEvent(iface.NewIssues(client.Issues), &github.IssuesEvent{
+				Issue: issue,
+				Repo: &github.Repository{
+					Name:  github.String("repo"),
+					Owner: &github.User{Login: github.String("owner")},
+				},
+				Sender: &github.User{Login: &tt.sender, Type: &tt.senderType},
+			}), comment, client.Repositories, client.Reactions)
+
+			got, err := Default("sprint/current", "backlog").Handle(context.Background(), ev)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Registry.Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantCount {
+				t.Errorf("Registry.Handle() = %d, want %d", got, tt.wantCount)
+			}
+			if labels := s.IssueLabels("owner", "repo", 1); !reflect.DeepEqual(labels, tt.wantLabels) {
+				t.Errorf("IssueLabels() = %v, want %v", labels, tt.wantLabels)
+			}
+			if state := s.Issue("owner", "repo", 1).GetState(); state != tt.wantState {
+				t.Errorf("Issue().State = %q, want %q", state, tt.wantState)
+			}
+			if r := s.Reactions("owner", "repo", comment.GetID()); !reflect.DeepEqual(r, tt.wantReaction) {
+				t.Errorf("Reactions() = %q, want %q", r, tt.wantReaction)
+			}
+			comments := s.Comments("owner", "repo", 1)
+			if tt.wantReply == "" && len(comments) != 1 {
+				t.Errorf("Comments() = %q, want no reply", comments)
+			}
+			if tt.wantReply != "" && (len(comments) != 2 || !strings.Contains(comments[1], tt.wantReply) ||
+				!strings.HasPrefix(comments[1], "@"+tt.sender+": ")) {
+				t.Errorf("Comments() = %q, want reply containing %q", comments, tt.wantReply)
+			}
+		})
+	}
+}
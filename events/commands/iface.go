@@ -0,0 +1,19 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// Repos defines the interface used to check commenter permissions. It is
+// implemented by *github.RepositoriesService.
+type Repos interface {
+	GetPermissionLevel(ctx context.Context, owner, repo, user string) (*github.RepositoryPermissionLevel, *github.Response, error)
+}
+
+// Reactions defines the interface used to acknowledge commands. It is
+// implemented by *github.ReactionsService.
+type Reactions interface {
+	CreateIssueCommentReaction(ctx context.Context, owner, repo string, id int64, content string) (*github.Reaction, *github.Response, error)
+}
@@ -0,0 +1,254 @@
+// Package ifacetest provides a fake of the issues interface for tests.
+//
+// The fake returns the configured values from every call and records each
+// call as a short string, e.g.:
+//
+//	f := &ifacetest.Issues{Labels: labels}
+//	f.Script("Edit", fmt.Errorf("fake error"), nil)
+//	ev := issues.NewEvent(f, event)
+//	...
+//	if got := f.Calls(); !reflect.DeepEqual(got, []string{`add ["bug"]`}) {
+package ifacetest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+)
+
+// Issues implements iface.Issues. The exported fields are returned by the
+// matching calls and must not be changed while the fake is in use.
+type Issues struct {
+	// Issue is returned by Get, Edit, AddAssignees and RemoveAssignees.
+	Issue *github.Issue
+	// Labels is returned by ListLabelsByIssue, AddLabelsToIssue and
+	// ReplaceLabelsForIssue.
+	Labels []*github.Label
+	// Comments is returned by ListComments.
+	Comments []*github.IssueComment
+	// Milestones is returned by ListMilestones. GetMilestone returns the
+	// milestone with the requested number.
+	Milestones []*github.Milestone
+	// Response is returned by every call.
+	Response *github.Response
+	// Err is returned by every call without a scripted error.
+	Err error
+
+	mu      sync.Mutex
+	calls   []string
+	scripts map[string][]error
+}
+
+var _ iface.Issues = &Issues{}
+
+// Script sets the errors returned by the next calls of the named method, in
+// order. A nil error lets the call succeed. Once the errors are used, calls
+// return Err.
+func (f *Issues) Script(method string, errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.scripts == nil {
+		f.scripts = map[string][]error{}
+	}
+	f.scripts[method] = append(f.scripts[method], errs...)
+}
+
+// Calls returns a description of every call received, in order.
+func (f *Issues) Calls() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// Reset forgets the recorded calls.
+func (f *Issues) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+}
+
+// record records the call and returns the error of the named method.
+func (f *Issues) record(method string, format string, args ...interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fmt.Sprintf(format, args...))
+	if errs := f.scripts[method]; len(errs) > 0 {
+		f.scripts[method] = errs[1:]
+		return errs[0]
+	}
+	return f.Err
+}
+
+// Get records "get".
+func (f *Issues) Get(
+	ctx context.Context, owner string, repo string,
+	number int) (*github.Issue, *github.Response, error) {
+	err := f.record("Get", "get")
+	return f.Issue, f.Response, err
+}
+
+// Edit records "edit" and the fields set in the request, e.g. "edit state=closed".
+func (f *Issues) Edit(
+	ctx context.Context, owner string, repo string, number int,
+	issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
+	err := f.record("Edit", "edit%s", describeRequest(issue))
+	return f.Issue, f.Response, err
+}
+
+// AddLabelsToIssue records "add" and the labels.
+func (f *Issues) AddLabelsToIssue(
+	ctx context.Context, owner string, repo string, number int,
+	labels []string) ([]*github.Label, *github.Response, error) {
+	err := f.record("AddLabelsToIssue", "add %q", labels)
+	return f.Labels, f.Response, err
+}
+
+// RemoveLabelForIssue records "remove" and the label.
+func (f *Issues) RemoveLabelForIssue(
+	ctx context.Context, owner string, repo string, number int,
+	label string) (*github.Response, error) {
+	err := f.record("RemoveLabelForIssue", "remove %q", label)
+	return f.Response, err
+}
+
+// ReplaceLabelsForIssue records "replace" and the labels.
+func (f *Issues) ReplaceLabelsForIssue(
+	ctx context.Context, owner string, repo string, number int,
+	labels []string) ([]*github.Label, *github.Response, error) {
+	err := f.record("ReplaceLabelsForIssue", "replace %q", labels)
+	return f.Labels, f.Response, err
+}
+
+// ListLabelsByIssue records "list".
+func (f *Issues) ListLabelsByIssue(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	err := f.record("ListLabelsByIssue", "list")
+	return f.Labels, f.Response, err
+}
+
+// CreateComment records "comment" and the body, and returns the comment.
+func (f *Issues) CreateComment(
+	ctx context.Context, owner string, repo string, number int,
+	comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	err := f.record("CreateComment", "comment %s", comment.GetBody())
+	return comment, f.Response, err
+}
+
+// ListComments records "list comments".
+func (f *Issues) ListComments(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	err := f.record("ListComments", "list comments")
+	return f.Comments, f.Response, err
+}
+
+// AddAssignees records "assign" and the assignees.
+func (f *Issues) AddAssignees(
+	ctx context.Context, owner string, repo string, number int,
+	assignees []string) (*github.Issue, *github.Response, error) {
+	err := f.record("AddAssignees", "assign %q", assignees)
+	return f.Issue, f.Response, err
+}
+
+// RemoveAssignees records "unassign" and the assignees.
+func (f *Issues) RemoveAssignees(
+	ctx context.Context, owner string, repo string, number int,
+	assignees []string) (*github.Issue, *github.Response, error) {
+	err := f.record("RemoveAssignees", "unassign %q", assignees)
+	return f.Issue, f.Response, err
+}
+
+// Lock records "lock" and the lock reason.
+func (f *Issues) Lock(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.LockIssueOptions) (*github.Response, error) {
+	reason := ""
+	if opt != nil {
+		reason = opt.LockReason
+	}
+	err := f.record("Lock", "lock %q", reason)
+	return f.Response, err
+}
+
+// Unlock records "unlock".
+func (f *Issues) Unlock(
+	ctx context.Context, owner string, repo string,
+	number int) (*github.Response, error) {
+	err := f.record("Unlock", "unlock")
+	return f.Response, err
+}
+
+// ListMilestones records "list milestones".
+func (f *Issues) ListMilestones(
+	ctx context.Context, owner string, repo string,
+	opt *github.MilestoneListOptions) ([]*github.Milestone, *github.Response, error) {
+	err := f.record("ListMilestones", "list milestones")
+	return f.Milestones, f.Response, err
+}
+
+// GetMilestone records "get milestone" and the number.
+func (f *Issues) GetMilestone(
+	ctx context.Context, owner string, repo string,
+	number int) (*github.Milestone, *github.Response, error) {
+	err := f.record("GetMilestone", "get milestone %d", number)
+	for _, m := range f.Milestones {
+		if m.GetNumber() == number {
+			return m, f.Response, err
+		}
+	}
+	return nil, f.Response, err
+}
+
+// CreateMilestone records "create milestone" and the title, and returns the
+// milestone.
+func (f *Issues) CreateMilestone(
+	ctx context.Context, owner string, repo string,
+	milestone *github.Milestone) (*github.Milestone, *github.Response, error) {
+	err := f.record("CreateMilestone", "create milestone %q", milestone.GetTitle())
+	return milestone, f.Response, err
+}
+
+// EditMilestone records "edit milestone", the number and the title, and
+// returns the milestone.
+func (f *Issues) EditMilestone(
+	ctx context.Context, owner string, repo string, number int,
+	milestone *github.Milestone) (*github.Milestone, *github.Response, error) {
+	err := f.record("EditMilestone", "edit milestone %d %q", number, milestone.GetTitle())
+	return milestone, f.Response, err
+}
+
+// DeleteMilestone records "delete milestone" and the number.
+func (f *Issues) DeleteMilestone(
+	ctx context.Context, owner string, repo string,
+	number int) (*github.Response, error) {
+	err := f.record("DeleteMilestone", "delete milestone %d", number)
+	return f.Response, err
+}
+
+// describeRequest returns the fields set in req, each preceded by a space.
+func describeRequest(req *github.IssueRequest) string {
+	if req == nil {
+		return ""
+	}
+	s := ""
+	if req.State != nil {
+		s += " state=" + req.GetState()
+	}
+	if req.Title != nil {
+		s += fmt.Sprintf(" title=%q", req.GetTitle())
+	}
+	if req.Labels != nil {
+		s += fmt.Sprintf(" labels=%q", *req.Labels)
+	}
+	if req.Assignees != nil {
+		s += fmt.Sprintf(" assignees=%q", *req.Assignees)
+	}
+	if req.Milestone != nil {
+		s += fmt.Sprintf(" milestone=%d", req.GetMilestone())
+	}
+	return s
+}
@@ -0,0 +1,45 @@
+package ifacetest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestIssues(t *testing.T) {
+	ctx := context.Background()
+	fail := fmt.Errorf("fake error")
+	f := &Issues{
+		Milestones: []*github.Milestone{{Number: github.Int(2), Title: github.String("v1")}},
+	}
+	f.Script("AddLabelsToIssue", fail, nil)
+
+	if _, _, err := f.AddLabelsToIssue(ctx, "o", "r", 1, []string{"a"}); err != fail {
+		t.Errorf("AddLabelsToIssue() error = %v, want %v", err, fail)
+	}
+	if _, _, err := f.AddLabelsToIssue(ctx, "o", "r", 1, []string{"b"}); err != nil {
+		t.Errorf("AddLabelsToIssue() error = %v, want nil", err)
+	}
+	f.Edit(ctx, "o", "r", 1, &github.IssueRequest{State: github.String("closed")})
+	f.Lock(ctx, "o", "r", 1, &github.LockIssueOptions{LockReason: "spam"})
+	if m, _, _ := f.GetMilestone(ctx, "o", "r", 2); m.GetTitle() != "v1" {
+		t.Errorf("GetMilestone() = %v, want v1", m)
+	}
+
+	want := []string{`add ["a"]`, `add ["b"]`, `edit state=closed`, `lock "spam"`, `get milestone 2`}
+	if got := f.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls() = %q, want %q", got, want)
+	}
+
+	f.Reset()
+	f.Err = fail
+	if _, err := f.Unlock(ctx, "o", "r", 1); err != fail {
+		t.Errorf("Unlock() error = %v, want %v", err, fail)
+	}
+	if got := f.Calls(); !reflect.DeepEqual(got, []string{"unlock"}) {
+		t.Errorf("Calls() = %q, want [unlock]", got)
+	}
+}
@@ -6,11 +6,27 @@ import (
 	"github.com/google/go-github/github"
 )
 
-// Issues defines the interface used by the issues event logic.
+// Issues defines the interface used by the issues event logic. It mirrors the
+// methods of github.IssuesService so that the service can be replaced by a fake
+// in tests.
 type Issues interface {
+	Get(ctx context.Context, owner string, repo string, number int) (*github.Issue, *github.Response, error)
 	Edit(ctx context.Context, owner string, repo string, number int, issue *github.IssueRequest) (*github.Issue, *github.Response, error)
 	AddLabelsToIssue(ctx context.Context, owner string, repo string, number int, labels []string) ([]*github.Label, *github.Response, error)
 	RemoveLabelForIssue(ctx context.Context, owner string, repo string, number int, label string) (*github.Response, error)
+	CreateComment(ctx context.Context, owner string, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error)
+	AddAssignees(ctx context.Context, owner string, repo string, number int, assignees []string) (*github.Issue, *github.Response, error)
+	ListLabelsByIssue(ctx context.Context, owner string, repo string, number int, opt *github.ListOptions) ([]*github.Label, *github.Response, error)
+	ReplaceLabelsForIssue(ctx context.Context, owner string, repo string, number int, labels []string) ([]*github.Label, *github.Response, error)
+	ListComments(ctx context.Context, owner string, repo string, number int, opt *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error)
+	RemoveAssignees(ctx context.Context, owner string, repo string, number int, assignees []string) (*github.Issue, *github.Response, error)
+	Lock(ctx context.Context, owner string, repo string, number int, opt *github.LockIssueOptions) (*github.Response, error)
+	Unlock(ctx context.Context, owner string, repo string, number int) (*github.Response, error)
+	ListMilestones(ctx context.Context, owner string, repo string, opt *github.MilestoneListOptions) ([]*github.Milestone, *github.Response, error)
+	GetMilestone(ctx context.Context, owner string, repo string, number int) (*github.Milestone, *github.Response, error)
+	CreateMilestone(ctx context.Context, owner string, repo string, milestone *github.Milestone) (*github.Milestone, *github.Response, error)
+	EditMilestone(ctx context.Context, owner string, repo string, number int, milestone *github.Milestone) (*github.Milestone, *github.Response, error)
+	DeleteMilestone(ctx context.Context, owner string, repo string, number int) (*github.Response, error)
 }
 
 // IssuesImpl implements the Issues interface.
@@ -23,6 +39,13 @@ func NewIssues(service *github.IssuesService) *IssuesImpl {
 	return &IssuesImpl{service}
 }
 
+// Get reads the repo issue.
+func (i *IssuesImpl) Get(
+	ctx context.Context, owner string, repo string,
+	number int) (*github.Issue, *github.Response, error) {
+	return i.IssuesService.Get(ctx, owner, repo, number)
+}
+
 // Edit an issue.
 func (i *IssuesImpl) Edit(
 	ctx context.Context, owner string, repo string, number int,
@@ -43,3 +66,94 @@ func (i *IssuesImpl) RemoveLabelForIssue(
 	label string) (*github.Response, error) {
 	return i.IssuesService.RemoveLabelForIssue(ctx, owner, repo, number, label)
 }
+
+// CreateComment creates a new comment on the repo issue.
+func (i *IssuesImpl) CreateComment(
+	ctx context.Context, owner string, repo string, number int,
+	comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	return i.IssuesService.CreateComment(ctx, owner, repo, number, comment)
+}
+
+// AddAssignees adds the given users as assignees of the repo issue.
+func (i *IssuesImpl) AddAssignees(
+	ctx context.Context, owner string, repo string, number int,
+	assignees []string) (*github.Issue, *github.Response, error) {
+	return i.IssuesService.AddAssignees(ctx, owner, repo, number, assignees)
+}
+
+// ListLabelsByIssue lists the labels of the repo issue.
+func (i *IssuesImpl) ListLabelsByIssue(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	return i.IssuesService.ListLabelsByIssue(ctx, owner, repo, number, opt)
+}
+
+// ReplaceLabelsForIssue replaces all labels of the repo issue.
+func (i *IssuesImpl) ReplaceLabelsForIssue(
+	ctx context.Context, owner string, repo string, number int,
+	labels []string) ([]*github.Label, *github.Response, error) {
+	return i.IssuesService.ReplaceLabelsForIssue(ctx, owner, repo, number, labels)
+}
+
+// ListComments lists the comments of the repo issue.
+func (i *IssuesImpl) ListComments(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	return i.IssuesService.ListComments(ctx, owner, repo, number, opt)
+}
+
+// RemoveAssignees removes the given users from the assignees of the repo issue.
+func (i *IssuesImpl) RemoveAssignees(
+	ctx context.Context, owner string, repo string, number int,
+	assignees []string) (*github.Issue, *github.Response, error) {
+	return i.IssuesService.RemoveAssignees(ctx, owner, repo, number, assignees)
+}
+
+// Lock locks the conversation of the repo issue.
+func (i *IssuesImpl) Lock(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.LockIssueOptions) (*github.Response, error) {
+	return i.IssuesService.Lock(ctx, owner, repo, number, opt)
+}
+
+// Unlock unlocks the conversation of the repo issue.
+func (i *IssuesImpl) Unlock(
+	ctx context.Context, owner string, repo string,
+	number int) (*github.Response, error) {
+	return i.IssuesService.Unlock(ctx, owner, repo, number)
+}
+
+// ListMilestones lists the milestones of the repo.
+func (i *IssuesImpl) ListMilestones(
+	ctx context.Context, owner string, repo string,
+	opt *github.MilestoneListOptions) ([]*github.Milestone, *github.Response, error) {
+	return i.IssuesService.ListMilestones(ctx, owner, repo, opt)
+}
+
+// GetMilestone reads the repo milestone.
+func (i *IssuesImpl) GetMilestone(
+	ctx context.Context, owner string, repo string,
+	number int) (*github.Milestone, *github.Response, error) {
+	return i.IssuesService.GetMilestone(ctx, owner, repo, number)
+}
+
+// CreateMilestone creates a new milestone in the repo.
+func (i *IssuesImpl) CreateMilestone(
+	ctx context.Context, owner string, repo string,
+	milestone *github.Milestone) (*github.Milestone, *github.Response, error) {
+	return i.IssuesService.CreateMilestone(ctx, owner, repo, milestone)
+}
+
+// EditMilestone changes the repo milestone.
+func (i *IssuesImpl) EditMilestone(
+	ctx context.Context, owner string, repo string, number int,
+	milestone *github.Milestone) (*github.Milestone, *github.Response, error) {
+	return i.IssuesService.EditMilestone(ctx, owner, repo, number, milestone)
+}
+
+// DeleteMilestone deletes the repo milestone.
+func (i *IssuesImpl) DeleteMilestone(
+	ctx context.Context, owner string, repo string,
+	number int) (*github.Response, error) {
+	return i.IssuesService.DeleteMilestone(ctx, owner, repo, number)
+}
@@ -0,0 +1,53 @@
+package iface
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// Labels defines the interface used to manage repository labels.
+type Labels interface {
+	ListLabels(ctx context.Context, owner string, repo string, opt *github.ListOptions) ([]*github.Label, *github.Response, error)
+	CreateLabel(ctx context.Context, owner string, repo string, label *github.Label) (*github.Label, *github.Response, error)
+	EditLabel(ctx context.Context, owner string, repo string, name string, label *github.Label) (*github.Label, *github.Response, error)
+	DeleteLabel(ctx context.Context, owner string, repo string, name string) (*github.Response, error)
+}
+
+// LabelsImpl implements the Labels interface.
+type LabelsImpl struct {
+	*github.IssuesService
+}
+
+// NewLabels creates a new Labels instance.
+func NewLabels(service *github.IssuesService) *LabelsImpl {
+	return &LabelsImpl{service}
+}
+
+// ListLabels lists all labels in the owner repo.
+func (l *LabelsImpl) ListLabels(
+	ctx context.Context, owner string, repo string,
+	opt *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	return l.IssuesService.ListLabels(ctx, owner, repo, opt)
+}
+
+// CreateLabel creates a new label in the owner repo.
+func (l *LabelsImpl) CreateLabel(
+	ctx context.Context, owner string, repo string,
+	label *github.Label) (*github.Label, *github.Response, error) {
+	return l.IssuesService.CreateLabel(ctx, owner, repo, label)
+}
+
+// EditLabel updates the named label, which may also rename it.
+func (l *LabelsImpl) EditLabel(
+	ctx context.Context, owner string, repo string, name string,
+	label *github.Label) (*github.Label, *github.Response, error) {
+	return l.IssuesService.EditLabel(ctx, owner, repo, name, label)
+}
+
+// DeleteLabel deletes the named label from the owner repo.
+func (l *LabelsImpl) DeleteLabel(
+	ctx context.Context, owner string, repo string,
+	name string) (*github.Response, error) {
+	return l.IssuesService.DeleteLabel(ctx, owner, repo, name)
+}
@@ -2,11 +2,16 @@ package issues
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net/http"
+	"time"
 
-	"github.com/stephen-soltesz/github-webhook-poc/slice"
+	"github.com/stephen-soltesz/github-webhook-poc/audit"
 
 	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
 	"github.com/stephen-soltesz/pretty"
 
 	"github.com/google/go-github/github"
@@ -17,6 +22,19 @@ type Event struct {
 	//	*github.Client
 	*github.IssuesEvent
 	iface.Issues
+
+	// Recorder, if not nil, receives an audit record for every mutating call.
+	Recorder audit.Recorder
+	// DeliveryID is the webhook delivery that caused this event, for auditing.
+	DeliveryID string
+	// Rule names the automation currently acting on the event, for auditing.
+	Rule string
+	// Refetch causes ReconcileLabels to read the current labels from GitHub
+	// instead of trusting the labels in the event.
+	Refetch bool
+
+	// labels tracks the issue labels as they change through mutating calls.
+	labels []string
 }
 
 // NewEvent creates a new Event based on the given client and event.
@@ -39,12 +57,24 @@ func (ev *Event) EditIssue(
 		ev.GetIssue().GetNumber(),
 		pretty.SprintPlain(req))
 
-	return ev.Issues.Edit(
+	before := ev.Labels()
+	issue, resp, err := ev.Issues.Edit(
 		ctx,
 		ev.GetRepo().GetOwner().GetLogin(),
 		ev.GetRepo().GetName(),
 		ev.GetIssue().GetNumber(),
 		req)
+	after := before
+	switch {
+	case err != nil:
+	case issue != nil && !githubx.DryRun():
+		// In dry-run mode the issue is a zero value, not the edited issue.
+		after = labelNames(issue.Labels)
+	case req.Labels != nil:
+		after = append([]string{}, *req.Labels...)
+	}
+	ev.audit("edit", pretty.SprintPlain(req), before, after, resp, err)
+	return issue, resp, err
 }
 
 func (ev *Event) CloseIssue(ctx context.Context, labels []string) (*github.Issue, *github.Response, error) {
@@ -63,6 +93,47 @@ func (ev *Event) CloseIssue(ctx context.Context, labels []string) (*github.Issue
 	return ev.EditIssue(ctx, req)
 }
 
+// ReopenIssue reopens the event issue.
+func (ev *Event) ReopenIssue(ctx context.Context) (*github.Issue, *github.Response, error) {
+	open := "open"
+	return ev.EditIssue(ctx, &github.IssueRequest{State: &open})
+}
+
+// CreateComment adds a comment with the given body to the event issue.
+func (ev *Event) CreateComment(ctx context.Context, body string) (*github.IssueComment, *github.Response, error) {
+	log.Println("Issues.CreateComment:",
+		ev.GetRepo().GetOwner().GetLogin(),
+		ev.GetRepo().GetName(),
+		ev.GetIssue().GetNumber())
+
+	labels := ev.Labels()
+	comment, resp, err := ev.Issues.CreateComment(
+		ctx,
+		ev.GetRepo().GetOwner().GetLogin(),
+		ev.GetRepo().GetName(),
+		ev.GetIssue().GetNumber(),
+		&github.IssueComment{Body: &body})
+	ev.audit("comment", body, labels, labels, resp, err)
+	return comment, resp, err
+}
+
+// AddAssignees assigns the given users to the event issue.
+func (ev *Event) AddAssignees(ctx context.Context, assignees []string) (*github.Issue, *github.Response, error) {
+	log.Println("Issues.AddAssignees:",
+		ev.GetRepo().GetOwner().GetLogin(),
+		ev.GetRepo().GetName(),
+		ev.GetIssue().GetNumber(), assignees)
+
+	labels := ev.Labels()
+	issue, resp, err := ev.Issues.AddAssignees(
+		ctx,
+		ev.GetRepo().GetOwner().GetLogin(),
+		ev.GetRepo().GetName(),
+		ev.GetIssue().GetNumber(), assignees)
+	ev.audit("assign", fmt.Sprintf("%q", assignees), labels, labels, resp, err)
+	return issue, resp, err
+}
+
 func (ev *Event) SetIssueLabels(ctx context.Context, labels []string) (
 	*github.Issue, *github.Response, error) {
 	return ev.EditIssue(
@@ -73,85 +144,259 @@ func (ev *Event) SetIssueLabels(ctx context.Context, labels []string) (
 	)
 }
 
-// AddIssueLabel adds a label to the event issue.
+// AddIssueLabels adds labels to the event issue.
 func (ev *Event) AddIssueLabels(
 	ctx context.Context, labels []string) ([]*github.Label, *github.Response, error) {
 
-	/*
-		// Check curent labels for the new label and collect the list.
-		currentLabels := []string{}
-		for _, current := range ev.GetIssue().Labels {
-			currentLabels = append(currentLabels, current.GetName())
-		}
-		// Append the new label to the current labels.
-		for _, label := range labels {
-			if !slice.ContainsString(currentLabels, label) {
-				currentLabels = append(currentLabels, label)
-			}
-		}
-		if len(ev.GetIssue().Labels) == len(currentLabels) {
-			return nil, nil, nil
-		}
-		return ev.EditIssue(
-			ctx,
-			&github.IssueRequest{
-				Labels: &currentLabels,
-			},
-		)
-	*/
 	log.Println("Issues.AddLabelsToIssue:",
 		ev.GetRepo().GetOwner().GetLogin(),
 		ev.GetRepo().GetName(),
 		ev.GetIssue().GetNumber(), labels)
 
-	return ev.Issues.AddLabelsToIssue(
+	before := ev.Labels()
+	added, resp, err := ev.Issues.AddLabelsToIssue(
 		ctx,
 		ev.GetRepo().GetOwner().GetLogin(),
 		ev.GetRepo().GetName(),
 		ev.GetIssue().GetNumber(), labels)
+	after := before
+	if err == nil {
+		after = append([]string{}, before...)
+		for _, label := range labels {
+			if !slice.ContainsFold(after, label) {
+				after = append(after, label)
+			}
+		}
+	}
+	ev.audit("add-labels", fmt.Sprintf("%q", labels), before, after, resp, err)
+	return added, resp, err
 }
 
-// RemoveIssueLabels removes the given label from the event issue. If the label
-// is not found, no action is taken.
+// RemoveIssueLabels removes the given labels from the event issue. Labels that
+// are not found are ignored. RemoveIssueLabels stops and returns the first
+// other error.
 func (ev *Event) RemoveIssueLabels(
 	ctx context.Context, labels []string) (*github.Response, error) {
-
-	/*
-		currentLabels := filterLabels(ev.GetIssue().Labels, labels)
-		if len(currentLabels) != len(ev.GetIssue().Labels) {
-			return ev.EditIssue(
-				ctx,
-				&github.IssueRequest{
-					Labels: &currentLabels,
-				},
-			)
-		}
-		return nil, nil, nil
-	*/
 	var resp *github.Response
-	var err error
 	for _, label := range labels {
-		resp, err = ev.Issues.RemoveLabelForIssue(ctx,
+		var err error
+		resp, err = ev.removeLabel(ctx, label)
+		if err != nil && !IsNotFound(err) {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// removeLabel removes a single label from the event issue.
+func (ev *Event) removeLabel(ctx context.Context, label string) (*github.Response, error) {
+	log.Println("Issues.RemoveLabelForIssue:",
+		ev.GetRepo().GetOwner().GetLogin(),
+		ev.GetRepo().GetName(),
+		ev.GetIssue().GetNumber(), label)
+
+	before := ev.Labels()
+	resp, err := ev.Issues.RemoveLabelForIssue(ctx,
+		ev.GetRepo().GetOwner().GetLogin(),
+		ev.GetRepo().GetName(),
+		ev.GetIssue().GetNumber(), label)
+	after := before
+	if err == nil || IsNotFound(err) {
+		after = filterFold(before, []string{label})
+	}
+	ev.audit("remove-label", fmt.Sprintf("%q", label), before, after, resp, err)
+	return resp, err
+}
+
+// LabelResult describes the changes made by ReconcileLabels.
+type LabelResult struct {
+	// Added are the labels that were added to the issue.
+	Added []string
+	// Removed are the labels that were removed from the issue.
+	Removed []string
+	// Absent are labels requested for removal that were already missing,
+	// including labels GitHub reported as not found.
+	Absent []string
+	// Present are labels requested for addition that were already present.
+	Present []string
+	// Labels are the issue labels after all changes.
+	Labels []string
+}
+
+// Changed reports whether ReconcileLabels modified the issue.
+func (r *LabelResult) Changed() bool {
+	return len(r.Added) > 0 || len(r.Removed) > 0
+}
+
+// ReconcileLabels brings the event issue labels to the desired state: every
+// label in add is present and every label in remove is absent. A label in both
+// lists is added. Only the difference from the current labels is sent to
+// GitHub; when nothing differs, no API calls are made. If ev.Refetch is true,
+// the current labels are read from GitHub first instead of from the event.
+//
+// Labels that GitHub reports as not found during removal are treated as
+// successfully removed. On any other error, the returned result describes the
+// changes made before the error.
+func (ev *Event) ReconcileLabels(ctx context.Context, add, remove []string) (*LabelResult, error) {
+	if ev.Refetch {
+		if err := ev.refetchLabels(ctx); err != nil {
+			return &LabelResult{Labels: ev.Labels()}, err
+		}
+	}
+	current := ev.Labels()
+	result := &LabelResult{}
+	toAdd := []string{}
+	for _, label := range add {
+		switch {
+		case slice.ContainsFold(current, label):
+			result.Present = appendFold(result.Present, label)
+		default:
+			toAdd = appendFold(toAdd, label)
+		}
+	}
+	toRemove := []string{}
+	for _, label := range remove {
+		switch {
+		case slice.ContainsFold(add, label):
+			// Adding wins.
+		case slice.ContainsFold(current, label):
+			toRemove = appendFold(toRemove, label)
+		default:
+			result.Absent = appendFold(result.Absent, label)
+		}
+	}
+
+	if len(toAdd) > 0 {
+		if _, _, err := ev.AddIssueLabels(ctx, toAdd); err != nil {
+			result.Labels = ev.Labels()
+			return result, err
+		}
+		result.Added = toAdd
+	}
+	for _, label := range toRemove {
+		_, err := ev.removeLabel(ctx, label)
+		switch {
+		case err == nil:
+			result.Removed = append(result.Removed, label)
+		case IsNotFound(err):
+			result.Absent = append(result.Absent, label)
+		default:
+			result.Labels = ev.Labels()
+			return result, err
+		}
+	}
+	result.Labels = ev.Labels()
+	return result, nil
+}
+
+// refetchLabels replaces the current labels with those read from GitHub.
+func (ev *Event) refetchLabels(ctx context.Context) error {
+	labels := []string{}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := ev.Issues.ListLabelsByIssue(ctx,
 			ev.GetRepo().GetOwner().GetLogin(),
 			ev.GetRepo().GetName(),
-			ev.GetIssue().GetNumber(), label)
+			ev.GetIssue().GetNumber(), opt)
 		if err != nil {
-			//TODO: skip 404 errors.
-			log.Println(err)
-			// return nil, err
+			return err
+		}
+		for _, label := range page {
+			labels = append(labels, label.GetName())
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
 		}
+		opt.Page = resp.NextPage
 	}
-	return resp, nil
+	ev.labels = labels
+	return nil
 }
 
-func filterLabels(labels []github.Label, remove []string) []string {
-	currentLabels := []string{}
-	for _, currentLabel := range labels {
-		if slice.ContainsString(remove, currentLabel.GetName()) {
-			// Skip this label since we want it removed.
-			continue
+// IsNotFound reports whether err is a GitHub "404 Not Found" error.
+func IsNotFound(err error) bool {
+	if e, ok := err.(*github.ErrorResponse); ok && e.Response != nil {
+		return e.Response.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// appendFold appends value to values unless an equal value is present,
+// ignoring case.
+func appendFold(values []string, value string) []string {
+	if slice.ContainsFold(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// filterFold returns values without any of the remove values, ignoring case.
+func filterFold(values, remove []string) []string {
+	result := []string{}
+	for _, v := range values {
+		if !slice.ContainsFold(remove, v) {
+			result = append(result, v)
 		}
-		currentLabels = append(currentLabels, currentLabel.GetName())
 	}
-	return currentLabels
+	return result
+}
+
+// Labels returns the current issue label names. Labels starts with the labels
+// from the event issue and reflects all successful changes made through ev.
+func (ev *Event) Labels() []string {
+	if ev.labels == nil && ev.GetIssue() != nil {
+		ev.labels = labelNames(ev.GetIssue().Labels)
+	}
+	return ev.labels
+}
+
+// Audit sends an audit record for a mutating call made outside the issues API,
+// such as requesting reviews of a pull request. The labels are unchanged.
+func (ev *Event) Audit(op, detail string, resp *github.Response, err error) {
+	labels := ev.Labels()
+	ev.audit(op, detail, labels, labels, resp, err)
+}
+
+// audit updates the current labels and sends an audit record to the Recorder.
+func (ev *Event) audit(op, detail string, before, after []string, resp *github.Response, err error) {
+	ev.labels = after
+	if ev.Recorder == nil {
+		return
+	}
+	r := &audit.Record{
+		Time:       time.Now().UTC(),
+		DeliveryID: ev.DeliveryID,
+		Rule:       ev.Rule,
+		Repo:       ev.GetRepo().GetFullName(),
+		Issue:      ev.GetIssue().GetNumber(),
+		Operation:  op,
+		Detail:     detail,
+		Before:     before,
+		After:      after,
+		Result:     "ok",
+	}
+	if r.Repo == "" {
+		r.Repo = ev.GetRepo().GetOwner().GetLogin() + "/" + ev.GetRepo().GetName()
+	}
+	switch {
+	case err != nil:
+		r.Result = err.Error()
+	case githubx.DryRun():
+		// The change was logged, not made.
+		r.Result = "dry-run"
+	}
+	if resp != nil && resp.Response != nil && !githubx.DryRun() {
+		r.Status = resp.StatusCode
+	}
+	if rerr := ev.Recorder.Record(r); rerr != nil {
+		log.Println("audit:", rerr)
+	}
+}
+
+func labelNames(labels []github.Label) []string {
+	names := []string{}
+	for _, label := range labels {
+		names = append(names, label.GetName())
+	}
+	return names
 }
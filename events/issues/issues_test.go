@@ -2,36 +2,20 @@ package issues
 
 import (
 	"context"
+	"net/http"
+	"net/url"
+	"reflect"
 	"testing"
 
 	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/audit"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface/ifacetest"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/fakegithub"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/recorder"
 )
 
-type fakeIssues struct {
-	*github.Issue
-	*github.Response
-	Error  error
-	labels []*github.Label
-}
-
-func (f *fakeIssues) Edit(
-	ctx context.Context, owner string, repo string, number int,
-	issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
-	return f.Issue, f.Response, f.Error
-}
-
-func (f *fakeIssues) AddLabelsToIssue(
-	ctx context.Context, owner string, repo string, number int,
-	labels []string) ([]*github.Label, *github.Response, error) {
-	return f.labels, f.Response, f.Error
-}
-
-func (f *fakeIssues) RemoveLabelForIssue(
-	ctx context.Context, owner string, repo string, number int,
-	label string) (*github.Response, error) {
-	return f.Response, f.Error
-}
-
 func newLabel(label string) github.Label {
 	return github.Label{
 		Name: &label,
@@ -46,7 +30,7 @@ func TestEvent_AddIssueLabels(t *testing.T) {
 	tests := []struct {
 		name        string
 		IssuesEvent *github.IssuesEvent
-		Issues      *fakeIssues
+		Issues      *ifacetest.Issues
 		ctx         context.Context
 		labels      []string
 		wantErr     bool
@@ -60,7 +44,7 @@ func TestEvent_AddIssueLabels(t *testing.T) {
 					},
 				},
 			},
-			Issues: &fakeIssues{
+			Issues: &ifacetest.Issues{
 				Issue: &github.Issue{
 					Labels: []github.Label{
 						newLabel("okay2"),
@@ -103,7 +87,7 @@ func TestEvent_RemoveIssueLabels(t *testing.T) {
 	tests := []struct {
 		name        string
 		IssuesEvent *github.IssuesEvent
-		Issues      *fakeIssues
+		Issues      *ifacetest.Issues
 		ctx         context.Context
 		labels      []string
 		wantErr     bool
@@ -117,7 +101,7 @@ func TestEvent_RemoveIssueLabels(t *testing.T) {
 					},
 				},
 			},
-			Issues: &fakeIssues{
+			Issues: &ifacetest.Issues{
 				Issue: &github.Issue{
 					Labels: []github.Label{},
 				},
@@ -155,3 +139,262 @@ func TestEvent_RemoveIssueLabels(t *testing.T) {
 		})
 	}
 }
+
+// fakeAPI sends requests for api.github.com to a fake server, so recording
+// fixtures needs no credentials and changes no real repository.
+type fakeAPI struct {
+	*fakegithub.Server
+}
+
+func (f fakeAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		return nil, err
+	}
+	r := *req
+	r.URL = &url.URL{Scheme: u.Scheme, Host: u.Host, Path: req.URL.Path, RawQuery: req.URL.RawQuery}
+	r.Host = ""
+	return http.DefaultTransport.RoundTrip(&r)
+}
+
+func TestEvent_Recorded(t *testing.T) {
+	owner := "owner"
+	name := "repo"
+	number := 7
+	// Run with RECORDER_MODE=record to record the fixture again.
+	var base http.RoundTripper
+	if recorder.ModeFromEnv() == recorder.Record {
+		srv := fakegithub.NewServer()
+		defer srv.Close()
+		srv.AddIssue(owner, name, &github.Issue{Number: &number, Labels: []github.Label{newLabel("bug")}})
+		base = fakeAPI{srv}
+	}
+	rec, err := recorder.New("testdata/add-remove-labels.json", recorder.ModeFromEnv(), base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := github.NewClient(rec.Client())
+	ev := NewEvent(iface.NewIssues(client.Issues), &github.IssuesEvent{
+		Repo: &github.Repository{
+			Owner: &github.User{Login: &owner},
+			Name:  &name,
+		},
+		Issue: &github.Issue{
+			Number: &number,
+			Labels: []github.Label{newLabel("bug")},
+		},
+	})
+	ctx := context.Background()
+
+	labels, _, err := ev.AddIssueLabels(ctx, []string{"review/triage"})
+	if err != nil {
+		t.Fatalf("Event.AddIssueLabels() error = %v", err)
+	}
+	if len(labels) != 2 {
+		t.Errorf("Event.AddIssueLabels() = %v, want 2 labels", labels)
+	}
+	if _, err := ev.RemoveIssueLabels(ctx, []string{"bug"}); err != nil {
+		t.Fatalf("Event.RemoveIssueLabels() error = %v", err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Done(); err != nil {
+		t.Error(err)
+	}
+}
+
+type fakeRecorder struct {
+	records []*audit.Record
+}
+
+func (f *fakeRecorder) Record(r *audit.Record) error {
+	f.records = append(f.records, r)
+	return nil
+}
+
+func TestEvent_Audit(t *testing.T) {
+	rec := &fakeRecorder{}
+	ev := NewEvent(&ifacetest.Issues{}, &github.IssuesEvent{
+		Issue: &github.Issue{
+			Labels: []github.Label{newLabel("backlog")},
+		},
+	})
+	ev.Recorder = rec
+	ev.DeliveryID = "delivery-1"
+	ev.Rule = "label-current"
+	ctx := context.Background()
+
+	if _, _, err := ev.AddIssueLabels(ctx, []string{"current"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ev.RemoveIssueLabels(ctx, []string{"backlog"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.records) != 2 {
+		t.Fatalf("got %d audit records, want 2", len(rec.records))
+	}
+	add, remove := rec.records[0], rec.records[1]
+	if add.DeliveryID != "delivery-1" || add.Rule != "label-current" || add.Operation != "add-labels" {
+		t.Errorf("add record = %#v", add)
+	}
+	if !reflect.DeepEqual(add.Before, []string{"backlog"}) || !reflect.DeepEqual(add.After, []string{"backlog", "current"}) {
+		t.Errorf("add record labels = %v -> %v", add.Before, add.After)
+	}
+	if !reflect.DeepEqual(remove.After, []string{"current"}) || remove.Result != "ok" {
+		t.Errorf("remove record = %v -> %v (%s)", remove.Before, remove.After, remove.Result)
+	}
+}
+
+func TestEvent_DryRun(t *testing.T) {
+	defer githubx.SetDryRun(githubx.DryRun())
+	githubx.SetDryRun(true)
+	s := fakegithub.NewServer()
+	defer s.Close()
+	s.AddIssue("owner", "repo", &github.Issue{
+		Number: github.Int(1),
+		Labels: []github.Label{newLabel("bug")},
+	})
+	client := github.NewClient(&http.Client{Transport: &githubx.DryRunTransport{}})
+	client.BaseURL, _ = url.Parse(s.URL + "/")
+	rec := &fakeRecorder{}
+	ev := NewEvent(iface.NewIssues(client.Issues), &github.IssuesEvent{
+		Repo:  &github.Repository{Owner: &github.User{Login: github.String("owner")}, Name: github.String("repo")},
+		Issue: s.Issue("owner", "repo", 1),
+	})
+	ev.Recorder = rec
+	ctx := context.Background()
+
+	if _, _, err := ev.ReopenIssue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := ev.Labels(); !reflect.DeepEqual(got, []string{"bug"}) {
+		t.Errorf("Labels() after edit = %q, want [bug]", got)
+	}
+	if _, _, err := ev.SetIssueLabels(ctx, []string{"current"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ev.Labels(); !reflect.DeepEqual(got, []string{"current"}) {
+		t.Errorf("Labels() after set = %q, want [current]", got)
+	}
+	for _, r := range rec.records {
+		if r.Result != "dry-run" || r.Status != 0 {
+			t.Errorf("record %s = %q (%d), want dry-run", r.Operation, r.Result, r.Status)
+		}
+	}
+	if got := s.Issue("owner", "repo", 1); len(got.Labels) != 1 || got.Labels[0].GetName() != "bug" {
+		t.Errorf("issue labels = %v, want unchanged", got.Labels)
+	}
+}
+
+func newErrorResponse(status int) error {
+	return &github.ErrorResponse{
+		Response: &http.Response{
+			StatusCode: status,
+			Request:    &http.Request{Method: http.MethodDelete, URL: &url.URL{}},
+		},
+		Message: http.StatusText(status),
+	}
+}
+
+func TestEvent_ReconcileLabels(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      []string
+		refetch     []string
+		add         []string
+		remove      []string
+		err         error
+		wantAdded   []string
+		wantRemoved []string
+		wantAbsent  []string
+		wantLabels  []string
+		wantErr     bool
+	}{
+		{
+			name:       "no-op",
+			labels:     []string{"a"},
+			add:        []string{"A"},
+			remove:     []string{"b"},
+			wantAbsent: []string{"b"},
+			wantLabels: []string{"a"},
+		},
+		{
+			name:        "diff",
+			labels:      []string{"a", "b"},
+			add:         []string{"a", "c"},
+			remove:      []string{"b", "c"},
+			wantAdded:   []string{"c"},
+			wantRemoved: []string{"b"},
+			wantLabels:  []string{"a", "c"},
+		},
+		{
+			name:        "refetch",
+			refetch:     []string{"x"},
+			remove:      []string{"x"},
+			wantRemoved: []string{"x"},
+			wantLabels:  []string{},
+		},
+		{
+			name:       "not-found-is-success",
+			labels:     []string{"a"},
+			remove:     []string{"a"},
+			err:        newErrorResponse(http.StatusNotFound),
+			wantAbsent: []string{"a"},
+			wantLabels: []string{},
+		},
+		{
+			name:       "error",
+			labels:     []string{"a"},
+			remove:     []string{"a"},
+			err:        newErrorResponse(http.StatusInternalServerError),
+			wantLabels: []string{"a"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue := &github.Issue{}
+			for _, l := range tt.labels {
+				issue.Labels = append(issue.Labels, newLabel(l))
+			}
+			f := &ifacetest.Issues{Err: tt.err}
+			for _, l := range tt.refetch {
+				label := newLabel(l)
+				f.Labels = append(f.Labels, &label)
+			}
+			ev := NewEvent(f, &github.IssuesEvent{Issue: issue})
+			ev.Refetch = tt.refetch != nil
+			got, err := ev.ReconcileLabels(context.Background(), tt.add, tt.remove)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReconcileLabels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got.Added, tt.wantAdded) ||
+				!reflect.DeepEqual(got.Removed, tt.wantRemoved) ||
+				!reflect.DeepEqual(got.Absent, tt.wantAbsent) ||
+				!reflect.DeepEqual(got.Labels, tt.wantLabels) {
+				t.Errorf("ReconcileLabels() = %+v, want added %v removed %v absent %v labels %v",
+					got, tt.wantAdded, tt.wantRemoved, tt.wantAbsent, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestEvent_RemoveIssueLabelsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "not-found", err: newErrorResponse(http.StatusNotFound)},
+		{name: "server-error", err: newErrorResponse(http.StatusInternalServerError), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev := NewEvent(&ifacetest.Issues{Err: tt.err}, &github.IssuesEvent{})
+			if _, err := ev.RemoveIssueLabels(context.Background(), []string{"a"}); (err != nil) != tt.wantErr {
+				t.Errorf("RemoveIssueLabels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package issues
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Issue references in the title or body of a pull request or commit message:
+// "#12", "owner/repo#12" or an issue URL.
+var (
+	shortRef = regexp.MustCompile(`(?:^|[^\w/#])(?:([\w.-]+)/([\w.-]+))?#(\d+)\b`)
+	urlRef   = regexp.MustCompile(`https?://[^/\s]+/([\w.-]+)/([\w.-]+)/issues/(\d+)\b`)
+)
+
+// Ref identifies a referenced issue.
+type Ref struct {
+	Owner  string
+	Repo   string
+	Number int
+}
+
+func (r Ref) String() string {
+	return fmt.Sprintf("%s/%s#%d", r.Owner, r.Repo, r.Number)
+}
+
+// Refs returns the issue references in text, in order and without duplicates.
+// Short references without a repository are in owner/repo.
+func Refs(owner, repo, text string) []Ref {
+	type match struct {
+		pos int
+		ref Ref
+	}
+	var matches []match
+	for _, re := range []*regexp.Regexp{shortRef, urlRef} {
+		for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+			ref := Ref{Owner: owner, Repo: repo}
+			if m[2] >= 0 {
+				ref.Owner, ref.Repo = text[m[2]:m[3]], text[m[4]:m[5]]
+			}
+			ref.Number, _ = strconv.Atoi(text[m[6]:m[7]])
+			matches = append(matches, match{m[0], ref})
+		}
+	}
+	// Order references as they appear in the text.
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].pos < matches[j].pos })
+	var refs []Ref
+	seen := map[Ref]bool{}
+	for _, m := range matches {
+		if m.ref.Number == 0 || seen[m.ref] {
+			continue
+		}
+		seen[m.ref] = true
+		refs = append(refs, m.ref)
+	}
+	return refs
+}
+
+// SplitFullName splits a repository full name, e.g. "m-lab/etl", into its
+// owner and name.
+func SplitFullName(fullName string) (string, string, error) {
+	f := strings.Split(fullName, "/")
+	if len(f) != 2 || f[0] == "" || f[1] == "" {
+		return "", "", fmt.Errorf("invalid repository name %q", fullName)
+	}
+	return f[0], f[1], nil
+}
@@ -0,0 +1,61 @@
+package issues
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRefs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Ref
+	}{
+		{
+			name: "short",
+			text: "Fixes #12 and #3, again #12",
+			want: []Ref{{"o", "r", 12}, {"o", "r", 3}},
+		},
+		{
+			name: "cross-repo-and-url",
+			text: "See m-lab/etl#7 and https://github.com/m-lab/ndt/issues/9.\nCloses #1",
+			want: []Ref{{"m-lab", "etl", 7}, {"m-lab", "ndt", 9}, {"o", "r", 1}},
+		},
+		{
+			name: "none",
+			text: "Update README; color #fff, issue#5, https://github.com/o/r/pull/4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Refs("o", "r", tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Refs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitFullName(t *testing.T) {
+	tests := []struct {
+		fullName  string
+		wantOwner string
+		wantName  string
+		wantErr   bool
+	}{
+		{fullName: "owner/repo", wantOwner: "owner", wantName: "repo"},
+		{fullName: "owner", wantErr: true},
+		{fullName: "owner/", wantErr: true},
+		{fullName: "a/b/c", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fullName, func(t *testing.T) {
+			owner, name, err := SplitFullName(tt.fullName)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitFullName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if owner != tt.wantOwner || name != tt.wantName {
+				t.Errorf("SplitFullName() = %q, %q, want %q, %q", owner, name, tt.wantOwner, tt.wantName)
+			}
+		})
+	}
+}
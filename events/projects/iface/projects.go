@@ -0,0 +1,67 @@
+package iface
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// Projects defines the interface used by the classic project board logic.
+type Projects interface {
+	ListProjectColumns(ctx context.Context, projectID int64, opt *github.ListOptions) ([]*github.ProjectColumn, *github.Response, error)
+	GetProjectColumn(ctx context.Context, id int64) (*github.ProjectColumn, *github.Response, error)
+	ListProjectCards(ctx context.Context, columnID int64, opt *github.ProjectCardListOptions) ([]*github.ProjectCard, *github.Response, error)
+	GetProjectCard(ctx context.Context, cardID int64) (*github.ProjectCard, *github.Response, error)
+	CreateProjectCard(ctx context.Context, columnID int64, opt *github.ProjectCardOptions) (*github.ProjectCard, *github.Response, error)
+	MoveProjectCard(ctx context.Context, cardID int64, opt *github.ProjectCardMoveOptions) (*github.Response, error)
+}
+
+// ProjectsImpl implements the Projects interface.
+type ProjectsImpl struct {
+	*github.ProjectsService
+}
+
+// NewProjects creates a new Projects instance.
+func NewProjects(service *github.ProjectsService) *ProjectsImpl {
+	return &ProjectsImpl{service}
+}
+
+// ListProjectColumns lists the columns of the project.
+func (p *ProjectsImpl) ListProjectColumns(
+	ctx context.Context, projectID int64,
+	opt *github.ListOptions) ([]*github.ProjectColumn, *github.Response, error) {
+	return p.ProjectsService.ListProjectColumns(ctx, projectID, opt)
+}
+
+// GetProjectColumn gets a project column by ID.
+func (p *ProjectsImpl) GetProjectColumn(
+	ctx context.Context, id int64) (*github.ProjectColumn, *github.Response, error) {
+	return p.ProjectsService.GetProjectColumn(ctx, id)
+}
+
+// ListProjectCards lists the cards in a project column.
+func (p *ProjectsImpl) ListProjectCards(
+	ctx context.Context, columnID int64,
+	opt *github.ProjectCardListOptions) ([]*github.ProjectCard, *github.Response, error) {
+	return p.ProjectsService.ListProjectCards(ctx, columnID, opt)
+}
+
+// GetProjectCard gets a project card by ID.
+func (p *ProjectsImpl) GetProjectCard(
+	ctx context.Context, cardID int64) (*github.ProjectCard, *github.Response, error) {
+	return p.ProjectsService.GetProjectCard(ctx, cardID)
+}
+
+// CreateProjectCard creates a card in the project column.
+func (p *ProjectsImpl) CreateProjectCard(
+	ctx context.Context, columnID int64,
+	opt *github.ProjectCardOptions) (*github.ProjectCard, *github.Response, error) {
+	return p.ProjectsService.CreateProjectCard(ctx, columnID, opt)
+}
+
+// MoveProjectCard moves a card within its project.
+func (p *ProjectsImpl) MoveProjectCard(
+	ctx context.Context, cardID int64,
+	opt *github.ProjectCardMoveOptions) (*github.Response, error) {
+	return p.ProjectsService.MoveProjectCard(ctx, cardID, opt)
+}
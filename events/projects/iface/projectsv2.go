@@ -0,0 +1,20 @@
+package iface
+
+import (
+	"context"
+	"time"
+
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/projectsv2"
+)
+
+// ProjectsV2 defines the interface used by the Projects v2 board logic. It is
+// implemented by *projectsv2.Client.
+type ProjectsV2 interface {
+	GetProject(ctx context.Context, owner string, number int) (*projectsv2.Project, error)
+	GetItem(ctx context.Context, id string) (*projectsv2.Item, error)
+	FindItem(ctx context.Context, projectID, contentID string) (*projectsv2.Item, error)
+	AddItem(ctx context.Context, projectID, contentID string) (string, error)
+	SetValue(ctx context.Context, p *projectsv2.Project, itemID, field, value string, now time.Time) error
+}
+
+var _ ProjectsV2 = &projectsv2.Client{}
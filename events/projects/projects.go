@@ -1,34 +1,397 @@
+// Package projects keeps issue labels and a project board in sync.
+//
+// Each configured column maps to a label. Moving a card to a column adds the
+// label of that column to the issue and removes the labels of the other
+// columns. Adding a column label to an issue moves its card to that column,
+// adding the issue to the board if needed. New issues may also be added to the
+// board when they are opened. For example, for a classic project:
+//
+//	project: 1234
+//	columns:
+//	- {name: Backlog, label: backlog}
+//	- {name: Current, label: current}
+//	- {name: Done, label: closed}
+//	create_column: Backlog
+//
+// For a Projects v2 board, the columns are the options of a single-select
+// field, "Status" by default:
+//
+//	owner: my-org
+//	number: 5
+//	field: Status
+//	columns:
+//	- {name: Todo, label: backlog}
+//	- {name: In Progress, label: current}
+//
+// Changes never loop: each direction first reads the current state from
+// Github, and does nothing if the event is stale or the other side already
+// matches. So the webhook caused by a change in one direction is a no-op in
+// the other.
 package projects
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/events/projects/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
 )
 
-// Event encapsulates operations on a *github.IssuesEvent.
+// Column maps a project column to an issue label.
+type Column struct {
+	Name  string `yaml:"name" json:"name"`
+	Label string `yaml:"label" json:"label"`
+}
+
+// Config is the project board automation configuration. Either Project, or
+// Owner and Number, select the board.
+type Config struct {
+	// Project is the ID of a classic project.
+	Project int64 `yaml:"project,omitempty" json:"project,omitempty"`
+	// Owner is the organization or user owning a Projects v2 board.
+	Owner string `yaml:"owner,omitempty" json:"owner,omitempty"`
+	// Number is the number of the Projects v2 board.
+	Number int `yaml:"number,omitempty" json:"number,omitempty"`
+	// Field is the single-select field of the Projects v2 board holding the
+	// column. Defaults to "Status".
+	Field string `yaml:"field,omitempty" json:"field,omitempty"`
+	// Columns map column names to labels.
+	Columns []Column `yaml:"columns" json:"columns"`
+	// CreateColumn, if set, adds opened issues to the board. The card is
+	// created in the column of the first column label of the issue, or else in
+	// the named column.
+	CreateColumn string `yaml:"create_column,omitempty" json:"create_column,omitempty"`
+}
+
+// DefaultField is the default column field of Projects v2 boards.
+const DefaultField = "Status"
+
+// Validate checks the configuration and sets defaults.
+func (c *Config) Validate() error {
+	switch {
+	case c.Project < 0 || c.Number < 0:
+		return fmt.Errorf("project and number must not be negative")
+	case c.Project == 0 && (c.Owner == "" || c.Number == 0):
+		return fmt.Errorf("project, or owner and number, are required")
+	case c.Project != 0 && (c.Owner != "" || c.Number != 0 || c.Field != ""):
+		return fmt.Errorf("project may not be used with owner, number or field")
+	}
+	if c.V2() && c.Field == "" {
+		c.Field = DefaultField
+	}
+	if len(c.Columns) == 0 {
+		return fmt.Errorf("columns are required")
+	}
+	for i, col := range c.Columns {
+		if col.Name == "" || col.Label == "" {
+			return fmt.Errorf("column %d: name and label are required", i)
+		}
+		for _, prev := range c.Columns[:i] {
+			if strings.EqualFold(prev.Name, col.Name) || strings.EqualFold(prev.Label, col.Label) {
+				return fmt.Errorf("column %d: duplicate name or label", i)
+			}
+		}
+	}
+	if c.CreateColumn != "" && c.LabelOf(c.CreateColumn) == "" {
+		return fmt.Errorf("create_column %q is not a configured column", c.CreateColumn)
+	}
+	return nil
+}
+
+// V2 reports whether the configuration selects a Projects v2 board.
+func (c *Config) V2() bool {
+	return c.Project == 0
+}
+
+// LabelOf returns the label of the named column, or "" if it is not mapped.
+func (c *Config) LabelOf(column string) string {
+	for _, col := range c.Columns {
+		if strings.EqualFold(col.Name, column) {
+			return col.Label
+		}
+	}
+	return ""
+}
+
+// ColumnOf returns the column name of the label, or "" if it is not mapped.
+func (c *Config) ColumnOf(label string) string {
+	for _, col := range c.Columns {
+		if strings.EqualFold(col.Label, label) {
+			return col.Name
+		}
+	}
+	return ""
+}
+
+// Event encapsulates operations on a *github.ProjectCardEvent.
 type Event struct {
-	*github.Client
 	*github.ProjectCardEvent
-	// iface.Issues
+	iface.Projects
 }
 
-// NewEvent creates a new Event based on the given client and event.
-func NewEvent(client *github.Client, event *github.ProjectCardEvent) *Event {
+// NewEvent creates a new Event based on the given projects and event.
+func NewEvent(projects iface.Projects, event *github.ProjectCardEvent) *Event {
 	return &Event{
-		Client:           client,
 		ProjectCardEvent: event,
+		Projects:         projects,
+	}
+}
+
+// GetColumn returns the current column of the event card.
+func (ev *Event) GetColumn(ctx context.Context) (*github.ProjectColumn, error) {
+	col, _, err := ev.Projects.GetProjectColumn(ctx, ev.ProjectCardEvent.GetProjectCard().GetColumnID())
+	return col, err
+}
+
+// ParseContentURL returns the repository and number of the issue or pull
+// request in a card content URL, e.g.
+// "https://api.github.com/repos/owner/repo/issues/1".
+func ParseContentURL(u string) (owner, repo string, number int, ok bool) {
+	parts := strings.Split(strings.TrimSuffix(u, "/"), "/")
+	n := len(parts)
+	if n < 5 || parts[n-5] != "repos" || parts[n-2] != "issues" {
+		return "", "", 0, false
+	}
+	number, err := strconv.Atoi(parts[n-1])
+	if err != nil {
+		return "", "", 0, false
+	}
+	return parts[n-4], parts[n-3], number, true
+}
+
+// Target is a project board that holds issues in columns.
+type Target interface {
+	// Place moves the event issue to the named column, adding it to the board
+	// if needed. Place reports whether the board changed.
+	Place(ctx context.Context, ev *issues.Event, column string) (bool, error)
+}
+
+// Board reads and changes the cards of a classic project. Columns are read
+// once.
+type Board struct {
+	iface.Projects
+	// ID is the project ID.
+	ID int64
+
+	columns []*github.ProjectColumn
+}
+
+// NewBoard creates a Board for the given project.
+func NewBoard(projects iface.Projects, id int64) *Board {
+	return &Board{Projects: projects, ID: id}
+}
+
+// Columns returns the project columns.
+func (b *Board) Columns(ctx context.Context) ([]*github.ProjectColumn, error) {
+	if b.columns != nil {
+		return b.columns, nil
+	}
+	cols := []*github.ProjectColumn{}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := b.ListProjectColumns(ctx, b.ID, opt)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, list...)
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	b.columns = cols
+	return cols, nil
+}
+
+// Column returns the named column, or nil if the project has no such column.
+func (b *Board) Column(ctx context.Context, name string) (*github.ProjectColumn, error) {
+	cols, err := b.Columns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range cols {
+		if strings.EqualFold(col.GetName(), name) {
+			return col, nil
+		}
+	}
+	return nil, nil
+}
+
+// columnByID returns the column with the given ID, or nil if it is not in the
+// project.
+func (b *Board) columnByID(ctx context.Context, id int64) (*github.ProjectColumn, error) {
+	cols, err := b.Columns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range cols {
+		if col.GetID() == id {
+			return col, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindCard returns the card of the issue and its column, or nil if the issue
+// is not on the board.
+func (b *Board) FindCard(ctx context.Context, owner, repo string, number int) (*github.ProjectCard, *github.ProjectColumn, error) {
+	cols, err := b.Columns(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, col := range cols {
+		opt := &github.ProjectCardListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			cards, resp, err := b.ListProjectCards(ctx, col.GetID(), opt)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, card := range cards {
+				o, r, n, ok := ParseContentURL(card.GetContentURL())
+				if ok && n == number && strings.EqualFold(o, owner) && strings.EqualFold(r, repo) {
+					return card, col, nil
+				}
+			}
+			if resp == nil || resp.NextPage == 0 {
+				break
+			}
+			opt.Page = resp.NextPage
+		}
+	}
+	return nil, nil, nil
+}
+
+// CardMoved labels the issue of the event card for its column, and removes the
+// labels of the other columns. CardMoved does nothing if the card is not on
+// the configured board, is not in a mapped column, or has moved since the
+// event. CardMoved reports whether the labels changed.
+func (c *Config) CardMoved(ctx context.Context, ev *Event, issue *issues.Event) (bool, error) {
+	if c.V2() || ev.ProjectCardEvent.GetProjectCard().GetContentURL() == "" {
+		// Notes have no issue.
+		return false, nil
+	}
+	card, _, err := ev.Projects.GetProjectCard(ctx, ev.ProjectCardEvent.GetProjectCard().GetID())
+	if err != nil {
+		return false, err
+	}
+	if card.GetArchived() {
+		return false, nil
+	}
+	id := ev.ProjectCardEvent.GetProjectCard().GetColumnID()
+	if current := columnIDFromURL(card.GetColumnURL()); current != 0 && current != id {
+		log.Printf("projects: card %d moved again, skipping stale event", card.GetID())
+		return false, nil
+	}
+	col, err := NewBoard(ev.Projects, c.Project).columnByID(ctx, id)
+	if err != nil || col == nil {
+		return false, err
+	}
+	return c.setColumnLabel(ctx, issue, col.GetName())
+}
+
+// setColumnLabel adds the label of the named column to the issue and removes
+// the labels of the other columns. The current labels are read first, so only
+// actual differences are changed.
+func (c *Config) setColumnLabel(ctx context.Context, issue *issues.Event, column string) (bool, error) {
+	label := c.LabelOf(column)
+	if label == "" {
+		return false, nil
+	}
+	var remove []string
+	for _, other := range c.Columns {
+		if other.Label != label {
+			remove = append(remove, other.Label)
+		}
+	}
+	issue.Refetch = true
+	result, err := issue.ReconcileLabels(ctx, []string{label}, remove)
+	if err != nil {
+		return false, err
+	}
+	return result.Changed(), nil
+}
+
+// IssueLabeled moves the card of the event issue to the column of the added
+// label, or adds the issue to the board in that column. IssueLabeled does
+// nothing if the label is not mapped or was removed since the event.
+// IssueLabeled reports whether the board changed.
+func (c *Config) IssueLabeled(ctx context.Context, b Target, ev *issues.Event) (bool, error) {
+	name := c.ColumnOf(ev.GetLabel().GetName())
+	if name == "" {
+		return false, nil
+	}
+	ev.Refetch = true
+	result, err := ev.ReconcileLabels(ctx, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	if !slice.ContainsFold(result.Labels, ev.GetLabel().GetName()) {
+		log.Printf("projects: label %q was removed, skipping stale event", ev.GetLabel().GetName())
+		return false, nil
+	}
+	return b.Place(ctx, ev, name)
+}
+
+// IssueOpened adds the event issue to the board, if CreateColumn is set.
+// IssueOpened reports whether the board changed.
+func (c *Config) IssueOpened(ctx context.Context, b Target, ev *issues.Event) (bool, error) {
+	if c.CreateColumn == "" {
+		return false, nil
+	}
+	name := c.CreateColumn
+	for _, col := range c.Columns {
+		if slice.ContainsFold(ev.Labels(), col.Label) {
+			name = col.Name
+			break
+		}
+	}
+	return b.Place(ctx, ev, name)
+}
+
+// Place moves the card of the event issue to the named column, creating the
+// card if needed.
+func (b *Board) Place(ctx context.Context, ev *issues.Event, name string) (bool, error) {
+	col, err := b.Column(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	if col == nil {
+		return false, fmt.Errorf("project %d has no column %q", b.ID, name)
+	}
+	owner, repo, number := ev.GetRepo().GetOwner().GetLogin(), ev.GetRepo().GetName(), ev.GetIssue().GetNumber()
+	card, current, err := b.FindCard(ctx, owner, repo, number)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case card == nil:
+		log.Printf("projects: adding %s/%s#%d to column %q", owner, repo, number, name)
+		_, _, err = b.CreateProjectCard(ctx, col.GetID(), &github.ProjectCardOptions{
+			ContentID:   ev.GetIssue().GetID(),
+			ContentType: "Issue",
+		})
+	case current.GetID() == col.GetID():
+		return false, nil
+	default:
+		log.Printf("projects: moving %s/%s#%d to column %q", owner, repo, number, name)
+		_, err = b.MoveProjectCard(ctx, card.GetID(), &github.ProjectCardMoveOptions{
+			Position: "top",
+			ColumnID: col.GetID(),
+		})
 	}
+	return err == nil, err
 }
 
-func (ev *Event) GetColumn(ctx context.Context) *github.ProjectColumn {
-	col, resp, err := ev.Client.Projects.GetProjectColumn(
-		ctx, ev.ProjectCardEvent.GetProjectCard().GetColumnID())
-	log.Printf("%#v", resp.Rate)
+// columnIDFromURL returns the column ID at the end of a column URL, or zero.
+func columnIDFromURL(u string) int64 {
+	id, err := strconv.ParseInt(u[strings.LastIndex(u, "/")+1:], 10, 64)
 	if err != nil {
-		log.Println(err)
-		return nil
+		return 0
 	}
-	return col
+	return id
 }
@@ -0,0 +1,338 @@
+package projects
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	issuesiface "github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/projects/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/fakegithub"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{
+			name:   "okay",
+			config: "project: 1\ncolumns: [{name: Backlog, label: backlog}, {name: Current, label: current}]\ncreate_column: Backlog\n",
+		},
+		{
+			name:   "json",
+			config: `{"project": 1, "columns": [{"name": "Current", "label": "current"}]}`,
+		},
+		{
+			name:   "v2",
+			config: "owner: org\nnumber: 5\ncolumns: [{name: In Progress, label: current}]\n",
+		},
+		{
+			name:    "error-v2-missing-number",
+			config:  "owner: org\ncolumns: [{name: Current, label: current}]\n",
+			wantErr: true,
+		},
+		{
+			name:    "error-classic-and-v2",
+			config:  "project: 1\nowner: org\nnumber: 5\ncolumns: [{name: Current, label: current}]\n",
+			wantErr: true,
+		},
+		{
+			name:    "error-missing-project",
+			config:  "columns: [{name: Current, label: current}]\n",
+			wantErr: true,
+		},
+		{
+			name:    "error-missing-columns",
+			config:  "project: 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "error-missing-label",
+			config:  "project: 1\ncolumns: [{name: Current}]\n",
+			wantErr: true,
+		},
+		{
+			name:    "error-duplicate-label",
+			config:  "project: 1\ncolumns: [{name: A, label: x}, {name: B, label: X}]\n",
+			wantErr: true,
+		},
+		{
+			name:    "error-unknown-create-column",
+			config:  "project: 1\ncolumns: [{name: A, label: a}]\ncreate_column: B\n",
+			wantErr: true,
+		},
+		{
+			name:    "error-unknown-field",
+			config:  "project: 1\ncolumns: [{name: A, label: a}]\ncolumn: A\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := configfile.Parse([]byte(tt.config), &Config{}); (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseContentURL(t *testing.T) {
+	tests := []struct {
+		url    string
+		owner  string
+		repo   string
+		number int
+		ok     bool
+	}{
+		{"https://api.github.com/repos/o/r/issues/12", "o", "r", 12, true},
+		{"http://127.0.0.1:1234/repos/o/r/issues/3/", "o", "r", 3, true},
+		{"https://api.github.com/repos/o/r/pulls/12", "", "", 0, false},
+		{"https://api.github.com/repos/o/r/issues/x", "", "", 0, false},
+		{"", "", "", 0, false},
+	}
+	for _, tt := range tests {
+		owner, repo, number, ok := ParseContentURL(tt.url)
+		if owner != tt.owner || repo != tt.repo || number != tt.number || ok != tt.ok {
+			t.Errorf("ParseContentURL(%q) = %q, %q, %d, %v; want %q, %q, %d, %v", tt.url,
+				owner, repo, number, ok, tt.owner, tt.repo, tt.number, tt.ok)
+		}
+	}
+}
+
+// board is a fake project with one issue, used by the sync tests.
+type board struct {
+	s       *fakegithub.Server
+	config  *Config
+	columns map[string]int64
+}
+
+func newBoard(labels ...string) *board {
+	s := fakegithub.NewServer()
+	p := s.AddProject("Backlog", "Current", "Done")
+	issue := &github.Issue{ID: github.Int64(1), Number: github.Int(1)}
+	for _, l := range labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: github.String(l)})
+	}
+	s.AddIssue("owner", "repo", issue)
+	b := &board{
+		s: s,
+		config: &Config{
+			Project: p.ID,
+			Columns: []Column{
+				{Name: "Backlog", Label: "backlog"},
+				{Name: "Current", Label: "current"},
+				{Name: "Done", Label: "closed"},
+			},
+			CreateColumn: "Backlog",
+		},
+		columns: map[string]int64{},
+	}
+	for _, col := range p.Columns {
+		b.columns[col.GetName()] = col.GetID()
+	}
+	return b
+}
+
+// addCard adds the issue to the named column and returns the card.
+func (b *board) addCard(t *testing.T, column string) *github.ProjectCard {
+	card, _, err := b.s.Client().Projects.CreateProjectCard(context.Background(), b.columns[column],
+		&github.ProjectCardOptions{ContentID: 1, ContentType: "Issue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return card
+}
+
+// moveCard moves the card to the named column.
+func (b *board) moveCard(t *testing.T, card *github.ProjectCard, column string) {
+	_, err := b.s.Client().Projects.MoveProjectCard(context.Background(), card.GetID(),
+		&github.ProjectCardMoveOptions{Position: "top", ColumnID: b.columns[column]})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cardColumns returns the column names holding a card.
+func (b *board) cardColumns() []string {
+	names := []string{}
+	for _, name := range []string{"Backlog", "Current", "Done"} {
+		if len(b.s.Cards(b.columns[name])) > 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (b *board) issueEvent(action, label string) *issues.Event {
+	ev := issues.NewEvent(issuesiface.NewIssues(b.s.Client().Issues), &github.IssuesEvent{
+		Action: github.String(action),
+		Issue:  b.s.Issue("owner", "repo", 1),
+		Repo: &github.Repository{
+			Name:  github.String("repo"),
+			Owner: &github.User{Login: github.String("owner")},
+		},
+	})
+	if label != "" {
+		ev.Label = &github.Label{Name: github.String(label)}
+	}
+	return ev
+}
+
+func (b *board) cardEvent(card *github.ProjectCard, column string) *Event {
+	return NewEvent(iface.NewProjects(b.s.Client().Projects), &github.ProjectCardEvent{
+		Action: github.String("moved"),
+		ProjectCard: &github.ProjectCard{
+			ID:         card.ID,
+			ColumnID:   github.Int64(b.columns[column]),
+			ContentURL: card.ContentURL,
+		},
+	})
+}
+
+func (b *board) Board() *Board {
+	return NewBoard(iface.NewProjects(b.s.Client().Projects), b.config.Project)
+}
+
+func TestConfig_CardMoved(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      []string
+		moveTo      string
+		eventColumn string
+		wantChanged bool
+		wantLabels  []string
+	}{
+		{
+			name:        "moved-to-current",
+			labels:      []string{"backlog", "bug"},
+			moveTo:      "Current",
+			eventColumn: "Current",
+			wantChanged: true,
+			wantLabels:  []string{"bug", "current"},
+		},
+		{
+			name:        "already-labeled",
+			labels:      []string{"current"},
+			moveTo:      "Current",
+			eventColumn: "Current",
+			wantLabels:  []string{"current"},
+		},
+		{
+			name:        "stale-event",
+			labels:      []string{"backlog"},
+			moveTo:      "Done",
+			eventColumn: "Current",
+			wantLabels:  []string{"backlog"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBoard(tt.labels...)
+			defer b.s.Close()
+			card := b.addCard(t, "Backlog")
+			b.moveCard(t, card, tt.moveTo)
+
+			changed, err := b.config.CardMoved(context.Background(), b.cardEvent(card, tt.eventColumn), b.issueEvent("", ""))
+			if err != nil {
+				t.Fatalf("Config.CardMoved() error = %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("Config.CardMoved() = %v, want %v", changed, tt.wantChanged)
+			}
+			if got := b.s.IssueLabels("owner", "repo", 1); !reflect.DeepEqual(got, tt.wantLabels) {
+				t.Errorf("IssueLabels() = %q, want %q", got, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestConfig_IssueLabeled(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      []string
+		card        string
+		added       string
+		wantChanged bool
+		wantColumns []string
+	}{
+		{
+			name:        "move",
+			labels:      []string{"current"},
+			card:        "Backlog",
+			added:       "current",
+			wantChanged: true,
+			wantColumns: []string{"Current"},
+		},
+		{
+			name:        "create",
+			labels:      []string{"current"},
+			added:       "current",
+			wantChanged: true,
+			wantColumns: []string{"Current"},
+		},
+		{
+			name:        "already-in-column",
+			labels:      []string{"current"},
+			card:        "Current",
+			added:       "current",
+			wantColumns: []string{"Current"},
+		},
+		{
+			name:        "label-removed-since",
+			card:        "Backlog",
+			added:       "current",
+			wantColumns: []string{"Backlog"},
+		},
+		{
+			name:        "unmapped-label",
+			labels:      []string{"bug"},
+			card:        "Backlog",
+			added:       "bug",
+			wantColumns: []string{"Backlog"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBoard(tt.labels...)
+			defer b.s.Close()
+			if tt.card != "" {
+				b.addCard(t, tt.card)
+			}
+			changed, err := b.config.IssueLabeled(context.Background(), b.Board(), b.issueEvent("labeled", tt.added))
+			if err != nil {
+				t.Fatalf("Config.IssueLabeled() error = %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("Config.IssueLabeled() = %v, want %v", changed, tt.wantChanged)
+			}
+			if got := b.cardColumns(); !reflect.DeepEqual(got, tt.wantColumns) {
+				t.Errorf("card columns = %q, want %q", got, tt.wantColumns)
+			}
+		})
+	}
+}
+
+func TestConfig_IssueOpened(t *testing.T) {
+	b := newBoard("bug", "current")
+	defer b.s.Close()
+	ctx := context.Background()
+	if changed, err := b.config.IssueOpened(ctx, b.Board(), b.issueEvent("opened", "")); err != nil || !changed {
+		t.Fatalf("Config.IssueOpened() = %v, %v; want true, nil", changed, err)
+	}
+	if got, want := b.cardColumns(), []string{"Current"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("card columns = %q, want %q", got, want)
+	}
+	// A second delivery of the same event finds the card.
+	if changed, err := b.config.IssueOpened(ctx, b.Board(), b.issueEvent("opened", "")); err != nil || changed {
+		t.Errorf("Config.IssueOpened() = %v, %v; want false, nil", changed, err)
+	}
+	b.config.CreateColumn = ""
+	if changed, err := b.config.IssueOpened(ctx, b.Board(), b.issueEvent("opened", "")); err != nil || changed {
+		t.Errorf("Config.IssueOpened() without create_column = %v, %v; want false, nil", changed, err)
+	}
+}
@@ -0,0 +1,114 @@
+package projects
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/events/projects/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/projectsv2"
+)
+
+// BoardV2 reads and changes the items of a Projects v2 board. The project and
+// its fields are read once.
+type BoardV2 struct {
+	iface.ProjectsV2
+	// Owner is the organization or user owning the project.
+	Owner string
+	// Number is the project number.
+	Number int
+	// Field is the single-select field holding the column.
+	Field string
+
+	project *projectsv2.Project
+}
+
+// NewBoardV2 creates a BoardV2 for the Projects v2 board of the configuration.
+func NewBoardV2(projects iface.ProjectsV2, c *Config) *BoardV2 {
+	return &BoardV2{ProjectsV2: projects, Owner: c.Owner, Number: c.Number, Field: c.Field}
+}
+
+// Project returns the project and its fields.
+func (b *BoardV2) Project(ctx context.Context) (*projectsv2.Project, error) {
+	if b.project != nil {
+		return b.project, nil
+	}
+	p, err := b.GetProject(ctx, b.Owner, b.Number)
+	if err != nil {
+		return nil, err
+	}
+	if f := p.Field(b.Field); f == nil || f.DataType != projectsv2.SingleSelect {
+		return nil, fmt.Errorf("project %s/%d has no single-select field %q", b.Owner, b.Number, b.Field)
+	}
+	b.project = p
+	return p, nil
+}
+
+// Place sets the column field of the item of the event issue, adding the issue
+// to the board if needed.
+func (b *BoardV2) Place(ctx context.Context, ev *issues.Event, column string) (bool, error) {
+	p, err := b.Project(ctx)
+	if err != nil {
+		return false, err
+	}
+	contentID := ev.GetIssue().GetNodeID()
+	if contentID == "" {
+		return false, fmt.Errorf("issue %s has no node ID", ev.GetIssue().GetHTMLURL())
+	}
+	item, err := b.FindItem(ctx, p.ID, contentID)
+	if err != nil {
+		return false, err
+	}
+	var itemID string
+	if item == nil {
+		log.Printf("projects: adding %s to %s/%d", ev.GetIssue().GetHTMLURL(), b.Owner, b.Number)
+		if itemID, err = b.AddItem(ctx, p.ID, contentID); err != nil {
+			return false, err
+		}
+	} else {
+		if v := item.Value(b.Field); v != nil && strings.EqualFold(v.Name, column) {
+			return false, nil
+		}
+		itemID = item.ID
+	}
+	log.Printf("projects: setting %s of %s to %q", b.Field, ev.GetIssue().GetHTMLURL(), column)
+	if err := b.SetValue(ctx, p, itemID, b.Field, column, time.Now()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ItemChanged labels the issue of a Projects v2 item for the column of the
+// item, and removes the labels of the other columns. The item must be read
+// after the event was received, so a stale event applies the current column.
+// ItemChanged does nothing unless the event added or restored the item, or
+// changed the column field, of an issue on the configured board. ItemChanged
+// reports whether the labels changed.
+func (c *Config) ItemChanged(ctx context.Context, b *BoardV2, ev *projectsv2.ProjectV2ItemEvent, item *projectsv2.Item, issue *issues.Event) (bool, error) {
+	switch ev.GetAction() {
+	case "created", "edited", "restored":
+	default:
+		return false, nil
+	}
+	if !c.V2() || item.Archived || item.Type != "ISSUE" {
+		return false, nil
+	}
+	p, err := b.Project(ctx)
+	if err != nil {
+		return false, err
+	}
+	if item.ProjectID != p.ID {
+		return false, nil
+	}
+	if ev.GetAction() == "edited" && ev.GetChangedFieldID() != p.Field(b.Field).ID {
+		return false, nil
+	}
+	v := item.Value(b.Field)
+	if v == nil {
+		return false, nil
+	}
+	return c.setColumnLabel(ctx, issue, v.Name)
+}
@@ -0,0 +1,238 @@
+package projects
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/projects/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/projectsv2"
+)
+
+type fakeProjectsV2 struct {
+	project *projectsv2.Project
+	// items maps content IDs to items.
+	items map[string]*projectsv2.Item
+	calls []string
+}
+
+func newFakeProjectsV2() *fakeProjectsV2 {
+	return &fakeProjectsV2{
+		project: &projectsv2.Project{
+			ID:     "PVT_1",
+			Number: 5,
+			Fields: []projectsv2.Field{
+				{ID: "F_status", Name: "Status", DataType: projectsv2.SingleSelect, Options: []projectsv2.Option{
+					{ID: "O_todo", Name: "Todo"}, {ID: "O_progress", Name: "In Progress"},
+				}},
+				{ID: "F_iter", Name: "Iteration", DataType: projectsv2.Iteration},
+			},
+		},
+		items: map[string]*projectsv2.Item{},
+	}
+}
+
+func (f *fakeProjectsV2) GetProject(ctx context.Context, owner string, number int) (*projectsv2.Project, error) {
+	if owner != "org" || number != f.project.Number {
+		return nil, fmt.Errorf("project %s/%d not found", owner, number)
+	}
+	return f.project, nil
+}
+
+func (f *fakeProjectsV2) GetItem(ctx context.Context, id string) (*projectsv2.Item, error) {
+	for _, it := range f.items {
+		if it.ID == id {
+			return it, nil
+		}
+	}
+	return nil, fmt.Errorf("project item %s not found", id)
+}
+
+func (f *fakeProjectsV2) FindItem(ctx context.Context, projectID, contentID string) (*projectsv2.Item, error) {
+	return f.items[contentID], nil
+}
+
+func (f *fakeProjectsV2) AddItem(ctx context.Context, projectID, contentID string) (string, error) {
+	f.calls = append(f.calls, "add "+contentID)
+	f.items[contentID] = &projectsv2.Item{
+		ID: "PVTI_" + contentID, Type: "ISSUE", ProjectID: projectID, ContentID: contentID,
+	}
+	return f.items[contentID].ID, nil
+}
+
+func (f *fakeProjectsV2) SetValue(ctx context.Context, p *projectsv2.Project, itemID, field, value string, now time.Time) error {
+	f.calls = append(f.calls, fmt.Sprintf("set %s %s=%s", itemID, field, value))
+	return nil
+}
+
+var _ iface.ProjectsV2 = &fakeProjectsV2{}
+
+// setStatus sets the status of the item for contentID, adding the item if
+// needed.
+func (f *fakeProjectsV2) setStatus(contentID, status string) *projectsv2.Item {
+	f.AddItem(context.Background(), f.project.ID, contentID)
+	it := f.items[contentID]
+	it.Number = 1
+	it.Repository = "owner/repo"
+	it.Values = []projectsv2.FieldValue{{FieldID: "F_status", FieldName: "Status", Name: status}}
+	f.calls = nil
+	return it
+}
+
+func v2Config() *Config {
+	c := &Config{
+		Owner:  "org",
+		Number: 5,
+		Columns: []Column{
+			{Name: "Todo", Label: "backlog"},
+			{Name: "In Progress", Label: "current"},
+		},
+	}
+	if err := c.Validate(); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func TestBoardV2_Place(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    string
+		column    string
+		wantCalls []string
+	}{
+		{
+			name:      "add",
+			column:    "In Progress",
+			wantCalls: []string{"add I_1", "set PVTI_I_1 Status=In Progress"},
+		},
+		{
+			name:      "move",
+			status:    "Todo",
+			column:    "In Progress",
+			wantCalls: []string{"set PVTI_I_1 Status=In Progress"},
+		},
+		{
+			name:   "already-in-column",
+			status: "In Progress",
+			column: "in progress",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeProjectsV2()
+			if tt.status != "" {
+				f.setStatus("I_1", tt.status)
+			}
+			b := newBoard()
+			defer b.s.Close()
+			ev := b.issueEvent("labeled", "")
+			ev.Issue.NodeID = github.String("I_1")
+			changed, err := NewBoardV2(f, v2Config()).Place(context.Background(), ev, tt.column)
+			if err != nil {
+				t.Fatalf("BoardV2.Place() error = %v", err)
+			}
+			if changed != (len(tt.wantCalls) > 0) || !reflect.DeepEqual(f.calls, tt.wantCalls) {
+				t.Errorf("BoardV2.Place() = %v, calls %q; want %q", changed, f.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestBoardV2_Place_Errors(t *testing.T) {
+	b := newBoard()
+	defer b.s.Close()
+	ev := b.issueEvent("labeled", "")
+	if _, err := NewBoardV2(newFakeProjectsV2(), v2Config()).Place(context.Background(), ev, "Todo"); err == nil {
+		t.Errorf("BoardV2.Place() without node ID error = nil")
+	}
+	ev.Issue.NodeID = github.String("I_1")
+	c := v2Config()
+	c.Field = "Iteration"
+	if _, err := NewBoardV2(newFakeProjectsV2(), c).Place(context.Background(), ev, "Todo"); err == nil {
+		t.Errorf("BoardV2.Place() with iteration field error = nil")
+	}
+}
+
+func TestConfig_ItemChanged(t *testing.T) {
+	tests := []struct {
+		name        string
+		action      string
+		field       string
+		project     string
+		archived    bool
+		wantChanged bool
+		wantLabels  []string
+	}{
+		{
+			name:        "status-edited",
+			action:      "edited",
+			field:       "F_status",
+			wantChanged: true,
+			wantLabels:  []string{"bug", "current"},
+		},
+		{
+			name:        "created",
+			action:      "created",
+			wantChanged: true,
+			wantLabels:  []string{"bug", "current"},
+		},
+		{
+			name:       "other-field-edited",
+			action:     "edited",
+			field:      "F_iter",
+			wantLabels: []string{"backlog", "bug"},
+		},
+		{
+			name:       "other-project",
+			action:     "edited",
+			field:      "F_status",
+			project:    "PVT_2",
+			wantLabels: []string{"backlog", "bug"},
+		},
+		{
+			name:       "archived",
+			action:     "edited",
+			field:      "F_status",
+			archived:   true,
+			wantLabels: []string{"backlog", "bug"},
+		},
+		{
+			name:       "deleted",
+			action:     "deleted",
+			wantLabels: []string{"backlog", "bug"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeProjectsV2()
+			item := f.setStatus("I_1", "In Progress")
+			item.Archived = tt.archived
+			if tt.project != "" {
+				item.ProjectID = tt.project
+			}
+			b := newBoard("backlog", "bug")
+			defer b.s.Close()
+			ev := &projectsv2.ProjectV2ItemEvent{Action: github.String(tt.action)}
+			if tt.field != "" {
+				ev.Changes = &projectsv2.ProjectV2Changes{
+					FieldValue: &projectsv2.ProjectV2FieldChange{FieldNodeID: github.String(tt.field)},
+				}
+			}
+			c := v2Config()
+			changed, err := c.ItemChanged(context.Background(), NewBoardV2(f, c), ev, item, b.issueEvent("", ""))
+			if err != nil {
+				t.Fatalf("Config.ItemChanged() error = %v", err)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("Config.ItemChanged() = %v, want %v", changed, tt.wantChanged)
+			}
+			if got := b.s.IssueLabels("owner", "repo", 1); !reflect.DeepEqual(got, tt.wantLabels) {
+				t.Errorf("IssueLabels() = %q, want %q", got, tt.wantLabels)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package pulls
+
+import (
+	"strings"
+)
+
+// CodeOwnersPaths are the locations searched for a CODEOWNERS file, in order.
+var CodeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// CodeOwners is a parsed CODEOWNERS file.
+type CodeOwners struct {
+	rules []ownerRule
+}
+
+type ownerRule struct {
+	glob   string
+	dir    bool
+	owners []string
+}
+
+// ParseCodeOwners parses the contents of a CODEOWNERS file. Each line is a
+// path pattern followed by owners: "@user", "@org/team" or an email address.
+// Patterns follow the gitignore rules used by Github: a pattern without a
+// slash matches at any depth, a leading or inner slash anchors it to the
+// repository root, and a directory pattern matches everything below it.
+// Invalid lines are ignored.
+func ParseCodeOwners(content string) *CodeOwners {
+	c := &CodeOwners{}
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pattern := fields[0]
+		anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+		glob := strings.Trim(pattern, "/")
+		if !anchored {
+			glob = "**/" + glob
+		}
+		if !validGlob(glob) {
+			continue
+		}
+		last := glob[strings.LastIndex(glob, "/")+1:]
+		c.rules = append(c.rules, ownerRule{
+			glob:   glob,
+			dir:    !strings.ContainsAny(last, "*?["),
+			owners: fields[1:],
+		})
+	}
+	return c
+}
+
+// Owners returns the owners of the named file. The last matching pattern
+// wins, so the result may be empty if that pattern lists no owners.
+func (c *CodeOwners) Owners(name string) []string {
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		r := c.rules[i]
+		if Match(r.glob, name) || (r.dir && Match(r.glob+"/**", name)) {
+			return r.owners
+		}
+	}
+	return nil
+}
+
+// Reviewers returns the users and teams that own any of the named files,
+// excluding the given author. Teams are returned as slugs, without the
+// organization, and email owners are skipped.
+func (c *CodeOwners) Reviewers(names []string, author string) (users, teams []string) {
+	seen := map[string]bool{}
+	for _, name := range names {
+		for _, owner := range c.Owners(name) {
+			key := strings.ToLower(owner)
+			if seen[key] || !strings.HasPrefix(owner, "@") {
+				continue
+			}
+			seen[key] = true
+			owner = owner[1:]
+			if i := strings.Index(owner, "/"); i >= 0 {
+				teams = append(teams, owner[i+1:])
+			} else if !strings.EqualFold(owner, author) {
+				users = append(users, owner)
+			}
+		}
+	}
+	return users, teams
+}
@@ -0,0 +1,74 @@
+package pulls
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"docs/**", "docs/a/b.md", true},
+		{"docs/**", "docs", true},
+		{"docs/**", "src/docs/a.md", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "a/b/c.md", true},
+		{"**/*.md", "a/b/c.go", false},
+		{"src/*.go", "src/a.go", true},
+		{"src/*.go", "src/a/b.go", false},
+		{"src/**/test/*", "src/x/y/test/a", true},
+		{"[", "[", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+const codeOwners = `
+# Default owners.
+*               @org/core
+*.js            @js-owner   # Frontend.
+/build/logs/    @builder
+docs/*          docs@example.com @writer
+apps/           @app-owner
+/scripts/       @org/ops @alice
+/vendor/
+`
+
+func TestCodeOwners_Owners(t *testing.T) {
+	c := ParseCodeOwners(codeOwners)
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"main.go", []string{"@org/core"}},
+		{"web/app.js", []string{"@js-owner"}},
+		{"build/logs/a/b.log", []string{"@builder"}},
+		{"x/build/logs/a.log", []string{"@org/core"}},
+		{"docs/intro.md", []string{"docs@example.com", "@writer"}},
+		{"docs/guide/intro.md", []string{"@org/core"}},
+		{"src/apps/x/main.go", []string{"@app-owner"}},
+		{"vendor/lib/a.go", []string{}},
+	}
+	for _, tt := range tests {
+		if got := c.Owners(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Owners(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCodeOwners_Reviewers(t *testing.T) {
+	c := ParseCodeOwners(codeOwners)
+	users, teams := c.Reviewers([]string{"docs/intro.md", "scripts/run.sh", "main.go", "docs/faq.md"}, "alice")
+	if want := []string{"writer"}; !reflect.DeepEqual(users, want) {
+		t.Errorf("Reviewers() users = %q, want %q", users, want)
+	}
+	if want := []string{"ops", "core"}; !reflect.DeepEqual(teams, want) {
+		t.Errorf("Reviewers() teams = %q, want %q", teams, want)
+	}
+}
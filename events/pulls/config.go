@@ -0,0 +1,291 @@
+package pulls
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+// DefaultYAML is the default pull request configuration.
+const DefaultYAML = `
+size_labels:
+- {label: size/XS, max: 9}
+- {label: size/S, max: 29}
+- {label: size/M, max: 99}
+- {label: size/L, max: 499}
+- {label: size/XL}
+draft_label: draft
+request_code_owners: true
+`
+
+// DefaultWIPPrefixes are title prefixes that mark a pull request as a draft.
+var DefaultWIPPrefixes = []string{"WIP", "[WIP]", "Draft:", "[Draft]"}
+
+// PathLabel adds Label to pull requests that change a file matching any of
+// Paths.
+type PathLabel struct {
+	Label string   `yaml:"label" json:"label"`
+	Paths []string `yaml:"paths" json:"paths"`
+}
+
+// SizeLabel is the label of pull requests that change at most Max lines. A
+// zero Max is unbounded, and is only allowed in the last size label.
+type SizeLabel struct {
+	Label string `yaml:"label" json:"label"`
+	Max   int    `yaml:"max,omitempty" json:"max,omitempty"`
+}
+
+// Config is the pull request automation configuration. For example:
+//
+//	path_labels:
+//	- label: docs
+//	  paths: ["docs/**", "**/*.md"]
+//	size_labels:
+//	- {label: size/S, max: 29}
+//	- {label: size/L}
+//	size_exclude: ["vendor/**", "**/*.pb.go"]
+//	draft_label: draft
+//	request_code_owners: true
+type Config struct {
+	// PathLabels label pull requests by the paths of changed files.
+	PathLabels []PathLabel `yaml:"path_labels,omitempty" json:"path_labels,omitempty"`
+	// SizeLabels label pull requests by the number of added and deleted lines,
+	// in increasing order of Max. The first matching label is added and the
+	// others are removed.
+	SizeLabels []SizeLabel `yaml:"size_labels,omitempty" json:"size_labels,omitempty"`
+	// SizeExclude are path globs not counted in the size, e.g. generated code.
+	SizeExclude []string `yaml:"size_exclude,omitempty" json:"size_exclude,omitempty"`
+	// DraftLabel, if set, labels draft pull requests until they are ready for
+	// review.
+	DraftLabel string `yaml:"draft_label,omitempty" json:"draft_label,omitempty"`
+	// WIPPrefixes are title prefixes that also mark a pull request as a draft.
+	// Defaults to DefaultWIPPrefixes.
+	WIPPrefixes []string `yaml:"wip_prefixes,omitempty" json:"wip_prefixes,omitempty"`
+	// RequestCodeOwners requests reviews from the CODEOWNERS of changed files
+	// when a pull request is opened or becomes ready for review.
+	RequestCodeOwners bool `yaml:"request_code_owners,omitempty" json:"request_code_owners,omitempty"`
+}
+
+// Default returns the default configuration.
+func Default() *Config {
+	c := &Config{}
+	if err := configfile.Parse([]byte(DefaultYAML), c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Validate checks the configuration and sets defaults.
+func (c *Config) Validate() error {
+	for i, p := range c.PathLabels {
+		if p.Label == "" || len(p.Paths) == 0 {
+			return fmt.Errorf("path_labels %d: label and paths are required", i)
+		}
+		for _, glob := range p.Paths {
+			if !validGlob(glob) {
+				return fmt.Errorf("path_labels %d: invalid path %q", i, glob)
+			}
+		}
+	}
+	for i, s := range c.SizeLabels {
+		switch {
+		case s.Label == "":
+			return fmt.Errorf("size_labels %d: label is required", i)
+		case s.Max < 0:
+			return fmt.Errorf("size_labels %d: max must not be negative", i)
+		case s.Max == 0 && i != len(c.SizeLabels)-1:
+			return fmt.Errorf("size_labels %d: only the last size label may omit max", i)
+		case i > 0 && s.Max != 0 && s.Max <= c.SizeLabels[i-1].Max:
+			return fmt.Errorf("size_labels %d: max must increase", i)
+		}
+	}
+	for _, glob := range c.SizeExclude {
+		if !validGlob(glob) {
+			return fmt.Errorf("size_exclude: invalid path %q", glob)
+		}
+	}
+	if c.WIPPrefixes == nil {
+		c.WIPPrefixes = DefaultWIPPrefixes
+	}
+	return nil
+}
+
+// Draft reports whether the event pull request is a draft. The action of
+// "converted_to_draft" and "ready_for_review" events decides. Otherwise a WIP
+// title prefix or the current draft label marks a draft, since the pull request
+// payload read by this client has no draft flag. Removing the WIP prefix from
+// the title marks the pull request ready.
+func (c *Config) Draft(ev *Event) bool {
+	switch ev.GetAction() {
+	case "converted_to_draft":
+		return true
+	case "ready_for_review":
+		return false
+	}
+	if c.wip(ev.GetPullRequest().GetTitle()) {
+		return true
+	}
+	if ch := ev.GetChanges(); ev.GetAction() == "edited" && ch != nil && ch.Title != nil &&
+		ch.Title.From != nil && c.wip(*ch.Title.From) {
+		return false
+	}
+	return c.DraftLabel != "" && ev.HasLabel(c.DraftLabel)
+}
+
+// wip reports whether title starts with one of WIPPrefixes.
+func (c *Config) wip(title string) bool {
+	title = strings.ToLower(strings.TrimSpace(title))
+	for _, p := range c.WIPPrefixes {
+		if strings.HasPrefix(title, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply runs the configured automation for the event pull request. Pull
+// requests that are closed are ignored.
+func (c *Config) Apply(ctx context.Context, ev *Event) error {
+	if ev.GetPullRequest().GetState() == "closed" {
+		return nil
+	}
+	switch ev.GetAction() {
+	case "opened", "reopened", "synchronize", "edited", "converted_to_draft", "ready_for_review":
+	default:
+		return nil
+	}
+	draft := c.Draft(ev)
+	if c.DraftLabel != "" {
+		ev.Rule = "pulls:draft"
+		var err error
+		if draft {
+			_, err = ev.ReconcileLabels(ctx, []string{c.DraftLabel}, nil)
+		} else {
+			_, err = ev.ReconcileLabels(ctx, nil, []string{c.DraftLabel})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if ev.GetAction() == "edited" || ev.GetAction() == "converted_to_draft" {
+		// The changed files are the same.
+		return nil
+	}
+
+	needFiles := len(c.PathLabels) > 0 || len(c.SizeLabels) > 0 ||
+		(c.RequestCodeOwners && !draft && ev.GetAction() != "synchronize")
+	if !needFiles {
+		return nil
+	}
+	files, err := ev.Files(ctx)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.GetFilename()
+	}
+	if err := c.labelPaths(ctx, ev, names); err != nil {
+		return err
+	}
+	if err := c.labelSize(ctx, ev); err != nil {
+		return err
+	}
+	if c.RequestCodeOwners && !draft && ev.GetAction() != "synchronize" {
+		return c.requestOwners(ctx, ev, names)
+	}
+	return nil
+}
+
+// labelPaths adds the path labels matching any of the file names.
+func (c *Config) labelPaths(ctx context.Context, ev *Event, names []string) error {
+	var add []string
+	for _, p := range c.PathLabels {
+		if matchAny(p.Paths, names) {
+			add = append(add, p.Label)
+		}
+	}
+	ev.Rule = "pulls:paths"
+	_, err := ev.ReconcileLabels(ctx, add, nil)
+	return err
+}
+
+// Size returns the number of added and deleted lines in files, excluding
+// files matching SizeExclude.
+func (c *Config) Size(files []*github.CommitFile) int {
+	size := 0
+	for _, f := range files {
+		if !matchAny(c.SizeExclude, []string{f.GetFilename()}) {
+			size += f.GetAdditions() + f.GetDeletions()
+		}
+	}
+	return size
+}
+
+// SizeLabel returns the size label for a change of the given number of lines,
+// or "" if there is none.
+func (c *Config) SizeLabel(size int) string {
+	for _, s := range c.SizeLabels {
+		if s.Max == 0 || size <= s.Max {
+			return s.Label
+		}
+	}
+	return ""
+}
+
+// labelSize adds the size label of the event pull request and removes the
+// other size labels.
+func (c *Config) labelSize(ctx context.Context, ev *Event) error {
+	if len(c.SizeLabels) == 0 {
+		return nil
+	}
+	files, err := ev.Files(ctx)
+	if err != nil {
+		return err
+	}
+	label := c.SizeLabel(c.Size(files))
+	var remove []string
+	for _, s := range c.SizeLabels {
+		if s.Label != label {
+			remove = append(remove, s.Label)
+		}
+	}
+	ev.Rule = "pulls:size"
+	var add []string
+	if label != "" {
+		add = []string{label}
+	}
+	_, err = ev.ReconcileLabels(ctx, add, remove)
+	return err
+}
+
+// requestOwners requests reviews from the CODEOWNERS of the changed files.
+func (c *Config) requestOwners(ctx context.Context, ev *Event, names []string) error {
+	for _, path := range CodeOwnersPaths {
+		content, found, err := ev.ReadFile(ctx, path)
+		if err != nil {
+			return err
+		}
+		if !found {
+			continue
+		}
+		users, teams := ParseCodeOwners(content).Reviewers(names, ev.GetPullRequest().GetUser().GetLogin())
+		ev.Rule = "pulls:codeowners"
+		return ev.RequestReviewers(ctx, users, teams)
+	}
+	return nil
+}
+
+func matchAny(globs, names []string) bool {
+	for _, glob := range globs {
+		for _, name := range names {
+			if Match(glob, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package pulls
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether the slash separated file name matches the glob
+// pattern. Pattern segments use path.Match syntax, and a "**" segment matches
+// zero or more directories, e.g. "docs/**" or "**/*.md".
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// validGlob reports whether every segment of pattern is a valid path.Match
+// pattern.
+func validGlob(pattern string) bool {
+	for _, p := range strings.Split(pattern, "/") {
+		if _, err := path.Match(p, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
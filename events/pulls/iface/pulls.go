@@ -0,0 +1,50 @@
+package iface
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// Pulls defines the interface used by the pull request event logic. Labels
+// are managed with an issues.Event, since pull requests share the issues API.
+type Pulls interface {
+	ListFiles(ctx context.Context, owner string, repo string, number int, opt *github.ListOptions) ([]*github.CommitFile, *github.Response, error)
+	RequestReviewers(ctx context.Context, owner string, repo string, number int, reviewers github.ReviewersRequest) (*github.PullRequest, *github.Response, error)
+	GetContents(ctx context.Context, owner string, repo string, path string, opt *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error)
+}
+
+// PullsImpl implements the Pulls interface.
+type PullsImpl struct {
+	pulls *github.PullRequestsService
+	repos *github.RepositoriesService
+}
+
+// NewPulls creates a new Pulls instance using the services of client.
+func NewPulls(client *github.Client) *PullsImpl {
+	return &PullsImpl{
+		pulls: client.PullRequests,
+		repos: client.Repositories,
+	}
+}
+
+// ListFiles lists the files changed by the repo pull request.
+func (p *PullsImpl) ListFiles(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.ListOptions) ([]*github.CommitFile, *github.Response, error) {
+	return p.pulls.ListFiles(ctx, owner, repo, number, opt)
+}
+
+// RequestReviewers requests reviews of the repo pull request.
+func (p *PullsImpl) RequestReviewers(
+	ctx context.Context, owner string, repo string, number int,
+	reviewers github.ReviewersRequest) (*github.PullRequest, *github.Response, error) {
+	return p.pulls.RequestReviewers(ctx, owner, repo, number, reviewers)
+}
+
+// GetContents reads a file or directory from the owner repo.
+func (p *PullsImpl) GetContents(
+	ctx context.Context, owner string, repo string, path string,
+	opt *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	return p.repos.GetContents(ctx, owner, repo, path, opt)
+}
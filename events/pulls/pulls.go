@@ -0,0 +1,119 @@
+// Package pulls automates labels and reviewers of pull requests.
+//
+// Apply handles a PullRequestEvent: it labels the pull request by the paths of
+// the changed files and by the size of the change, requests reviews from the
+// owners of the changed files listed in CODEOWNERS, and labels draft pull
+// requests until they are ready for review.
+package pulls
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	issuesiface "github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/pulls/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
+)
+
+// Event encapsulates operations on a *github.PullRequestEvent. A pull request
+// is an issue for the labels API, so labels are tracked and changed, and every
+// mutating call is audited, through the embedded issues.Event.
+type Event struct {
+	*github.PullRequestEvent
+	*issues.Event
+	iface.Pulls
+
+	files []*github.CommitFile
+}
+
+// NewEvent creates a new Event based on the given issues, pulls and event.
+func NewEvent(issuesClient issuesiface.Issues, pulls iface.Pulls, event *github.PullRequestEvent) *Event {
+	pr := event.GetPullRequest()
+	issue := &github.Issue{
+		Number:  pr.Number,
+		State:   pr.State,
+		Title:   pr.Title,
+		User:    pr.User,
+		HTMLURL: pr.HTMLURL,
+	}
+	for _, l := range pr.Labels {
+		if l != nil {
+			issue.Labels = append(issue.Labels, *l)
+		}
+	}
+	return &Event{
+		PullRequestEvent: event,
+		Event: issues.NewEvent(issuesClient, &github.IssuesEvent{
+			Action:       event.Action,
+			Issue:        issue,
+			Repo:         event.Repo,
+			Sender:       event.Sender,
+			Installation: event.Installation,
+		}),
+		Pulls: pulls,
+	}
+}
+
+func (ev *Event) owner() string { return ev.GetRepo().GetOwner().GetLogin() }
+func (ev *Event) name() string  { return ev.GetRepo().GetName() }
+func (ev *Event) number() int   { return ev.GetPullRequest().GetNumber() }
+
+// HasLabel reports whether the pull request has the label, ignoring case.
+func (ev *Event) HasLabel(label string) bool {
+	return slice.ContainsFold(ev.Labels(), label)
+}
+
+// RequestReviewers requests reviews from the given users and team slugs.
+func (ev *Event) RequestReviewers(ctx context.Context, users, teams []string) error {
+	if len(users) == 0 && len(teams) == 0 {
+		return nil
+	}
+	log.Println("Pulls.RequestReviewers:", ev.owner(), ev.name(), ev.number(), users, teams)
+	_, resp, err := ev.Pulls.RequestReviewers(ctx, ev.owner(), ev.name(), ev.number(),
+		github.ReviewersRequest{Reviewers: users, TeamReviewers: teams})
+	ev.Audit("request-reviewers", fmt.Sprintf("users %q teams %q", users, teams), resp, err)
+	return err
+}
+
+// Files returns the files changed by the pull request. The list is fetched
+// once and cached.
+func (ev *Event) Files(ctx context.Context) ([]*github.CommitFile, error) {
+	if ev.files != nil {
+		return ev.files, nil
+	}
+	files := []*github.CommitFile{}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := ev.Pulls.ListFiles(ctx, ev.owner(), ev.name(), ev.number(), opt)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, list...)
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	ev.files = files
+	return files, nil
+}
+
+// ReadFile returns the contents of a file on the default branch, or false if
+// it does not exist.
+func (ev *Event) ReadFile(ctx context.Context, path string) (string, bool, error) {
+	file, _, _, err := ev.Pulls.GetContents(ctx, ev.owner(), ev.name(), path, nil)
+	if issues.IsNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if file == nil {
+		return "", false, nil
+	}
+	content, err := file.GetContent()
+	return content, err == nil, err
+}
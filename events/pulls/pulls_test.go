@@ -0,0 +1,284 @@
+package pulls
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/audit"
+	issuesiface "github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface/ifacetest"
+	"github.com/stephen-soltesz/github-webhook-poc/events/pulls/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+// fakePulls implements both the pulls and the issues interfaces, and tracks
+// the labels of the pull request.
+type fakePulls struct {
+	ifacetest.Issues
+
+	files     []*github.CommitFile
+	contents  map[string]string
+	labels    []string
+	reviewers []github.ReviewersRequest
+	err       error
+}
+
+func (f *fakePulls) ListFiles(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.ListOptions) ([]*github.CommitFile, *github.Response, error) {
+	return f.files, nil, f.err
+}
+
+func (f *fakePulls) RequestReviewers(
+	ctx context.Context, owner string, repo string, number int,
+	reviewers github.ReviewersRequest) (*github.PullRequest, *github.Response, error) {
+	f.reviewers = append(f.reviewers, reviewers)
+	return nil, nil, f.err
+}
+
+func (f *fakePulls) AddLabelsToIssue(
+	ctx context.Context, owner string, repo string, number int,
+	labels []string) ([]*github.Label, *github.Response, error) {
+	f.labels = append(f.labels, labels...)
+	return nil, nil, f.err
+}
+
+func (f *fakePulls) RemoveLabelForIssue(
+	ctx context.Context, owner string, repo string, number int,
+	label string) (*github.Response, error) {
+	for i, l := range f.labels {
+		if l == label {
+			f.labels = append(f.labels[:i], f.labels[i+1:]...)
+			return nil, f.err
+		}
+	}
+	return notFound()
+}
+
+func (f *fakePulls) GetContents(
+	ctx context.Context, owner string, repo string, path string,
+	opt *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	content, ok := f.contents[path]
+	if !ok {
+		resp, err := notFound()
+		return nil, nil, resp, err
+	}
+	return &github.RepositoryContent{Content: &content}, nil, nil, nil
+}
+
+var (
+	_ iface.Pulls        = &fakePulls{}
+	_ issuesiface.Issues = &fakePulls{}
+)
+
+func notFound() (*github.Response, error) {
+	resp := &http.Response{StatusCode: http.StatusNotFound, Request: &http.Request{URL: &url.URL{}}}
+	return &github.Response{Response: resp}, &github.ErrorResponse{Response: resp, Message: "Not Found"}
+}
+
+type memRecorder []*audit.Record
+
+func (m *memRecorder) Record(r *audit.Record) error {
+	*m = append(*m, r)
+	return nil
+}
+
+func newFile(name string, additions, deletions int) *github.CommitFile {
+	return &github.CommitFile{Filename: &name, Additions: &additions, Deletions: &deletions}
+}
+
+func newEvent(f *fakePulls, action, title string, labels ...string) *Event {
+	pr := &github.PullRequest{
+		Number: github.Int(1),
+		State:  github.String("open"),
+		Title:  &title,
+		User:   &github.User{Login: github.String("author")},
+	}
+	for _, l := range labels {
+		pr.Labels = append(pr.Labels, &github.Label{Name: github.String(l)})
+	}
+	f.labels = append([]string{}, labels...)
+	return NewEvent(f, f, &github.PullRequestEvent{
+		Action:      &action,
+		PullRequest: pr,
+		Repo: &github.Repository{
+			Name:  github.String("repo"),
+			Owner: &github.User{Login: github.String("owner")},
+		},
+	})
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "default", config: DefaultYAML},
+		{name: "paths", config: "path_labels: [{label: docs, paths: ['docs/**']}]"},
+		{name: "error-path-missing-label", config: "path_labels: [{paths: ['docs/**']}]", wantErr: true},
+		{name: "error-path-invalid", config: "path_labels: [{label: x, paths: ['[']}]", wantErr: true},
+		{name: "error-size-order", config: "size_labels: [{label: a, max: 10}, {label: b, max: 5}]", wantErr: true},
+		{name: "error-size-unbounded", config: "size_labels: [{label: a}, {label: b, max: 5}]", wantErr: true},
+		{name: "error-unknown-field", config: "sizes: []", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := configfile.Parse([]byte(tt.config), &Config{}); (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfig_SizeLabel(t *testing.T) {
+	c := Default()
+	for size, want := range map[int]string{0: "size/XS", 9: "size/XS", 10: "size/S", 499: "size/L", 5000: "size/XL"} {
+		if got := c.SizeLabel(size); got != want {
+			t.Errorf("SizeLabel(%d) = %q, want %q", size, got, want)
+		}
+	}
+	c.SizeExclude = []string{"vendor/**"}
+	if got := c.Size([]*github.CommitFile{newFile("a.go", 3, 2), newFile("vendor/b.go", 100, 0)}); got != 5 {
+		t.Errorf("Size() = %d, want 5", got)
+	}
+}
+
+func TestConfig_Apply(t *testing.T) {
+	config := func() *Config {
+		c := Default()
+		c.PathLabels = []PathLabel{
+			{Label: "docs", Paths: []string{"docs/**", "**/*.md"}},
+			{Label: "api", Paths: []string{"api/**"}},
+		}
+		return c
+	}
+	files := []*github.CommitFile{newFile("docs/intro.md", 10, 5), newFile("main.go", 10, 0)}
+	owners := map[string]string{".github/CODEOWNERS": "* @org/core\n*.md @writer @author\n"}
+	tests := []struct {
+		name          string
+		action        string
+		title         string
+		labels        []string
+		changedTitle  string
+		wantLabels    []string
+		wantReviewers []github.ReviewersRequest
+	}{
+		{
+			name:       "opened",
+			action:     "opened",
+			title:      "Add docs",
+			wantLabels: []string{"docs", "size/S"},
+			wantReviewers: []github.ReviewersRequest{
+				{Reviewers: []string{"writer"}, TeamReviewers: []string{"core"}},
+			},
+		},
+		{
+			name:       "opened-wip",
+			action:     "opened",
+			title:      "[WIP] Add docs",
+			wantLabels: []string{"docs", "draft", "size/S"},
+		},
+		{
+			name:       "synchronize-resizes",
+			action:     "synchronize",
+			title:      "Add docs",
+			labels:     []string{"docs", "size/XL"},
+			wantLabels: []string{"docs", "size/S"},
+		},
+		{
+			name:       "converted-to-draft",
+			action:     "converted_to_draft",
+			title:      "Add docs",
+			labels:     []string{"docs", "size/S"},
+			wantLabels: []string{"docs", "draft", "size/S"},
+		},
+		{
+			name:       "ready-for-review",
+			action:     "ready_for_review",
+			title:      "Add docs",
+			labels:     []string{"docs", "draft", "size/S"},
+			wantLabels: []string{"docs", "size/S"},
+			wantReviewers: []github.ReviewersRequest{
+				{Reviewers: []string{"writer"}, TeamReviewers: []string{"core"}},
+			},
+		},
+		{
+			name:         "edited-removes-wip",
+			action:       "edited",
+			title:        "Add docs",
+			changedTitle: "WIP: Add docs",
+			labels:       []string{"draft"},
+			wantLabels:   []string{},
+		},
+		{
+			name:       "edited-body-keeps-draft",
+			action:     "edited",
+			title:      "Add docs",
+			labels:     []string{"draft"},
+			wantLabels: []string{"draft"},
+		},
+		{
+			name:       "closed",
+			action:     "closed",
+			title:      "Add docs",
+			wantLabels: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakePulls{files: files, contents: owners}
+			ev := newEvent(f, tt.action, tt.title, tt.labels...)
+			if tt.changedTitle != "" {
+				ev.Changes = &github.EditChange{}
+				ev.Changes.Title = &struct {
+					From *string `json:"from,omitempty"`
+				}{From: &tt.changedTitle}
+			}
+			rec := &memRecorder{}
+			ev.Recorder = rec
+			if err := config().Apply(context.Background(), ev); err != nil {
+				t.Fatalf("Config.Apply() error = %v", err)
+			}
+			sort.Strings(f.labels)
+			if !reflect.DeepEqual(f.labels, tt.wantLabels) {
+				t.Errorf("labels = %q, want %q", f.labels, tt.wantLabels)
+			}
+			got := append([]string{}, ev.Labels()...)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.wantLabels) {
+				t.Errorf("Event.Labels() = %q, want %q", got, tt.wantLabels)
+			}
+			if !reflect.DeepEqual(f.reviewers, tt.wantReviewers) {
+				t.Errorf("reviewers = %+v, want %+v", f.reviewers, tt.wantReviewers)
+			}
+			for _, r := range *rec {
+				if r.Repo != "owner/repo" || r.Issue != 1 || r.Rule == "" {
+					t.Errorf("audit record = %+v, want repo, issue and rule", r)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_Apply_NoCodeOwners(t *testing.T) {
+	f := &fakePulls{files: []*github.CommitFile{newFile("a.go", 1, 1)}}
+	ev := newEvent(f, "opened", "Fix")
+	if err := Default().Apply(context.Background(), ev); err != nil {
+		t.Fatalf("Config.Apply() error = %v", err)
+	}
+	if len(f.reviewers) != 0 {
+		t.Errorf("reviewers = %+v, want none", f.reviewers)
+	}
+	f.err = fmt.Errorf("boom")
+	ev = newEvent(f, "opened", "Fix")
+	if err := Default().Apply(context.Background(), ev); err == nil {
+		t.Errorf("Config.Apply() error = nil, want error")
+	}
+}
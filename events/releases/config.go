@@ -0,0 +1,113 @@
+package releases
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+// DefaultYAML is the default release notes configuration.
+const DefaultYAML = `
+categories:
+- {title: Features, labels: [feature, enhancement]}
+- {title: Bug fixes, labels: [bug]}
+- {title: Maintenance, labels: [chore, dependencies, documentation]}
+other: Other changes
+exclude_labels: [skip-release-notes]
+`
+
+// DefaultTemplate formats the release notes in Markdown.
+const DefaultTemplate = `## What's changed{{if .Previous}} since {{.Previous}}{{end}}
+{{range .Categories}}
+### {{.Title}}
+
+{{range .Items}}- {{.Title}} (#{{.Number}}{{if .Author}}, @{{.Author}}{{end}})
+{{end}}{{else}}
+No changes.
+{{end}}`
+
+// Modes of updating releases.
+const (
+	// ModeUpdate writes the notes into the body of the release.
+	ModeUpdate = "update"
+	// ModeDraft writes the notes of published releases between the draft
+	// markers instead, for maintainers to review.
+	ModeDraft = "draft"
+)
+
+// Category groups the changes with any of Labels under Title.
+type Category struct {
+	Title  string   `yaml:"title" json:"title"`
+	Labels []string `yaml:"labels" json:"labels"`
+}
+
+// Config is the release notes configuration. For example:
+//
+//	categories:
+//	- {title: Features, labels: [feature, enhancement]}
+//	- {title: Bug fixes, labels: [bug]}
+//	other: Other changes
+//	exclude_labels: [skip-release-notes]
+//	mode: update
+//	template: |
+//	  {{range .Categories}}## {{.Title}}
+//	  {{range .Items}}- {{.Title}} #{{.Number}}
+//	  {{end}}{{end}}
+type Config struct {
+	// Categories group the changes, in order. A change is listed in the
+	// first category with one of its labels.
+	Categories []Category `yaml:"categories,omitempty" json:"categories,omitempty"`
+	// Other, if set, is the title of changes in no category. Otherwise
+	// these changes are omitted.
+	Other string `yaml:"other,omitempty" json:"other,omitempty"`
+	// ExcludeLabels omit changes with any of these labels.
+	ExcludeLabels []string `yaml:"exclude_labels,omitempty" json:"exclude_labels,omitempty"`
+	// Mode is ModeUpdate or ModeDraft. Defaults to ModeUpdate.
+	Mode string `yaml:"mode,omitempty" json:"mode,omitempty"`
+	// Template is a text/template executed over the Notes. Defaults to
+	// DefaultTemplate.
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+
+	tmpl *template.Template
+}
+
+// Default returns the default configuration.
+func Default() *Config {
+	c := &Config{}
+	if err := configfile.Parse([]byte(DefaultYAML), c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Validate checks the configuration, sets defaults and parses the template.
+func (c *Config) Validate() error {
+	for i, cat := range c.Categories {
+		if cat.Title == "" || len(cat.Labels) == 0 {
+			return fmt.Errorf("category %d: title and labels are required", i)
+		}
+		for _, prev := range c.Categories[:i] {
+			if strings.EqualFold(prev.Title, cat.Title) {
+				return fmt.Errorf("category %d: duplicate title %q", i, cat.Title)
+			}
+		}
+	}
+	switch c.Mode {
+	case "":
+		c.Mode = ModeUpdate
+	case ModeUpdate, ModeDraft:
+	default:
+		return fmt.Errorf("mode %q must be %q or %q", c.Mode, ModeUpdate, ModeDraft)
+	}
+	if c.Template == "" {
+		c.Template = DefaultTemplate
+	}
+	tmpl, err := template.New("release-notes").Parse(c.Template)
+	if err != nil {
+		return fmt.Errorf("template: %v", err)
+	}
+	c.tmpl = tmpl
+	return nil
+}
@@ -0,0 +1,50 @@
+package iface
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// Releases defines the interface used by the release notes logic. Merged pull
+// requests and closed issues are found with the search API.
+type Releases interface {
+	ListReleases(ctx context.Context, owner string, repo string, opt *github.ListOptions) ([]*github.RepositoryRelease, *github.Response, error)
+	EditRelease(ctx context.Context, owner string, repo string, id int64, release *github.RepositoryRelease) (*github.RepositoryRelease, *github.Response, error)
+	SearchIssues(ctx context.Context, query string, opt *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error)
+}
+
+// ReleasesImpl implements the Releases interface.
+type ReleasesImpl struct {
+	repos  *github.RepositoriesService
+	search *github.SearchService
+}
+
+// NewReleases creates a new Releases instance using the services of client.
+func NewReleases(client *github.Client) *ReleasesImpl {
+	return &ReleasesImpl{
+		repos:  client.Repositories,
+		search: client.Search,
+	}
+}
+
+// ListReleases lists the releases of the owner repo, newest first.
+func (r *ReleasesImpl) ListReleases(
+	ctx context.Context, owner string, repo string,
+	opt *github.ListOptions) ([]*github.RepositoryRelease, *github.Response, error) {
+	return r.repos.ListReleases(ctx, owner, repo, opt)
+}
+
+// EditRelease updates the given fields of a release.
+func (r *ReleasesImpl) EditRelease(
+	ctx context.Context, owner string, repo string, id int64,
+	release *github.RepositoryRelease) (*github.RepositoryRelease, *github.Response, error) {
+	return r.repos.EditRelease(ctx, owner, repo, id, release)
+}
+
+// SearchIssues searches issues and pull requests.
+func (r *ReleasesImpl) SearchIssues(
+	ctx context.Context, query string,
+	opt *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error) {
+	return r.search.Issues(ctx, query, opt)
+}
@@ -0,0 +1,270 @@
+// Package releases writes release notes from the pull requests merged and the
+// issues closed since the previous release.
+//
+// When a release is created or published, the changes between the previous
+// release and this one are found with the search API, grouped by the
+// categories of their labels, and formatted with a template. By default the
+// notes are written into the release body, between markers so that text
+// written by maintainers is kept, and updating the notes again replaces them.
+// In draft mode, the notes of published releases are written between separate
+// draft markers instead, so that maintainers can review them before moving
+// them into place.
+package releases
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/releases/iface"
+)
+
+// Markers delimit the notes in release bodies.
+const (
+	BeginMarker = "<!-- release-notes -->"
+	EndMarker   = "<!-- /release-notes -->"
+)
+
+// Draft markers delimit the notes of published releases in draft mode.
+const (
+	DraftBeginMarker = "<!-- release-notes-draft -->"
+	DraftEndMarker   = "<!-- /release-notes-draft -->"
+)
+
+// searchTime is the time format of search qualifiers.
+const searchTime = "2006-01-02T15:04:05Z"
+
+// timeNow returns the end of the changes of draft releases, which have no
+// publication time yet.
+var timeNow = time.Now
+
+// Item is a merged pull request or a closed issue.
+type Item struct {
+	Number      int
+	Title       string
+	URL         string
+	Author      string
+	Labels      []string
+	PullRequest bool
+}
+
+// Section lists the items of a category.
+type Section struct {
+	Title string
+	Items []*Item
+}
+
+// Notes are the changes of a release, and the data of the template.
+type Notes struct {
+	// Repo is the "owner/name" of the repository.
+	Repo string
+	// Tag is the tag of the release.
+	Tag string
+	// Previous is the tag of the previous release, or empty for the first
+	// release.
+	Previous string
+	// Since is the publication time of the previous release, or zero.
+	Since time.Time
+	// Until is the publication time of the release.
+	Until time.Time
+	// Categories list the non-empty categories in the configured order,
+	// followed by the other changes.
+	Categories []*Section
+}
+
+// ReleaseEvent writes the notes of a release when it is created or published.
+func (c *Config) ReleaseEvent(ctx context.Context, releases iface.Releases, event *github.ReleaseEvent) error {
+	action := event.GetAction()
+	if action != "created" && action != "published" {
+		return nil
+	}
+	rel := event.GetRelease()
+	owner, name := event.GetRepo().GetOwner().GetLogin(), event.GetRepo().GetName()
+	notes, err := c.Notes(ctx, releases, owner, name, rel)
+	if err != nil {
+		return err
+	}
+	text, err := c.Render(notes)
+	if err != nil {
+		return err
+	}
+	begin, end := BeginMarker, EndMarker
+	if c.Mode == ModeDraft && !rel.GetDraft() {
+		begin, end = DraftBeginMarker, DraftEndMarker
+	}
+	body := merge(rel.GetBody(), text, begin, end)
+	if body == rel.GetBody() {
+		return nil
+	}
+	log.Println("releases: update release notes of", notes.Repo, rel.GetTagName())
+	_, _, err = releases.EditRelease(ctx, owner, name, rel.GetID(), &github.RepositoryRelease{Body: &body})
+	return err
+}
+
+// Notes finds the changes between the previous release and rel.
+func (c *Config) Notes(ctx context.Context, releases iface.Releases, owner, name string, rel *github.RepositoryRelease) (*Notes, error) {
+	n := &Notes{Repo: owner + "/" + name, Tag: rel.GetTagName(), Until: rel.GetPublishedAt().Time}
+	if n.Until.IsZero() {
+		n.Until = timeNow()
+	}
+	n.Until = n.Until.UTC()
+	prev, err := previous(ctx, releases, owner, name, rel.GetTagName(), n.Until)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		n.Previous = prev.GetTagName()
+		n.Since = prev.GetPublishedAt().Time.UTC()
+	}
+	pulls, err := search(ctx, releases, fmt.Sprintf("repo:%s is:pr is:merged %s", n.Repo, window("merged", n.Since, n.Until)))
+	if err != nil {
+		return nil, err
+	}
+	issues, err := search(ctx, releases, fmt.Sprintf("repo:%s is:issue is:closed %s", n.Repo, window("closed", n.Since, n.Until)))
+	if err != nil {
+		return nil, err
+	}
+	n.Categories = c.categorize(append(pulls, issues...))
+	return n, nil
+}
+
+// Render formats the notes with the template.
+func (c *Config) Render(n *Notes) (string, error) {
+	var b bytes.Buffer
+	if err := c.tmpl.Execute(&b, n); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// categorize groups the items by category, ordered by number.
+func (c *Config) categorize(items []*Item) []*Section {
+	sections := make([]*Section, len(c.Categories)+1)
+	for i, cat := range c.Categories {
+		sections[i] = &Section{Title: cat.Title}
+	}
+	sections[len(c.Categories)] = &Section{Title: c.Other}
+	for _, item := range items {
+		if containsAny(item.Labels, c.ExcludeLabels) {
+			continue
+		}
+		i := 0
+		for i < len(c.Categories) && !containsAny(item.Labels, c.Categories[i].Labels) {
+			i++
+		}
+		if i == len(c.Categories) && c.Other == "" {
+			continue
+		}
+		sections[i].Items = append(sections[i].Items, item)
+	}
+	var result []*Section
+	for _, s := range sections {
+		if len(s.Items) == 0 {
+			continue
+		}
+		sort.Slice(s.Items, func(i, j int) bool { return s.Items[i].Number < s.Items[j].Number })
+		result = append(result, s)
+	}
+	return result
+}
+
+// Merge returns body with the notes between the markers, replacing any
+// earlier notes. The notes are appended to a body without markers.
+func Merge(body, notes string) string {
+	return merge(body, notes, BeginMarker, EndMarker)
+}
+
+// merge returns body with the notes between the begin and end markers.
+func merge(body, notes, begin, end string) string {
+	block := begin + "\n" + notes + "\n" + end
+	if i := strings.Index(body, begin); i >= 0 {
+		if j := strings.Index(body[i:], end); j >= 0 {
+			return body[:i] + block + body[i+j+len(end):]
+		}
+	}
+	if strings.TrimSpace(body) == "" {
+		return block
+	}
+	return strings.TrimRight(body, "\r\n") + "\n\n" + block
+}
+
+// previous returns the latest published release before until with another
+// tag, or nil if there is none.
+func previous(ctx context.Context, releases iface.Releases, owner, name, tag string, until time.Time) (*github.RepositoryRelease, error) {
+	var prev *github.RepositoryRelease
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := releases.ListReleases(ctx, owner, name, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range list {
+			published := r.GetPublishedAt().Time
+			if r.GetDraft() || r.GetTagName() == tag || published.IsZero() || !published.Before(until) {
+				continue
+			}
+			if prev == nil || published.After(prev.GetPublishedAt().Time) {
+				prev = r
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return prev, nil
+}
+
+// search returns the issues or pull requests matching the query.
+func search(ctx context.Context, releases iface.Releases, query string) ([]*Item, error) {
+	var items []*Item
+	opt := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		result, resp, err := releases.SearchIssues(ctx, query, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range result.Issues {
+			item := &Item{
+				Number:      issue.GetNumber(),
+				Title:       issue.GetTitle(),
+				URL:         issue.GetHTMLURL(),
+				Author:      issue.GetUser().GetLogin(),
+				PullRequest: issue.IsPullRequest(),
+			}
+			for _, l := range issue.Labels {
+				item.Labels = append(item.Labels, l.GetName())
+			}
+			items = append(items, item)
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return items, nil
+}
+
+// window returns the search qualifier of field between since and until.
+func window(field string, since, until time.Time) string {
+	if since.IsZero() {
+		return fmt.Sprintf("%s:<=%s", field, until.Format(searchTime))
+	}
+	return fmt.Sprintf("%s:%s..%s", field, since.Format(searchTime), until.Format(searchTime))
+}
+
+func containsAny(values, want []string) bool {
+	for _, v := range values {
+		for _, w := range want {
+			if strings.EqualFold(v, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+package releases
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/releases/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+type fakeReleases struct {
+	releases []*github.RepositoryRelease
+	results  map[string][]github.Issue
+	queries  []string
+	edited   []*github.RepositoryRelease
+	err      error
+}
+
+func (f *fakeReleases) ListReleases(
+	ctx context.Context, owner string, repo string,
+	opt *github.ListOptions) ([]*github.RepositoryRelease, *github.Response, error) {
+	return f.releases, nil, f.err
+}
+
+func (f *fakeReleases) EditRelease(
+	ctx context.Context, owner string, repo string, id int64,
+	release *github.RepositoryRelease) (*github.RepositoryRelease, *github.Response, error) {
+	f.edited = append(f.edited, release)
+	return release, nil, nil
+}
+
+func (f *fakeReleases) SearchIssues(
+	ctx context.Context, query string,
+	opt *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error) {
+	f.queries = append(f.queries, query)
+	kind := "issue"
+	if strings.Contains(query, "is:pr") {
+		kind = "pr"
+	}
+	return &github.IssuesSearchResult{Issues: f.results[kind]}, nil, nil
+}
+
+var _ iface.Releases = &fakeReleases{}
+
+func newRelease(id int64, tag string, published time.Time, draft bool, body string) *github.RepositoryRelease {
+	r := &github.RepositoryRelease{ID: &id, TagName: &tag, Draft: &draft, Body: &body}
+	if !published.IsZero() {
+		r.PublishedAt = &github.Timestamp{Time: published}
+	}
+	return r
+}
+
+func newItem(number int, title, author string, pr bool, labels ...string) github.Issue {
+	issue := github.Issue{Number: &number, Title: &title, User: &github.User{Login: &author}}
+	for _, l := range labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: github.String(l)})
+	}
+	if pr {
+		issue.PullRequestLinks = &github.PullRequestLinks{}
+	}
+	return issue
+}
+
+var (
+	jan = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	feb = time.Date(2019, 2, 1, 12, 0, 0, 0, time.UTC)
+	mar = time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newFake() *fakeReleases {
+	return &fakeReleases{
+		releases: []*github.RepositoryRelease{
+			newRelease(3, "v1.2.0", mar, false, ""),
+			newRelease(2, "v1.1.0", feb, false, ""),
+			newRelease(1, "v1.0.0", jan, false, ""),
+			newRelease(4, "v1.1.1", time.Time{}, true, ""),
+		},
+		results: map[string][]github.Issue{
+			"pr": {
+				newItem(12, "Fix crash", "alice", true, "bug"),
+				newItem(10, "Add export", "bob", true, "Enhancement"),
+				newItem(11, "Bump deps", "bot", true, "dependencies", "skip-release-notes"),
+				newItem(13, "Refactor", "carol", true),
+			},
+			"issue": {
+				newItem(9, "Crash on start", "dave", false, "bug"),
+			},
+		},
+	}
+}
+
+const wantNotes = `## What's changed since v1.1.0
+
+### Features
+
+- Add export (#10, @bob)
+
+### Bug fixes
+
+- Crash on start (#9, @dave)
+- Fix crash (#12, @alice)
+
+### Other changes
+
+- Refactor (#13, @carol)`
+
+func TestConfig_Notes(t *testing.T) {
+	f := newFake()
+	c := Default()
+	n, err := c.Notes(context.Background(), f, "o", "r", newRelease(3, "v1.2.0", mar, false, ""))
+	if err != nil {
+		t.Fatalf("Notes() error = %v", err)
+	}
+	if n.Previous != "v1.1.0" || !n.Since.Equal(feb) || !n.Until.Equal(mar) {
+		t.Errorf("Notes() = %s %v..%v, want v1.1.0 since February", n.Previous, n.Since, n.Until)
+	}
+	wantQueries := []string{
+		"repo:o/r is:pr is:merged merged:2019-02-01T12:00:00Z..2019-03-01T00:00:00Z",
+		"repo:o/r is:issue is:closed closed:2019-02-01T12:00:00Z..2019-03-01T00:00:00Z",
+	}
+	if !reflect.DeepEqual(f.queries, wantQueries) {
+		t.Errorf("queries = %q, want %q", f.queries, wantQueries)
+	}
+	got, err := c.Render(n)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got != wantNotes {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, wantNotes)
+	}
+
+	// The first release lists every change, and no other changes without a
+	// title for them.
+	c.Other = ""
+	f.queries = nil
+	n, err = c.Notes(context.Background(), f, "o", "r", newRelease(1, "v1.0.0", jan, false, ""))
+	if err != nil {
+		t.Fatalf("Notes() error = %v", err)
+	}
+	if n.Previous != "" || !strings.HasSuffix(f.queries[0], " merged:<=2019-01-01T00:00:00Z") || len(n.Categories) != 2 {
+		t.Errorf("Notes() = %+v, queries %q; want first release", n, f.queries)
+	}
+}
+
+func TestConfig_ReleaseEvent(t *testing.T) {
+	defer func() { timeNow = time.Now }()
+	timeNow = func() time.Time { return mar }
+	marked := "Highlights.\r\n\r\n" + BeginMarker + "\nold\n" + EndMarker + "\n\nThanks!"
+	tests := []struct {
+		name       string
+		mode       string
+		action     string
+		release    *github.RepositoryRelease
+		wantEdited string
+	}{
+		{
+			name:       "published",
+			action:     "published",
+			release:    newRelease(3, "v1.2.0", mar, false, ""),
+			wantEdited: BeginMarker + "\n" + wantNotes + "\n" + EndMarker,
+		},
+		{
+			name:       "replace-notes",
+			action:     "created",
+			release:    newRelease(3, "v1.2.0", mar, false, marked),
+			wantEdited: "Highlights.\r\n\r\n" + BeginMarker + "\n" + wantNotes + "\n" + EndMarker + "\n\nThanks!",
+		},
+		{
+			name:       "append-notes-draft",
+			action:     "created",
+			release:    newRelease(5, "v1.2.0", time.Time{}, true, "Highlights.\n"),
+			wantEdited: "Highlights.\n\n" + BeginMarker + "\n" + wantNotes + "\n" + EndMarker,
+		},
+		{
+			name:    "unchanged",
+			action:  "published",
+			release: newRelease(3, "v1.2.0", mar, false, BeginMarker+"\n"+wantNotes+"\n"+EndMarker),
+		},
+		{
+			name:    "edited",
+			action:  "edited",
+			release: newRelease(3, "v1.2.0", mar, false, ""),
+		},
+		{
+			name:       "draft-mode",
+			mode:       ModeDraft,
+			action:     "published",
+			release:    newRelease(3, "v1.2.0", mar, false, "Highlights."),
+			wantEdited: "Highlights.\n\n" + DraftBeginMarker + "\n" + wantNotes + "\n" + DraftEndMarker,
+		},
+		{
+			name:       "draft-mode-keeps-notes",
+			mode:       ModeDraft,
+			action:     "created",
+			release:    newRelease(3, "v1.2.0", mar, false, marked),
+			wantEdited: marked + "\n\n" + DraftBeginMarker + "\n" + wantNotes + "\n" + DraftEndMarker,
+		},
+		{
+			name:    "draft-mode-unchanged",
+			mode:    ModeDraft,
+			action:  "published",
+			release: newRelease(3, "v1.2.0", mar, false, "Highlights.\n\n"+DraftBeginMarker+"\n"+wantNotes+"\n"+DraftEndMarker),
+		},
+		{
+			name:       "draft-mode-draft-release",
+			mode:       ModeDraft,
+			action:     "created",
+			release:    newRelease(5, "v1.2.0", time.Time{}, true, ""),
+			wantEdited: BeginMarker + "\n" + wantNotes + "\n" + EndMarker,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFake()
+			c := Default()
+			if tt.mode != "" {
+				c.Mode = tt.mode
+			}
+			event := &github.ReleaseEvent{
+				Action:  &tt.action,
+				Release: tt.release,
+				Repo:    &github.Repository{Name: github.String("r"), Owner: &github.User{Login: github.String("o")}},
+			}
+			if err := c.ReleaseEvent(context.Background(), f, event); err != nil {
+				t.Fatalf("ReleaseEvent() error = %v", err)
+			}
+			var edited string
+			if len(f.edited) > 0 {
+				edited = f.edited[0].GetBody()
+			}
+			if edited != tt.wantEdited {
+				t.Errorf("edited body =\n%q\nwant\n%q", edited, tt.wantEdited)
+			}
+		})
+	}
+
+	f := newFake()
+	f.err = fmt.Errorf("forbidden")
+	event := &github.ReleaseEvent{Action: github.String("published"), Release: newRelease(3, "v1.2.0", mar, false, "")}
+	if err := Default().ReleaseEvent(context.Background(), f, event); err == nil {
+		t.Errorf("ReleaseEvent() error = nil, want error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "default", yaml: DefaultYAML},
+		{name: "template", yaml: "template: '{{range .Categories}}{{.Title}}{{end}}'\nmode: draft\n"},
+		{name: "bad-template", yaml: "template: '{{range}}'\n", wantErr: "template"},
+		{name: "bad-mode", yaml: "mode: replace\n", wantErr: "mode"},
+		{name: "no-labels", yaml: "categories: [{title: Bugs}]\n", wantErr: "title and labels"},
+		{name: "duplicate", yaml: "categories: [{title: Bugs, labels: [bug]}, {title: bugs, labels: [defect]}]\n", wantErr: "duplicate"},
+		{name: "unknown-field", yaml: "categores: []\n", wantErr: "categores"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := configfile.Parse([]byte(tt.yaml), c)
+			if tt.wantErr == "" {
+				if err != nil || c.tmpl == nil || c.Mode == "" {
+					t.Errorf("Parse() = %+v, %v; want defaults", c, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
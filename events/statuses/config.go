@@ -0,0 +1,110 @@
+package statuses
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultContext prefixes the status contexts of every validator.
+const DefaultContext = "webhook-receiver"
+
+// Validator checks the commits of a push or pull request.
+type Validator interface {
+	// Name is the validator type, e.g. "signed_off_by".
+	Name() string
+	// Validate returns a description of every problem found in t, or nil.
+	Validate(t *Target) []string
+}
+
+// Factory creates a validator from its configuration.
+type Factory func(c *ValidatorConfig) (Validator, error)
+
+type registration struct {
+	factory     Factory
+	description string
+}
+
+var registry = map[string]registration{}
+
+// Register makes a validator type available to configurations. The
+// description explains the validator on the Explain page. Register panics if
+// the type is already registered.
+func Register(typ, description string, f Factory) {
+	if _, ok := registry[typ]; ok {
+		panic("statuses: duplicate validator " + typ)
+	}
+	registry[typ] = registration{factory: f, description: description}
+}
+
+// Types returns the registered validator types, sorted.
+func Types() []string {
+	var types []string
+	for typ := range registry {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// ValidatorConfig configures a validator. Options only apply to the validator
+// types noted.
+type ValidatorConfig struct {
+	// Type selects the validator, e.g. "message_format".
+	Type string `yaml:"type" json:"type"`
+	// Pattern is a regular expression that the first line of commit messages
+	// must match (message_format). Empty allows any first line.
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	// MaxLength is the longest first line of commit messages (message_format).
+	// Defaults to DefaultMaxLength; negative allows any length.
+	MaxLength int `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	// MatchAuthor requires a sign-off with the email of the commit author
+	// (signed_off_by).
+	MatchAuthor bool `yaml:"match_author,omitempty" json:"match_author,omitempty"`
+}
+
+// Config is the commit status configuration. For example:
+//
+//	context: ci/webhook
+//	validators:
+//	- type: message_format
+//	  pattern: '^(feat|fix|docs|chore)(\(.+\))?: '
+//	- type: signed_off_by
+//	  match_author: true
+//	- type: issue_reference
+type Config struct {
+	// Context prefixes the status contexts. Defaults to DefaultContext.
+	Context string `yaml:"context,omitempty" json:"context,omitempty"`
+	// Validators set one status each, in order.
+	Validators []*ValidatorConfig `yaml:"validators" json:"validators"`
+
+	validators []Validator
+}
+
+// Validate checks the configuration, sets defaults and creates the
+// validators.
+func (c *Config) Validate() error {
+	if c.Context == "" {
+		c.Context = DefaultContext
+	}
+	if len(c.Validators) == 0 {
+		return fmt.Errorf("validators are required")
+	}
+	c.validators = nil
+	seen := map[string]bool{}
+	for i, vc := range c.Validators {
+		reg, ok := registry[vc.Type]
+		if !ok {
+			return fmt.Errorf("validator %d: unknown type %q, want one of %q", i, vc.Type, Types())
+		}
+		if seen[vc.Type] {
+			return fmt.Errorf("validator %d: duplicate type %q", i, vc.Type)
+		}
+		seen[vc.Type] = true
+		v, err := reg.factory(vc)
+		if err != nil {
+			return fmt.Errorf("validator %d: %v", i, err)
+		}
+		c.validators = append(c.validators, v)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package statuses
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+)
+
+// Limits of the problems listed in links to the Explain page.
+const (
+	maxProblems = 10
+	maxProblem  = 200
+)
+
+// ExplainURL returns the link to the Explain page at base for the problems
+// that the named validator found in t. Long lists of problems are shortened
+// to keep the link short.
+func ExplainURL(base, name string, t *Target, problems []string) string {
+	q := url.Values{}
+	q.Set("validator", name)
+	q.Set("repo", t.Owner+"/"+t.Repo)
+	q.Set("sha", t.SHA)
+	for i, p := range problems {
+		if i == maxProblems {
+			q.Add("problem", fmt.Sprintf("... and %d more", len(problems)-i))
+			break
+		}
+		q.Add("problem", truncate(p, maxProblem))
+	}
+	return base + "?" + q.Encode()
+}
+
+var explainTemplate = template.Must(template.New("explain").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Validator}}</title></head>
+<body>
+<h1>{{.Validator}}</h1>
+<p>{{.Description}}</p>
+{{if .Repo}}<h2>{{.Repo}}@{{.SHA}}</h2>
+{{end}}{{if .Problems}}<ul>
+{{range .Problems}}<li>{{.}}</li>
+{{end}}</ul>
+{{else}}<p>No problems were found.</p>
+{{end}}</body>
+</html>
+`))
+
+// Explain serves a page describing the validator in the "validator"
+// parameter, and the "problem" parameters it found in the "repo" and "sha"
+// parameters. Links to the page are created by ExplainURL, so the page needs
+// no state and may be served by any replica.
+func Explain(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("validator")
+	reg, ok := registry[name]
+	if !ok {
+		http.Error(w, "unknown validator", http.StatusNotFound)
+		return
+	}
+	data := struct {
+		Validator   string
+		Description string
+		Repo        string
+		SHA         string
+		Problems    []string
+	}{
+		Validator:   name,
+		Description: reg.description,
+		Repo:        r.FormValue("repo"),
+		SHA:         r.FormValue("sha"),
+		Problems:    r.Form["problem"],
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := explainTemplate.Execute(w, data); err != nil {
+		log.Println("statuses:", err)
+	}
+}
@@ -0,0 +1,49 @@
+package statuses
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		want       []string
+	}{
+		{
+			name:       "problems",
+			url:        "/statuses?validator=signed_off_by&repo=o/r&sha=abc&problem=abc:+no+%3Ctrailer%3E",
+			wantStatus: http.StatusOK,
+			want:       []string{"<h1>signed_off_by</h1>", "Signed-off-by: Name", "o/r@abc", "<li>abc: no &lt;trailer&gt;</li>"},
+		},
+		{
+			name:       "passed",
+			url:        "/statuses?validator=issue_reference&repo=o/r&sha=abc",
+			wantStatus: http.StatusOK,
+			want:       []string{"No problems were found."},
+		},
+		{
+			name:       "unknown",
+			url:        "/statuses?validator=spelling",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Explain(w, httptest.NewRequest("GET", tt.url, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Explain() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(w.Body.String(), s) {
+					t.Errorf("Explain() body = %q, want %q", w.Body.String(), s)
+				}
+			}
+		})
+	}
+}
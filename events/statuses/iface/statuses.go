@@ -0,0 +1,41 @@
+package iface
+
+import (
+	"context"
+
+	"github.com/google/go-github/github"
+)
+
+// Statuses defines the interface used by the commit status logic.
+type Statuses interface {
+	CreateStatus(ctx context.Context, owner string, repo string, ref string, status *github.RepoStatus) (*github.RepoStatus, *github.Response, error)
+	ListCommits(ctx context.Context, owner string, repo string, number int, opt *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error)
+}
+
+// StatusesImpl implements the Statuses interface.
+type StatusesImpl struct {
+	repos *github.RepositoriesService
+	pulls *github.PullRequestsService
+}
+
+// NewStatuses creates a new Statuses instance using the services of client.
+func NewStatuses(client *github.Client) *StatusesImpl {
+	return &StatusesImpl{
+		repos: client.Repositories,
+		pulls: client.PullRequests,
+	}
+}
+
+// CreateStatus sets a commit status on ref in the owner repo.
+func (s *StatusesImpl) CreateStatus(
+	ctx context.Context, owner string, repo string, ref string,
+	status *github.RepoStatus) (*github.RepoStatus, *github.Response, error) {
+	return s.repos.CreateStatus(ctx, owner, repo, ref, status)
+}
+
+// ListCommits lists the commits of the repo pull request.
+func (s *StatusesImpl) ListCommits(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	return s.pulls.ListCommits(ctx, owner, repo, number, opt)
+}
@@ -0,0 +1,188 @@
+// Package statuses sets commit statuses from pluggable validators of pushes
+// and pull requests.
+//
+// Commit statuses are a lighter alternative to check runs: they work with any
+// authentication and only report a state, a short description and a link.
+// Each validator sets its own status, with the context
+// "<context>/<validator>/push" on the head commit of a push, and
+// "<context>/<validator>/pr" on the head commit of a pull request, so that the
+// two never overwrite each other. The link of a status points to the Explain
+// page, which describes the validator and lists the problems found.
+package statuses
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/events/statuses/iface"
+)
+
+// Events that set statuses, used as the last element of status contexts.
+const (
+	Push        = "push"
+	PullRequest = "pr"
+)
+
+// States of commit statuses.
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+// maxDescription is the longest status description that Github accepts.
+const maxDescription = 140
+
+// Commit is a commit to validate.
+type Commit struct {
+	SHA         string
+	Message     string
+	AuthorEmail string
+}
+
+// Short returns the abbreviated commit SHA.
+func (c *Commit) Short() string {
+	if len(c.SHA) > 7 {
+		return c.SHA[:7]
+	}
+	return c.SHA
+}
+
+// Target is a push or pull request to validate.
+type Target struct {
+	Owner string
+	Repo  string
+	// Event is Push or PullRequest.
+	Event string
+	// SHA is the head commit, which receives the statuses.
+	SHA string
+	// Commits are the pushed commits, or the commits of the pull request.
+	Commits []*Commit
+	// PullRequest is nil for pushes.
+	PullRequest *github.PullRequest
+}
+
+// Reporter sets the commit statuses of the configured validators.
+type Reporter struct {
+	iface.Statuses
+	*Config
+
+	// URL is the address of the Explain page, e.g.
+	// "https://example.com/statuses". Empty omits the links of statuses.
+	URL string
+}
+
+// NewReporter creates a Reporter for the validators of c.
+func NewReporter(statuses iface.Statuses, c *Config, url string) *Reporter {
+	return &Reporter{Statuses: statuses, Config: c, URL: url}
+}
+
+// PushEvent validates the commits of a branch push. Tags and deleted branches
+// are ignored.
+func (r *Reporter) PushEvent(ctx context.Context, event *github.PushEvent) error {
+	if event.GetDeleted() || !strings.HasPrefix(event.GetRef(), "refs/heads/") || len(event.Commits) == 0 {
+		return nil
+	}
+	owner, repo, err := issues.SplitFullName(event.GetRepo().GetFullName())
+	if err != nil {
+		return err
+	}
+	t := &Target{Owner: owner, Repo: repo, Event: Push, SHA: event.GetAfter()}
+	for _, c := range event.Commits {
+		t.Commits = append(t.Commits, &Commit{
+			SHA:         c.GetID(),
+			Message:     c.GetMessage(),
+			AuthorEmail: c.GetAuthor().GetEmail(),
+		})
+	}
+	return r.Report(ctx, t)
+}
+
+// PullRequestEvent validates the commits of a pull request when it is opened,
+// edited, reopened or gets new commits.
+func (r *Reporter) PullRequestEvent(ctx context.Context, event *github.PullRequestEvent) error {
+	switch event.GetAction() {
+	case "opened", "edited", "reopened", "synchronize":
+	default:
+		return nil
+	}
+	pr := event.GetPullRequest()
+	t := &Target{
+		Owner:       event.GetRepo().GetOwner().GetLogin(),
+		Repo:        event.GetRepo().GetName(),
+		Event:       PullRequest,
+		SHA:         pr.GetHead().GetSHA(),
+		PullRequest: pr,
+	}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		list, resp, err := r.ListCommits(ctx, t.Owner, t.Repo, pr.GetNumber(), opt)
+		if err != nil {
+			return err
+		}
+		for _, c := range list {
+			t.Commits = append(t.Commits, &Commit{
+				SHA:         c.GetSHA(),
+				Message:     c.GetCommit().GetMessage(),
+				AuthorEmail: c.GetCommit().GetAuthor().GetEmail(),
+			})
+		}
+		if resp == nil || resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return r.Report(ctx, t)
+}
+
+// Report sets the status of every validator on the head commit of the target.
+// Every status is set, even if an earlier one fails; the first error is
+// returned.
+func (r *Reporter) Report(ctx context.Context, t *Target) error {
+	var first error
+	for _, v := range r.Config.validators {
+		problems := v.Validate(t)
+		status := &github.RepoStatus{
+			State:       github.String(Success),
+			Description: github.String("Passed"),
+			Context:     github.String(r.Config.Context + "/" + v.Name() + "/" + t.Event),
+		}
+		if len(problems) > 0 {
+			status.State = github.String(Failure)
+			status.Description = github.String(describe(problems))
+		}
+		if r.URL != "" {
+			status.TargetURL = github.String(ExplainURL(r.URL, v.Name(), t, problems))
+		}
+		log.Printf("statuses: %s of %s/%s@%s: %s", status.GetContext(), t.Owner, t.Repo, t.SHA, status.GetDescription())
+		if _, _, err := r.CreateStatus(ctx, t.Owner, t.Repo, t.SHA, status); err != nil {
+			log.Println("statuses:", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// describe summarizes the problems in a status description.
+func describe(problems []string) string {
+	d := problems[0]
+	if len(problems) > 1 {
+		d = fmt.Sprintf("%s (and %d more)", d, len(problems)-1)
+	}
+	return truncate(d, maxDescription)
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,230 @@
+package statuses
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/statuses/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+type fakeStatuses struct {
+	commits  [][]*github.RepositoryCommit
+	statuses []string
+	urls     []string
+	err      error
+}
+
+func (f *fakeStatuses) CreateStatus(
+	ctx context.Context, owner string, repo string, ref string,
+	status *github.RepoStatus) (*github.RepoStatus, *github.Response, error) {
+	f.statuses = append(f.statuses, fmt.Sprintf("%s/%s@%s %s %s %q",
+		owner, repo, ref, status.GetContext(), status.GetState(), status.GetDescription()))
+	f.urls = append(f.urls, status.GetTargetURL())
+	return status, nil, f.err
+}
+
+func (f *fakeStatuses) ListCommits(
+	ctx context.Context, owner string, repo string, number int,
+	opt *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	page := opt.Page
+	if page == 0 {
+		page = 1
+	}
+	resp := &github.Response{}
+	if page < len(f.commits) {
+		resp.NextPage = page + 1
+	}
+	return f.commits[page-1], resp, nil
+}
+
+var _ iface.Statuses = &fakeStatuses{}
+
+func newRepositoryCommit(sha, message, email string) *github.RepositoryCommit {
+	return &github.RepositoryCommit{
+		SHA: &sha,
+		Commit: &github.Commit{
+			Message: &message,
+			Author:  &github.CommitAuthor{Email: &email},
+		},
+	}
+}
+
+func newPushCommit(id, message string) github.PushEventCommit {
+	return github.PushEventCommit{ID: &id, Message: &message}
+}
+
+const testConfig = `
+validators:
+- type: signed_off_by
+- type: issue_reference
+`
+
+func TestReporter_PushEvent(t *testing.T) {
+	c := &Config{}
+	if err := configfile.Parse([]byte(testConfig), c); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		event *github.PushEvent
+		want  []string
+	}{
+		{
+			name: "push",
+			event: &github.PushEvent{
+				Ref:   github.String("refs/heads/master"),
+				After: github.String("bbbbbbbbbb"),
+				Repo:  &github.PushEventRepository{FullName: github.String("o/r")},
+				Commits: []github.PushEventCommit{
+					newPushCommit("aaaaaaaaaa", "Fix #1\n\nSigned-off-by: A <a@example.com>"),
+					newPushCommit("bbbbbbbbbb", "Tidy"),
+				},
+			},
+			want: []string{
+				`o/r@bbbbbbbbbb webhook-receiver/signed_off_by/push failure "bbbbbbb: no Signed-off-by trailer"`,
+				`o/r@bbbbbbbbbb webhook-receiver/issue_reference/push success "Passed"`,
+			},
+		},
+		{
+			name: "tag",
+			event: &github.PushEvent{
+				Ref:     github.String("refs/tags/v1"),
+				Repo:    &github.PushEventRepository{FullName: github.String("o/r")},
+				Commits: []github.PushEventCommit{newPushCommit("aaaaaaaaaa", "Tidy")},
+			},
+		},
+		{
+			name: "deleted",
+			event: &github.PushEvent{
+				Ref:     github.String("refs/heads/old"),
+				Deleted: github.Bool(true),
+				Repo:    &github.PushEventRepository{FullName: github.String("o/r")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeStatuses{}
+			r := NewReporter(f, c, "https://example.com/statuses")
+			if err := r.PushEvent(context.Background(), tt.event); err != nil {
+				t.Fatalf("PushEvent() error = %v", err)
+			}
+			if !reflect.DeepEqual(f.statuses, tt.want) {
+				t.Errorf("statuses = %q, want %q", f.statuses, tt.want)
+			}
+			for _, u := range f.urls {
+				if !strings.HasPrefix(u, "https://example.com/statuses?") {
+					t.Errorf("target URL = %q, want explain URL", u)
+				}
+			}
+		})
+	}
+}
+
+func TestReporter_PullRequestEvent(t *testing.T) {
+	c := &Config{}
+	if err := configfile.Parse([]byte(testConfig), c); err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeStatuses{commits: [][]*github.RepositoryCommit{
+		{newRepositoryCommit("aaaaaaaaaa", "Add\n\nSigned-off-by: A <a@example.com>", "a@example.com")},
+		{newRepositoryCommit("bbbbbbbbbb", "Test\n\nSigned-off-by: B <b@example.com>", "b@example.com")},
+	}}
+	event := &github.PullRequestEvent{
+		Action: github.String("synchronize"),
+		Repo:   &github.Repository{Name: github.String("r"), Owner: &github.User{Login: github.String("o")}},
+		PullRequest: &github.PullRequest{
+			Number: github.Int(5),
+			Body:   github.String("See #5"),
+			Head:   &github.PullRequestBranch{SHA: github.String("bbbbbbbbbb")},
+		},
+	}
+	r := NewReporter(f, c, "")
+	if err := r.PullRequestEvent(context.Background(), event); err != nil {
+		t.Fatalf("PullRequestEvent() error = %v", err)
+	}
+	want := []string{
+		`o/r@bbbbbbbbbb webhook-receiver/signed_off_by/pr success "Passed"`,
+		`o/r@bbbbbbbbbb webhook-receiver/issue_reference/pr failure "no issue reference in the pull request or its commit messages"`,
+	}
+	if !reflect.DeepEqual(f.statuses, want) {
+		t.Errorf("statuses = %q, want %q", f.statuses, want)
+	}
+	if !reflect.DeepEqual(f.urls, []string{"", ""}) {
+		t.Errorf("target URLs = %q, want none", f.urls)
+	}
+
+	event.Action = github.String("closed")
+	f.statuses = nil
+	if err := r.PullRequestEvent(context.Background(), event); err != nil || f.statuses != nil {
+		t.Errorf("PullRequestEvent(closed) = %v, statuses %q; want nothing", err, f.statuses)
+	}
+}
+
+func TestReporter_Report_error(t *testing.T) {
+	c := &Config{}
+	if err := configfile.Parse([]byte(testConfig), c); err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeStatuses{err: fmt.Errorf("forbidden")}
+	target := &Target{Owner: "o", Repo: "r", Event: Push, SHA: "abc", Commits: []*Commit{{SHA: "abc", Message: "x"}}}
+	if err := NewReporter(f, c, "").Report(context.Background(), target); err == nil {
+		t.Errorf("Report() error = nil, want error")
+	}
+	if len(f.statuses) != 2 {
+		t.Errorf("statuses = %q, want every validator attempted", f.statuses)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{name: "valid", yaml: "context: ci\nvalidators:\n- {type: message_format, pattern: '^[A-Z]', max_length: 50}\n"},
+		{name: "empty", yaml: "context: ci\n", wantErr: "validators are required"},
+		{name: "unknown", yaml: "validators: [{type: spelling}]\n", wantErr: "unknown type"},
+		{name: "duplicate", yaml: "validators: [{type: signed_off_by}, {type: signed_off_by}]\n", wantErr: "duplicate"},
+		{name: "pattern", yaml: "validators: [{type: message_format, pattern: '('}]\n", wantErr: "invalid pattern"},
+		{name: "field", yaml: "validators: [{type: signed_off_by, typo: 1}]\n", wantErr: "typo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := configfile.Parse([]byte(tt.yaml), c)
+			if tt.wantErr == "" {
+				if err != nil || len(c.validators) != len(c.Validators) {
+					t.Errorf("Parse() = %+v, %v; want validators", c, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExplainURL(t *testing.T) {
+	var problems []string
+	for i := 0; i < 12; i++ {
+		problems = append(problems, strings.Repeat("x", 300))
+	}
+	u, err := url.Parse(ExplainURL("/statuses", SignedOffBy, &Target{Owner: "o", Repo: "r", SHA: "abc"}, problems))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	got := q["problem"]
+	if q.Get("validator") != SignedOffBy || q.Get("repo") != "o/r" || q.Get("sha") != "abc" ||
+		len(got) != maxProblems+1 || len(got[0]) != maxProblem || got[maxProblems] != "... and 2 more" {
+		t.Errorf("ExplainURL() = %q, want shortened problems", u)
+	}
+}
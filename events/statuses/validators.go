@@ -0,0 +1,149 @@
+package statuses
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+)
+
+// Built-in validator types.
+const (
+	MessageFormat  = "message_format"
+	SignedOffBy    = "signed_off_by"
+	IssueReference = "issue_reference"
+)
+
+// DefaultMaxLength is the default longest first line of commit messages.
+const DefaultMaxLength = 72
+
+var signedOffBy = regexp.MustCompile(`(?mi)^Signed-off-by: .*<([^<>\s]+)>\s*$`)
+
+func init() {
+	Register(MessageFormat, "Every commit message starts with a summary line of limited length, "+
+		"matching the format of the repository, followed by a blank line before any details. "+
+		"Merge commits are not checked. Fix the messages with \"git commit --amend\" or \"git rebase -i\", "+
+		"then force push.", newMessageFormat)
+	Register(SignedOffBy, "Every commit message ends with a \"Signed-off-by: Name <email>\" trailer, "+
+		"certifying that the author may contribute the change under the license of the repository. "+
+		"Merge commits are not checked. Add the trailer with \"git commit --amend --signoff\", or "+
+		"\"git rebase --signoff\" for several commits, then force push.", newSignedOffBy)
+	Register(IssueReference, "The change references the issue it addresses, e.g. \"Fixes #123\" or "+
+		"\"owner/repo#123\", in a commit message, or in the title or description of the pull request.",
+		newIssueReference)
+}
+
+// isMerge reports whether the message is the default message of a merge
+// commit.
+func isMerge(message string) bool {
+	return strings.HasPrefix(message, "Merge pull request ") || strings.HasPrefix(message, "Merge branch ") ||
+		strings.HasPrefix(message, "Merge remote-tracking branch ")
+}
+
+type messageFormat struct {
+	pattern   *regexp.Regexp
+	maxLength int
+}
+
+func newMessageFormat(c *ValidatorConfig) (Validator, error) {
+	v := &messageFormat{maxLength: c.MaxLength}
+	if v.maxLength == 0 {
+		v.maxLength = DefaultMaxLength
+	}
+	if c.Pattern != "" {
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+		v.pattern = re
+	}
+	return v, nil
+}
+
+func (v *messageFormat) Name() string { return MessageFormat }
+
+func (v *messageFormat) Validate(t *Target) []string {
+	var problems []string
+	for _, c := range t.Commits {
+		if isMerge(c.Message) {
+			continue
+		}
+		lines := strings.SplitN(c.Message, "\n", 3)
+		subject := strings.TrimRight(lines[0], "\r")
+		switch {
+		case strings.TrimSpace(subject) == "":
+			problems = append(problems, fmt.Sprintf("%s: the first line is empty", c.Short()))
+		case v.maxLength > 0 && utf8.RuneCountInString(subject) > v.maxLength:
+			problems = append(problems, fmt.Sprintf("%s: the first line is longer than %d characters", c.Short(), v.maxLength))
+		case v.pattern != nil && !v.pattern.MatchString(subject):
+			problems = append(problems, fmt.Sprintf("%s: the first line does not match %q", c.Short(), v.pattern))
+		case len(lines) > 1 && strings.TrimSpace(lines[1]) != "":
+			problems = append(problems, fmt.Sprintf("%s: the second line is not blank", c.Short()))
+		}
+	}
+	return problems
+}
+
+type signedOff struct {
+	matchAuthor bool
+}
+
+func newSignedOffBy(c *ValidatorConfig) (Validator, error) {
+	return &signedOff{matchAuthor: c.MatchAuthor}, nil
+}
+
+func (v *signedOff) Name() string { return SignedOffBy }
+
+func (v *signedOff) Validate(t *Target) []string {
+	var problems []string
+	for _, c := range t.Commits {
+		if isMerge(c.Message) {
+			continue
+		}
+		matches := signedOffBy.FindAllStringSubmatch(c.Message, -1)
+		if len(matches) == 0 {
+			problems = append(problems, fmt.Sprintf("%s: no Signed-off-by trailer", c.Short()))
+			continue
+		}
+		if !v.matchAuthor || c.AuthorEmail == "" {
+			continue
+		}
+		found := false
+		for _, m := range matches {
+			found = found || strings.EqualFold(m[1], c.AuthorEmail)
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("%s: not signed off by the author %s", c.Short(), c.AuthorEmail))
+		}
+	}
+	return problems
+}
+
+type issueReference struct{}
+
+func newIssueReference(c *ValidatorConfig) (Validator, error) {
+	return issueReference{}, nil
+}
+
+func (issueReference) Name() string { return IssueReference }
+
+func (issueReference) Validate(t *Target) []string {
+	texts := []string{t.PullRequest.GetTitle(), t.PullRequest.GetBody()}
+	for _, c := range t.Commits {
+		texts = append(texts, c.Message)
+	}
+	for _, text := range texts {
+		for _, ref := range issues.Refs(t.Owner, t.Repo, text) {
+			// A pull request does not reference itself.
+			if t.PullRequest == nil || ref != (issues.Ref{Owner: t.Owner, Repo: t.Repo, Number: t.PullRequest.GetNumber()}) {
+				return nil
+			}
+		}
+	}
+	if t.PullRequest != nil {
+		return []string{"no issue reference in the pull request or its commit messages"}
+	}
+	return []string{"no issue reference in the pushed commit messages"}
+}
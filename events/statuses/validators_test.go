@@ -0,0 +1,91 @@
+package statuses
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestValidators(t *testing.T) {
+	commits := []*Commit{
+		{SHA: "1111111111", Message: "feat: add status reporter\n\nDetails.\n\nSigned-off-by: A <a@example.com>", AuthorEmail: "a@example.com"},
+		{SHA: "2222222222", Message: "Fix a very long subject line that goes on and on well past the limit of a summary", AuthorEmail: "b@example.com"},
+		{SHA: "3333333333", Message: "fix: typo\nno blank line\n\nSigned-off-by: A <a@example.com>", AuthorEmail: "b@example.com"},
+		{SHA: "4444444444", Message: "Merge pull request #3 from o/branch"},
+		{SHA: "5555555555", Message: "\n\nSigned-off-by: B <B@example.com>", AuthorEmail: "b@example.com"},
+	}
+	tests := []struct {
+		name   string
+		config ValidatorConfig
+		target *Target
+		want   []string
+	}{
+		{
+			name:   "message-format",
+			config: ValidatorConfig{Type: MessageFormat, Pattern: `^(feat|fix): `},
+			target: &Target{Commits: commits},
+			want: []string{
+				"2222222: the first line is longer than 72 characters",
+				"3333333: the second line is not blank",
+				"5555555: the first line is empty",
+			},
+		},
+		{
+			name:   "message-format-pattern",
+			config: ValidatorConfig{Type: MessageFormat, Pattern: `^(feat|fix): `, MaxLength: -1},
+			target: &Target{Commits: commits[1:2]},
+			want:   []string{`2222222: the first line does not match "^(feat|fix): "`},
+		},
+		{
+			name:   "signed-off-by",
+			config: ValidatorConfig{Type: SignedOffBy},
+			target: &Target{Commits: commits},
+			want:   []string{"2222222: no Signed-off-by trailer"},
+		},
+		{
+			name:   "signed-off-by-author",
+			config: ValidatorConfig{Type: SignedOffBy, MatchAuthor: true},
+			target: &Target{Commits: commits},
+			want: []string{
+				"2222222: no Signed-off-by trailer",
+				"3333333: not signed off by the author b@example.com",
+			},
+		},
+		{
+			name:   "issue-reference-commit",
+			config: ValidatorConfig{Type: IssueReference},
+			target: &Target{Owner: "o", Repo: "r", Commits: []*Commit{{Message: "Fixes o/other#2"}}},
+		},
+		{
+			name:   "issue-reference-pr-body",
+			config: ValidatorConfig{Type: IssueReference},
+			target: &Target{Owner: "o", Repo: "r", Commits: commits[:1],
+				PullRequest: &github.PullRequest{Number: github.Int(7), Body: github.String("Closes #6")}},
+		},
+		{
+			name:   "issue-reference-self",
+			config: ValidatorConfig{Type: IssueReference},
+			target: &Target{Owner: "o", Repo: "r", Commits: commits[:1],
+				PullRequest: &github.PullRequest{Number: github.Int(7), Title: github.String("Add (#7)")}},
+			want: []string{"no issue reference in the pull request or its commit messages"},
+		},
+		{
+			name:   "issue-reference-push",
+			config: ValidatorConfig{Type: IssueReference},
+			target: &Target{Owner: "o", Repo: "r", Commits: commits[:1]},
+			want:   []string{"no issue reference in the pushed commit messages"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := registry[tt.config.Type].factory(&tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := v.Validate(tt.target); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
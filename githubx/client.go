@@ -5,8 +5,10 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/bradleyfalzon/ghinstallation"
 
@@ -19,6 +21,13 @@ import (
 // environment variables. If required environment variables are not found,
 // NewClient logs a warning and returns nil.
 //
+// All clients send requests to the GitHub API URL given in the GITHUB_API_URL
+// environment variable, or to https://api.github.com if it is not set. Tests
+// may use this to send requests to a fake server.
+//
+// When dry-run mode is enabled (see SetDryRun), clients only send read-only
+// requests and log all other requests instead of sending them.
+//
 // If the installationID is zero, then NewClient authenticates using a personal
 // access token from the GITHUB_AUTH_TOKEN environment vairable. Personal access
 // tokens perform actions as the user associated with the token.
@@ -46,22 +55,49 @@ func NewClient(installationID int64) *github.Client {
 	}
 
 	// GitHub Apps authentication.
+	privateKey, appID, ok := appCredentials()
+	if !ok {
+		return nil
+	}
+	return NewAppClient(privateKey, appID, installationID)
+}
+
+// NewJWTClientFromEnv creates a new *github.Client authenticated as the
+// Github App itself, using the GITHUB_PRIVATE_KEY and GITHUB_APP_ID environment
+// variables. If required environment variables are not found,
+// NewJWTClientFromEnv logs a warning and returns nil.
+//
+// Unlike installation clients, the App client authenticates with a JWT and may
+// only access the App endpoints, e.g. listing installations. Use it from
+// scheduled jobs or CLI tools that have no triggering event, then use
+// FindInstallation or ListInstallations to create installation clients.
+func NewJWTClientFromEnv() *github.Client {
+	privateKey, appID, ok := appCredentials()
+	if !ok {
+		return nil
+	}
+	return NewJWTClient(privateKey, appID)
+}
+
+// appCredentials reads the Github App private key file name and App ID from
+// the environment.
+func appCredentials() (string, int64, bool) {
 	privateKey, found := os.LookupEnv("GITHUB_PRIVATE_KEY")
 	if !found {
 		log.Println("WARNING: GITHUB_PRIVATE_KEY is not set.")
-		return nil
+		return "", 0, false
 	}
 	appIDStr, found := os.LookupEnv("GITHUB_APP_ID")
 	if !found {
 		log.Println("WARNING: GITHUB_APP_ID is not set.")
-		return nil
+		return "", 0, false
 	}
 	appID, err := strconv.ParseInt(appIDStr, 10, 32)
 	if err != nil {
 		log.Println(err)
-		return nil
+		return "", 0, false
 	}
-	return NewAppClient(privateKey, appID, installationID)
+	return privateKey, appID, true
 }
 
 // NewPersonalClient creates a *github.Client authenticated using the given
@@ -72,7 +108,7 @@ func NewPersonalClient(authToken string) *github.Client {
 	tokenSource := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: authToken},
 	)
-	return github.NewClient(oauth2.NewClient(ctx, tokenSource))
+	return withBaseURL(github.NewClient(withDryRun(oauth2.NewClient(ctx, tokenSource))))
 }
 
 // NewAppClient creates a new *github.Client authenticated using the given
@@ -88,6 +124,47 @@ func NewAppClient(privateKey string, appID, installationID int64) *github.Client
 		log.Println(err)
 		return nil
 	}
+	if u := apiURL(); u != "" {
+		itr.BaseURL = strings.TrimSuffix(u, "/")
+	}
 	// Use the installation transport with a new *github.Client.
-	return github.NewClient(&http.Client{Transport: itr})
+	return withBaseURL(github.NewClient(withDryRun(&http.Client{Transport: itr})))
+}
+
+// NewJWTClient creates a new *github.Client authenticated as the Github App
+// using the given privateKey file name and appID. Future operations are
+// performed with a JWT signed by the private key, which only grants access to
+// the Github App endpoints.
+func NewJWTClient(privateKey string, appID int64) *github.Client {
+	atr, err := ghinstallation.NewAppsTransportKeyFromFile(
+		http.DefaultTransport, appID, privateKey)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	return withBaseURL(github.NewClient(withDryRun(&http.Client{Transport: atr})))
+}
+
+// apiURL returns the value of the GITHUB_API_URL environment variable.
+func apiURL() string {
+	return os.Getenv("GITHUB_API_URL")
+}
+
+// withBaseURL updates the client base URL when GITHUB_API_URL is set. A
+// malformed URL is logged and ignored.
+func withBaseURL(client *github.Client) *github.Client {
+	u := apiURL()
+	if u == "" {
+		return client
+	}
+	if !strings.HasSuffix(u, "/") {
+		u += "/"
+	}
+	base, err := url.Parse(u)
+	if err != nil {
+		log.Println(err)
+		return client
+	}
+	client.BaseURL = base
+	return client
 }
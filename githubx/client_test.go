@@ -105,3 +105,72 @@ func TestNewAppClient(t *testing.T) {
 		})
 	}
 }
+
+func TestNewJWTClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		privateKey string
+		wantNil    bool
+	}{
+		{
+			name:       "error returns nil",
+			privateKey: "",
+			wantNil:    true,
+		},
+		{
+			name:       "success",
+			privateKey: "testdata/unused_insecure_rsa_key.pem",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewJWTClient(tt.privateKey, 1)
+			if (got == nil) != tt.wantNil {
+				t.Errorf("NewJWTClient() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestNewJWTClientFromEnv(t *testing.T) {
+	tests := []struct {
+		name             string
+		githubPrivateKey string
+		githubAppID      string
+		wantNil          bool
+	}{
+		{
+			name:             "success",
+			githubPrivateKey: "testdata/unused_insecure_rsa_key.pem",
+			githubAppID:      "1",
+		},
+		{
+			name:    "missing-private-key",
+			wantNil: true,
+		},
+		{
+			name:             "invalid-app-id",
+			githubPrivateKey: "testdata/unused_insecure_rsa_key.pem",
+			githubAppID:      "NOT-A-NUMBER",
+			wantNil:          true,
+		},
+	}
+	for _, tt := range tests {
+		if tt.githubPrivateKey != "" {
+			os.Setenv("GITHUB_PRIVATE_KEY", tt.githubPrivateKey)
+		} else {
+			os.Unsetenv("GITHUB_PRIVATE_KEY")
+		}
+		if tt.githubAppID != "" {
+			os.Setenv("GITHUB_APP_ID", tt.githubAppID)
+		} else {
+			os.Unsetenv("GITHUB_APP_ID")
+		}
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewJWTClientFromEnv()
+			if (got == nil) != tt.wantNil {
+				t.Errorf("NewJWTClientFromEnv() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package githubx
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	dryRunMu sync.Mutex
+	dryRun   bool
+)
+
+func init() {
+	dryRun = os.Getenv("DRY_RUN") == "1"
+}
+
+// SetDryRun enables or disables dry-run mode for all clients created after the
+// call. The initial value is true when the DRY_RUN environment variable is "1".
+func SetDryRun(enable bool) {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	dryRun = enable
+}
+
+// DryRun reports whether dry-run mode is enabled.
+func DryRun() bool {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	return dryRun
+}
+
+// DryRunTransport is an http.RoundTripper that sends read-only requests using
+// the underlying transport and logs all other requests without sending them.
+// GraphQL queries are read-only, but GraphQL mutations are not. Mutating
+// requests receive an empty "200 OK" response with a JSON "null" body, which
+// go-github decodes as a zero value.
+type DryRunTransport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *DryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		base := t.Base
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		return base.RoundTrip(req)
+	}
+	body := []byte{}
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/graphql") && !isMutation(body) {
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		base := t.Base
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		return base.RoundTrip(req)
+	}
+	log.Printf("DRY RUN: would do %s %s %s", req.Method, req.URL, body)
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(bytes.NewBufferString("null")),
+		Request:    req,
+	}, nil
+}
+
+// isMutation reports whether body is a GraphQL request for a mutation, or is
+// not a GraphQL request at all.
+func isMutation(body []byte) bool {
+	var req struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return true
+	}
+	query := strings.TrimSpace(req.Query)
+	return query == "" || !(query[0] == '{' || strings.HasPrefix(query, "query"))
+}
+
+// withDryRun wraps the client transport with a DryRunTransport when dry-run
+// mode is enabled.
+func withDryRun(client *http.Client) *http.Client {
+	if DryRun() {
+		client.Transport = &DryRunTransport{Base: client.Transport}
+	}
+	return client
+}
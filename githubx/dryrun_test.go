@@ -0,0 +1,85 @@
+package githubx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestDryRunTransport(t *testing.T) {
+	mutations := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			mutations++
+		}
+		fmt.Fprint(w, `{"number":1,"title":"real"}`)
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(&http.Client{Transport: &DryRunTransport{}})
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	ctx := context.Background()
+
+	issue, _, err := client.Issues.Get(ctx, "o", "r", 1)
+	if err != nil || issue.GetTitle() != "real" {
+		t.Errorf("Get() = %v, %v; want real issue", issue, err)
+	}
+	labels, _, err := client.Issues.AddLabelsToIssue(ctx, "o", "r", 1, []string{"a"})
+	if err != nil || len(labels) != 0 {
+		t.Errorf("AddLabelsToIssue() = %v, %v; want no labels", labels, err)
+	}
+	if _, err := client.Issues.RemoveLabelForIssue(ctx, "o", "r", 1, "a"); err != nil {
+		t.Errorf("RemoveLabelForIssue() = %v", err)
+	}
+	if mutations != 0 {
+		t.Errorf("DryRunTransport sent %d mutating requests, want 0", mutations)
+	}
+}
+
+func TestDryRunTransport_GraphQL(t *testing.T) {
+	sent := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Query string }
+		json.NewDecoder(r.Body).Decode(&req)
+		sent = append(sent, req.Query)
+		fmt.Fprint(w, `{"data":{}}`)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &DryRunTransport{}}
+	for _, query := range []string{
+		"query($id: ID!) { node(id: $id) { id } }",
+		"{ viewer { login } }",
+		"mutation { addProjectV2ItemById(input: {}) { item { id } } }",
+	} {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		resp, err := client.Post(srv.URL+"/graphql", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if len(sent) != 2 {
+		t.Errorf("DryRunTransport sent %q, want only the queries", sent)
+	}
+}
+
+func TestSetDryRun(t *testing.T) {
+	defer SetDryRun(DryRun())
+	SetDryRun(true)
+	c := NewPersonalClient("token")
+	if !DryRun() || c == nil {
+		t.Fatalf("SetDryRun(true) did not enable dry-run mode")
+	}
+	SetDryRun(false)
+	if DryRun() {
+		t.Errorf("SetDryRun(false) did not disable dry-run mode")
+	}
+}
@@ -0,0 +1,922 @@
+// Package fakegithub provides an in-process fake of the GitHub REST API for
+// integration tests.
+//
+// The Server implements the subset of endpoints used by this project: issues,
+// labels, comments, comment reactions, assignees, collaborator permissions,
+// issue events, classic projects, installations and installation access
+// tokens. All state is kept in memory and may be set up and inspected directly
+// from tests. Point a client at the server by setting the GITHUB_API_URL
+// environment variable to Server.URL, or by using Server.Client.
+package fakegithub
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
+)
+
+// defaultLabelColor is the color given to labels created implicitly.
+const defaultLabelColor = "ededed"
+
+// Repo holds the state of a single fake repository.
+type Repo struct {
+	Owner    string
+	Name     string
+	Issues   map[int]*github.Issue
+	Labels   map[string]*github.Label
+	Comments map[int][]*github.IssueComment
+	// Events are the issue timeline events. Labels added through the API are
+	// recorded as "labeled" events.
+	Events map[int][]*github.IssueEvent
+	// Files maps file paths to their contents.
+	Files map[string]string
+	// Permissions maps user logins to their permission level, e.g. "write".
+	// Other users have "read" permission.
+	Permissions map[string]string
+	// Reactions maps issue comment IDs to the reaction contents added.
+	Reactions map[int64][]string
+}
+
+// Project holds the state of a single fake classic project.
+type Project struct {
+	ID      int64
+	Columns []*github.ProjectColumn
+	Cards   map[int64][]*github.ProjectCard
+}
+
+// Server is a fake GitHub API server.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	repos         map[string]*Repo
+	projects      map[int64]*Project
+	installations map[int64][]string
+	nextID        int64
+	requests      []string
+}
+
+// NewServer creates and starts a new fake GitHub API server. Callers should
+// call Close when finished.
+func NewServer() *Server {
+	s := &Server{
+		repos:         map[string]*Repo{},
+		projects:      map[int64]*Project{},
+		installations: map[int64][]string{},
+		nextID:        1000,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client returns a new *github.Client that sends requests to the server.
+func (s *Server) Client() *github.Client {
+	client := github.NewClient(nil)
+	u, _ := url.Parse(s.URL + "/")
+	client.BaseURL = u
+	return client
+}
+
+// AddRepo creates an empty repository.
+func (s *Server) AddRepo(owner, name string) *Repo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repo(owner, name)
+}
+
+// AddIssue adds the given issue to a repository, creating the repository and
+// any labels as needed.
+func (s *Server) AddIssue(owner, name string, issue *github.Issue) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(owner, name)
+	if issue.State == nil {
+		issue.State = github.String("open")
+	}
+	for _, l := range issue.Labels {
+		r.label(l.GetName())
+	}
+	r.Issues[issue.GetNumber()] = issue
+}
+
+// AddIssueEvent adds an event to the timeline of an issue.
+func (s *Server) AddIssueEvent(owner, name string, number int, event *github.IssueEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(owner, name)
+	r.Events[number] = append(r.Events[number], event)
+}
+
+// AddFile adds a file with the given content to a repository.
+func (s *Server) AddFile(owner, name, path, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repo(owner, name).Files[path] = content
+}
+
+// SetPermission sets the permission level of a user in a repository, e.g.
+// "read", "write" or "admin".
+func (s *Server) SetPermission(owner, name, login, permission string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repo(owner, name).Permissions[login] = permission
+}
+
+// AddComment adds a comment to an issue and returns it with a new ID.
+func (s *Server) AddComment(owner, name string, number int, comment *github.IssueComment) *github.IssueComment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.repo(owner, name)
+	comment.ID = github.Int64(s.id())
+	r.Comments[number] = append(r.Comments[number], comment)
+	return comment
+}
+
+// Reactions returns the reaction contents added to an issue comment.
+func (s *Server) Reactions(owner, name string, commentID int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.repo(owner, name).Reactions[commentID]...)
+}
+
+// AddInstallation registers an App installation with access to the given
+// repositories, named as "owner/repo".
+func (s *Server) AddInstallation(id int64, repos ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.installations[id] = repos
+}
+
+// AddProject creates a classic project with columns using the given names and
+// returns it.
+func (s *Server) AddProject(columns ...string) *Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := &Project{ID: s.id(), Cards: map[int64][]*github.ProjectCard{}}
+	for _, name := range columns {
+		p.Columns = append(p.Columns, &github.ProjectColumn{
+			ID:   github.Int64(s.id()),
+			Name: github.String(name),
+		})
+	}
+	s.projects[p.ID] = p
+	return p
+}
+
+// Issue returns a copy of the issue, or nil if it does not exist.
+func (s *Server) Issue(owner, name string, number int) *github.Issue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	issue, ok := s.repo(owner, name).Issues[number]
+	if !ok {
+		return nil
+	}
+	c := *issue
+	c.Labels = append([]github.Label(nil), issue.Labels...)
+	return &c
+}
+
+// IssueLabels returns the sorted label names of an issue.
+func (s *Server) IssueLabels(owner, name string, number int) []string {
+	issue := s.Issue(owner, name, number)
+	if issue == nil {
+		return nil
+	}
+	return labelNames(issue.Labels)
+}
+
+// Comments returns the comment bodies of an issue.
+func (s *Server) Comments(owner, name string, number int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bodies := []string{}
+	for _, c := range s.repo(owner, name).Comments[number] {
+		bodies = append(bodies, c.GetBody())
+	}
+	return bodies
+}
+
+// Labels returns copies of all labels defined in the repository, sorted by
+// name.
+func (s *Server) Labels(owner, name string) []github.Label {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.repo(owner, name).labels()
+}
+
+// Cards returns copies of the cards in the given project column.
+func (s *Server) Cards(columnID int64) []github.ProjectCard {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cards := []github.ProjectCard{}
+	for _, p := range s.projects {
+		for _, c := range p.Cards[columnID] {
+			cards = append(cards, *c)
+		}
+	}
+	return cards
+}
+
+// Requests returns the "METHOD path" of every request received, in order.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// NewWebhookRequest creates a POST request to url for the given webhook event
+// type and payload, signed with secret as GitHub does.
+func NewWebhookRequest(url, secret, event string, payload interface{}) (*http.Request, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(b)
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", fmt.Sprintf("fake-%d", time.Now().UnixNano()))
+	req.Header.Set("X-Hub-Signature", "sha1="+hex.EncodeToString(mac.Sum(nil)))
+	return req, nil
+}
+
+// repo returns the named repo, creating it if necessary. Caller must hold mu.
+func (s *Server) repo(owner, name string) *Repo {
+	key := owner + "/" + name
+	r, ok := s.repos[key]
+	if !ok {
+		r = &Repo{
+			Owner:    owner,
+			Name:     name,
+			Issues:   map[int]*github.Issue{},
+			Labels:   map[string]*github.Label{},
+			Comments: map[int][]*github.IssueComment{},
+			Events:   map[int][]*github.IssueEvent{},
+			Files:    map[string]string{},
+
+			Permissions: map[string]string{},
+			Reactions:   map[int64][]string{},
+		}
+		s.repos[key] = r
+	}
+	return r
+}
+
+// id allocates a new unique ID. Caller must hold mu.
+func (s *Server) id() int64 {
+	s.nextID++
+	return s.nextID
+}
+
+// label returns the named label, creating it if necessary.
+func (r *Repo) label(name string) *github.Label {
+	for k, l := range r.Labels {
+		if strings.EqualFold(k, name) {
+			return l
+		}
+	}
+	l := &github.Label{
+		Name:  github.String(name),
+		Color: github.String(defaultLabelColor),
+	}
+	r.Labels[name] = l
+	return l
+}
+
+func (r *Repo) labels() []github.Label {
+	labels := []github.Label{}
+	for _, l := range r.Labels {
+		labels = append(labels, *l)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].GetName() < labels[j].GetName()
+	})
+	return labels
+}
+
+func labelNames(labels []github.Label) []string {
+	names := []string{}
+	for _, l := range labels {
+		names = append(names, l.GetName())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for i := range parts {
+		parts[i], _ = url.PathUnescape(parts[i])
+	}
+	switch {
+	case len(parts) >= 3 && parts[0] == "repos":
+		s.serveRepo(w, r, s.repo(parts[1], parts[2]), parts[3:])
+	case len(parts) >= 1 && parts[0] == "projects":
+		s.serveProjects(w, r, parts[1:])
+	case len(parts) >= 1 && (parts[0] == "app" || parts[0] == "installations" || parts[0] == "installation"):
+		s.serveApps(w, r, parts)
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) serveRepo(w http.ResponseWriter, r *http.Request, repo *Repo, parts []string) {
+	switch {
+	case len(parts) == 1 && parts[0] == "installation" && r.Method == http.MethodGet:
+		for id, repos := range s.installations {
+			for _, name := range repos {
+				if name == repo.Owner+"/"+repo.Name {
+					writeJSON(w, http.StatusOK, &github.Installation{ID: github.Int64(id)})
+					return
+				}
+			}
+		}
+		notFound(w)
+	case len(parts) >= 1 && parts[0] == "contents":
+		content, ok := repo.Files[strings.Join(parts[1:], "/")]
+		if !ok || r.Method != http.MethodGet {
+			notFound(w)
+			return
+		}
+		sum := sha1.Sum([]byte(content))
+		etag := `"` + hex.EncodeToString(sum[:]) + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if strings.HasSuffix(r.Header.Get("Accept"), ".raw") {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, content)
+			return
+		}
+		writeJSON(w, http.StatusOK, &github.RepositoryContent{
+			Type:     github.String("file"),
+			Encoding: github.String(""),
+			Path:     github.String(strings.Join(parts[1:], "/")),
+			Content:  github.String(content),
+		})
+	case len(parts) >= 1 && parts[0] == "labels":
+		s.serveRepoLabels(w, r, repo, parts[1:])
+	case len(parts) == 3 && parts[0] == "collaborators" && parts[2] == "permission" && r.Method == http.MethodGet:
+		permission, ok := repo.Permissions[parts[1]]
+		if !ok {
+			permission = "read"
+		}
+		writeJSON(w, http.StatusOK, &github.RepositoryPermissionLevel{
+			Permission: github.String(permission),
+			User:       &github.User{Login: github.String(parts[1])},
+		})
+	case len(parts) == 1 && parts[0] == "issues":
+		s.serveIssueList(w, r, repo)
+	case len(parts) == 4 && parts[0] == "issues" && parts[1] == "comments" && parts[3] == "reactions" &&
+		r.Method == http.MethodPost:
+		id, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			notFound(w)
+			return
+		}
+		var reaction github.Reaction
+		if !readJSON(w, r, &reaction) {
+			return
+		}
+		reaction.ID = github.Int64(s.id())
+		repo.Reactions[id] = append(repo.Reactions[id], reaction.GetContent())
+		writeJSON(w, http.StatusCreated, &reaction)
+	case len(parts) >= 2 && parts[0] == "issues":
+		number, err := strconv.Atoi(parts[1])
+		if err != nil {
+			notFound(w)
+			return
+		}
+		issue, ok := repo.Issues[number]
+		if !ok {
+			notFound(w)
+			return
+		}
+		s.serveIssue(w, r, repo, issue, parts[2:])
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) serveIssueList(w http.ResponseWriter, r *http.Request, repo *Repo) {
+	switch r.Method {
+	case http.MethodGet:
+		state := r.URL.Query().Get("state")
+		if state == "" {
+			state = "open"
+		}
+		filter := []string{}
+		if v := r.URL.Query().Get("labels"); v != "" {
+			filter = strings.Split(v, ",")
+		}
+		numbers := []int{}
+		for n := range repo.Issues {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		result := []*github.Issue{}
+		for _, n := range numbers {
+			issue := repo.Issues[n]
+			if state != "all" && issue.GetState() != state {
+				continue
+			}
+			if !hasAll(labelNames(issue.Labels), filter) {
+				continue
+			}
+			result = append(result, issue)
+		}
+		writeJSON(w, http.StatusOK, result)
+	case http.MethodPost:
+		var req github.IssueRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		issue := &github.Issue{
+			ID:     github.Int64(s.id()),
+			Number: github.Int(len(repo.Issues) + 1),
+			Title:  req.Title,
+			Body:   req.Body,
+			State:  github.String("open"),
+		}
+		applyIssueRequest(repo, issue, &req)
+		repo.Issues[issue.GetNumber()] = issue
+		writeJSON(w, http.StatusCreated, issue)
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) serveIssue(w http.ResponseWriter, r *http.Request, repo *Repo, issue *github.Issue, parts []string) {
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, issue)
+	case len(parts) == 0 && r.Method == http.MethodPatch:
+		var req github.IssueRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		applyIssueRequest(repo, issue, &req)
+		issue.UpdatedAt = newTime(time.Now())
+		writeJSON(w, http.StatusOK, issue)
+	case len(parts) == 1 && parts[0] == "labels":
+		s.serveIssueLabels(w, r, repo, issue)
+	case len(parts) == 2 && parts[0] == "labels" && r.Method == http.MethodDelete:
+		labels := []github.Label{}
+		found := false
+		for _, l := range issue.Labels {
+			if strings.EqualFold(l.GetName(), parts[1]) {
+				found = true
+				continue
+			}
+			labels = append(labels, l)
+		}
+		if !found {
+			notFound(w)
+			return
+		}
+		issue.Labels = labels
+		writeJSON(w, http.StatusOK, issue.Labels)
+	case len(parts) == 1 && parts[0] == "assignees" && r.Method == http.MethodPost:
+		var req struct {
+			Assignees []string `json:"assignees"`
+		}
+		if !readJSON(w, r, &req) {
+			return
+		}
+		for _, login := range req.Assignees {
+			found := false
+			for _, a := range issue.Assignees {
+				found = found || strings.EqualFold(a.GetLogin(), login)
+			}
+			if !found {
+				issue.Assignees = append(issue.Assignees, &github.User{Login: github.String(login)})
+			}
+		}
+		writeJSON(w, http.StatusCreated, issue)
+	case len(parts) == 1 && parts[0] == "events" && r.Method == http.MethodGet:
+		events := repo.Events[issue.GetNumber()]
+		if events == nil {
+			events = []*github.IssueEvent{}
+		}
+		writeJSON(w, http.StatusOK, events)
+	case len(parts) == 1 && parts[0] == "comments":
+		switch r.Method {
+		case http.MethodGet:
+			comments := repo.Comments[issue.GetNumber()]
+			if comments == nil {
+				comments = []*github.IssueComment{}
+			}
+			writeJSON(w, http.StatusOK, comments)
+		case http.MethodPost:
+			var c github.IssueComment
+			if !readJSON(w, r, &c) {
+				return
+			}
+			c.ID = github.Int64(s.id())
+			c.CreatedAt = newTime(time.Now())
+			repo.Comments[issue.GetNumber()] = append(repo.Comments[issue.GetNumber()], &c)
+			issue.Comments = github.Int(len(repo.Comments[issue.GetNumber()]))
+			writeJSON(w, http.StatusCreated, &c)
+		default:
+			notFound(w)
+		}
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) serveIssueLabels(w http.ResponseWriter, r *http.Request, repo *Repo, issue *github.Issue) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, issue.Labels)
+	case http.MethodPost, http.MethodPut:
+		var names []string
+		if !readJSON(w, r, &names) {
+			return
+		}
+		if r.Method == http.MethodPut {
+			issue.Labels = nil
+		}
+		current := labelNames(issue.Labels)
+		for _, name := range names {
+			if slice.ContainsFold(current, name) {
+				continue
+			}
+			issue.Labels = append(issue.Labels, *repo.label(name))
+			current = append(current, name)
+			repo.Events[issue.GetNumber()] = append(repo.Events[issue.GetNumber()], &github.IssueEvent{
+				ID:        github.Int64(s.id()),
+				Event:     github.String("labeled"),
+				Label:     repo.label(name),
+				CreatedAt: newTime(time.Now()),
+			})
+		}
+		writeJSON(w, http.StatusOK, issue.Labels)
+	case http.MethodDelete:
+		issue.Labels = nil
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) serveRepoLabels(w http.ResponseWriter, r *http.Request, repo *Repo, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, repo.labels())
+		case http.MethodPost:
+			var l github.Label
+			if !readJSON(w, r, &l) {
+				return
+			}
+			if _, ok := repo.Labels[l.GetName()]; ok {
+				writeJSON(w, http.StatusUnprocessableEntity, &github.ErrorResponse{Message: "Validation Failed"})
+				return
+			}
+			repo.Labels[l.GetName()] = &l
+			writeJSON(w, http.StatusCreated, &l)
+		default:
+			notFound(w)
+		}
+		return
+	}
+	var key string
+	for k := range repo.Labels {
+		if strings.EqualFold(k, parts[0]) {
+			key = k
+		}
+	}
+	if key == "" {
+		notFound(w)
+		return
+	}
+	l := repo.Labels[key]
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, l)
+	case http.MethodPatch:
+		var edit github.Label
+		if !readJSON(w, r, &edit) {
+			return
+		}
+		if edit.Color != nil {
+			l.Color = edit.Color
+		}
+		if edit.Description != nil {
+			l.Description = edit.Description
+		}
+		if edit.Name != nil && edit.GetName() != key {
+			// Renaming a label also renames it on every issue.
+			delete(repo.Labels, key)
+			l.Name = edit.Name
+			repo.Labels[edit.GetName()] = l
+			for _, issue := range repo.Issues {
+				for i := range issue.Labels {
+					if issue.Labels[i].GetName() == key {
+						issue.Labels[i] = *l
+					}
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, l)
+	case http.MethodDelete:
+		delete(repo.Labels, key)
+		for _, issue := range repo.Issues {
+			labels := []github.Label{}
+			for _, il := range issue.Labels {
+				if il.GetName() != key {
+					labels = append(labels, il)
+				}
+			}
+			issue.Labels = labels
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		notFound(w)
+	}
+}
+
+func (s *Server) serveProjects(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	// GET /projects/columns/{id}
+	case len(parts) == 2 && parts[0] == "columns" && r.Method == http.MethodGet:
+		col, _ := s.column(parts[1])
+		if col == nil {
+			notFound(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, col)
+	// GET, POST /projects/columns/{id}/cards
+	case len(parts) == 3 && parts[0] == "columns" && parts[2] == "cards":
+		col, p := s.column(parts[1])
+		if col == nil {
+			notFound(w)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			cards := p.Cards[col.GetID()]
+			if cards == nil {
+				cards = []*github.ProjectCard{}
+			}
+			writeJSON(w, http.StatusOK, cards)
+		case http.MethodPost:
+			var opt github.ProjectCardOptions
+			if !readJSON(w, r, &opt) {
+				return
+			}
+			card := &github.ProjectCard{
+				ID:        github.Int64(s.id()),
+				ColumnID:  col.ID,
+				ColumnURL: github.String(s.columnURL(col.GetID())),
+			}
+			if opt.Note != "" {
+				card.Note = github.String(opt.Note)
+			}
+			if opt.ContentID != 0 {
+				card.ContentURL = github.String(s.contentURL(opt.ContentID))
+			}
+			p.Cards[col.GetID()] = append(p.Cards[col.GetID()], card)
+			writeJSON(w, http.StatusCreated, card)
+		default:
+			notFound(w)
+		}
+	// GET /projects/columns/cards/{id}
+	case len(parts) == 3 && parts[0] == "columns" && parts[1] == "cards" && r.Method == http.MethodGet:
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		card := s.card(id)
+		if card == nil {
+			notFound(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, card)
+	// POST /projects/columns/cards/{id}/moves
+	case len(parts) == 4 && parts[0] == "columns" && parts[1] == "cards" && parts[3] == "moves":
+		var opt github.ProjectCardMoveOptions
+		if !readJSON(w, r, &opt) {
+			return
+		}
+		id, _ := strconv.ParseInt(parts[2], 10, 64)
+		if !s.moveCard(id, opt.ColumnID) {
+			notFound(w)
+			return
+		}
+		writeJSON(w, http.StatusCreated, struct{}{})
+	// GET /projects/{id}/columns
+	case len(parts) == 2 && parts[1] == "columns" && r.Method == http.MethodGet:
+		id, _ := strconv.ParseInt(parts[0], 10, 64)
+		p, ok := s.projects[id]
+		if !ok {
+			notFound(w)
+			return
+		}
+		writeJSON(w, http.StatusOK, p.Columns)
+	default:
+		notFound(w)
+	}
+}
+
+// column finds a column by ID string. Caller must hold mu.
+func (s *Server) column(idStr string) (*github.ProjectColumn, *Project) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	for _, p := range s.projects {
+		for _, c := range p.Columns {
+			if c.GetID() == id {
+				return c, p
+			}
+		}
+	}
+	return nil, nil
+}
+
+// moveCard moves a card to a new column in the same project. Caller must hold
+// mu.
+func (s *Server) moveCard(id, columnID int64) bool {
+	for _, p := range s.projects {
+		for col, cards := range p.Cards {
+			for i, c := range cards {
+				if c.GetID() != id {
+					continue
+				}
+				p.Cards[col] = append(cards[:i:i], cards[i+1:]...)
+				c.ColumnID = github.Int64(columnID)
+				c.ColumnURL = github.String(s.columnURL(columnID))
+				p.Cards[columnID] = append(p.Cards[columnID], c)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// card returns the card with the given ID, or nil. Caller must hold mu.
+func (s *Server) card(id int64) *github.ProjectCard {
+	for _, p := range s.projects {
+		for _, cards := range p.Cards {
+			for _, c := range cards {
+				if c.GetID() == id {
+					return c
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// columnURL returns the API URL of the column with the given ID.
+func (s *Server) columnURL(id int64) string {
+	return fmt.Sprintf("%s/projects/columns/%d", s.URL, id)
+}
+
+// contentURL returns the API URL of the issue with the given ID. Caller must
+// hold mu.
+func (s *Server) contentURL(id int64) string {
+	for _, repo := range s.repos {
+		for _, issue := range repo.Issues {
+			if issue.GetID() == id {
+				return fmt.Sprintf("%s/repos/%s/%s/issues/%d", s.URL, repo.Owner, repo.Name, issue.GetNumber())
+			}
+		}
+	}
+	return ""
+}
+
+func (s *Server) serveApps(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	// GET /app/installations
+	case len(parts) == 2 && parts[0] == "app" && parts[1] == "installations" && r.Method == http.MethodGet:
+		ids := []int64{}
+		for id := range s.installations {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		installs := []*github.Installation{}
+		for _, id := range ids {
+			installs = append(installs, &github.Installation{ID: github.Int64(id)})
+		}
+		writeJSON(w, http.StatusOK, installs)
+	// POST /app/installations/{id}/access_tokens, or the older
+	// POST /installations/{id}/access_tokens
+	case len(parts) >= 3 && parts[len(parts)-1] == "access_tokens" && r.Method == http.MethodPost:
+		id, _ := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+		if _, ok := s.installations[id]; !ok {
+			notFound(w)
+			return
+		}
+		writeJSON(w, http.StatusCreated, &github.InstallationToken{
+			Token:     github.String(fmt.Sprintf("fake-token-%d", id)),
+			ExpiresAt: newTime(time.Now().Add(time.Hour)),
+		})
+	// GET /installation/repositories
+	case len(parts) == 2 && parts[0] == "installation" && parts[1] == "repositories":
+		repos := []*github.Repository{}
+		auth := r.Header.Get("Authorization")
+		for id, names := range s.installations {
+			if auth != fmt.Sprintf("token fake-token-%d", id) {
+				continue
+			}
+			for _, name := range names {
+				f := strings.SplitN(name, "/", 2)
+				repos = append(repos, &github.Repository{
+					Owner:    &github.User{Login: github.String(f[0])},
+					Name:     github.String(f[1]),
+					FullName: github.String(name),
+				})
+			}
+		}
+		writeJSON(w, http.StatusOK, struct {
+			TotalCount   int                  `json:"total_count"`
+			Repositories []*github.Repository `json:"repositories"`
+		}{len(repos), repos})
+	default:
+		notFound(w)
+	}
+}
+
+func applyIssueRequest(repo *Repo, issue *github.Issue, req *github.IssueRequest) {
+	if req.Title != nil {
+		issue.Title = req.Title
+	}
+	if req.Body != nil {
+		issue.Body = req.Body
+	}
+	if req.State != nil {
+		issue.State = req.State
+		if req.GetState() == "closed" {
+			issue.ClosedAt = newTime(time.Now())
+		} else {
+			issue.ClosedAt = nil
+		}
+	}
+	if req.Labels != nil {
+		issue.Labels = nil
+		for _, name := range *req.Labels {
+			issue.Labels = append(issue.Labels, *repo.label(name))
+		}
+	}
+	if req.Assignees != nil {
+		issue.Assignees = nil
+		for _, login := range *req.Assignees {
+			issue.Assignees = append(issue.Assignees, &github.User{Login: github.String(login)})
+		}
+	}
+}
+
+func hasAll(have, want []string) bool {
+	for _, w := range want {
+		if !slice.ContainsFold(have, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, &github.ErrorResponse{Message: err.Error()})
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTime(t time.Time) *time.Time {
+	return &t
+}
+
+func notFound(w http.ResponseWriter) {
+	writeJSON(w, http.StatusNotFound, &github.ErrorResponse{Message: "Not Found"})
+}
@@ -0,0 +1,130 @@
+package fakegithub
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+)
+
+func newLabel(name string) github.Label {
+	return github.Label{Name: &name}
+}
+
+func TestServer_IssueLabels(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddIssue("o", "r", &github.Issue{
+		Number: github.Int(1),
+		Labels: []github.Label{newLabel("bug")},
+	})
+	client := s.Client()
+	ctx := context.Background()
+
+	if _, _, err := client.Issues.AddLabelsToIssue(ctx, "o", "r", 1, []string{"review/triage", "bug"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.IssueLabels("o", "r", 1), []string{"bug", "review/triage"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels() = %v, want %v", got, want)
+	}
+	if _, err := client.Issues.RemoveLabelForIssue(ctx, "o", "r", 1, "bug"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Issues.RemoveLabelForIssue(ctx, "o", "r", 1, "bug")
+	if err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("RemoveLabelForIssue() missing label = %v, want 404", err)
+	}
+	if got, want := s.IssueLabels("o", "r", 1), []string{"review/triage"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels() = %v, want %v", got, want)
+	}
+
+	closed := "closed"
+	issue, _, err := client.Issues.Edit(ctx, "o", "r", 1, &github.IssueRequest{State: &closed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.GetState() != "closed" || s.Issue("o", "r", 1).GetState() != "closed" {
+		t.Errorf("Edit() state = %q, want closed", issue.GetState())
+	}
+	if len(s.Labels("o", "r")) != 2 {
+		t.Errorf("Labels() = %v, want 2 labels", s.Labels("o", "r"))
+	}
+}
+
+func TestServer_Comments(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddIssue("o", "r", &github.Issue{Number: github.Int(2)})
+	client := s.Client()
+	ctx := context.Background()
+
+	body := "hello"
+	if _, _, err := client.Issues.CreateComment(ctx, "o", "r", 2, &github.IssueComment{Body: &body}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Comments("o", "r", 2); !reflect.DeepEqual(got, []string{"hello"}) {
+		t.Errorf("Comments() = %v, want [hello]", got)
+	}
+}
+
+func TestServer_Projects(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	p := s.AddProject("Backlog", "Current")
+	client := s.Client()
+	ctx := context.Background()
+
+	cols, _, err := client.Projects.ListProjectColumns(ctx, p.ID, nil)
+	if err != nil || len(cols) != 2 {
+		t.Fatalf("ListProjectColumns() = %v, %v", cols, err)
+	}
+	card, _, err := client.Projects.CreateProjectCard(ctx, cols[0].GetID(), &github.ProjectCardOptions{Note: "n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Projects.MoveProjectCard(ctx, card.GetID(), &github.ProjectCardMoveOptions{
+		Position: "top", ColumnID: cols[1].GetID(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Cards(cols[0].GetID())) != 0 || len(s.Cards(cols[1].GetID())) != 1 {
+		t.Errorf("MoveProjectCard() did not move card")
+	}
+	got, _, err := client.Projects.GetProjectCard(ctx, card.GetID())
+	if err != nil || !strings.HasSuffix(got.GetColumnURL(), fmt.Sprintf("/%d", cols[1].GetID())) {
+		t.Errorf("GetProjectCard() = %v, %v; want column %d", got, err, cols[1].GetID())
+	}
+}
+
+func TestServer_InstallationClient(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddInstallation(7, "o/r")
+
+	os.Setenv("GITHUB_API_URL", s.URL)
+	os.Setenv("GITHUB_PRIVATE_KEY", "../testdata/unused_insecure_rsa_key.pem")
+	os.Setenv("GITHUB_APP_ID", "1")
+	defer os.Unsetenv("GITHUB_API_URL")
+	ctx := context.Background()
+
+	apps := githubx.NewJWTClientFromEnv()
+	install, err := githubx.FindInstallation(ctx, apps, "o", "r")
+	if err != nil || install.GetID() != 7 {
+		t.Fatalf("FindInstallation() = %v, %v; want 7", install, err)
+	}
+	client := githubx.NewClient(install.GetID())
+	repos, err := githubx.ListInstallationRepos(ctx, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repos) != 1 || repos[0].GetFullName() != "o/r" {
+		t.Errorf("ListInstallationRepos() = %v, want [o/r]", repos)
+	}
+}
@@ -0,0 +1,101 @@
+package githubx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/go-github/github"
+)
+
+var (
+	// ErrNoInstallation is returned when the Github App is not installed on the
+	// requested repository.
+	ErrNoInstallation = fmt.Errorf("Github App is not installed on repository")
+)
+
+// newClient creates installation clients. Tests may replace it.
+var newClient = NewClient
+
+// ListInstallations returns all installations of the Github App. The given
+// client must be authenticated as the App, e.g. using NewJWTClient.
+func ListInstallations(ctx context.Context, client *github.Client) ([]*github.Installation, error) {
+	all := []*github.Installation{}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		installs, resp, err := client.Apps.ListInstallations(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, installs...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// FindInstallation returns the installation of the Github App for the given
+// owner and repo. The given client must be authenticated as the App. If the App
+// is not installed on the repo, FindInstallation returns ErrNoInstallation.
+func FindInstallation(ctx context.Context, client *github.Client, owner, repo string) (*github.Installation, error) {
+	install, resp, err := client.Apps.FindRepositoryInstallation(ctx, owner, repo)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNoInstallation
+	}
+	if err != nil {
+		return nil, err
+	}
+	return install, nil
+}
+
+// ListInstallationRepos returns all repositories accessible to an installation.
+// The given client must be authenticated as the installation, e.g. using
+// NewAppClient.
+func ListInstallationRepos(ctx context.Context, client *github.Client) ([]*github.Repository, error) {
+	all := []*github.Repository{}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		repos, resp, err := client.Apps.ListRepos(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, repos...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// ForEachInstallation calls fn with an installation client for every
+// installation of the Github App. The given client must be authenticated as
+// the App. Errors creating a client or from fn are logged, and
+// ForEachInstallation continues with the next installation and returns the
+// last error.
+func ForEachInstallation(
+	ctx context.Context, client *github.Client,
+	fn func(ctx context.Context, client *github.Client, install *github.Installation) error) error {
+	installs, err := ListInstallations(ctx, client)
+	if err != nil {
+		return err
+	}
+	var lastErr error
+	for _, install := range installs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		ic := newClient(install.GetID())
+		if ic == nil {
+			lastErr = fmt.Errorf("failed to create client for installation %d", install.GetID())
+			log.Println(lastErr)
+			continue
+		}
+		if err := fn(ctx, ic, install); err != nil {
+			log.Printf("installation %d (%s): %v", install.GetID(), install.GetAccount().GetLogin(), err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
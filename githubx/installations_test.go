@@ -0,0 +1,167 @@
+package githubx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+// newTestClient returns a *github.Client that sends all requests to a test
+// server using the given handler.
+func newTestClient(t *testing.T, mux *http.ServeMux) (*github.Client, func()) {
+	srv := httptest.NewServer(mux)
+	client := github.NewClient(nil)
+	u, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = u
+	return client, srv.Close
+}
+
+func TestListInstallations(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app/installations", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", `<http://`+r.Host+`/app/installations?page=2>; rel="next"`)
+			fmt.Fprint(w, `[{"id":1}]`)
+			return
+		}
+		fmt.Fprint(w, `[{"id":2}]`)
+	})
+	client, done := newTestClient(t, mux)
+	defer done()
+
+	got, err := ListInstallations(context.Background(), client)
+	if err != nil {
+		t.Fatalf("ListInstallations() error = %v", err)
+	}
+	if len(got) != 2 || got[0].GetID() != 1 || got[1].GetID() != 2 {
+		t.Errorf("ListInstallations() = %v, want ids [1 2]", got)
+	}
+}
+
+func TestFindInstallation(t *testing.T) {
+	tests := []struct {
+		name    string
+		repo    string
+		wantID  int64
+		wantErr error
+	}{
+		{
+			name:   "success",
+			repo:   "installed",
+			wantID: 3,
+		},
+		{
+			name:    "not-installed",
+			repo:    "missing",
+			wantErr: ErrNoInstallation,
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/owner/installed/installation", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":3}`)
+	})
+	mux.HandleFunc("/repos/owner/missing/installation", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+	})
+	client, done := newTestClient(t, mux)
+	defer done()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindInstallation(context.Background(), client, "owner", tt.repo)
+			if err != tt.wantErr {
+				t.Fatalf("FindInstallation() error = %v, want %v", err, tt.wantErr)
+			}
+			if got.GetID() != tt.wantID {
+				t.Errorf("FindInstallation() = %d, want %d", got.GetID(), tt.wantID)
+			}
+		})
+	}
+}
+
+func TestListInstallationRepos(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/installation/repositories", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"total_count":1,"repositories":[{"name":"repo"}]}`)
+	})
+	client, done := newTestClient(t, mux)
+	defer done()
+
+	got, err := ListInstallationRepos(context.Background(), client)
+	if err != nil {
+		t.Fatalf("ListInstallationRepos() error = %v", err)
+	}
+	if len(got) != 1 || got[0].GetName() != "repo" {
+		t.Errorf("ListInstallationRepos() = %v, want [repo]", got)
+	}
+}
+
+func TestForEachInstallation(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app/installations", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":1},{"id":2}]`)
+	})
+	client, done := newTestClient(t, mux)
+	defer done()
+	os.Setenv("GITHUB_PRIVATE_KEY", "testdata/unused_insecure_rsa_key.pem")
+	os.Setenv("GITHUB_APP_ID", "1")
+	defer os.Unsetenv("GITHUB_PRIVATE_KEY")
+	defer os.Unsetenv("GITHUB_APP_ID")
+
+	got := []int64{}
+	err := ForEachInstallation(context.Background(), client,
+		func(ctx context.Context, ic *github.Client, install *github.Installation) error {
+			got = append(got, install.GetID())
+			if ic == nil {
+				t.Errorf("ForEachInstallation() client = nil")
+			}
+			if install.GetID() == 1 {
+				return fmt.Errorf("fake error")
+			}
+			return nil
+		})
+	if err == nil {
+		t.Errorf("ForEachInstallation() error = nil, want error")
+	}
+	if len(got) != 2 {
+		t.Errorf("ForEachInstallation() visited %v, want [1 2]", got)
+	}
+}
+
+func TestForEachInstallation_NoClient(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/app/installations", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"id":1},{"id":2}]`)
+	})
+	client, done := newTestClient(t, mux)
+	defer done()
+	defer func(f func(int64) *github.Client) { newClient = f }(newClient)
+	newClient = func(id int64) *github.Client {
+		if id == 1 {
+			return nil
+		}
+		return client
+	}
+
+	got := []int64{}
+	err := ForEachInstallation(context.Background(), client,
+		func(ctx context.Context, ic *github.Client, install *github.Installation) error {
+			got = append(got, install.GetID())
+			return nil
+		})
+	if err == nil {
+		t.Errorf("ForEachInstallation() error = nil, want error")
+	}
+	if len(got) != 1 || got[0] != 2 {
+		t.Errorf("ForEachInstallation() visited %v, want [2]", got)
+	}
+}
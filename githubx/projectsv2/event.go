@@ -0,0 +1,121 @@
+package projectsv2
+
+import (
+	"github.com/google/go-github/github"
+)
+
+// ProjectV2ItemEvent is triggered when an item of a Projects v2 board is
+// created, edited, archived, restored, converted, reordered or deleted. The
+// webhook event name is "projects_v2_item". Only organization projects send
+// webhooks.
+//
+// The go-github package has no type for this event.
+type ProjectV2ItemEvent struct {
+	Action       *string              `json:"action,omitempty"`
+	Item         *ProjectV2Item       `json:"projects_v2_item,omitempty"`
+	Changes      *ProjectV2Changes    `json:"changes,omitempty"`
+	Org          *github.Organization `json:"organization,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// ProjectV2Item is the item of a ProjectV2ItemEvent.
+type ProjectV2Item struct {
+	ID            *int64            `json:"id,omitempty"`
+	NodeID        *string           `json:"node_id,omitempty"`
+	ProjectNodeID *string           `json:"project_node_id,omitempty"`
+	ContentNodeID *string           `json:"content_node_id,omitempty"`
+	ContentType   *string           `json:"content_type,omitempty"`
+	Creator       *github.User      `json:"creator,omitempty"`
+	CreatedAt     *github.Timestamp `json:"created_at,omitempty"`
+	UpdatedAt     *github.Timestamp `json:"updated_at,omitempty"`
+	ArchivedAt    *github.Timestamp `json:"archived_at,omitempty"`
+}
+
+// ProjectV2Changes describes the change of an "edited" ProjectV2ItemEvent.
+type ProjectV2Changes struct {
+	FieldValue *ProjectV2FieldChange `json:"field_value,omitempty"`
+}
+
+// ProjectV2FieldChange identifies the field changed by an "edited"
+// ProjectV2ItemEvent.
+type ProjectV2FieldChange struct {
+	FieldNodeID *string `json:"field_node_id,omitempty"`
+	FieldType   *string `json:"field_type,omitempty"`
+}
+
+// GetAction returns the Action field if it's non-nil, zero value otherwise.
+func (e *ProjectV2ItemEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+	return *e.Action
+}
+
+// GetItem returns the Item field.
+func (e *ProjectV2ItemEvent) GetItem() *ProjectV2Item {
+	if e == nil {
+		return nil
+	}
+	return e.Item
+}
+
+// GetInstallation returns the Installation field.
+func (e *ProjectV2ItemEvent) GetInstallation() *github.Installation {
+	if e == nil {
+		return nil
+	}
+	return e.Installation
+}
+
+// GetSender returns the Sender field.
+func (e *ProjectV2ItemEvent) GetSender() *github.User {
+	if e == nil {
+		return nil
+	}
+	return e.Sender
+}
+
+// GetChangedFieldID returns the node ID of the field changed by an "edited"
+// event, or "" if no field value changed.
+func (e *ProjectV2ItemEvent) GetChangedFieldID() string {
+	if e == nil || e.Changes == nil || e.Changes.FieldValue == nil || e.Changes.FieldValue.FieldNodeID == nil {
+		return ""
+	}
+	return *e.Changes.FieldValue.FieldNodeID
+}
+
+// GetNodeID returns the NodeID field if it's non-nil, zero value otherwise.
+func (i *ProjectV2Item) GetNodeID() string {
+	if i == nil || i.NodeID == nil {
+		return ""
+	}
+	return *i.NodeID
+}
+
+// GetProjectNodeID returns the ProjectNodeID field if it's non-nil, zero value
+// otherwise.
+func (i *ProjectV2Item) GetProjectNodeID() string {
+	if i == nil || i.ProjectNodeID == nil {
+		return ""
+	}
+	return *i.ProjectNodeID
+}
+
+// GetContentNodeID returns the ContentNodeID field if it's non-nil, zero value
+// otherwise.
+func (i *ProjectV2Item) GetContentNodeID() string {
+	if i == nil || i.ContentNodeID == nil {
+		return ""
+	}
+	return *i.ContentNodeID
+}
+
+// GetContentType returns the ContentType field if it's non-nil, zero value
+// otherwise.
+func (i *ProjectV2Item) GetContentType() string {
+	if i == nil || i.ContentType == nil {
+		return ""
+	}
+	return *i.ContentType
+}
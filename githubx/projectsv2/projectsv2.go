@@ -0,0 +1,482 @@
+// Package projectsv2 reads and updates GitHub Projects (v2) using the GraphQL
+// API, which is the only API for the new project boards.
+//
+// A Client sends GraphQL requests through an authenticated *github.Client, so
+// it shares the authentication, the base URL and the dry-run mode of clients
+// created by the githubx package.
+//
+// Projects v2 belong to an organization or a user, and hold items for issues,
+// pull requests and draft issues. Columns of the board view are the options of
+// a single-select field, usually "Status". Iteration fields, e.g. "Iteration",
+// assign items to sprints.
+package projectsv2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+// Field data types of single-select and iteration fields.
+const (
+	SingleSelect = "SINGLE_SELECT"
+	Iteration    = "ITERATION"
+)
+
+// CurrentIteration is a field value that selects the iteration containing the
+// current date.
+const CurrentIteration = "@current"
+
+// Error is an error returned in a GraphQL response.
+type Error struct {
+	Type    string        `json:"type,omitempty"`
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Errors are the errors of a GraphQL response. GitHub returns them with an
+// HTTP "200 OK" status.
+type Errors []Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Message
+	}
+	return "graphql: " + strings.Join(msgs, "; ")
+}
+
+// Option is an option of a single-select field.
+type Option struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// IterationValue is an iteration of an iteration field.
+type IterationValue struct {
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	StartDate string `json:"startDate"`
+	// Duration is the length of the iteration in days.
+	Duration int `json:"duration"`
+}
+
+// Contains reports whether t is in the iteration, using the date of t in its
+// location.
+func (it *IterationValue) Contains(t time.Time) bool {
+	start, err := time.ParseInLocation("2006-01-02", it.StartDate, t.Location())
+	if err != nil {
+		return false
+	}
+	return !t.Before(start) && t.Before(start.AddDate(0, 0, it.Duration))
+}
+
+// Field is a project field. Options are set for single-select fields and
+// Iterations for iteration fields.
+type Field struct {
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	DataType   string           `json:"dataType"`
+	Options    []Option         `json:"options,omitempty"`
+	Iterations []IterationValue `json:"-"`
+}
+
+// Option returns the option with the given name, ignoring case, or nil.
+func (f *Field) Option(name string) *Option {
+	for i := range f.Options {
+		if strings.EqualFold(f.Options[i].Name, name) {
+			return &f.Options[i]
+		}
+	}
+	return nil
+}
+
+// Iteration returns the iteration with the given title, ignoring case, or the
+// iteration containing now if title is CurrentIteration. Iteration returns nil
+// if there is no such iteration.
+func (f *Field) Iteration(title string, now time.Time) *IterationValue {
+	for i := range f.Iterations {
+		it := &f.Iterations[i]
+		if title == CurrentIteration && it.Contains(now) || strings.EqualFold(it.Title, title) {
+			return it
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a field node, flattening the iteration configuration.
+func (f *Field) UnmarshalJSON(b []byte) error {
+	type field Field
+	var v struct {
+		field
+		Configuration *struct {
+			Iterations []IterationValue `json:"iterations"`
+		} `json:"configuration"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*f = Field(v.field)
+	if v.Configuration != nil {
+		f.Iterations = v.Configuration.Iterations
+	}
+	return nil
+}
+
+// Project is a Projects v2 board.
+type Project struct {
+	ID     string  `json:"id"`
+	Number int     `json:"number"`
+	Title  string  `json:"title"`
+	Fields []Field `json:"-"`
+}
+
+// Field returns the field with the given name, ignoring case, or nil.
+func (p *Project) Field(name string) *Field {
+	for i := range p.Fields {
+		if strings.EqualFold(p.Fields[i].Name, name) {
+			return &p.Fields[i]
+		}
+	}
+	return nil
+}
+
+// FieldByID returns the field with the given node ID, or nil.
+func (p *Project) FieldByID(id string) *Field {
+	for i := range p.Fields {
+		if p.Fields[i].ID == id {
+			return &p.Fields[i]
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes a project node, flattening the field connection.
+func (p *Project) UnmarshalJSON(b []byte) error {
+	type project Project
+	var v struct {
+		project
+		Fields struct {
+			Nodes []Field `json:"nodes"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*p = Project(v.project)
+	for _, f := range v.Fields.Nodes {
+		// Fields of other types decode without an ID.
+		if f.ID != "" {
+			p.Fields = append(p.Fields, f)
+		}
+	}
+	return nil
+}
+
+// FieldValue is the value of a single-select or iteration field of an item.
+type FieldValue struct {
+	FieldID   string
+	FieldName string
+	// OptionID is set for single-select fields.
+	OptionID string
+	// IterationID is set for iteration fields.
+	IterationID string
+	// Name is the option name or the iteration title.
+	Name string
+}
+
+// Item is an item of a project.
+type Item struct {
+	ID string
+	// Type is "ISSUE", "PULL_REQUEST", "DRAFT_ISSUE" or "REDACTED".
+	Type       string
+	Archived   bool
+	ProjectID  string
+	ContentID  string
+	Number     int
+	Repository string
+	Values     []FieldValue
+}
+
+// Value returns the value of the named field, ignoring case, or nil if the
+// field is empty.
+func (it *Item) Value(field string) *FieldValue {
+	for i := range it.Values {
+		if strings.EqualFold(it.Values[i].FieldName, field) {
+			return &it.Values[i]
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes an item node.
+func (it *Item) UnmarshalJSON(b []byte) error {
+	type ref struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	var v struct {
+		ID         string `json:"id"`
+		Type       string `json:"type"`
+		IsArchived bool   `json:"isArchived"`
+		Project    ref    `json:"project"`
+		Content    *struct {
+			ID         string `json:"id"`
+			Number     int    `json:"number"`
+			Repository struct {
+				NameWithOwner string `json:"nameWithOwner"`
+			} `json:"repository"`
+		} `json:"content"`
+		FieldValues struct {
+			Nodes []struct {
+				OptionID    string `json:"optionId"`
+				IterationID string `json:"iterationId"`
+				Name        string `json:"name"`
+				Title       string `json:"title"`
+				Field       ref    `json:"field"`
+			} `json:"nodes"`
+		} `json:"fieldValues"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*it = Item{ID: v.ID, Type: v.Type, Archived: v.IsArchived, ProjectID: v.Project.ID}
+	if v.Content != nil {
+		it.ContentID = v.Content.ID
+		it.Number = v.Content.Number
+		it.Repository = v.Content.Repository.NameWithOwner
+	}
+	for _, n := range v.FieldValues.Nodes {
+		if n.OptionID == "" && n.IterationID == "" {
+			// Values of other field types.
+			continue
+		}
+		fv := FieldValue{
+			FieldID:     n.Field.ID,
+			FieldName:   n.Field.Name,
+			OptionID:    n.OptionID,
+			IterationID: n.IterationID,
+			Name:        n.Name,
+		}
+		if n.IterationID != "" {
+			fv.Name = n.Title
+		}
+		it.Values = append(it.Values, fv)
+	}
+	return nil
+}
+
+const projectFragment = `
+fragment project on ProjectV2 {
+  id number title
+  fields(first: 50) {
+    nodes {
+      ... on ProjectV2FieldCommon { id name dataType }
+      ... on ProjectV2SingleSelectField { options { id name } }
+      ... on ProjectV2IterationField { configuration { iterations { id title startDate duration } } }
+    }
+  }
+}`
+
+const itemFragment = `
+fragment item on ProjectV2Item {
+  id type isArchived
+  project { id }
+  content {
+    ... on Issue { id number repository { nameWithOwner } }
+    ... on PullRequest { id number repository { nameWithOwner } }
+  }
+  fieldValues(first: 50) {
+    nodes {
+      ... on ProjectV2ItemFieldSingleSelectValue { optionId name field { ... on ProjectV2FieldCommon { id name } } }
+      ... on ProjectV2ItemFieldIterationValue { iterationId title field { ... on ProjectV2FieldCommon { id name } } }
+    }
+  }
+}`
+
+// Client sends Projects v2 GraphQL requests.
+type Client struct {
+	client *github.Client
+}
+
+// NewClient creates a Client that sends requests using the given client.
+func NewClient(client *github.Client) *Client {
+	return &Client{client: client}
+}
+
+// url returns the GraphQL endpoint of the client base URL. GitHub Enterprise
+// serves the REST API at "/api/v3/" and GraphQL at "/api/graphql".
+func (c *Client) url() string {
+	base := c.client.BaseURL.String()
+	if strings.HasSuffix(base, "/api/v3/") {
+		return strings.TrimSuffix(base, "v3/") + "graphql"
+	}
+	return base + "graphql"
+}
+
+// Do sends a GraphQL query or mutation with the given variables, and decodes
+// the response data into data. GraphQL errors are returned as Errors.
+func (c *Client) Do(ctx context.Context, query string, variables map[string]interface{}, data interface{}) error {
+	req, err := c.client.NewRequest("POST", c.url(), map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors Errors          `json:"errors"`
+	}
+	if _, err := c.client.Do(ctx, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+	if data == nil || len(resp.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resp.Data, data)
+}
+
+// GetProject returns the project with the given number, owned by the named
+// organization or user. GetProject returns an error if there is no such
+// project.
+func (c *Client) GetProject(ctx context.Context, owner string, number int) (*Project, error) {
+	var data struct {
+		RepositoryOwner *struct {
+			ProjectV2 *Project `json:"projectV2"`
+		} `json:"repositoryOwner"`
+	}
+	err := c.Do(ctx, `query($owner: String!, $number: Int!) {
+  repositoryOwner(login: $owner) {
+    ... on ProjectV2Owner { projectV2(number: $number) { ...project } }
+  }
+}`+projectFragment, map[string]interface{}{"owner": owner, "number": number}, &data)
+	if err != nil {
+		return nil, err
+	}
+	if data.RepositoryOwner == nil || data.RepositoryOwner.ProjectV2 == nil {
+		return nil, fmt.Errorf("project %s/%d not found", owner, number)
+	}
+	return data.RepositoryOwner.ProjectV2, nil
+}
+
+// GetItem returns the project item with the given node ID.
+func (c *Client) GetItem(ctx context.Context, id string) (*Item, error) {
+	var data struct {
+		Node *Item `json:"node"`
+	}
+	err := c.Do(ctx, `query($id: ID!) {
+  node(id: $id) { ...item }
+}`+itemFragment, map[string]interface{}{"id": id}, &data)
+	if err != nil {
+		return nil, err
+	}
+	if data.Node == nil || data.Node.ID == "" {
+		return nil, fmt.Errorf("project item %s not found", id)
+	}
+	return data.Node, nil
+}
+
+// FindItem returns the item of the issue or pull request with the given node
+// ID in the project, or nil if it is not in the project.
+func (c *Client) FindItem(ctx context.Context, projectID, contentID string) (*Item, error) {
+	type items struct {
+		ProjectItems struct {
+			Nodes []*Item `json:"nodes"`
+		} `json:"projectItems"`
+	}
+	var data struct {
+		Node *items `json:"node"`
+	}
+	err := c.Do(ctx, `query($id: ID!) {
+  node(id: $id) {
+    ... on Issue { projectItems(first: 50) { nodes { ...item } } }
+    ... on PullRequest { projectItems(first: 50) { nodes { ...item } } }
+  }
+}`+itemFragment, map[string]interface{}{"id": contentID}, &data)
+	if err != nil || data.Node == nil {
+		return nil, err
+	}
+	for _, it := range data.Node.ProjectItems.Nodes {
+		if it.ProjectID == projectID {
+			return it, nil
+		}
+	}
+	return nil, nil
+}
+
+// AddItem adds the issue or pull request with the given node ID to the
+// project, and returns the item ID. Adding content that is already in the
+// project returns the existing item.
+func (c *Client) AddItem(ctx context.Context, projectID, contentID string) (string, error) {
+	var data struct {
+		AddProjectV2ItemByID struct {
+			Item struct {
+				ID string `json:"id"`
+			} `json:"item"`
+		} `json:"addProjectV2ItemById"`
+	}
+	err := c.Do(ctx, `mutation($project: ID!, $content: ID!) {
+  addProjectV2ItemById(input: {projectId: $project, contentId: $content}) { item { id } }
+}`, map[string]interface{}{"project": projectID, "content": contentID}, &data)
+	return data.AddProjectV2ItemByID.Item.ID, err
+}
+
+// SetOption sets a single-select field of the item to the given option.
+func (c *Client) SetOption(ctx context.Context, projectID, itemID, fieldID, optionID string) error {
+	return c.setValue(ctx, projectID, itemID, fieldID, map[string]interface{}{"singleSelectOptionId": optionID})
+}
+
+// SetIteration sets an iteration field of the item to the given iteration.
+func (c *Client) SetIteration(ctx context.Context, projectID, itemID, fieldID, iterationID string) error {
+	return c.setValue(ctx, projectID, itemID, fieldID, map[string]interface{}{"iterationId": iterationID})
+}
+
+func (c *Client) setValue(ctx context.Context, projectID, itemID, fieldID string, value map[string]interface{}) error {
+	return c.Do(ctx, `mutation($project: ID!, $item: ID!, $field: ID!, $value: ProjectV2FieldValue!) {
+  updateProjectV2ItemFieldValue(input: {projectId: $project, itemId: $item, fieldId: $field, value: $value}) { projectV2Item { id } }
+}`, map[string]interface{}{"project": projectID, "item": itemID, "field": fieldID, "value": value}, nil)
+}
+
+// ClearValue clears a field of the item.
+func (c *Client) ClearValue(ctx context.Context, projectID, itemID, fieldID string) error {
+	return c.Do(ctx, `mutation($project: ID!, $item: ID!, $field: ID!) {
+  clearProjectV2ItemFieldValue(input: {projectId: $project, itemId: $item, fieldId: $field}) { projectV2Item { id } }
+}`, map[string]interface{}{"project": projectID, "item": itemID, "field": fieldID}, nil)
+}
+
+// SetValue sets the named single-select or iteration field of the item to the
+// option name or iteration title in value. An empty value clears the field.
+// For iteration fields, CurrentIteration selects the iteration containing now.
+func (c *Client) SetValue(ctx context.Context, p *Project, itemID, field, value string, now time.Time) error {
+	f := p.Field(field)
+	if f == nil {
+		return fmt.Errorf("project %q has no field %q", p.Title, field)
+	}
+	if value == "" {
+		return c.ClearValue(ctx, p.ID, itemID, f.ID)
+	}
+	switch f.DataType {
+	case SingleSelect:
+		o := f.Option(value)
+		if o == nil {
+			return fmt.Errorf("field %q has no option %q", f.Name, value)
+		}
+		return c.SetOption(ctx, p.ID, itemID, f.ID, o.ID)
+	case Iteration:
+		it := f.Iteration(value, now)
+		if it == nil {
+			return fmt.Errorf("field %q has no iteration %q", f.Name, value)
+		}
+		return c.SetIteration(ctx, p.ID, itemID, f.ID, it.ID)
+	default:
+		return fmt.Errorf("field %q has unsupported type %s", f.Name, f.DataType)
+	}
+}
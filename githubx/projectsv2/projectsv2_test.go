@@ -0,0 +1,253 @@
+package projectsv2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const projectJSON = `{"data": {"repositoryOwner": {"projectV2": {
+  "id": "PVT_1", "number": 3, "title": "Roadmap",
+  "fields": {"nodes": [
+    {"id": "F_title", "name": "Title", "dataType": "TITLE"},
+    {"id": "F_status", "name": "Status", "dataType": "SINGLE_SELECT",
+     "options": [{"id": "O_todo", "name": "Todo"}, {"id": "O_current", "name": "Current"}]},
+    {"id": "F_iter", "name": "Iteration", "dataType": "ITERATION",
+     "configuration": {"iterations": [
+       {"id": "I_1", "title": "Sprint 1", "startDate": "2024-01-01", "duration": 14},
+       {"id": "I_2", "title": "Sprint 2", "startDate": "2024-01-15", "duration": 14}]}},
+    {}
+  ]}
+}}}}`
+
+const itemJSON = `{
+  "id": "PVTI_1", "type": "ISSUE", "isArchived": false,
+  "project": {"id": "PVT_1"},
+  "content": {"id": "I_issue", "number": 7, "repository": {"nameWithOwner": "o/r"}},
+  "fieldValues": {"nodes": [
+    {},
+    {"optionId": "O_todo", "name": "Todo", "field": {"id": "F_status", "name": "Status"}},
+    {"iterationId": "I_1", "title": "Sprint 1", "field": {"id": "F_iter", "name": "Iteration"}}
+  ]}
+}`
+
+// request is a GraphQL request received by the fake server.
+type request struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+func newServer(t *testing.T, responses ...string) (*Client, *[]request, func()) {
+	reqs := &[]request{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/graphql" {
+			t.Errorf("request path = %q, want /graphql", r.URL.Path)
+		}
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		n := len(*reqs)
+		*reqs = append(*reqs, req)
+		if n >= len(responses) {
+			fmt.Fprint(w, `{"data": {}}`)
+			return
+		}
+		fmt.Fprint(w, responses[n])
+	}))
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return NewClient(client), reqs, srv.Close
+}
+
+func TestClient_GetProject(t *testing.T) {
+	c, reqs, done := newServer(t, projectJSON)
+	defer done()
+	p, err := c.GetProject(context.Background(), "org", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != "PVT_1" || len(p.Fields) != 3 {
+		t.Fatalf("GetProject() = %+v, want 3 fields", p)
+	}
+	if o := p.Field("status").Option("current"); o == nil || o.ID != "O_current" {
+		t.Errorf("Option(current) = %v, want O_current", o)
+	}
+	now := time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC)
+	if it := p.Field("Iteration").Iteration(CurrentIteration, now); it == nil || it.ID != "I_2" {
+		t.Errorf("Iteration(@current) = %v, want I_2", it)
+	}
+	if it := p.Field("Iteration").Iteration("sprint 1", now); it == nil || it.ID != "I_1" {
+		t.Errorf("Iteration(sprint 1) = %v, want I_1", it)
+	}
+	want := map[string]interface{}{"owner": "org", "number": float64(3)}
+	if got := (*reqs)[0].Variables; !reflect.DeepEqual(got, want) {
+		t.Errorf("variables = %v, want %v", got, want)
+	}
+
+	c, _, done = newServer(t, `{"data": {"repositoryOwner": {}}}`)
+	defer done()
+	if _, err := c.GetProject(context.Background(), "org", 4); err == nil {
+		t.Errorf("GetProject() missing project error = nil")
+	}
+}
+
+func TestClient_GetItem(t *testing.T) {
+	c, _, done := newServer(t, `{"data": {"node": `+itemJSON+`}}`, `{"data": {"node": null}}`)
+	defer done()
+	it, err := c.GetItem(context.Background(), "PVTI_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Item{
+		ID:         "PVTI_1",
+		Type:       "ISSUE",
+		ProjectID:  "PVT_1",
+		ContentID:  "I_issue",
+		Number:     7,
+		Repository: "o/r",
+		Values: []FieldValue{
+			{FieldID: "F_status", FieldName: "Status", OptionID: "O_todo", Name: "Todo"},
+			{FieldID: "F_iter", FieldName: "Iteration", IterationID: "I_1", Name: "Sprint 1"},
+		},
+	}
+	if !reflect.DeepEqual(it, want) {
+		t.Errorf("GetItem() = %+v, want %+v", it, want)
+	}
+	if v := it.Value("status"); v == nil || v.Name != "Todo" {
+		t.Errorf("Value(status) = %v, want Todo", v)
+	}
+	if _, err := c.GetItem(context.Background(), "PVTI_2"); err == nil {
+		t.Errorf("GetItem() missing item error = nil")
+	}
+}
+
+func TestClient_FindItem(t *testing.T) {
+	other := strings.Replace(itemJSON, `"PVT_1"`, `"PVT_2"`, 1)
+	c, _, done := newServer(t, `{"data": {"node": {"projectItems": {"nodes": [`+other+`, `+itemJSON+`]}}}}`)
+	defer done()
+	it, err := c.FindItem(context.Background(), "PVT_1", "I_issue")
+	if err != nil || it == nil || it.ProjectID != "PVT_1" {
+		t.Errorf("FindItem() = %v, %v; want item of PVT_1", it, err)
+	}
+	it, err = c.FindItem(context.Background(), "PVT_1", "I_issue")
+	if err != nil || it != nil {
+		t.Errorf("FindItem() = %v, %v; want nil", it, err)
+	}
+}
+
+func TestClient_SetValue(t *testing.T) {
+	var p Project
+	var data struct {
+		Data struct {
+			RepositoryOwner struct {
+				ProjectV2 json.RawMessage `json:"projectV2"`
+			} `json:"repositoryOwner"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(projectJSON), &data); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data.Data.RepositoryOwner.ProjectV2, &p); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		field     string
+		value     string
+		wantQuery string
+		wantValue interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "option",
+			field:     "Status",
+			value:     "current",
+			wantQuery: "updateProjectV2ItemFieldValue",
+			wantValue: map[string]interface{}{"singleSelectOptionId": "O_current"},
+		},
+		{
+			name:      "iteration",
+			field:     "Iteration",
+			value:     CurrentIteration,
+			wantQuery: "updateProjectV2ItemFieldValue",
+			wantValue: map[string]interface{}{"iterationId": "I_1"},
+		},
+		{
+			name:      "clear",
+			field:     "Status",
+			wantQuery: "clearProjectV2ItemFieldValue",
+		},
+		{
+			name:    "error-option",
+			field:   "Status",
+			value:   "Done",
+			wantErr: true,
+		},
+		{
+			name:    "error-field",
+			field:   "Priority",
+			value:   "High",
+			wantErr: true,
+		},
+		{
+			name:    "error-type",
+			field:   "Title",
+			value:   "x",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, reqs, done := newServer(t)
+			defer done()
+			err := c.SetValue(context.Background(), &p, "PVTI_1", tt.field, tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(*reqs) != 0 {
+					t.Errorf("SetValue() sent %d requests, want none", len(*reqs))
+				}
+				return
+			}
+			req := (*reqs)[0]
+			if !strings.HasPrefix(req.Query, "mutation") || !strings.Contains(req.Query, tt.wantQuery) {
+				t.Errorf("query = %q, want %s mutation", req.Query, tt.wantQuery)
+			}
+			if got := req.Variables["value"]; !reflect.DeepEqual(got, tt.wantValue) {
+				t.Errorf("value = %v, want %v", got, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestClient_Do_Errors(t *testing.T) {
+	c, _, done := newServer(t, `{"data": null, "errors": [{"type": "NOT_FOUND", "message": "not found"}, {"message": "denied"}]}`)
+	defer done()
+	err := c.Do(context.Background(), "query { viewer { login } }", nil, nil)
+	if _, ok := err.(Errors); !ok || err.Error() != "graphql: not found; denied" {
+		t.Errorf("Do() error = %v, want GraphQL errors", err)
+	}
+}
+
+func TestClient_url(t *testing.T) {
+	client := github.NewClient(nil)
+	if got := NewClient(client).url(); got != "https://api.github.com/graphql" {
+		t.Errorf("url() = %q, want api.github.com", got)
+	}
+	client.BaseURL, _ = url.Parse("https://ghe.example.com/api/v3/")
+	if got := NewClient(client).url(); got != "https://ghe.example.com/api/graphql" {
+		t.Errorf("url() = %q, want Enterprise GraphQL URL", got)
+	}
+}
@@ -0,0 +1,328 @@
+// Package recorder provides an http.RoundTripper that records GitHub API
+// exchanges to fixture files and replays them in tests.
+//
+// In Record mode, every request is sent using the underlying transport and the
+// request and response are saved. Authentication headers, and token fields of
+// JSON bodies, are scrubbed before saving, so fixtures are safe to commit. In
+// Replay mode, no requests reach the network. Each request must match the next
+// recorded interaction exactly, otherwise RoundTrip returns an error.
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Transport records or replays interactions.
+type Mode int
+
+const (
+	// Replay serves responses from a fixture file.
+	Replay Mode = iota
+	// Record sends requests to the network and saves them to a fixture file.
+	Record
+)
+
+// scrubbedValue replaces the value of sensitive headers in fixture files.
+const scrubbedValue = "REDACTED"
+
+var (
+	// scrubbedHeaders are removed from recorded requests and responses.
+	scrubbedHeaders = []string{
+		"Authorization",
+		"Cookie",
+		"Set-Cookie",
+		"X-Hub-Signature",
+	}
+	// scrubbedFields are JSON object fields replaced at any depth of recorded
+	// request and response bodies, e.g. the "token" of installation access
+	// tokens.
+	scrubbedFields = []string{
+		"token",
+		"access_token",
+		"refresh_token",
+		"client_secret",
+		"secret",
+	}
+)
+
+// ModeFromEnv returns Record if the RECORDER_MODE environment variable is
+// "record", and Replay otherwise.
+func ModeFromEnv() Mode {
+	if os.Getenv("RECORDER_MODE") == "record" {
+		return Record
+	}
+	return Replay
+}
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Transport is an http.RoundTripper that records or replays interactions.
+type Transport struct {
+	// Mode selects recording or replaying.
+	Mode Mode
+	// Path is the fixture file name.
+	Path string
+	// Base is the transport used to send requests in Record mode. If nil,
+	// http.DefaultTransport is used.
+	Base http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	next         int
+}
+
+// New creates a new Transport for the given fixture path. In Replay mode, New
+// loads the fixture file immediately and returns an error if it cannot be read.
+func New(path string, mode Mode, base http.RoundTripper) (*Transport, error) {
+	t := &Transport{Mode: mode, Path: path, Base: base}
+	if mode == Record {
+		return t, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &t.interactions); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return t, nil
+}
+
+// Client returns an *http.Client that uses the Transport.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	rec := Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: scrub(req.Header),
+		Body:   scrubBody(body),
+	}
+	if t.Mode == Record {
+		return t.record(req, rec)
+	}
+	return t.replay(req, rec)
+}
+
+func (t *Transport) record(req *http.Request, rec Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interactions = append(t.interactions, Interaction{
+		Request: rec,
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     scrub(resp.Header),
+			Body:       scrubBody(string(b)),
+		},
+	})
+	return resp, nil
+}
+
+func (t *Transport) replay(req *http.Request, rec Request) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.next >= len(t.interactions) {
+		return nil, fmt.Errorf("recorder: unexpected request %s %s", rec.Method, rec.URL)
+	}
+	want := t.interactions[t.next]
+	if err := match(want.Request, rec); err != nil {
+		return nil, err
+	}
+	t.next++
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", want.Response.StatusCode, http.StatusText(want.Response.StatusCode)),
+		StatusCode:    want.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cloneHeader(want.Response.Header),
+		Body:          ioutil.NopCloser(bytes.NewBufferString(want.Response.Body)),
+		ContentLength: int64(len(want.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes all recorded interactions to the fixture file. Save does nothing
+// in Replay mode.
+func (t *Transport) Save() error {
+	if t.Mode != Record {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, err := json.MarshalIndent(t.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.Path, append(b, '\n'), 0644)
+}
+
+// Done returns an error if any recorded interaction was not replayed. Done
+// always returns nil in Record mode.
+func (t *Transport) Done() error {
+	if t.Mode != Replay {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.next != len(t.interactions) {
+		next := t.interactions[t.next].Request
+		return fmt.Errorf("recorder: %d unused interactions, next: %s %s",
+			len(t.interactions)-t.next, next.Method, next.URL)
+	}
+	return nil
+}
+
+// match returns an error if the got request does not match the want request.
+func match(want, got Request) error {
+	if want.Method != got.Method || want.URL != got.URL {
+		return fmt.Errorf("recorder: request mismatch: got %s %s, want %s %s",
+			got.Method, got.URL, want.Method, want.URL)
+	}
+	if !jsonEqual(want.Body, got.Body) {
+		return fmt.Errorf("recorder: body mismatch for %s %s: got %q, want %q",
+			got.Method, got.URL, got.Body, want.Body)
+	}
+	return nil
+}
+
+// jsonEqual compares two bodies, ignoring formatting differences in JSON.
+func jsonEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var va, vb interface{}
+	if json.Unmarshal([]byte(a), &va) != nil || json.Unmarshal([]byte(b), &vb) != nil {
+		return false
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+	return bytes.Equal(ca, cb)
+}
+
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return "", nil
+	}
+	b, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return string(b), nil
+}
+
+// scrub returns a copy of header with sensitive values replaced.
+func scrub(header http.Header) http.Header {
+	h := cloneHeader(header)
+	for _, key := range scrubbedHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(key)]; ok {
+			h.Set(key, scrubbedValue)
+		}
+	}
+	return h
+}
+
+// scrubBody returns body with the values of sensitive JSON fields replaced.
+// Bodies that are not JSON, or have no sensitive fields, are returned as is.
+func scrubBody(body string) string {
+	var v interface{}
+	d := json.NewDecoder(strings.NewReader(body))
+	d.UseNumber()
+	if d.Decode(&v) != nil || !scrubValue(v) {
+		return body
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(b)
+}
+
+// scrubValue replaces the sensitive string fields of v in place, and reports
+// whether any was found.
+func scrubValue(v interface{}) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if _, ok := child.(string); ok && isScrubbedField(k) {
+				v[k] = scrubbedValue
+				found = true
+				continue
+			}
+			found = scrubValue(child) || found
+		}
+	case []interface{}:
+		for _, child := range v {
+			found = scrubValue(child) || found
+		}
+	}
+	return found
+}
+
+func isScrubbedField(name string) bool {
+	for _, f := range scrubbedFields {
+		if strings.EqualFold(name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneHeader(header http.Header) http.Header {
+	h := http.Header{}
+	for k, v := range header {
+		h[k] = append([]string(nil), v...)
+	}
+	return h
+}
@@ -0,0 +1,198 @@
+package recorder
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTransport_RecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fixture.json")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, `{"method":%q,"body":%q}`, r.Method, string(b))
+	}))
+
+	// Record one exchange.
+	rec, err := New(path, Record, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", srv.URL+"/repos/o/r/issues/1/labels", strings.NewReader(`["a"]`))
+	req.Header.Set("Authorization", "token secret")
+	resp, err := rec.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("secret")) {
+		t.Errorf("fixture contains unscrubbed auth header: %s", b)
+	}
+
+	// Replay the exchange with the server stopped.
+	rep, err := New(path, Replay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rep.Done(); err == nil {
+		t.Errorf("Done() = nil before replay, want error")
+	}
+	req, _ = http.NewRequest("POST", srv.URL+"/repos/o/r/issues/1/labels", strings.NewReader(`[ "a" ]`))
+	resp, err = rep.Client().Do(req)
+	if err != nil {
+		t.Fatalf("replay error = %v", err)
+	}
+	got, _ := ioutil.ReadAll(resp.Body)
+	if want := `{"method":"POST","body":"[\"a\"]"}`; string(got) != want {
+		t.Errorf("replay body = %s, want %s", got, want)
+	}
+	if err := rep.Done(); err != nil {
+		t.Errorf("Done() = %v, want nil", err)
+	}
+
+	// Any further request is unexpected.
+	req, _ = http.NewRequest("GET", srv.URL+"/repos/o/r/issues/1", nil)
+	if _, err := rep.Client().Do(req); err == nil {
+		t.Errorf("unexpected request succeeded")
+	}
+}
+
+func TestTransport_ScrubBody(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fixture.json")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"token":"v1.secret-token","expires_at":"2019-01-01T00:00:00Z","repositories":[{"id":12345678901234567}]}`)
+	}))
+	defer srv.Close()
+
+	rec, err := New(path, Record, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("POST", srv.URL+"/repos/o/r/hooks", strings.NewReader(`{"config":{"url":"https://example.com","secret":"hook-secret"}}`))
+	resp, err := rec.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.Contains(got, []byte("v1.secret-token")) {
+		t.Errorf("recorded response = %s, want the token unchanged for the caller", got)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"v1.secret-token", "hook-secret"} {
+		if bytes.Contains(b, []byte(secret)) {
+			t.Errorf("fixture contains unscrubbed %q: %s", secret, b)
+		}
+	}
+	if !bytes.Contains(b, []byte("12345678901234567")) || !bytes.Contains(b, []byte("2019-01-01T00:00:00Z")) {
+		t.Errorf("fixture lost other fields: %s", b)
+	}
+
+	// The scrubbed request body still matches on replay.
+	rep, err := New(path, Replay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest("POST", srv.URL+"/repos/o/r/hooks", strings.NewReader(`{"config":{"url":"https://example.com","secret":"other-secret"}}`))
+	if _, err := rep.Client().Do(req); err != nil {
+		t.Errorf("replay error = %v", err)
+	}
+}
+
+func TestTransport_ReplayMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   string
+	}{
+		{
+			name:   "method",
+			method: "DELETE",
+			url:    "https://api.github.com/repos/o/r/issues/1/labels",
+			body:   `["a"]`,
+		},
+		{
+			name:   "url",
+			method: "POST",
+			url:    "https://api.github.com/repos/o/r/issues/2/labels",
+			body:   `["a"]`,
+		},
+		{
+			name:   "body",
+			method: "POST",
+			url:    "https://api.github.com/repos/o/r/issues/1/labels",
+			body:   `["b"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Transport{
+				Mode: Replay,
+				interactions: []Interaction{{
+					Request: Request{
+						Method: "POST",
+						URL:    "https://api.github.com/repos/o/r/issues/1/labels",
+						Body:   `["a"]`,
+					},
+					Response: Response{StatusCode: 200, Body: `[]`},
+				}},
+			}
+			req, _ := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if _, err := tr.RoundTrip(req); err == nil {
+				t.Errorf("RoundTrip() error = nil, want mismatch")
+			}
+		})
+	}
+}
+
+func TestNew_MissingFixture(t *testing.T) {
+	if _, err := New("testdata/does-not-exist.json", Replay, nil); err == nil {
+		t.Errorf("New() error = nil, want error")
+	}
+}
+
+func TestModeFromEnv(t *testing.T) {
+	os.Setenv("RECORDER_MODE", "record")
+	if got := ModeFromEnv(); got != Record {
+		t.Errorf("ModeFromEnv() = %v, want Record", got)
+	}
+	os.Unsetenv("RECORDER_MODE")
+	if got := ModeFromEnv(); got != Replay {
+		t.Errorf("ModeFromEnv() = %v, want Replay", got)
+	}
+}
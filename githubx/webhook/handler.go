@@ -2,6 +2,7 @@
 package webhook
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,14 +10,19 @@ import (
 	"reflect"
 	"runtime"
 	"strconv"
+	"sync"
 
 	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/projectsv2"
 	"github.com/stephen-soltesz/pretty"
 )
 
 var (
 	enableDebugLogging string
 
+	// deliveries maps events to delivery IDs while event handlers run.
+	deliveries sync.Map
+
 	// Copied from go-github/github/messages.go due to being a private variable.
 	eventTypeMapping = map[string]string{
 		"check_run":                      "CheckRunEvent",
@@ -44,6 +50,7 @@ var (
 		"project":                        "ProjectEvent",
 		"project_card":                   "ProjectCardEvent",
 		"project_column":                 "ProjectColumnEvent",
+		"projects_v2_item":               "ProjectV2ItemEvent",
 		"public":                         "PublicEvent",
 		"pull_request_review":            "PullRequestReviewEvent",
 		"pull_request_review_comment":    "PullRequestReviewCommentEvent",
@@ -57,12 +64,38 @@ var (
 		"team_add":                       "TeamAddEvent",
 		"watch":                          "WatchEvent",
 	}
+
+	// extraEventTypes creates events that go-github cannot parse.
+	extraEventTypes = map[string]func() interface{}{
+		"projects_v2_item": func() interface{} { return &projectsv2.ProjectV2ItemEvent{} },
+	}
 )
 
 func init() {
 	enableDebugLogging = os.Getenv("DEBUG_LOGGING")
 }
 
+// DeliveryID returns the unique ID of the webhook delivery (the
+// X-GitHub-Delivery header) that produced the given event. DeliveryID is only
+// valid while the event handler function is running, and returns an empty
+// string otherwise.
+func DeliveryID(event interface{}) string {
+	if id, ok := deliveries.Load(event); ok {
+		return id.(string)
+	}
+	return ""
+}
+
+// Delivery is a webhook delivery that passed signature validation.
+type Delivery struct {
+	// ID is the unique ID of the delivery (the X-GitHub-Delivery header).
+	ID string
+	// Event is the event name (the X-GitHub-Event header), e.g. "issues".
+	Event string
+	// Payload is the unmodified request body.
+	Payload []byte
+}
+
 // A Handler defines parameters for implementing a GitHub webhook event handler
 // as part of a GitHub App (https://developer.github.com/apps/) or ad-hoc
 // Webhook server (https://developer.github.com/webhooks/).
@@ -73,6 +106,12 @@ type Handler struct {
 	// WebhookSecret should match the value used to register the github webhook.
 	WebhookSecret string
 
+	// OnDelivery functions are called with every delivery that passes
+	// signature validation, before the event handler function. They must not
+	// block or modify the delivery. When OnDelivery is set, events without a
+	// handler function succeed, and so do pings for them.
+	OnDelivery []func(*Delivery)
+
 	// All functions accept the corresponding event type. The functions may
 	// return an error. The `ServeHTTP` handler reports errors as HTTP 500
 	// failures to the caller.
@@ -101,6 +140,7 @@ type Handler struct {
 	ProjectEvent                      func(*github.ProjectEvent) error
 	ProjectCardEvent                  func(*github.ProjectCardEvent) error
 	ProjectColumnEvent                func(*github.ProjectColumnEvent) error
+	ProjectV2ItemEvent                func(*projectsv2.ProjectV2ItemEvent) error
 	PublicEvent                       func(*github.PublicEvent) error
 	PullRequestEvent                  func(*github.PullRequestEvent) error
 	PullRequestReviewEvent            func(*github.PullRequestReviewEvent) error
@@ -148,8 +188,18 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	log.Println("--")
 	log.Println("Handling request for:", github.WebHookType(r))
+	if len(h.OnDelivery) > 0 {
+		d := &Delivery{
+			ID:      r.Header.Get("X-GitHub-Delivery"),
+			Event:   github.WebHookType(r),
+			Payload: payload,
+		}
+		for _, fn := range h.OnDelivery {
+			fn(d)
+		}
+	}
 	// Convert the payload into a specific github event type.
-	event, err := github.ParseWebHook(github.WebHookType(r), payload)
+	event, err := parseWebHook(github.WebHookType(r), payload)
 	if err != nil {
 		log.Println(string(payload))
 		httpError(w, "Failed to parse webhook", http.StatusInternalServerError)
@@ -159,7 +209,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Check for the PingEvent type to handle differently than all other events.
 	if event, ok := event.(*github.PingEvent); ok {
 		log.Println("Zen:", event.GetZen())
-		if !allEventsSupported(h, event) {
+		if len(h.OnDelivery) == 0 && !allEventsSupported(h, event) {
 			// if !pingEventsSupported(event, h.supportedEvents) {
 			msg := fmt.Sprintln("Unsupported event type:", event.Hook.Events, r.Header)
 			httpError(w, msg, http.StatusNotImplemented)
@@ -180,6 +230,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Lookup the field in the Handler with the same event type name.
 	rHandlerFunc := rHandler.FieldByName(eventType)
 	if reflect.DeepEqual(rHandlerFunc, reflect.Value{}) || rHandlerFunc.IsNil() {
+		if len(h.OnDelivery) > 0 {
+			log.Printf("No handler for %q, delivery passed to OnDelivery only", eventType)
+			return
+		}
 		// We've received an event for an unknown event type. Or, We've received an
 		// event for a known event type, but it is undefined. Normally, a "ping" event
 		// would discover this and the handler would fail to register. However, it's
@@ -191,6 +245,9 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("Calling handler for %q", eventType)
+	// Make the delivery ID available to the handler function.
+	deliveries.Store(event, r.Header.Get("X-GitHub-Delivery"))
+	defer deliveries.Delete(event)
 	// Call the handler function with current event.
 	args := []reflect.Value{reflect.ValueOf(event)}
 	ret := rHandlerFunc.Call(args)
@@ -205,6 +262,20 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+// parseWebHook parses the payload of the named event, including events in
+// extraEventTypes.
+func parseWebHook(messageType string, payload []byte) (interface{}, error) {
+	newEvent, ok := extraEventTypes[messageType]
+	if !ok {
+		return github.ParseWebHook(messageType, payload)
+	}
+	event := newEvent()
+	if err := json.Unmarshal(payload, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
 func allEventsSupported(h *Handler, event *github.PingEvent) bool {
 	// Ping events occur during webhook registration.
 	// If we return true, the webhook is registered successfully.
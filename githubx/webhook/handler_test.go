@@ -14,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/projectsv2"
 	"github.com/stephen-soltesz/pretty"
 )
 
@@ -268,3 +269,78 @@ func TestHandler_ServeHTTP(t *testing.T) {
 		})
 	}
 }
+
+func TestDeliveryID(t *testing.T) {
+	var got string
+	h := &Handler{
+		WebhookSecret: "test",
+		IssuesEvent: func(event *github.IssuesEvent) error {
+			got = DeliveryID(event)
+			return nil
+		},
+	}
+	r := newRequest(http.MethodPost, mustReadAll("testdata/issues.json"), "test", "issues")
+	r.Header.Set("X-GitHub-Delivery", "delivery-1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got != "delivery-1" {
+		t.Errorf("DeliveryID() = %q, want %q", got, "delivery-1")
+	}
+	if id := DeliveryID(&github.IssuesEvent{}); id != "" {
+		t.Errorf("DeliveryID() unknown event = %q, want empty", id)
+	}
+}
+
+func TestHandler_ProjectV2ItemEvent(t *testing.T) {
+	var got *projectsv2.ProjectV2ItemEvent
+	h := &Handler{
+		WebhookSecret: "test",
+		ProjectV2ItemEvent: func(event *projectsv2.ProjectV2ItemEvent) error {
+			got = event
+			return nil
+		},
+	}
+	r := newRequest(http.MethodPost, mustReadAll("testdata/projects_v2_item.json"), "test", "projects_v2_item")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if got.GetAction() != "edited" || got.GetItem().GetNodeID() != "PVTI_item" ||
+		got.GetChangedFieldID() != "PVTSSF_status" || got.GetInstallation().GetID() != 7 {
+		t.Errorf("ProjectV2ItemEvent = %+v, want the parsed payload", got)
+	}
+}
+
+func TestHandler_OnDelivery(t *testing.T) {
+	var got []*Delivery
+	h := &Handler{
+		WebhookSecret: "test",
+		OnDelivery: []func(*Delivery){func(d *Delivery) {
+			got = append(got, d)
+		}},
+	}
+	payload := mustReadAll("testdata/push.json")
+	r := newRequest(http.MethodPost, payload, "test", "push")
+	r.Header.Set("X-GitHub-Delivery", "delivery-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() without handler status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if len(got) != 1 || got[0].ID != "delivery-1" || got[0].Event != "push" || string(got[0].Payload) != payload {
+		t.Errorf("OnDelivery() = %+v, want the push delivery", got)
+	}
+
+	// Unverified deliveries are not passed to OnDelivery.
+	got = nil
+	h.ServeHTTP(httptest.NewRecorder(), newRequest(http.MethodPost, payload, "not the secret", "push"))
+	if len(got) != 0 {
+		t.Errorf("OnDelivery() = %+v, want no unverified deliveries", got)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, newRequest(http.MethodPost, mustReadAll("testdata/ping-unsupported.json"), "test", "ping"))
+	if w.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() ping status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,36 @@
+// Package configfile reads configuration files in YAML or JSON format.
+package configfile
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Validator checks a configuration and sets its defaults.
+type Validator interface {
+	Validate() error
+}
+
+// Load reads and validates a configuration from the named YAML or JSON file
+// into v.
+func Load(path string, v Validator) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := Parse(b, v); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// Parse parses and validates a configuration in YAML or JSON format into v.
+// Unknown fields are an error.
+func Parse(b []byte, v Validator) error {
+	if err := yaml.UnmarshalStrict(b, v); err != nil {
+		return err
+	}
+	return v.Validate()
+}
@@ -0,0 +1,63 @@
+package configfile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type config struct {
+	Name string `yaml:"name"`
+}
+
+func (c *config) Validate() error {
+	if c.Name == "" {
+		return errors.New("missing name")
+	}
+	return nil
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr string
+	}{
+		{name: "yaml", content: "name: a\n", want: "a"},
+		{name: "json", content: `{"name": "b"}`, want: "b"},
+		{name: "unknown-field", content: "name: a\nother: b\n", wantErr: "field other not found"},
+		{name: "invalid", content: "name: ''\n", wantErr: "missing name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".yaml")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			c := &config{}
+			err := Load(path, c)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !strings.HasPrefix(err.Error(), path) {
+					t.Errorf("Load() error = %v, want %q for %s", err, tt.wantErr, path)
+				}
+				return
+			}
+			if err != nil || c.Name != tt.want {
+				t.Errorf("Load() = %q, %v; want %q", c.Name, err, tt.want)
+			}
+		})
+	}
+	if err := Load(filepath.Join(dir, "missing.yaml"), &config{}); !os.IsNotExist(err) {
+		t.Errorf("Load(missing) error = %v, want not exist", err)
+	}
+}
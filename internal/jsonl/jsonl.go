@@ -0,0 +1,102 @@
+// Package jsonl saves values as JSON lines in a local file.
+package jsonl
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// maxLine is the longest line that Read accepts.
+const maxLine = 1024 * 1024
+
+// File is a file of JSON lines. It is safe for concurrent use.
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+// New creates a new File for the given file name. The file is created on the
+// first call to Append.
+func New(path string) *File {
+	return &File{path: path}
+}
+
+// Path returns the file name.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Append marshals v as JSON and appends it to the file as a single line.
+func (f *File) Append(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Read calls fn with every line of the file, oldest first, and stops at the
+// first error. A missing file has no lines. fn must not retain the line, and
+// must not call other methods of f.
+func (f *File) Read(fn func(line []byte) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLine)
+	for scanner.Scan() {
+		if err := fn(scanner.Bytes()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// MoveTo moves the lines of the file to the end of the named file, and removes
+// the file. A missing file moves nothing.
+func (f *File) MoveTo(path string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return os.Rename(f.path, path)
+	}
+	w, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return os.Remove(f.path)
+}
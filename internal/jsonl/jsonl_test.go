@@ -0,0 +1,73 @@
+package jsonl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readAll(t *testing.T, f *File) []int {
+	var got []int
+	err := f.Read(func(line []byte) error {
+		var v int
+		if err := json.Unmarshal(line, &v); err != nil {
+			return err
+		}
+		got = append(got, v)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	return got
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := New(filepath.Join(dir, "a.jsonl"))
+	dst := New(filepath.Join(dir, "b.jsonl"))
+
+	// A missing file has no lines, and moves nothing.
+	if got := readAll(t, f); len(got) != 0 {
+		t.Errorf("Read() = %v, want no lines", got)
+	}
+	if err := f.MoveTo(dst.Path()); err != nil {
+		t.Fatalf("MoveTo() error = %v", err)
+	}
+	if _, err := os.Stat(dst.Path()); !os.IsNotExist(err) {
+		t.Errorf("MoveTo() created %s", dst.Path())
+	}
+
+	for _, v := range []int{1, 2} {
+		if err := f.Append(v); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+	if got, want := readAll(t, f), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %v, want %v", got, want)
+	}
+
+	// Moving renames the file first, and appends to the target afterwards.
+	if err := f.MoveTo(dst.Path()); err != nil {
+		t.Fatalf("MoveTo() error = %v", err)
+	}
+	if err := f.Append(3); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if err := f.MoveTo(dst.Path()); err != nil {
+		t.Fatalf("MoveTo() error = %v", err)
+	}
+	if got := readAll(t, f); len(got) != 0 {
+		t.Errorf("Read() after MoveTo() = %v, want no lines", got)
+	}
+	if got, want := readAll(t, dst), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() of target = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,401 @@
+// Package iteration maps dates to planning iterations and their labels.
+//
+// A calendar has one of four kinds:
+//
+//   - week: ISO 8601 weeks, labeled "Week 42" and "2019" by default.
+//   - sprint: N-week sprints counted from an anchor date, labeled "Sprint 7".
+//   - quarter: calendar quarters, labeled "2019-Q3".
+//   - named: a configured list of iterations with start dates, labeled by name.
+//
+// For example, two-week sprints starting on a Monday in New York:
+//
+//	kind: sprint
+//	weeks: 2
+//	anchor: 2019-01-07
+//	timezone: America/New_York
+//	labels: ["Sprint {{.Number}}"]
+//
+// Labels are Go text/templates executed with an Iteration.
+package iteration
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
+)
+
+// Supported calendar kinds.
+const (
+	KindWeek    = "week"
+	KindSprint  = "sprint"
+	KindQuarter = "quarter"
+	KindNamed   = "named"
+)
+
+// dateLayout is the format of configured dates.
+const dateLayout = "2006-01-02"
+
+var (
+	// ErrNoIteration is returned when a named calendar has no iteration for
+	// the requested time.
+	ErrNoIteration = fmt.Errorf("no iteration at the given time")
+
+	defaultLabels = map[string][]string{
+		KindWeek:    {"Week {{.Number}}", "{{.Year}}"},
+		KindSprint:  {"Sprint {{.Number}}"},
+		KindQuarter: {"{{.Year}}-Q{{.Number}}"},
+		KindNamed:   {"{{.Name}}"},
+	}
+)
+
+// Iteration is a single planning period.
+type Iteration struct {
+	// Name is a display name, e.g. "Week 42", "Sprint 7", "Q3" or a configured
+	// name.
+	Name string
+	// Number is the ISO week, sprint number counting from 1 at the anchor,
+	// quarter, or 1-based position in the named list.
+	Number int
+	// Year is the year of Start, or the ISO year for weeks.
+	Year int
+	// Start is the first instant of the iteration.
+	Start time.Time
+	// End is the first instant after the iteration. End is zero for the last
+	// named iteration without an end date.
+	End time.Time
+}
+
+// Named is a configured iteration of a named calendar.
+type Named struct {
+	Name string `yaml:"name" json:"name"`
+	// Start is the first day of the iteration, e.g. "2019-01-07".
+	Start string `yaml:"start" json:"start"`
+	// End is the first day after the iteration. Defaults to the start of the
+	// next iteration.
+	End string `yaml:"end,omitempty" json:"end,omitempty"`
+}
+
+// Calendar maps times to iterations.
+type Calendar struct {
+	Kind string `yaml:"kind" json:"kind"`
+	// Timezone is the IANA zone name used to find day boundaries. Defaults to
+	// UTC.
+	Timezone string `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	// Labels are the label templates for an iteration. Defaults depend on Kind.
+	Labels []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+
+	// Anchor is the first day of sprint 1, for sprint calendars.
+	Anchor string `yaml:"anchor,omitempty" json:"anchor,omitempty"`
+	// Weeks is the sprint length, for sprint calendars.
+	Weeks int `yaml:"weeks,omitempty" json:"weeks,omitempty"`
+
+	// Iterations are the iterations of a named calendar, in order.
+	Iterations []Named `yaml:"iterations,omitempty" json:"iterations,omitempty"`
+
+	loc      *time.Location
+	labels   []*template.Template
+	patterns []*regexp.Regexp
+	anchor   time.Time
+	named    []*Iteration
+	names    map[string]bool
+}
+
+// Default returns the ISO week calendar in UTC.
+func Default() *Calendar {
+	c := &Calendar{Kind: KindWeek}
+	if err := c.Validate(); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Validate checks the calendar and compiles the timezone, dates and label
+// templates.
+func (c *Calendar) Validate() error {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return fmt.Errorf("timezone: %v", err)
+	}
+	c.loc = loc
+
+	labels, ok := defaultLabels[c.Kind]
+	if !ok {
+		return fmt.Errorf("unsupported kind %q", c.Kind)
+	}
+	if len(c.Labels) > 0 {
+		labels = c.Labels
+	}
+	c.labels = nil
+	for _, l := range labels {
+		if strings.TrimSpace(l) == "" {
+			return fmt.Errorf("labels: empty label")
+		}
+		t, err := template.New(c.Kind).Option("missingkey=error").Parse(l)
+		if err != nil {
+			return fmt.Errorf("labels: %v", err)
+		}
+		c.labels = append(c.labels, t)
+	}
+	c.patterns = nil
+	for _, t := range c.labels {
+		if re := pattern(c.Kind, t); re != nil {
+			c.patterns = append(c.patterns, re)
+		}
+	}
+
+	switch c.Kind {
+	case KindSprint:
+		if c.Weeks <= 0 {
+			return fmt.Errorf("sprint weeks must be positive")
+		}
+		if c.anchor, err = time.ParseInLocation(dateLayout, c.Anchor, c.loc); err != nil {
+			return fmt.Errorf("anchor: %v", err)
+		}
+	case KindNamed:
+		return c.validateNamed()
+	}
+	return nil
+}
+
+func (c *Calendar) validateNamed() error {
+	if len(c.Iterations) == 0 {
+		return fmt.Errorf("named calendar has no iterations")
+	}
+	c.named = nil
+	for i, n := range c.Iterations {
+		if n.Name == "" {
+			return fmt.Errorf("iteration %d: missing name", i)
+		}
+		start, err := time.ParseInLocation(dateLayout, n.Start, c.loc)
+		if err != nil {
+			return fmt.Errorf("iteration %q: start: %v", n.Name, err)
+		}
+		it := &Iteration{Name: n.Name, Number: i + 1, Year: start.Year(), Start: start}
+		if n.End != "" {
+			if it.End, err = time.ParseInLocation(dateLayout, n.End, c.loc); err != nil {
+				return fmt.Errorf("iteration %q: end: %v", n.Name, err)
+			}
+			if !it.End.After(start) {
+				return fmt.Errorf("iteration %q: end must be after start", n.Name)
+			}
+		}
+		if i > 0 {
+			prev := c.named[i-1]
+			if !start.After(prev.Start) {
+				return fmt.Errorf("iteration %q: start must be after the previous start", n.Name)
+			}
+			if prev.End.IsZero() {
+				prev.End = start
+			} else if prev.End.After(start) {
+				return fmt.Errorf("iteration %q: overlaps %q", n.Name, prev.Name)
+			}
+		}
+		c.named = append(c.named, it)
+	}
+	c.names = map[string]bool{}
+	for _, it := range c.named {
+		labels, err := c.LabelsFor(it)
+		if err != nil {
+			return fmt.Errorf("iteration %q: labels: %v", it.Name, err)
+		}
+		for _, l := range labels {
+			c.names[strings.ToLower(l)] = true
+		}
+	}
+	return nil
+}
+
+// At returns the iteration containing t.
+func (c *Calendar) At(t time.Time) (*Iteration, error) {
+	t = t.In(c.loc)
+	switch c.Kind {
+	case KindWeek:
+		year, week := t.ISOWeek()
+		// Monday is day 1 of the ISO week; Sunday is day 7.
+		offset := (int(t.Weekday()) + 6) % 7
+		start := midnight(t).AddDate(0, 0, -offset)
+		return &Iteration{
+			Name:   fmt.Sprintf("Week %d", week),
+			Number: week,
+			Year:   year,
+			Start:  start,
+			End:    start.AddDate(0, 0, 7),
+		}, nil
+
+	case KindSprint:
+		days := daysBetween(c.anchor, t)
+		n := floorDiv(days, 7*c.Weeks)
+		start := c.anchor.AddDate(0, 0, n*7*c.Weeks)
+		return &Iteration{
+			Name:   fmt.Sprintf("Sprint %d", n+1),
+			Number: n + 1,
+			Year:   start.Year(),
+			Start:  start,
+			End:    start.AddDate(0, 0, 7*c.Weeks),
+		}, nil
+
+	case KindQuarter:
+		q := (int(t.Month())-1)/3 + 1
+		start := time.Date(t.Year(), time.Month((q-1)*3+1), 1, 0, 0, 0, 0, c.loc)
+		return &Iteration{
+			Name:   fmt.Sprintf("Q%d", q),
+			Number: q,
+			Year:   t.Year(),
+			Start:  start,
+			End:    start.AddDate(0, 3, 0),
+		}, nil
+
+	case KindNamed:
+		for _, it := range c.named {
+			if !t.Before(it.Start) && (it.End.IsZero() || t.Before(it.End)) {
+				found := *it
+				return &found, nil
+			}
+		}
+		return nil, ErrNoIteration
+	}
+	return nil, fmt.Errorf("unsupported kind %q", c.Kind)
+}
+
+// Previous returns the iteration before the given one.
+func (c *Calendar) Previous(it *Iteration) (*Iteration, error) {
+	return c.At(it.Start.Add(-time.Nanosecond))
+}
+
+// LabelsFor returns the labels of the given iteration.
+func (c *Calendar) LabelsFor(it *Iteration) ([]string, error) {
+	labels := []string{}
+	for _, t := range c.labels {
+		var b bytes.Buffer
+		if err := t.Execute(&b, it); err != nil {
+			return nil, err
+		}
+		labels = append(labels, b.String())
+	}
+	return labels, nil
+}
+
+// Rollover returns the labels of the iteration containing t to add, and the
+// given labels that the calendar generates for any other iteration to remove,
+// so labels of every past iteration are removed, not only the previous one. For
+// example, a week calendar in week 1 of 2020 given ["Week 51" "2019" "bug"]
+// returns add ["Week 1" "2020"] and remove ["Week 51" "2019"]. If no iteration
+// contains t, as between or after named iterations, add is empty and every
+// iteration label is removed.
+func (c *Calendar) Rollover(t time.Time, labels []string) (add, remove []string, err error) {
+	it, err := c.At(t)
+	switch {
+	case err == ErrNoIteration:
+		add = []string{}
+	case err != nil:
+		return nil, nil, err
+	default:
+		if add, err = c.LabelsFor(it); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, l := range labels {
+		if c.Generates(l) && !slice.ContainsFold(add, l) {
+			remove = append(remove, l)
+		}
+	}
+	return add, remove, nil
+}
+
+// Generates reports whether label is a label of some iteration of the
+// calendar, ignoring case. Named calendars match the labels of their
+// iterations exactly. Other calendars match the label templates, where
+// .Number and .Year match any number; templates without text or numbers to
+// match never match.
+func (c *Calendar) Generates(label string) bool {
+	if c.Kind == KindNamed {
+		return c.names[strings.ToLower(label)]
+	}
+	for _, re := range c.patterns {
+		if re.MatchString(label) {
+			return true
+		}
+	}
+	return false
+}
+
+// namePatterns match the Iteration.Name of each periodic calendar kind.
+var namePatterns = map[string]string{
+	KindWeek:    `Week \d+`,
+	KindSprint:  `Sprint \d+`,
+	KindQuarter: `Q\d`,
+}
+
+// pattern returns a regular expression matching the output of t for any
+// iteration of the given kind, or nil if t has no text or typed fields that
+// would keep the expression from matching unrelated labels.
+func pattern(kind string, t *template.Template) *regexp.Regexp {
+	var b strings.Builder
+	safe := false
+	for _, n := range t.Tree.Root.Nodes {
+		switch n := n.(type) {
+		case *parse.TextNode:
+			b.WriteString(regexp.QuoteMeta(string(n.Text)))
+			safe = safe || strings.TrimSpace(string(n.Text)) != ""
+		case *parse.ActionNode:
+			switch f := fieldName(n); {
+			case f == "Number" || f == "Year":
+				b.WriteString(`\d+`)
+				safe = true
+			case f == "Name" && namePatterns[kind] != "":
+				b.WriteString(namePatterns[kind])
+				safe = true
+			default:
+				b.WriteString(`.+`)
+			}
+		default:
+			b.WriteString(`.*`)
+		}
+	}
+	if !safe {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)^` + b.String() + `$`)
+}
+
+// fieldName returns the name of the field printed by an action such as
+// "{{.Number}}", or "" for any other action.
+func fieldName(n *parse.ActionNode) string {
+	if len(n.Pipe.Decl) != 0 || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+		return ""
+	}
+	f, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(f.Ident) != 1 {
+		return ""
+	}
+	return f.Ident[0]
+}
+
+// midnight returns the start of the day of t, in the location of t.
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween returns the number of calendar days from the date of a to the
+// date of b, ignoring daylight saving time changes.
+func daysBetween(a, b time.Time) int {
+	b = b.In(a.Location())
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+// floorDiv returns a/b rounded toward negative infinity.
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
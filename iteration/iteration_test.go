@@ -0,0 +1,245 @@
+package iteration
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar string
+		wantErr  string
+	}{
+		{name: "week", calendar: "kind: week\n"},
+		{name: "sprint", calendar: "kind: sprint\nweeks: 2\nanchor: 2019-01-07\n"},
+		{name: "quarter", calendar: "kind: quarter\ntimezone: America/New_York\n"},
+		{name: "named", calendar: "kind: named\niterations:\n- {name: a, start: 2019-01-01}\n"},
+		{name: "bad-kind", calendar: "kind: month\n", wantErr: "unsupported kind"},
+		{name: "bad-timezone", calendar: "kind: week\ntimezone: Mars/Olympus\n", wantErr: "timezone"},
+		{name: "bad-label", calendar: "kind: week\nlabels: ['{{.Nope']\n", wantErr: "labels"},
+		{name: "empty-label", calendar: "kind: week\nlabels: [' ']\n", wantErr: "empty label"},
+		{name: "sprint-weeks", calendar: "kind: sprint\nanchor: 2019-01-07\n", wantErr: "weeks"},
+		{name: "sprint-anchor", calendar: "kind: sprint\nweeks: 2\nanchor: Monday\n", wantErr: "anchor"},
+		{name: "named-empty", calendar: "kind: named\n", wantErr: "no iterations"},
+		{name: "named-order", calendar: "kind: named\niterations:\n- {name: a, start: 2019-02-01}\n- {name: b, start: 2019-01-01}\n", wantErr: "after the previous"},
+		{name: "named-overlap", calendar: "kind: named\niterations:\n- {name: a, start: 2019-01-01, end: 2019-03-01}\n- {name: b, start: 2019-02-01}\n", wantErr: "overlaps"},
+		{name: "unknown-field", calendar: "kind: week\nlength: 2\n", wantErr: "length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := configfile.Parse([]byte(tt.calendar), &Calendar{})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalendar_At(t *testing.T) {
+	named := `
+kind: named
+iterations:
+- {name: Alpha, start: 2019-01-01}
+- {name: Beta, start: 2019-02-01, end: 2019-03-01}
+`
+	tests := []struct {
+		name      string
+		calendar  string
+		now       time.Time
+		want      string
+		wantStart string
+		wantErr   error
+	}{
+		{
+			name:      "week",
+			calendar:  "kind: week\n",
+			now:       time.Date(2019, 02, 17, 12, 0, 0, 0, time.UTC),
+			want:      "Week 7",
+			wantStart: "2019-02-11",
+		},
+		{
+			name:      "week-iso-year",
+			calendar:  "kind: week\n",
+			now:       time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC),
+			want:      "Week 1",
+			wantStart: "2019-12-30",
+		},
+		{
+			name:      "week-timezone",
+			calendar:  "kind: week\ntimezone: America/New_York\n",
+			now:       time.Date(2019, 02, 18, 2, 0, 0, 0, time.UTC),
+			want:      "Week 7",
+			wantStart: "2019-02-11",
+		},
+		{
+			name:      "sprint-first",
+			calendar:  "kind: sprint\nweeks: 2\nanchor: 2019-01-07\n",
+			now:       time.Date(2019, 01, 20, 23, 0, 0, 0, time.UTC),
+			want:      "Sprint 1",
+			wantStart: "2019-01-07",
+		},
+		{
+			name:      "sprint-later",
+			calendar:  "kind: sprint\nweeks: 2\nanchor: 2019-01-07\ntimezone: America/New_York\n",
+			now:       time.Date(2019, 03, 18, 12, 0, 0, 0, time.UTC),
+			want:      "Sprint 6",
+			wantStart: "2019-03-18",
+		},
+		{
+			name:      "sprint-before-anchor",
+			calendar:  "kind: sprint\nweeks: 2\nanchor: 2019-01-07\n",
+			now:       time.Date(2019, 01, 06, 0, 0, 0, 0, time.UTC),
+			want:      "Sprint 0",
+			wantStart: "2018-12-24",
+		},
+		{
+			name:      "quarter",
+			calendar:  "kind: quarter\n",
+			now:       time.Date(2019, 8, 15, 0, 0, 0, 0, time.UTC),
+			want:      "Q3",
+			wantStart: "2019-07-01",
+		},
+		{
+			name:      "named",
+			calendar:  named,
+			now:       time.Date(2019, 01, 31, 0, 0, 0, 0, time.UTC),
+			want:      "Alpha",
+			wantStart: "2019-01-01",
+		},
+		{
+			name:     "named-after-end",
+			calendar: named,
+			now:      time.Date(2019, 03, 01, 0, 0, 0, 0, time.UTC),
+			wantErr:  ErrNoIteration,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Calendar{}
+			if err := configfile.Parse([]byte(tt.calendar), c); err != nil {
+				t.Fatal(err)
+			}
+			it, err := c.At(tt.now)
+			if err != tt.wantErr {
+				t.Fatalf("At() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if it.Name != tt.want || it.Start.Format(dateLayout) != tt.wantStart {
+				t.Errorf("At() = %q starting %s, want %q starting %s",
+					it.Name, it.Start.Format(dateLayout), tt.want, tt.wantStart)
+			}
+			if it.Start.After(tt.now) || (!it.End.IsZero() && !tt.now.Before(it.End)) {
+				t.Errorf("At() = %v to %v, does not contain %v", it.Start, it.End, tt.now)
+			}
+		})
+	}
+}
+
+func TestCalendar_Rollover(t *testing.T) {
+	tests := []struct {
+		name       string
+		calendar   string
+		now        time.Time
+		labels     []string
+		wantAdd    []string
+		wantRemove []string
+	}{
+		{
+			name:       "week",
+			calendar:   "kind: week\n",
+			now:        time.Date(2019, 02, 17, 0, 0, 0, 0, time.UTC),
+			labels:     []string{"current", "Week 6", "2019"},
+			wantAdd:    []string{"Week 7", "2019"},
+			wantRemove: []string{"Week 6"},
+		},
+		{
+			name:       "week-new-year",
+			calendar:   "kind: week\n",
+			now:        time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC),
+			labels:     []string{"Week 52", "2019"},
+			wantAdd:    []string{"Week 1", "2020"},
+			wantRemove: []string{"Week 52", "2019"},
+		},
+		{
+			name:       "week-missed-iterations",
+			calendar:   "kind: week\n",
+			now:        time.Date(2019, 02, 17, 0, 0, 0, 0, time.UTC),
+			labels:     []string{"week 3", "Week 5", "2018", "Weekly", "v2"},
+			wantAdd:    []string{"Week 7", "2019"},
+			wantRemove: []string{"week 3", "Week 5", "2018"},
+		},
+		{
+			name:       "sprint-template",
+			calendar:   "kind: sprint\nweeks: 2\nanchor: 2019-01-07\nlabels: ['Sprint {{.Number}} ({{.Start.Format \"Jan 2\"}})']\n",
+			now:        time.Date(2019, 01, 22, 0, 0, 0, 0, time.UTC),
+			labels:     []string{"Sprint 1 (Jan 7)", "Sprint 2"},
+			wantAdd:    []string{"Sprint 2 (Jan 21)"},
+			wantRemove: []string{"Sprint 1 (Jan 7)"},
+		},
+		{
+			name:     "sprint-untyped-template",
+			calendar: "kind: sprint\nweeks: 2\nanchor: 2019-01-07\nlabels: ['{{.Start.Format \"Jan 2\"}}']\n",
+			now:      time.Date(2019, 01, 22, 0, 0, 0, 0, time.UTC),
+			labels:   []string{"Jan 7", "bug"},
+			wantAdd:  []string{"Jan 21"},
+		},
+		{
+			name:       "quarter",
+			calendar:   "kind: quarter\n",
+			now:        time.Date(2019, 01, 22, 0, 0, 0, 0, time.UTC),
+			labels:     []string{"2018-Q3", "2018-Q4", "2019-Q1"},
+			wantAdd:    []string{"2019-Q1"},
+			wantRemove: []string{"2018-Q3", "2018-Q4"},
+		},
+		{
+			name:     "named-first",
+			calendar: "kind: named\niterations:\n- {name: Alpha, start: 2019-01-01}\n",
+			now:      time.Date(2019, 01, 22, 0, 0, 0, 0, time.UTC),
+			labels:   []string{"Beta"},
+			wantAdd:  []string{"Alpha"},
+		},
+		{
+			name: "named-third",
+			calendar: "kind: named\niterations:\n- {name: Alpha, start: 2019-01-01}\n" +
+				"- {name: Beta, start: 2019-01-15}\n- {name: Gamma, start: 2019-02-01}\n",
+			now:        time.Date(2019, 02, 22, 0, 0, 0, 0, time.UTC),
+			labels:     []string{"alpha", "Beta", "Delta"},
+			wantAdd:    []string{"Gamma"},
+			wantRemove: []string{"alpha", "Beta"},
+		},
+		{
+			name: "named-gap",
+			calendar: "kind: named\niterations:\n- {name: Alpha, start: 2019-01-01, end: 2019-01-15}\n" +
+				"- {name: Beta, start: 2019-02-01}\n",
+			now:        time.Date(2019, 01, 22, 0, 0, 0, 0, time.UTC),
+			labels:     []string{"Alpha", "bug"},
+			wantAdd:    []string{},
+			wantRemove: []string{"Alpha"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Calendar{}
+			if err := configfile.Parse([]byte(tt.calendar), c); err != nil {
+				t.Fatal(err)
+			}
+			add, remove, err := c.Rollover(tt.now, tt.labels)
+			if err != nil {
+				t.Fatalf("Rollover() error = %v", err)
+			}
+			if !reflect.DeepEqual(add, tt.wantAdd) || !reflect.DeepEqual(remove, tt.wantRemove) {
+				t.Errorf("Rollover() = %q, %q, want %q, %q", add, remove, tt.wantAdd, tt.wantRemove)
+			}
+		})
+	}
+}
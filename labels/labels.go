@@ -0,0 +1,232 @@
+// Package labels keeps repository labels in sync with a label catalog.
+//
+// A catalog lists the labels every repository should have, with their colors,
+// descriptions and former names (aliases). Sync creates missing labels,
+// updates colors and descriptions, renames labels found under an alias, and
+// optionally deletes labels that are not in the catalog. For example:
+//
+//	labels:
+//	- name: review/triage
+//	  color: fbca04
+//	  description: New issue waiting for triage
+//	  aliases: [triage, needs-triage]
+//	delete_unknown: true
+//	keep: ['^Week \d+$', '^\d{4}$']
+package labels
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+// DefaultYAML is the default catalog, covering the labels used by the default
+// rules.
+const DefaultYAML = `
+labels:
+- name: review/triage
+  color: fbca04
+  description: New issue waiting for triage.
+- name: backlog
+  color: c5def5
+  description: Accepted, but not scheduled.
+- name: current
+  color: 0e8a16
+  description: Scheduled for the current iteration.
+- name: closed
+  color: cccccc
+  description: Add this label to close the issue.
+`
+
+var colorRE = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+
+// Label is a catalog entry.
+type Label struct {
+	Name        string   `yaml:"name"`
+	Color       string   `yaml:"color"`
+	Description string   `yaml:"description,omitempty"`
+	Aliases     []string `yaml:"aliases,omitempty"`
+}
+
+// Catalog is the set of labels every repository should have.
+type Catalog struct {
+	Labels []Label `yaml:"labels"`
+	// DeleteUnknown deletes repository labels that are not in the catalog and
+	// do not match a Keep pattern.
+	DeleteUnknown bool `yaml:"delete_unknown,omitempty"`
+	// Keep are regular expressions for label names never deleted, e.g. the
+	// iteration labels added by rules.
+	Keep []string `yaml:"keep,omitempty"`
+
+	keep []*regexp.Regexp
+}
+
+// Default returns the default catalog.
+func Default() *Catalog {
+	c := &Catalog{}
+	if err := configfile.Parse([]byte(DefaultYAML), c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Validate checks that names and aliases are unique and colors are valid, and
+// compiles the Keep patterns.
+func (c *Catalog) Validate() error {
+	seen := map[string]bool{}
+	for _, l := range c.Labels {
+		if l.Name == "" {
+			return fmt.Errorf("label with empty name")
+		}
+		if !colorRE.MatchString(l.Color) {
+			return fmt.Errorf("label %q: color %q must be six hex digits", l.Name, l.Color)
+		}
+		for _, name := range append([]string{l.Name}, l.Aliases...) {
+			k := strings.ToLower(name)
+			if seen[k] {
+				return fmt.Errorf("label %q: duplicate name or alias %q", l.Name, name)
+			}
+			seen[k] = true
+		}
+	}
+	c.keep = nil
+	for _, k := range c.Keep {
+		re, err := regexp.Compile(k)
+		if err != nil {
+			return fmt.Errorf("keep: %v", err)
+		}
+		c.keep = append(c.keep, re)
+	}
+	return nil
+}
+
+// kept reports whether the label name matches a Keep pattern.
+func (c *Catalog) kept(name string) bool {
+	for _, re := range c.keep {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Result lists the label names changed by Sync.
+type Result struct {
+	Created []string
+	Updated []string
+	// Renamed entries have the form "old -> new".
+	Renamed []string
+	Deleted []string
+}
+
+// Changed reports whether Sync modified the repository.
+func (r *Result) Changed() bool {
+	return len(r.Created)+len(r.Updated)+len(r.Renamed)+len(r.Deleted) > 0
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("created %q updated %q renamed %q deleted %q",
+		r.Created, r.Updated, r.Renamed, r.Deleted)
+}
+
+// Sync updates the labels of the owner repo to match the catalog. Sync stops
+// at the first error and returns the changes made so far.
+func Sync(ctx context.Context, svc iface.Labels, owner, repo string, c *Catalog) (*Result, error) {
+	result := &Result{}
+	existing, err := listLabels(ctx, svc, owner, repo)
+	if err != nil {
+		return result, err
+	}
+	// Names of existing labels that belong to the catalog.
+	known := map[string]bool{}
+
+	for _, want := range c.Labels {
+		desired := &github.Label{
+			Name:        github.String(want.Name),
+			Color:       github.String(strings.ToLower(want.Color)),
+			Description: github.String(want.Description),
+		}
+		if have, ok := existing[strings.ToLower(want.Name)]; ok {
+			known[strings.ToLower(want.Name)] = true
+			if !strings.EqualFold(have.GetColor(), want.Color) ||
+				have.GetDescription() != want.Description || have.GetName() != want.Name {
+				if _, _, err := svc.EditLabel(ctx, owner, repo, have.GetName(), desired); err != nil {
+					return result, err
+				}
+				result.Updated = append(result.Updated, want.Name)
+			}
+			continue
+		}
+		alias := findAlias(existing, want.Aliases)
+		if alias != nil {
+			known[strings.ToLower(alias.GetName())] = true
+			if _, _, err := svc.EditLabel(ctx, owner, repo, alias.GetName(), desired); err != nil {
+				return result, err
+			}
+			result.Renamed = append(result.Renamed, alias.GetName()+" -> "+want.Name)
+			continue
+		}
+		if _, _, err := svc.CreateLabel(ctx, owner, repo, desired); err != nil {
+			return result, err
+		}
+		result.Created = append(result.Created, want.Name)
+	}
+
+	if c.DeleteUnknown {
+		names := []string{}
+		for k := range existing {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			have := existing[k]
+			if known[k] || c.kept(have.GetName()) {
+				continue
+			}
+			if _, err := svc.DeleteLabel(ctx, owner, repo, have.GetName()); err != nil {
+				return result, err
+			}
+			result.Deleted = append(result.Deleted, have.GetName())
+		}
+	}
+	if result.Changed() {
+		log.Printf("labels: %s/%s: %s", owner, repo, result)
+	}
+	return result, nil
+}
+
+// findAlias returns the first existing label named by one of the aliases.
+func findAlias(existing map[string]*github.Label, aliases []string) *github.Label {
+	for _, a := range aliases {
+		if l, ok := existing[strings.ToLower(a)]; ok {
+			return l
+		}
+	}
+	return nil
+}
+
+// listLabels returns all labels of the owner repo keyed by lower case name.
+func listLabels(ctx context.Context, svc iface.Labels, owner, repo string) (map[string]*github.Label, error) {
+	all := map[string]*github.Label{}
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		labels, resp, err := svc.ListLabels(ctx, owner, repo, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range labels {
+			all[strings.ToLower(l.GetName())] = l
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,91 @@
+package labels
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/fakegithub"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		catalog string
+		wantErr string
+	}{
+		{name: "default", catalog: DefaultYAML},
+		{name: "bad-color", catalog: "labels:\n- {name: a, color: red}\n", wantErr: "six hex digits"},
+		{name: "empty-name", catalog: "labels:\n- {color: ffffff}\n", wantErr: "empty name"},
+		{name: "duplicate", catalog: "labels:\n- {name: a, color: ffffff}\n- {name: b, color: ffffff, aliases: [A]}\n", wantErr: "duplicate"},
+		{name: "bad-keep", catalog: "keep: ['(']\n", wantErr: "keep"},
+		{name: "unknown-field", catalog: "lables: []\n", wantErr: "lables"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := configfile.Parse([]byte(tt.catalog), &Catalog{})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSync(t *testing.T) {
+	s := fakegithub.NewServer()
+	defer s.Close()
+	s.AddIssue("o", "r", &github.Issue{
+		Number: github.Int(1),
+		Labels: []github.Label{
+			{Name: github.String("triage")},
+			{Name: github.String("backlog")},
+			{Name: github.String("Week 42")},
+			{Name: github.String("random")},
+		},
+	})
+	catalog := &Catalog{}
+	err := configfile.Parse([]byte(`
+labels:
+- {name: review/triage, color: fbca04, aliases: [triage]}
+- {name: backlog, color: c5def5, description: Later.}
+- {name: current, color: 0E8A16}
+delete_unknown: true
+keep: ['^Week \d+$']
+`), catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := iface.NewLabels(s.Client().Issues)
+	ctx := context.Background()
+
+	got, err := Sync(ctx, svc, "o", "r", catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Result{
+		Created: []string{"current"},
+		Updated: []string{"backlog"},
+		Renamed: []string{"triage -> review/triage"},
+		Deleted: []string{"random"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sync() = %v, want %v", got, want)
+	}
+	// Renames apply to existing issues.
+	if labels := s.IssueLabels("o", "r", 1); !reflect.DeepEqual(labels, []string{"Week 42", "backlog", "review/triage"}) {
+		t.Errorf("IssueLabels() = %v", labels)
+	}
+
+	// A second sync is a no-op.
+	got, err = Sync(ctx, svc, "o", "r", catalog)
+	if err != nil || got.Changed() {
+		t.Errorf("Sync() second run = %v, %v; want no changes", got, err)
+	}
+}
@@ -0,0 +1,53 @@
+package local
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/go-github/github"
+	checksiface "github.com/stephen-soltesz/github-webhook-poc/events/checks/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+)
+
+func getChecks(client *github.Client) checksiface.Checks {
+	return checksiface.NewChecks(client)
+}
+
+// CheckRunEvent runs a check again when a user re-runs it, and posts a chat
+// message when a check run fails.
+func (c *Config) CheckRunEvent(event *github.CheckRunEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if c.Notify != nil {
+		c.Notify.CheckRunEvent(ctx, event)
+	}
+	if c.Checks == nil {
+		return nil
+	}
+	client := githubx.NewClient(getSafeID(event))
+	if client == nil {
+		return ErrNewClient
+	}
+	if err := c.Checks.CheckRunEvent(ctx, c.getChecks(client), event); err != nil {
+		log.Println("CheckRunEvent: checks:", err)
+	}
+	return nil
+}
+
+// CheckSuiteEvent runs every check again when a user re-runs all checks.
+func (c *Config) CheckSuiteEvent(event *github.CheckSuiteEvent) error {
+	if c.Checks == nil {
+		return nil
+	}
+	client := githubx.NewClient(getSafeID(event))
+	if client == nil {
+		return ErrNewClient
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := c.Checks.CheckSuiteEvent(ctx, c.getChecks(client), event); err != nil {
+		log.Println("CheckSuiteEvent: checks:", err)
+	}
+	return nil
+}
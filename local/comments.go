@@ -0,0 +1,57 @@
+package local
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/commands"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
+	"github.com/stephen-soltesz/github-webhook-poc/stale"
+)
+
+// IssueCommentEvent runs the slash commands in a new comment on an issue or
+// pull request, and removes the stale label when a person comments.
+func (c *Config) IssueCommentEvent(event *github.IssueCommentEvent) error {
+	if event.GetAction() != "created" || event.GetSender().GetType() == "Bot" {
+		return nil
+	}
+	client := githubx.NewClient(getSafeID(event))
+	if client == nil {
+		return ErrNewClient
+	}
+	ev := issues.NewEvent(c.getIface(client), &github.IssuesEvent{
+		Action:       event.Action,
+		Issue:        event.Issue,
+		Repo:         event.Repo,
+		Sender:       event.Sender,
+		Installation: event.Installation,
+	})
+	ev.Recorder = c.Audit
+	ev.DeliveryID = webhook.DeliveryID(event)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if c.Commands != nil {
+		cmd := commands.NewEvent(ev, event.GetComment(), client.Repositories, getReactions(client))
+		if n, err := c.Commands.Handle(ctx, cmd); err != nil {
+			log.Println("IssueCommentEvent: commands:", err)
+		} else if n > 0 {
+			log.Println("IssueCommentEvent: ran", n, "commands:", ev.GetIssue().GetHTMLURL())
+		}
+	}
+	if !strings.Contains(event.GetComment().GetBody(), stale.Marker) {
+		ev.Rule = "stale"
+		c.unmarkStale(ctx, client, ev)
+	}
+	return nil
+}
+
+func getReactions(client *github.Client) commands.Reactions {
+	return client.Reactions
+}
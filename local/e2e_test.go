@@ -0,0 +1,239 @@
+package local
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/projects"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/fakegithub"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
+)
+
+// setenv sets the environment variable key to value, and returns a func that
+// restores the previous value.
+func setenv(key, value string) func() {
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestEndToEnd_IssuesEvent(t *testing.T) {
+	s := fakegithub.NewServer()
+	defer s.Close()
+	s.AddIssue("owner", "repo", &github.Issue{
+		Number: github.Int(1),
+		Labels: []github.Label{newLabel("bug")},
+	})
+
+	defer setenv("GITHUB_API_URL", s.URL)()
+	defer setenv("GITHUB_AUTH_TOKEN", "test")()
+
+	h := &webhook.Handler{
+		WebhookSecret: "secret",
+		IssuesEvent:   NewConfig(0).IssuesEvent,
+	}
+	event := &github.IssuesEvent{
+		Action: github.String("opened"),
+		Issue:  s.Issue("owner", "repo", 1),
+		Repo: &github.Repository{
+			Owner: &github.User{Login: github.String("owner")},
+			Name:  github.String("repo"),
+		},
+	}
+	req, err := fakegithub.NewWebhookRequest("/event_handler", "secret", "issues", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	want := []string{"bug", "review/triage"}
+	if got := s.IssueLabels("owner", "repo", 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels() = %v, want %v", got, want)
+	}
+}
+
+func TestEndToEnd_InstallationRepositoriesEvent(t *testing.T) {
+	s := fakegithub.NewServer()
+	defer s.Close()
+	s.AddRepo("owner", "repo")
+
+	defer setenv("GITHUB_API_URL", s.URL)()
+	defer setenv("GITHUB_AUTH_TOKEN", "test")()
+
+	h := &webhook.Handler{
+		WebhookSecret:                 "secret",
+		InstallationRepositoriesEvent: NewConfig(0).InstallationRepositoriesEvent,
+	}
+	event := &github.InstallationRepositoriesEvent{
+		Action: github.String("added"),
+		RepositoriesAdded: []*github.Repository{
+			{FullName: github.String("owner/repo")},
+		},
+	}
+	req, err := fakegithub.NewWebhookRequest("/event_handler", "secret", "installation_repositories", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	got := []string{}
+	for _, l := range s.Labels("owner", "repo") {
+		got = append(got, l.GetName())
+	}
+	want := []string{"backlog", "closed", "current", "review/triage"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Labels() = %v, want %v", got, want)
+	}
+}
+
+func TestEndToEnd_IssueCommentEvent(t *testing.T) {
+	s := fakegithub.NewServer()
+	defer s.Close()
+	s.AddIssue("owner", "repo", &github.Issue{
+		Number: github.Int(1),
+		Labels: []github.Label{newLabel("backlog")},
+	})
+	s.SetPermission("owner", "repo", "alice", "write")
+	comment := s.AddComment("owner", "repo", 1, &github.IssueComment{Body: github.String("/current\n/assign")})
+
+	defer setenv("GITHUB_API_URL", s.URL)()
+	defer setenv("GITHUB_AUTH_TOKEN", "test")()
+
+	h := &webhook.Handler{
+		WebhookSecret:     "secret",
+		IssueCommentEvent: NewConfig(0).IssueCommentEvent,
+	}
+	event := &github.IssueCommentEvent{
+		Action:  github.String("created"),
+		Issue:   s.Issue("owner", "repo", 1),
+		Comment: comment,
+		Repo: &github.Repository{
+			Owner: &github.User{Login: github.String("owner")},
+			Name:  github.String("repo"),
+		},
+		Sender: &github.User{Login: github.String("alice"), Type: github.String("User")},
+	}
+	req, err := fakegithub.NewWebhookRequest("/event_handler", "secret", "issue_comment", event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	want := []string{"current"}
+	if got := s.IssueLabels("owner", "repo", 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels() = %v, want %v", got, want)
+	}
+	if got := s.Issue("owner", "repo", 1).Assignees; len(got) != 1 || got[0].GetLogin() != "alice" {
+		t.Errorf("Assignees = %v, want alice", got)
+	}
+	if got := s.Reactions("owner", "repo", comment.GetID()); !reflect.DeepEqual(got, []string{"+1"}) {
+		t.Errorf("Reactions() = %q, want +1", got)
+	}
+}
+
+func TestEndToEnd_ProjectCardEvent(t *testing.T) {
+	s := fakegithub.NewServer()
+	defer s.Close()
+	s.AddIssue("owner", "repo", &github.Issue{
+		ID:     github.Int64(1),
+		Number: github.Int(1),
+		Labels: []github.Label{newLabel("backlog")},
+	})
+	p := s.AddProject("Backlog", "Current")
+	backlog, current := p.Columns[0], p.Columns[1]
+	client := s.Client()
+	ctx := context.Background()
+	card, _, err := client.Projects.CreateProjectCard(ctx, backlog.GetID(),
+		&github.ProjectCardOptions{ContentID: 1, ContentType: "Issue"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Projects.MoveProjectCard(ctx, card.GetID(),
+		&github.ProjectCardMoveOptions{Position: "top", ColumnID: current.GetID()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer setenv("GITHUB_API_URL", s.URL)()
+	defer setenv("GITHUB_AUTH_TOKEN", "test")()
+
+	config := NewConfig(0)
+	config.Projects = &projects.Config{
+		Project: p.ID,
+		Columns: []projects.Column{
+			{Name: "Backlog", Label: "backlog"},
+			{Name: "Current", Label: "current"},
+		},
+	}
+	h := &webhook.Handler{
+		WebhookSecret:    "secret",
+		IssuesEvent:      config.IssuesEvent,
+		ProjectCardEvent: config.ProjectCardEvent,
+	}
+	send := func(name string, event interface{}) {
+		req, err := fakegithub.NewWebhookRequest("/event_handler", "secret", name, event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("ServeHTTP() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+	}
+	repo := &github.Repository{
+		Owner: &github.User{Login: github.String("owner")},
+		Name:  github.String("repo"),
+	}
+	send("project_card", &github.ProjectCardEvent{
+		Action: github.String("moved"),
+		ProjectCard: &github.ProjectCard{
+			ID:         card.ID,
+			ColumnID:   current.ID,
+			ContentURL: card.ContentURL,
+		},
+		Repo: repo,
+	})
+	want := []string{"current"}
+	if got := s.IssueLabels("owner", "repo", 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels() = %v, want %v", got, want)
+	}
+
+	// The label change causes a "labeled" event, which leaves the board as is.
+	before := len(s.Requests())
+	send("issues", &github.IssuesEvent{
+		Action: github.String("labeled"),
+		Label:  &github.Label{Name: github.String("current")},
+		Issue:  s.Issue("owner", "repo", 1),
+		Repo:   repo,
+	})
+	for _, r := range s.Requests()[before:] {
+		if strings.HasPrefix(r, "POST /projects/") {
+			t.Errorf("labeled event sent %q, want no board changes", r)
+		}
+	}
+	if got := s.Cards(current.GetID()); len(got) != 1 {
+		t.Errorf("Cards(Current) = %v, want the card", got)
+	}
+}
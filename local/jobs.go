@@ -0,0 +1,207 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+)
+
+const (
+	// triageMarker identifies triage SLA comments, so each issue is pinged at
+	// most once per triage.
+	triageMarker = "<!-- triage-sla -->"
+
+	// DefaultTriageComment is the default comment added to issues waiting for
+	// triage longer than the SLA.
+	DefaultTriageComment = "This issue has been waiting for triage for more than %s. " +
+		"Please label it `backlog` or `current`."
+)
+
+// RolloverJob updates the iteration labels of every open issue labeled
+// "current" in every repository of every installation. Issues already
+// labeled for the current iteration are unchanged, so the job may run as often
+// as needed, e.g. daily for calendars that do not start on Mondays.
+func (c *Config) RolloverJob(ctx context.Context) error {
+	return c.forEachRepo(ctx, c.rolloverRepo)
+}
+
+// TriageJob comments on every open issue that has been labeled "review/triage"
+// for longer than TriageSLA. TriageJob does nothing if TriageSLA is zero.
+func (c *Config) TriageJob(ctx context.Context) error {
+	if c.TriageSLA <= 0 {
+		return nil
+	}
+	return c.forEachRepo(ctx, c.triageRepo)
+}
+
+// DigestJob emails the issue activity saved since the last digest. DigestJob
+// does nothing if Digest is nil.
+func (c *Config) DigestJob(ctx context.Context) error {
+	if c.Digest == nil {
+		return nil
+	}
+	return c.Digest.Send()
+}
+
+// forEachRepo calls fn for every repository of every installation of the
+// Github App. Errors are logged and the last error is returned.
+func (c *Config) forEachRepo(
+	ctx context.Context, fn func(context.Context, *github.Client, *github.Repository) error) error {
+	apps := githubx.NewJWTClientFromEnv()
+	if apps == nil {
+		return ErrNewClient
+	}
+	return githubx.ForEachInstallation(ctx, apps,
+		func(ctx context.Context, client *github.Client, install *github.Installation) error {
+			repos, err := githubx.ListInstallationRepos(ctx, client)
+			if err != nil {
+				return err
+			}
+			var lastErr error
+			for _, repo := range repos {
+				if err := fn(ctx, client, repo); err != nil {
+					log.Println(repo.GetFullName()+":", err)
+					lastErr = err
+				}
+			}
+			return lastErr
+		})
+}
+
+func (c *Config) rolloverRepo(ctx context.Context, client *github.Client, repo *github.Repository) error {
+	engine := c.rulesFor(ctx, client, repo)
+	return c.forEachIssue(ctx, client, repo, c.CurrentLabel, false, func(ev *issues.Event) error {
+		add, remove, err := engine.Rollover(ev.Labels())
+		if err != nil {
+			return err
+		}
+		ev.Rule = "rollover"
+		result, err := ev.ReconcileLabels(ctx, add, remove)
+		if err != nil {
+			return err
+		}
+		if result.Changed() {
+			log.Printf("rollover: %s added %q removed %q", ev.GetIssue().GetHTMLURL(), result.Added, result.Removed)
+		}
+		return nil
+	})
+}
+
+func (c *Config) triageRepo(ctx context.Context, client *github.Client, repo *github.Repository) error {
+	now := time.Now()
+	return c.forEachIssue(ctx, client, repo, c.TriageLabel, false, func(ev *issues.Event) error {
+		owner, name, number := repo.GetOwner().GetLogin(), repo.GetName(), ev.GetIssue().GetNumber()
+		since, err := labeledAt(ctx, client, owner, name, number, c.TriageLabel)
+		if err != nil {
+			return err
+		}
+		if since.IsZero() {
+			since = ev.GetIssue().GetCreatedAt()
+		}
+		if now.Sub(since) < c.TriageSLA {
+			return nil
+		}
+		pinged, err := commentedSince(ctx, client, owner, name, number, since)
+		if err != nil || pinged {
+			return err
+		}
+		ev.Rule = "triage-sla"
+		body := c.TriageComment
+		if body == "" {
+			body = DefaultTriageComment
+		}
+		if strings.Contains(body, "%s") {
+			body = fmt.Sprintf(body, c.TriageSLA)
+		}
+		_, _, err = ev.CreateComment(ctx, body+"\n"+triageMarker)
+		return err
+	})
+}
+
+// forEachIssue calls fn with an Event for every open issue in repo with the
+// given label, or every open issue if label is empty. Pull requests are
+// skipped unless pulls is true. fn stops at the first error.
+func (c *Config) forEachIssue(
+	ctx context.Context, client *github.Client, repo *github.Repository,
+	label string, pulls bool, fn func(*issues.Event) error) error {
+	opt := &github.IssueListByRepoOptions{
+		State:       "open",
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	if label != "" {
+		opt.Labels = []string{label}
+	}
+	for {
+		list, resp, err := client.Issues.ListByRepo(ctx, repo.GetOwner().GetLogin(), repo.GetName(), opt)
+		if err != nil {
+			return err
+		}
+		for _, issue := range list {
+			if issue.IsPullRequest() && !pulls {
+				continue
+			}
+			ev := issues.NewEvent(c.getIface(client), &github.IssuesEvent{Issue: issue, Repo: repo})
+			ev.Recorder = c.Audit
+			if err := fn(ev); err != nil {
+				return err
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// labeledAt returns when the label was last added to the issue, or the zero
+// time if the issue events do not include it.
+func labeledAt(ctx context.Context, client *github.Client, owner, repo string, number int, label string) (time.Time, error) {
+	var last time.Time
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		events, resp, err := client.Issues.ListIssueEvents(ctx, owner, repo, number, opt)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for _, e := range events {
+			if e.GetEvent() == "labeled" && strings.EqualFold(e.GetLabel().GetName(), label) &&
+				e.GetCreatedAt().After(last) {
+				last = e.GetCreatedAt()
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return last, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// commentedSince reports whether a triage SLA comment was added to the issue
+// after the given time.
+func commentedSince(ctx context.Context, client *github.Client, owner, repo string, number int, since time.Time) (bool, error) {
+	opt := &github.IssueListCommentsOptions{
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	for {
+		comments, resp, err := client.Issues.ListComments(ctx, owner, repo, number, opt)
+		if err != nil {
+			return false, err
+		}
+		for _, c := range comments {
+			if strings.Contains(c.GetBody(), triageMarker) && !c.GetCreatedAt().Before(since) {
+				return true, nil
+			}
+		}
+		if resp == nil || resp.NextPage == 0 {
+			return false, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
@@ -0,0 +1,159 @@
+package local
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/fakegithub"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+	"github.com/stephen-soltesz/github-webhook-poc/stale"
+)
+
+func setupJobServer(t *testing.T) (*fakegithub.Server, func()) {
+	s := fakegithub.NewServer()
+	s.AddInstallation(7, "owner/repo")
+	os.Setenv("GITHUB_API_URL", s.URL)
+	os.Setenv("GITHUB_PRIVATE_KEY", "../githubx/testdata/unused_insecure_rsa_key.pem")
+	os.Setenv("GITHUB_APP_ID", "1")
+	return s, func() {
+		s.Close()
+		os.Unsetenv("GITHUB_API_URL")
+		os.Unsetenv("GITHUB_PRIVATE_KEY")
+		os.Unsetenv("GITHUB_APP_ID")
+	}
+}
+
+func TestConfig_RolloverJob(t *testing.T) {
+	s, done := setupJobServer(t)
+	defer done()
+	s.AddIssue("owner", "repo", &github.Issue{
+		Number: github.Int(1),
+		Labels: []github.Label{newLabel("current"), newLabel("Week 4"), newLabel("Week 6"), newLabel("2019")},
+	})
+	s.AddIssue("owner", "repo", &github.Issue{
+		Number: github.Int(2),
+		Labels: []github.Label{newLabel("backlog"), newLabel("Week 6")},
+	})
+
+	c := NewConfig(0)
+	c.Rules.Now = func() time.Time { return time.Date(2019, 02, 12, 0, 0, 0, 0, time.UTC) }
+	if err := c.RolloverJob(context.Background()); err != nil {
+		t.Fatalf("RolloverJob() error = %v", err)
+	}
+	want := []string{"2019", "Week 7", "current"}
+	if got := s.IssueLabels("owner", "repo", 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels(1) = %v, want %v", got, want)
+	}
+	want = []string{"Week 6", "backlog"}
+	if got := s.IssueLabels("owner", "repo", 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels(2) = %v, want %v", got, want)
+	}
+}
+
+func TestConfig_TriageJob(t *testing.T) {
+	s, done := setupJobServer(t)
+	defer done()
+	old := time.Now().Add(-72 * time.Hour)
+	s.AddIssue("owner", "repo", &github.Issue{
+		Number:    github.Int(1),
+		CreatedAt: &old,
+		Labels:    []github.Label{newLabel("review/triage")},
+	})
+	// Issue 2 is old, but was labeled for triage recently.
+	s.AddIssue("owner", "repo", &github.Issue{
+		Number:    github.Int(2),
+		CreatedAt: &old,
+		Labels:    []github.Label{newLabel("review/triage")},
+	})
+	recent := time.Now().Add(-time.Hour)
+	s.AddIssueEvent("owner", "repo", 2, &github.IssueEvent{
+		Event:     github.String("labeled"),
+		Label:     &github.Label{Name: github.String("review/triage")},
+		CreatedAt: &recent,
+	})
+
+	c := NewConfig(0)
+	if err := c.TriageJob(context.Background()); err != nil {
+		t.Fatalf("TriageJob() error = %v", err)
+	}
+	if got := s.Comments("owner", "repo", 1); len(got) != 0 {
+		t.Errorf("TriageJob() with zero SLA commented %q", got)
+	}
+
+	c.TriageSLA = 48 * time.Hour
+	for i := 0; i < 2; i++ {
+		if err := c.TriageJob(context.Background()); err != nil {
+			t.Fatalf("TriageJob() error = %v", err)
+		}
+	}
+	got := s.Comments("owner", "repo", 1)
+	if len(got) != 1 || !strings.Contains(got[0], "48h0m0s") || !strings.Contains(got[0], triageMarker) {
+		t.Errorf("Comments(1) = %q, want one SLA comment", got)
+	}
+	if got := s.Comments("owner", "repo", 2); len(got) != 0 {
+		t.Errorf("Comments(2) = %q, want none", got)
+	}
+}
+
+func TestConfig_StaleJob(t *testing.T) {
+	s, done := setupJobServer(t)
+	defer done()
+	old := time.Now().Add(-90 * 24 * time.Hour)
+	s.AddIssue("owner", "repo", &github.Issue{Number: github.Int(1), State: github.String("open"), UpdatedAt: &old})
+	s.AddIssue("owner", "repo", &github.Issue{
+		Number:    github.Int(2),
+		State:     github.String("open"),
+		UpdatedAt: &old,
+		Labels:    []github.Label{newLabel("pinned")},
+	})
+
+	c := NewConfig(0)
+	if err := c.StaleJob(context.Background()); err != nil {
+		t.Fatalf("StaleJob() error = %v", err)
+	}
+	if got := s.IssueLabels("owner", "repo", 1); len(got) != 0 {
+		t.Errorf("StaleJob() without config labeled %v", got)
+	}
+
+	c.Stale = &stale.Config{}
+	if err := configfile.Parse([]byte("days_until_stale: 60\nexempt_labels: [pinned]\n"), c.Stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.StaleJob(context.Background()); err != nil {
+		t.Fatalf("StaleJob() error = %v", err)
+	}
+	if got, want := s.IssueLabels("owner", "repo", 1), []string{"stale"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels(1) = %v, want %v", got, want)
+	}
+	if got, want := s.IssueLabels("owner", "repo", 2), []string{"pinned"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels(2) = %v, want %v", got, want)
+	}
+
+	// A comment from the stale workflow itself is not activity.
+	event := &github.IssueCommentEvent{
+		Action:       github.String("created"),
+		Issue:        s.Issue("owner", "repo", 1),
+		Comment:      &github.IssueComment{Body: github.String(stale.DefaultComment + "\n" + stale.Marker)},
+		Repo:         &github.Repository{Name: github.String("repo"), Owner: &github.User{Login: github.String("owner")}},
+		Sender:       &github.User{Login: github.String("someone"), Type: github.String("User")},
+		Installation: &github.Installation{ID: github.Int64(7)},
+	}
+	if err := c.IssueCommentEvent(event); err != nil {
+		t.Fatalf("IssueCommentEvent() error = %v", err)
+	}
+	if got, want := s.IssueLabels("owner", "repo", 1), []string{"stale"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels(1) after marker comment = %v, want %v", got, want)
+	}
+	event.Comment.Body = github.String("still relevant")
+	if err := c.IssueCommentEvent(event); err != nil {
+		t.Fatalf("IssueCommentEvent() error = %v", err)
+	}
+	if got := s.IssueLabels("owner", "repo", 1); len(got) != 0 {
+		t.Errorf("IssueLabels(1) after comment = %v, want none", got)
+	}
+}
@@ -0,0 +1,68 @@
+package local
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+	"github.com/stephen-soltesz/github-webhook-poc/labels"
+)
+
+func getLabels(client *github.Client) iface.Labels {
+	return iface.NewLabels(client.Issues)
+}
+
+// syncRepos syncs the label catalog to each of the given installation
+// repositories. Errors are logged, and the last error is returned after all
+// repositories are tried.
+func (c *Config) syncRepos(installationID int64, repos []*github.Repository) error {
+	if c.Labels == nil || len(repos) == 0 {
+		return nil
+	}
+	client := githubx.NewClient(installationID)
+	if client == nil {
+		return ErrNewClient
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var lastErr error
+	for _, repo := range repos {
+		owner, name, err := issues.SplitFullName(repo.GetFullName())
+		if err != nil {
+			log.Println("labels:", err)
+			lastErr = err
+			continue
+		}
+		if _, err := c.SyncLabels(ctx, client, owner, name); err != nil {
+			log.Println("labels:", repo.GetFullName(), err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// SyncLabels syncs the label catalog for the owner repo, using the
+// per-repository catalog when one is configured.
+func (c *Config) SyncLabels(ctx context.Context, client *github.Client, owner, name string) (*labels.Result, error) {
+	catalog := c.Labels
+	repo := &github.Repository{
+		Owner: &github.User{Login: &owner},
+		Name:  &name,
+	}
+	if rc := c.repoConfig(ctx, client, repo); rc != nil && rc.Labels != nil {
+		catalog = rc.Labels
+	}
+	if catalog == nil {
+		return &labels.Result{}, nil
+	}
+	get := c.getLabels
+	if get == nil {
+		get = getLabels
+	}
+	return labels.Sync(ctx, get(client), owner, name, catalog)
+}
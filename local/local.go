@@ -4,10 +4,29 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
+	"github.com/stephen-soltesz/github-webhook-poc/audit"
+	"github.com/stephen-soltesz/github-webhook-poc/digest"
+	"github.com/stephen-soltesz/github-webhook-poc/events/checks"
+	checksiface "github.com/stephen-soltesz/github-webhook-poc/events/checks/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/commands"
 	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/projects"
+	projectsiface "github.com/stephen-soltesz/github-webhook-poc/events/projects/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/pulls"
+	pullsiface "github.com/stephen-soltesz/github-webhook-poc/events/pulls/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/releases"
+	releasesiface "github.com/stephen-soltesz/github-webhook-poc/events/releases/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/statuses"
+	statusesiface "github.com/stephen-soltesz/github-webhook-poc/events/statuses/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
+	"github.com/stephen-soltesz/github-webhook-poc/labels"
+	"github.com/stephen-soltesz/github-webhook-poc/notify"
+	"github.com/stephen-soltesz/github-webhook-poc/repoconfig"
+	"github.com/stephen-soltesz/github-webhook-poc/rules"
+	"github.com/stephen-soltesz/github-webhook-poc/sla"
+	"github.com/stephen-soltesz/github-webhook-poc/stale"
 
 	"github.com/stephen-soltesz/pretty"
 
@@ -27,13 +46,105 @@ type Config struct {
 	// Delay is
 	Delay time.Duration
 
-	getIface func(client *github.Client) iface.Issues
+	// Audit, if not nil, records every change made to issues.
+	Audit audit.Recorder
+
+	// Rules are applied to every issues event. If nil, the default rules are used.
+	Rules *rules.Engine
+
+	// Labels, if not nil, is synced to repositories when the App is installed.
+	Labels *labels.Catalog
+
+	// Repos, if not nil, loads per-repository configuration that overrides
+	// the settings above.
+	Repos *repoconfig.Loader
+
+	// CurrentLabel marks issues scheduled for the current iteration.
+	CurrentLabel string
+	// BacklogLabel marks issues in the backlog.
+	BacklogLabel string
+	// TriageLabel marks issues waiting for triage.
+	TriageLabel string
+	// TriageSLA is how long an issue may wait for triage before TriageJob
+	// comments on it. Zero disables the comment.
+	TriageSLA time.Duration
+	// TriageComment is the comment added by TriageJob. A "%s" is replaced by
+	// TriageSLA. Defaults to DefaultTriageComment.
+	TriageComment string
+	// SLA, if not nil, records the triage state of every issue for the triage
+	// SLA report.
+	SLA *sla.Tracker
+
+	// Pulls, if not nil, labels pull requests and requests reviewers. A
+	// per-repository pulls section overrides it.
+	Pulls *pulls.Config
+
+	// Checks, if not nil, gates pull requests with check runs. Check runs
+	// require Github App authentication.
+	Checks *checks.Runner
+
+	// Statuses, if not nil, sets commit statuses on pushes and pull requests.
+	// A per-repository statuses section overrides it.
+	Statuses *statuses.Config
+	// StatusURL is the address of the page explaining commit statuses. Empty
+	// omits the links of statuses.
+	StatusURL string
+
+	// Commands, if not nil, runs slash commands found in issue comments.
+	// NewConfig builds the default commands with CurrentLabel and
+	// BacklogLabel; rebuild them if either label changes.
+	Commands *commands.Registry
+
+	// Stale, if not nil, enables the stale workflow in every repository. A
+	// per-repository stale section overrides it.
+	Stale *stale.Config
+
+	// Digest, if not nil, saves issue activity for the DigestJob email.
+	Digest *digest.Digest
+
+	// Releases, if not nil, writes the notes of releases. A per-repository
+	// releases section overrides it.
+	Releases *releases.Config
+
+	// Notify, if not nil, posts chat messages for selected events.
+	Notify *notify.Notifier
+
+	// Projects, if not nil, syncs issue labels with the columns of a project
+	// board. A per-repository projects section overrides it.
+	Projects *projects.Config
+
+	getIface      func(client *github.Client) iface.Issues
+	getLabels     func(client *github.Client) iface.Labels
+	getPulls      func(client *github.Client) pullsiface.Pulls
+	getChecks     func(client *github.Client) checksiface.Checks
+	getStatuses   func(client *github.Client) statusesiface.Statuses
+	getReleases   func(client *github.Client) releasesiface.Releases
+	getProjects   func(client *github.Client) projectsiface.Projects
+	getProjectsV2 func(client *github.Client) projectsiface.ProjectsV2
 }
 
 // NewConfig creates a new config instantce.
 func NewConfig(delay time.Duration) *Config {
 	// Initialize the new config instance with a default getIface function.
-	return &Config{Delay: delay, getIface: getIface}
+	c := &Config{
+		Delay:         delay,
+		Rules:         rules.NewEngine(rules.Default()),
+		Labels:        labels.Default(),
+		Pulls:         pulls.Default(),
+		CurrentLabel:  "current",
+		BacklogLabel:  "backlog",
+		TriageLabel:   "review/triage",
+		getIface:      getIface,
+		getLabels:     getLabels,
+		getPulls:      getPulls,
+		getChecks:     getChecks,
+		getStatuses:   getStatuses,
+		getReleases:   getReleases,
+		getProjects:   getProjects,
+		getProjectsV2: getProjectsV2,
+	}
+	c.Commands = commands.Default(c.CurrentLabel, c.BacklogLabel)
+	return c
 }
 
 // Client collects local data needed for these operations.
@@ -52,98 +163,123 @@ func getIface(client *github.Client) iface.Issues {
 	return iface.NewIssues(client.Issues)
 }
 
-func genWeekLabels(now time.Time) []string {
-	year, week := now.ISOWeek()
-	weekLabel := fmt.Sprintf("Week %d", week)
-	yearLabel := fmt.Sprintf("%d", year)
-	return []string{weekLabel, yearLabel}
-}
-
-// IssuesEvent .
+// IssuesEvent applies the configured rules to the event issue.
 func (c *Config) IssuesEvent(event *github.IssuesEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	log.Println("IssuesEvent:", event.GetAction(), event.GetIssue().GetHTMLURL())
+	pretty.Print(event)
+	c.observeIssue(ctx, event)
+
 	client := githubx.NewClient(getSafeID(event))
 	if client == nil {
 		return ErrNewClient
 	}
 	ev := issues.NewEvent(c.getIface(client), event)
-
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-	defer cancel()
+	ev.Recorder = c.Audit
+	ev.DeliveryID = webhook.DeliveryID(event)
 
 	// Lose the race for loading page load after "Submit new issue"
 	// so that the new label is visible to user.
 	// time.Sleep(c.Delay)
 
-	var err error
-	var resp *github.Response
-	var issue *github.Issue
-	var labels []*github.Label
-
-	log.Println("IssuesEvent:", ev.GetAction(), ev.GetIssue().GetHTMLURL())
-	switch {
-	case ev.GetAction() == "opened" || ev.GetAction() == "reopened":
-		pretty.Print(event)
-		labels, resp, err = ev.AddIssueLabels(ctx, []string{"review/triage"})
-	case ev.GetAction() == "closed":
-		pretty.Print(event)
-		resp, err = ev.RemoveIssueLabels(ctx, []string{"review/triage"})
-	case ev.GetAction() == "labeled":
-		// Note: the issue labels always include the label triggering the event.
-		fmt.Println("label")
-		pretty.Print(event)
-		current := []string{}
-		for _, label := range event.GetIssue().Labels {
-			current = append(current, label.GetName())
+	if ev.GetAction() == "edited" && ev.GetSender().GetType() != "Bot" {
+		c.unmarkStale(ctx, client, ev)
+	}
+	err := c.rulesFor(ctx, client, event.GetRepo()).Apply(ctx, ev)
+	c.syncProject(ctx, client, ev)
+	if err != nil {
+		log.Println("IssuesEvent: error:", err)
+		return nil
+	}
+	log.Println("IssuesEvent: okay:", ev.Labels())
+	return nil
+}
+
+// observeIssue passes the event to the SLA tracker, chat notifications and
+// digest. They need no Github client, so they see every event, whatever the
+// outcome of the rules.
+func (c *Config) observeIssue(ctx context.Context, event *github.IssuesEvent) {
+	if c.SLA != nil {
+		if err := c.SLA.IssuesEvent(event); err != nil {
+			log.Println("IssuesEvent: sla:", err)
 		}
-		eventLabel := event.GetLabel().GetName()
-		fmt.Println("Event label:", eventLabel)
-		fmt.Println("Current labels:", current)
-		switch strings.ToLower(eventLabel) {
-		case "backlog":
-			resp, err = ev.RemoveIssueLabels(ctx, []string{"review/triage", "current", "closed"})
-		case "current":
-			_, resp, err = ev.AddIssueLabels(ctx, genWeekLabels(time.Now()))
-			if err == nil && len(current) != 0 {
-				resp, err = ev.RemoveIssueLabels(ctx, []string{"review/triage", "backlog", "closed"})
-			}
-			// current = append(current, genSprintLabels(time.Now())...)
-			// current = slice.FilterStrings(current, []string{"review/triage", "backlog", "closed"})
-			// issue, resp, err = ev.SetIssueLabels(ctx, current)
-		case "closed":
-			issue, resp, err = ev.CloseIssue(ctx, nil)
+	}
+	if c.Notify != nil {
+		c.Notify.IssuesEvent(ctx, event)
+	}
+	if c.Digest != nil {
+		if err := c.Digest.IssuesEvent(event); err != nil {
+			log.Println("IssuesEvent: digest:", err)
 		}
-	case ev.GetAction() == "unlabeled":
-		pretty.Print(event)
-		fmt.Println("unlabel")
-	default:
-		log.Println("IssuesEvent: ignoring unsupported action:", ev.GetAction())
 	}
+}
+
+// rules returns the configured rules engine, or the default rules.
+func (c *Config) rules() *rules.Engine {
+	if c.Rules == nil {
+		c.Rules = rules.NewEngine(rules.Default())
+	}
+	return c.Rules
+}
+
+// repoConfig returns the per-repository configuration, or nil if there is none
+// or it fails to load.
+func (c *Config) repoConfig(ctx context.Context, client *github.Client, repo *github.Repository) *repoconfig.Config {
+	if c.Repos == nil {
+		return nil
+	}
+	rc, err := c.Repos.Load(ctx, client, repo.GetOwner().GetLogin(), repo.GetName())
 	if err != nil {
-		log.Println("IssuesEvent: error:       ", resp, err)
+		log.Println("repoconfig:", err)
+		return nil
+	}
+	return rc
+}
+
+// rulesFor returns the rules engine for the given repository.
+func (c *Config) rulesFor(ctx context.Context, client *github.Client, repo *github.Repository) *rules.Engine {
+	e := c.rules()
+	rc := c.repoConfig(ctx, client, repo)
+	if r := rc.RuleConfig(); r != nil {
+		e = e.WithRules(r)
 	}
-	if labels != nil {
-		log.Println("IssuesEvent: okay: ", resp, labels)
+	if rc != nil && rc.Iteration != nil {
+		e = e.WithCalendar(rc.Iteration)
 	}
-	if issue != nil {
-		labels := ""
-		for _, currentLabel := range issue.Labels {
-			labels += currentLabel.GetName() + " "
+	return e
+}
+
+// PushEvent invalidates cached per-repository configuration when it changes,
+// and sets the commit statuses of the pushed commits.
+func (c *Config) PushEvent(event *github.PushEvent) error {
+	if c.Repos != nil {
+		if err := c.Repos.PushEvent(event); err != nil {
+			return err
 		}
-		log.Println("IssuesEvent: okay: ", resp, labels)
 	}
-	return nil
+	return c.pushStatuses(event)
 }
 
 // InstallationEvent handles events when an application is installed for the
-// first time.
-func InstallationEvent(event *github.InstallationEvent) error {
+// first time. On "created", the label catalog is synced to every repository in
+// the new installation.
+func (c *Config) InstallationEvent(event *github.InstallationEvent) error {
 	pretty.Print(event)
-	return nil
+	if event.GetAction() != "created" {
+		return nil
+	}
+	return c.syncRepos(event.GetInstallation().GetID(), event.Repositories)
 }
 
 // InstallationRepositoriesEvent handles events when repositories are added or
-// removed from a particular application installation.
-func InstallationRepositoriesEvent(event *github.InstallationRepositoriesEvent) error {
+// removed from a particular application installation. On "added", the label
+// catalog is synced to every added repository.
+func (c *Config) InstallationRepositoriesEvent(event *github.InstallationRepositoriesEvent) error {
 	pretty.Print(event)
-	return nil
+	if event.GetAction() != "added" {
+		return nil
+	}
+	return c.syncRepos(event.GetInstallation().GetID(), event.RepositoriesAdded)
 }
@@ -1,44 +1,20 @@
 package local
 
 import (
-	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
-	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/google/go-github/github"
 	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface/ifacetest"
+	"github.com/stephen-soltesz/github-webhook-poc/notify"
 )
 
-type fakeIssues struct {
-	*github.Issue
-	*github.Response
-	Error  error
-	labels []*github.Label
-}
-
-func (f *fakeIssues) Edit(
-	ctx context.Context, owner string, repo string, number int,
-	issue *github.IssueRequest) (*github.Issue, *github.Response, error) {
-	return f.Issue, f.Response, f.Error
-}
-
-// AddLabelsToIssue adds the given lables to the issue number in the owner repo.
-func (f *fakeIssues) AddLabelsToIssue(
-	ctx context.Context, owner string, repo string, number int,
-	labels []string) ([]*github.Label, *github.Response, error) {
-	return f.labels, f.Response, f.Error
-}
-
-// RemoveLabelForIssue removes the given label from the repo issue.
-func (f *fakeIssues) RemoveLabelForIssue(
-	ctx context.Context, owner string, repo string, number int,
-	label string) (*github.Response, error) {
-	return f.Response, f.Error
-}
-
 func newLabel(label string) github.Label {
 	return github.Label{
 		Name: &label,
@@ -150,18 +126,18 @@ func TestConfig_IssuesEvent(t *testing.T) {
 	for _, tt := range tests {
 		getIface := func(client *github.Client) iface.Issues {
 			if tt.wantEventErr {
-				return &fakeIssues{
+				return &ifacetest.Issues{
 					Issue: tt.issue,
-					Error: fmt.Errorf("fake error"),
+					Err:   fmt.Errorf("fake error"),
 				}
 			} else {
 				var labels []*github.Label
 				for i := range tt.issue.Labels {
 					labels = append(labels, &tt.issue.Labels[i])
 				}
-				return &fakeIssues{
+				return &ifacetest.Issues{
 					Issue:  tt.issue,
-					labels: labels,
+					Labels: labels,
 				}
 			}
 		}
@@ -185,14 +161,52 @@ func TestConfig_IssuesEvent(t *testing.T) {
 	}
 }
 
+func TestConfig_IssuesEventNotify(t *testing.T) {
+	var mu sync.Mutex
+	posts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		posts++
+	}))
+	defer srv.Close()
+	nc := &notify.Config{Routes: []notify.Route{{Name: "triage", On: []string{notify.Triage}, URL: srv.URL}}}
+	if err := nc.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	triage := newLabel("review/triage")
+	event := &github.IssuesEvent{
+		Action: newString("labeled"),
+		Label:  &triage,
+		Issue:  &github.Issue{Labels: []github.Label{triage}},
+	}
+	c := &Config{
+		Notify: notify.New(nc),
+		getIface: func(client *github.Client) iface.Issues {
+			return &ifacetest.Issues{Issue: event.Issue, Err: fmt.Errorf("fake error")}
+		},
+	}
+
+	// Chat messages are sent even if the rules fail, or there is no client.
+	os.Setenv("GITHUB_AUTH_TOKEN", "test")
+	c.IssuesEvent(event)
+	os.Unsetenv("GITHUB_AUTH_TOKEN")
+	if err := c.IssuesEvent(event); err != ErrNewClient {
+		t.Errorf("Config.IssuesEvent() error = %v, want %v", err, ErrNewClient)
+	}
+	if posts != 2 {
+		t.Errorf("Config.IssuesEvent() sent %d messages, want 2", posts)
+	}
+}
+
 func TestInstallationEvent(t *testing.T) {
 	event := &github.InstallationEvent{}
-	_ = InstallationEvent(event)
+	_ = NewConfig(0).InstallationEvent(event)
 }
 
 func TestInstallationRepositoriesEvent(t *testing.T) {
 	event := &github.InstallationRepositoriesEvent{}
-	_ = InstallationRepositoriesEvent(event)
+	_ = NewConfig(0).InstallationRepositoriesEvent(event)
 }
 
 func newInt64(i int64) *int64 {
@@ -211,39 +225,3 @@ func Test_getSafeID(t *testing.T) {
 func Test_getIface(t *testing.T) {
 	_ = getIface(&github.Client{})
 }
-
-func Test_genSprintLabels(t *testing.T) {
-	tests := []struct {
-		name string
-		now  time.Time
-		want []string
-	}{
-		{
-			name: "week 1 2019",
-			now:  time.Date(2019, 01, 1, 0, 0, 0, 0, time.UTC),
-			want: []string{"Week 1", "2019"},
-		},
-		{
-			name: "week 7 2019",
-			now:  time.Date(2019, 02, 17, 0, 0, 0, 0, time.UTC),
-			want: []string{"Week 7", "2019"},
-		},
-		{
-			name: "sprint 26 2019",
-			now:  time.Date(2019, 12, 29, 0, 0, 0, 0, time.UTC),
-			want: []string{"Week 52", "2019"},
-		},
-		{
-			name: "week 1 2020",
-			now:  time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC),
-			want: []string{"Week 1", "2020"},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			if got := genWeekLabels(tt.now); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("genWeekLabels() = %v, want %v", got, tt.want)
-			}
-		})
-	}
-}
@@ -1,37 +1,187 @@
 package local
 
 import (
+	"context"
+	"log"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/events/projects"
+	projectsiface "github.com/stephen-soltesz/github-webhook-poc/events/projects/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/projectsv2"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
 )
 
+// mux serializes project board changes, so a card event and the label event
+// it causes observe each other's results.
 var mux = sync.Mutex{}
 
-/*
-// ProjectCardEvent prints a card event.
-func ProjectCardEvent(event *github.ProjectCardEvent) error {
+func getProjects(client *github.Client) projectsiface.Projects {
+	return projectsiface.NewProjects(client.Projects)
+}
+
+// getProjectsV2 returns a Projects v2 client. In dry-run mode the client
+// transport only sends GraphQL queries.
+func getProjectsV2(client *github.Client) projectsiface.ProjectsV2 {
+	return projectsv2.NewClient(client)
+}
+
+// projectsFor returns the project board configuration for the given
+// repository, or nil if project sync is disabled.
+func (c *Config) projectsFor(ctx context.Context, client *github.Client, repo *github.Repository) *projects.Config {
+	if rc := c.repoConfig(ctx, client, repo); rc != nil && rc.Projects != nil {
+		return rc.Projects
+	}
+	return c.Projects
+}
+
+// ProjectCardEvent labels the issue of a card that was added to or moved
+// between the columns of the configured project.
+func (c *Config) ProjectCardEvent(event *github.ProjectCardEvent) error {
+	switch event.GetAction() {
+	case "created", "moved":
+	default:
+		return nil
+	}
+	owner, name, number, ok := projects.ParseContentURL(event.GetProjectCard().GetContentURL())
+	if !ok {
+		// Notes and cards from other sources have no issue to label.
+		return nil
+	}
+	client := githubx.NewClient(getSafeID(event))
+	if client == nil {
+		return ErrNewClient
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	repo := &github.Repository{
+		Name:  github.String(name),
+		Owner: &github.User{Login: github.String(owner)},
+	}
+	cfg := c.projectsFor(ctx, client, repo)
+	if cfg == nil {
+		return nil
+	}
+	ev := issues.NewEvent(c.getIface(client), &github.IssuesEvent{
+		Issue:        &github.Issue{Number: github.Int(number)},
+		Repo:         repo,
+		Sender:       event.Sender,
+		Installation: event.Installation,
+	})
+	ev.Recorder = c.Audit
+	ev.DeliveryID = webhook.DeliveryID(event)
+	ev.Rule = "projects"
+
 	mux.Lock()
 	defer mux.Unlock()
+	log.Println("ProjectCardEvent:", event.GetAction(), owner, name, number)
+	changed, err := cfg.CardMoved(ctx, projects.NewEvent(c.getProjects(client), event), ev)
+	if err != nil {
+		log.Println("ProjectCardEvent: error:", err)
+		return nil
+	}
+	if changed {
+		log.Println("ProjectCardEvent: okay:", ev.Labels())
+	}
+	return nil
+}
+
+// ProjectV2ItemEvent labels the issue of a Projects v2 item when the item is
+// added to the configured board or its column field changes.
+func (c *Config) ProjectV2ItemEvent(event *projectsv2.ProjectV2ItemEvent) error {
 	switch event.GetAction() {
-	case "moved":
-		event.GetProjectCard().GetColumnID()
-		client := githubx.NewClient(event.GetInstallation().GetID())
-		if client == nil {
-			return ErrNewClient
-		}
-		ev := projects.NewEvent(client, event)
-		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-		defer cancel()
-		col := ev.GetColumn(ctx)
-		if col != nil {
-			pretty.Print(col)
-		}
-		// ev := issues.NewEvent(c.getIface(client), event)
-	}
-	log.Print("project card event:")
-	pretty.Print(event)
+	case "created", "edited", "restored":
+	default:
+		return nil
+	}
+	if event.GetItem().GetContentType() != "Issue" {
+		return nil
+	}
+	client := githubx.NewClient(getSafeID(event))
+	if client == nil {
+		return ErrNewClient
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	mux.Lock()
+	defer mux.Unlock()
+	p := c.getProjectsV2(client)
+	// Read the item after the event, so a stale event applies the current
+	// column.
+	item, err := p.GetItem(ctx, event.GetItem().GetNodeID())
+	if err != nil {
+		log.Println("ProjectV2ItemEvent: error:", err)
+		return nil
+	}
+	parts := strings.SplitN(item.Repository, "/", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	repo := &github.Repository{
+		Name:  github.String(parts[1]),
+		Owner: &github.User{Login: github.String(parts[0])},
+	}
+	cfg := c.projectsFor(ctx, client, repo)
+	if cfg == nil || !cfg.V2() {
+		return nil
+	}
+	ev := issues.NewEvent(c.getIface(client), &github.IssuesEvent{
+		Issue:        &github.Issue{Number: github.Int(item.Number)},
+		Repo:         repo,
+		Sender:       event.Sender,
+		Installation: event.Installation,
+	})
+	ev.Recorder = c.Audit
+	ev.DeliveryID = webhook.DeliveryID(event)
+	ev.Rule = "projects"
+
+	log.Println("ProjectV2ItemEvent:", event.GetAction(), item.Repository, item.Number)
+	changed, err := cfg.ItemChanged(ctx, projects.NewBoardV2(p, cfg), event, item, ev)
+	if err != nil {
+		log.Println("ProjectV2ItemEvent: error:", err)
+		return nil
+	}
+	if changed {
+		log.Println("ProjectV2ItemEvent: okay:", ev.Labels())
+	}
 	return nil
 }
 
+// syncProject moves the card of the event issue to the column of an added
+// label, or adds an opened issue to the board.
+func (c *Config) syncProject(ctx context.Context, client *github.Client, ev *issues.Event) {
+	if ev.GetAction() != "labeled" && ev.GetAction() != "opened" {
+		return
+	}
+	cfg := c.projectsFor(ctx, client, ev.GetRepo())
+	if cfg == nil {
+		return
+	}
+	apply := cfg.IssueOpened
+	if ev.GetAction() == "labeled" {
+		apply = cfg.IssueLabeled
+	}
+	var target projects.Target = projects.NewBoard(c.getProjects(client), cfg.Project)
+	if cfg.V2() {
+		target = projects.NewBoardV2(c.getProjectsV2(client), cfg)
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	ev.Rule = "projects"
+	if changed, err := apply(ctx, target, ev); err != nil {
+		log.Println("projects:", err)
+	} else if changed {
+		log.Println("projects: updated board for", ev.GetIssue().GetHTMLURL())
+	}
+}
+
+/*
 // ProjectColumnEvent prints a column event.
 func ProjectColumnEvent(event *github.ProjectColumnEvent) error {
 	mux.Lock()
@@ -0,0 +1,60 @@
+package local
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/pulls"
+	pullsiface "github.com/stephen-soltesz/github-webhook-poc/events/pulls/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/events/statuses"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
+)
+
+func getPulls(client *github.Client) pullsiface.Pulls {
+	return pullsiface.NewPulls(client)
+}
+
+// PullRequestEvent runs the checks of the event pull request, sets its commit
+// statuses, labels it by changed paths, size and draft state, and requests
+// reviews from code owners.
+func (c *Config) PullRequestEvent(event *github.PullRequestEvent) error {
+	client := githubx.NewClient(getSafeID(event))
+	if client == nil {
+		return ErrNewClient
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if c.Checks != nil {
+		if err := c.Checks.PullRequestEvent(ctx, c.getChecks(client), event); err != nil {
+			log.Println("PullRequestEvent: checks:", err)
+		}
+	}
+	if sc := c.statusesFor(ctx, client, event.GetRepo()); sc != nil {
+		r := statuses.NewReporter(c.getStatuses(client), sc, c.StatusURL)
+		if err := r.PullRequestEvent(ctx, event); err != nil {
+			log.Println("PullRequestEvent: statuses:", err)
+		}
+	}
+	cfg := c.Pulls
+	if rc := c.repoConfig(ctx, client, event.GetRepo()); rc != nil && rc.Pulls != nil {
+		cfg = rc.Pulls
+	}
+	if cfg == nil {
+		return nil
+	}
+	ev := pulls.NewEvent(c.getIface(client), c.getPulls(client), event)
+	ev.Recorder = c.Audit
+	ev.DeliveryID = webhook.DeliveryID(event)
+
+	log.Println("PullRequestEvent:", event.GetAction(), event.GetPullRequest().GetHTMLURL())
+	if err := cfg.Apply(ctx, ev); err != nil {
+		log.Println("PullRequestEvent: error:", err)
+		return nil
+	}
+	log.Println("PullRequestEvent: okay:", ev.Labels())
+	return nil
+}
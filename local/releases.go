@@ -0,0 +1,50 @@
+package local
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/releases"
+	releasesiface "github.com/stephen-soltesz/github-webhook-poc/events/releases/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+)
+
+func getReleases(client *github.Client) releasesiface.Releases {
+	return releasesiface.NewReleases(client)
+}
+
+// releasesFor returns the release notes configuration of the repository, or
+// nil if release notes are disabled.
+func (c *Config) releasesFor(ctx context.Context, client *github.Client, repo *github.Repository) *releases.Config {
+	if rc := c.repoConfig(ctx, client, repo); rc != nil && rc.Releases != nil {
+		return rc.Releases
+	}
+	return c.Releases
+}
+
+// ReleaseEvent posts a chat message when a release is published, and writes
+// the release notes of created and published releases.
+func (c *Config) ReleaseEvent(event *github.ReleaseEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if c.Notify != nil {
+		c.Notify.ReleaseEvent(ctx, event)
+	}
+	if c.Releases == nil && c.Repos == nil {
+		return nil
+	}
+	client := githubx.NewClient(getSafeID(event))
+	if client == nil {
+		return ErrNewClient
+	}
+	cfg := c.releasesFor(ctx, client, event.GetRepo())
+	if cfg == nil {
+		return nil
+	}
+	if err := cfg.ReleaseEvent(ctx, c.getReleases(client), event); err != nil {
+		log.Println("ReleaseEvent: releases:", err)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package local
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/stale"
+)
+
+// StaleJob marks inactive issues and pull requests stale, and closes stale
+// ones that remain inactive, in every repository with a stale configuration.
+func (c *Config) StaleJob(ctx context.Context) error {
+	return c.forEachRepo(ctx, c.staleRepo)
+}
+
+func (c *Config) staleRepo(ctx context.Context, client *github.Client, repo *github.Repository) error {
+	cfg := c.staleFor(ctx, client, repo)
+	if cfg == nil {
+		return nil
+	}
+	now := time.Now()
+	return c.forEachIssue(ctx, client, repo, "", true, func(ev *issues.Event) error {
+		ev.Rule = "stale"
+		action, err := cfg.Check(ctx, ev, now)
+		if action != stale.None {
+			log.Printf("stale: %s: %s", action, ev.GetIssue().GetHTMLURL())
+		}
+		return err
+	})
+}
+
+// staleFor returns the stale configuration for the given repository, or nil
+// if the stale workflow is disabled.
+func (c *Config) staleFor(ctx context.Context, client *github.Client, repo *github.Repository) *stale.Config {
+	if rc := c.repoConfig(ctx, client, repo); rc != nil && rc.Stale != nil {
+		return rc.Stale
+	}
+	return c.Stale
+}
+
+// unmarkStale removes the stale label from the event issue, if the stale
+// workflow is enabled for the repository.
+func (c *Config) unmarkStale(ctx context.Context, client *github.Client, ev *issues.Event) {
+	cfg := c.staleFor(ctx, client, ev.GetRepo())
+	if cfg == nil {
+		return
+	}
+	if removed, err := cfg.Unmark(ctx, ev); err != nil {
+		log.Println("stale: unmark:", err)
+	} else if removed {
+		log.Println("stale: unmarked", ev.GetIssue().GetHTMLURL())
+	}
+}
@@ -0,0 +1,53 @@
+package local
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/events/statuses"
+	statusesiface "github.com/stephen-soltesz/github-webhook-poc/events/statuses/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx"
+)
+
+func getStatuses(client *github.Client) statusesiface.Statuses {
+	return statusesiface.NewStatuses(client)
+}
+
+// statusesFor returns the commit status configuration of the repository, or
+// nil if statuses are disabled.
+func (c *Config) statusesFor(ctx context.Context, client *github.Client, repo *github.Repository) *statuses.Config {
+	if rc := c.repoConfig(ctx, client, repo); rc != nil && rc.Statuses != nil {
+		return rc.Statuses
+	}
+	return c.Statuses
+}
+
+// pushStatuses sets the commit statuses of a push.
+func (c *Config) pushStatuses(event *github.PushEvent) error {
+	if c.Statuses == nil && c.Repos == nil {
+		return nil
+	}
+	client := githubx.NewClient(getSafeID(event))
+	if client == nil {
+		return ErrNewClient
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	owner, name, err := issues.SplitFullName(event.GetRepo().GetFullName())
+	if err != nil {
+		return err
+	}
+	repo := &github.Repository{Owner: &github.User{Login: &owner}, Name: &name}
+	sc := c.statusesFor(ctx, client, repo)
+	if sc == nil {
+		return nil
+	}
+	if err := statuses.NewReporter(c.getStatuses(client), sc, c.StatusURL).PushEvent(ctx, event); err != nil {
+		log.Println("PushEvent: statuses:", err)
+	}
+	return nil
+}
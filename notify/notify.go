@@ -0,0 +1,323 @@
+// Package notify posts chat messages for selected Github events to
+// Slack-compatible incoming webhooks.
+//
+// Each route selects events by trigger, repository and label, and formats the
+// message with a text/template executed over the parsed github event, e.g. a
+// *github.IssuesEvent. Templates should pass values written by users, such as
+// issue titles, to the escape function, so that they cannot mention channels
+// or break links. For example:
+//
+//	routes:
+//	- name: triage
+//	  on: [triage]
+//	  url_env: TRIAGE_WEBHOOK_URL
+//	  repos: [m-lab/*]
+//	- name: releases
+//	  on: [release, check_failed]
+//	  url: https://hooks.slack.com/services/T000/B000/XXXX
+//	  channel: "#releases"
+//	  template: "{{.GetRepo.GetFullName}} {{.GetRelease.GetTagName | escape}} is out"
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
+)
+
+// Triggers select the events that send notifications.
+const (
+	// Triage is an issue labeled with the triage label.
+	Triage = "triage"
+	// Current is an issue labeled with the current label.
+	Current = "current"
+	// CheckFailed is a check run that completed with a failure or timeout.
+	CheckFailed = "check_failed"
+	// Release is a published release.
+	Release = "release"
+)
+
+// DefaultTemplates format the messages of routes without a template.
+var DefaultTemplates = map[string]string{
+	Triage: `New issue needs triage in {{.GetRepo.GetFullName | escape}}: ` +
+		`<{{.GetIssue.GetHTMLURL | escape}}|#{{.GetIssue.GetNumber}} {{.GetIssue.GetTitle | escape}}>`,
+	Current: `Moved to current in {{.GetRepo.GetFullName | escape}}: ` +
+		`<{{.GetIssue.GetHTMLURL | escape}}|#{{.GetIssue.GetNumber}} {{.GetIssue.GetTitle | escape}}>`,
+	CheckFailed: `Check {{.GetCheckRun.GetName | escape}} {{.GetCheckRun.GetConclusion | escape}} on ` +
+		`{{.GetRepo.GetFullName | escape}}@{{printf "%.7s" .GetCheckRun.GetHeadSHA | escape}}: ` +
+		`<{{.GetCheckRun.GetHTMLURL | escape}}|details>`,
+	Release: `Released {{.GetRepo.GetFullName | escape}} ` +
+		`<{{.GetRelease.GetHTMLURL | escape}}|{{or .GetRelease.GetName .GetRelease.GetTagName | escape}}>`,
+}
+
+// funcs are the functions available to templates.
+var funcs = template.FuncMap{
+	"escape": Escape,
+}
+
+// escaper replaces the control characters of Slack mrkdwn.
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Escape returns s with the control characters of Slack mrkdwn escaped, so
+// that text such as "<!channel>" is shown as is.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// Route sends the matching events to an incoming webhook.
+type Route struct {
+	// Name identifies the route in logs.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// On lists the triggers of the route.
+	On []string `yaml:"on" json:"on"`
+	// URL of the incoming webhook.
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// URLEnv names the environment variable holding the URL, since incoming
+	// webhook URLs are secrets.
+	URLEnv string `yaml:"url_env,omitempty" json:"url_env,omitempty"`
+	// Channel and Username override the defaults of the webhook.
+	Channel  string `yaml:"channel,omitempty" json:"channel,omitempty"`
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	// Repos limits the route to repositories matching any of these
+	// "owner/name" patterns, e.g. "m-lab/*".
+	Repos []string `yaml:"repos,omitempty" json:"repos,omitempty"`
+	// Labels limits the route to issues with any of these labels.
+	Labels []string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	// Template formats the message for every trigger of the route. Defaults to
+	// the DefaultTemplates entry of each trigger.
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
+
+	tmpl *template.Template
+}
+
+// Match reports whether the route sends a message for the trigger in the
+// repository, given the issue labels.
+func (r *Route) Match(trigger, repo string, labels []string) bool {
+	if !slice.ContainsFold(r.On, trigger) {
+		return false
+	}
+	if len(r.Repos) > 0 {
+		found := false
+		for _, p := range r.Repos {
+			if ok, _ := path.Match(strings.ToLower(p), strings.ToLower(repo)); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(r.Labels) == 0 {
+		return true
+	}
+	for _, l := range labels {
+		if slice.ContainsFold(r.Labels, l) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config is the notification configuration.
+type Config struct {
+	// TriageLabel marks issues waiting for triage. Defaults to
+	// "review/triage".
+	TriageLabel string `yaml:"triage_label,omitempty" json:"triage_label,omitempty"`
+	// CurrentLabel marks issues scheduled for the current iteration. Defaults
+	// to "current".
+	CurrentLabel string `yaml:"current_label,omitempty" json:"current_label,omitempty"`
+	// Routes are checked in order, and every matching route sends a message.
+	Routes []Route `yaml:"routes" json:"routes"`
+}
+
+// Validate checks the configuration, reads URLs from the environment, parses
+// the templates and sets defaults.
+func (c *Config) Validate() error {
+	if c.TriageLabel == "" {
+		c.TriageLabel = "review/triage"
+	}
+	if c.CurrentLabel == "" {
+		c.CurrentLabel = "current"
+	}
+	if len(c.Routes) == 0 {
+		return fmt.Errorf("routes must not be empty")
+	}
+	for i := range c.Routes {
+		r := &c.Routes[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("route %d", i+1)
+		}
+		if len(r.On) == 0 {
+			return fmt.Errorf("%s: on must not be empty", r.Name)
+		}
+		for _, on := range r.On {
+			if _, ok := DefaultTemplates[on]; !ok {
+				return fmt.Errorf("%s: unknown trigger %q", r.Name, on)
+			}
+		}
+		if r.URLEnv != "" {
+			if r.URL != "" {
+				return fmt.Errorf("%s: only one of url and url_env may be set", r.Name)
+			}
+			r.URL = os.Getenv(r.URLEnv)
+		}
+		if !strings.HasPrefix(r.URL, "http://") && !strings.HasPrefix(r.URL, "https://") {
+			return fmt.Errorf("%s: url must be an http or https URL", r.Name)
+		}
+		for _, p := range r.Repos {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("%s: repos: %q: %v", r.Name, p, err)
+			}
+		}
+		if r.Template != "" {
+			tmpl, err := template.New(r.Name).Funcs(funcs).Parse(r.Template)
+			if err != nil {
+				return fmt.Errorf("%s: template: %v", r.Name, err)
+			}
+			r.tmpl = tmpl
+		}
+	}
+	return nil
+}
+
+// defaultTemplates are the parsed DefaultTemplates.
+var defaultTemplates = map[string]*template.Template{}
+
+func init() {
+	for name, text := range DefaultTemplates {
+		defaultTemplates[name] = template.Must(template.New(name).Funcs(funcs).Parse(text))
+	}
+}
+
+// Message is the payload posted to an incoming webhook.
+type Message struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Notifier sends the notifications of a configuration.
+type Notifier struct {
+	*Config
+	// Client posts the messages. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// New creates a new Notifier for the given configuration.
+func New(c *Config) *Notifier {
+	return &Notifier{Config: c, Client: http.DefaultClient}
+}
+
+// IssuesEvent notifies the routes of the Triage and Current triggers when an
+// issue is labeled with the triage or current label.
+func (n *Notifier) IssuesEvent(ctx context.Context, event *github.IssuesEvent) error {
+	if event.GetAction() != "labeled" {
+		return nil
+	}
+	var trigger string
+	switch label := event.GetLabel().GetName(); {
+	case strings.EqualFold(label, n.TriageLabel):
+		trigger = Triage
+	case strings.EqualFold(label, n.CurrentLabel):
+		trigger = Current
+	default:
+		return nil
+	}
+	var labels []string
+	for _, l := range event.GetIssue().Labels {
+		labels = append(labels, l.GetName())
+	}
+	return n.Notify(ctx, trigger, event.GetRepo().GetFullName(), labels, event)
+}
+
+// CheckRunEvent notifies the routes of the CheckFailed trigger when a check run
+// completes with a failure or timeout.
+func (n *Notifier) CheckRunEvent(ctx context.Context, event *github.CheckRunEvent) error {
+	run := event.GetCheckRun()
+	if event.GetAction() != "completed" || run.GetStatus() != "completed" {
+		return nil
+	}
+	switch run.GetConclusion() {
+	case "failure", "timed_out":
+		return n.Notify(ctx, CheckFailed, event.GetRepo().GetFullName(), nil, event)
+	}
+	return nil
+}
+
+// ReleaseEvent notifies the routes of the Release trigger when a release is
+// published.
+func (n *Notifier) ReleaseEvent(ctx context.Context, event *github.ReleaseEvent) error {
+	if event.GetAction() != "published" {
+		return nil
+	}
+	return n.Notify(ctx, Release, event.GetRepo().GetFullName(), nil, event)
+}
+
+// Notify posts a message for the event to every route matching the trigger,
+// repository and labels. Errors are logged and the last error is returned.
+func (n *Notifier) Notify(ctx context.Context, trigger, repo string, labels []string, event interface{}) error {
+	var lastErr error
+	for i := range n.Routes {
+		r := &n.Routes[i]
+		if !r.Match(trigger, repo, labels) {
+			continue
+		}
+		if err := n.send(ctx, r, trigger, event); err != nil {
+			log.Printf("notify: %s: %v", r.Name, err)
+			lastErr = err
+			continue
+		}
+		log.Printf("notify: %s: sent %s for %s", r.Name, trigger, repo)
+	}
+	return lastErr
+}
+
+func (n *Notifier) send(ctx context.Context, r *Route, trigger string, event interface{}) error {
+	tmpl := r.tmpl
+	if tmpl == nil {
+		tmpl = defaultTemplates[trigger]
+	}
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, event); err != nil {
+		return err
+	}
+	b, err := json.Marshal(&Message{Text: text.String(), Channel: r.Channel, Username: r.Username})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, r.URL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
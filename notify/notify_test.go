@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+// stub is a local incoming webhook that records the posted messages.
+type stub struct {
+	*httptest.Server
+	mu       sync.Mutex
+	messages []Message
+}
+
+func newStub(t *testing.T) *stub {
+	s := &stub{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m Message
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Error(err)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.messages = append(s.messages, m)
+		if m.Channel == "#broken" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s
+}
+
+func (s *stub) texts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var texts []string
+	for _, m := range s.messages {
+		texts = append(texts, m.Text)
+	}
+	return texts
+}
+
+func issuesEvent(repo, label string, labels ...string) *github.IssuesEvent {
+	ev := &github.IssuesEvent{
+		Action: github.String("labeled"),
+		Label:  &github.Label{Name: github.String(label)},
+		Repo:   &github.Repository{FullName: github.String(repo)},
+		Issue: &github.Issue{
+			Number:  github.Int(7),
+			Title:   github.String("Fix the bug"),
+			HTMLURL: github.String("https://github.com/" + repo + "/issues/7"),
+		},
+	}
+	for _, l := range append(labels, label) {
+		ev.Issue.Labels = append(ev.Issue.Labels, github.Label{Name: github.String(l)})
+	}
+	return ev
+}
+
+func TestParse(t *testing.T) {
+	os.Setenv("NOTIFY_TEST_URL", "https://hooks.example.com/x")
+	defer os.Unsetenv("NOTIFY_TEST_URL")
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "okay", config: "routes:\n- {on: [triage], url_env: NOTIFY_TEST_URL, repos: ['o/*']}\n"},
+		{name: "no-routes", config: "triage_label: triage\n", wantErr: true},
+		{name: "no-triggers", config: "routes:\n- {url: 'https://x'}\n", wantErr: true},
+		{name: "unknown-trigger", config: "routes:\n- {on: [push], url: 'https://x'}\n", wantErr: true},
+		{name: "no-url", config: "routes:\n- {on: [triage], url_env: NOTIFY_TEST_MISSING}\n", wantErr: true},
+		{name: "bad-template", config: "routes:\n- {on: [triage], url: 'https://x', template: '{{.Foo'}\n", wantErr: true},
+		{name: "bad-repo", config: "routes:\n- {on: [triage], url: 'https://x', repos: ['[']}\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := configfile.Parse([]byte(tt.config), c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (c.Routes[0].URL != "https://hooks.example.com/x" || c.TriageLabel != "review/triage") {
+				t.Errorf("Parse() = %+v, want URL from env and default labels", c)
+			}
+		})
+	}
+}
+
+func TestNotifier(t *testing.T) {
+	s := newStub(t)
+	defer s.Close()
+	c := &Config{Routes: []Route{
+		{Name: "triage", On: []string{Triage, Current}, URL: s.URL, Repos: []string{"o/*"}},
+		{Name: "bugs", On: []string{Current}, URL: s.URL, Labels: []string{"bug"},
+			Template: `bug {{.GetIssue.GetNumber}} is current: {{.GetIssue.GetTitle | escape}}`},
+		{Name: "ci", On: []string{CheckFailed, Release}, URL: s.URL, Repos: []string{"o/r"}},
+		{Name: "broken", On: []string{Release}, URL: s.URL, Channel: "#broken", Repos: []string{"x/*"}},
+	}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	n := New(c)
+	ctx := context.Background()
+	completed := func(conclusion string) *github.CheckRunEvent {
+		return &github.CheckRunEvent{
+			Action: github.String("completed"),
+			Repo:   &github.Repository{FullName: github.String("o/r")},
+			CheckRun: &github.CheckRun{
+				Name:       github.String("unit"),
+				Status:     github.String("completed"),
+				Conclusion: github.String(conclusion),
+				HeadSHA:    github.String("0123456789abcdef"),
+				HTMLURL:    github.String("https://ci/1"),
+			},
+		}
+	}
+
+	n.IssuesEvent(ctx, issuesEvent("o/r", "review/triage"))
+	// Titles cannot mention the channel or break the link.
+	loud := issuesEvent("o/r", "review/triage")
+	loud.Issue.Title = github.String("<!channel> a > b | c & d")
+	n.IssuesEvent(ctx, loud)
+	n.IssuesEvent(ctx, issuesEvent("other/r", "Current", "bug"))
+	n.IssuesEvent(ctx, issuesEvent("o/r", "backlog"))
+	n.CheckRunEvent(ctx, completed("success"))
+	n.CheckRunEvent(ctx, completed("failure"))
+	n.ReleaseEvent(ctx, &github.ReleaseEvent{
+		Action:  github.String("published"),
+		Repo:    &github.Repository{FullName: github.String("o/r")},
+		Release: &github.RepositoryRelease{TagName: github.String("v1.0.0"), HTMLURL: github.String("https://rel")},
+	})
+	if err := n.ReleaseEvent(ctx, &github.ReleaseEvent{
+		Action: github.String("published"),
+		Repo:   &github.Repository{FullName: github.String("x/y")},
+	}); err == nil {
+		t.Errorf("ReleaseEvent() to broken webhook error = nil")
+	}
+
+	want := []string{
+		"New issue needs triage in o/r: <https://github.com/o/r/issues/7|#7 Fix the bug>",
+		"New issue needs triage in o/r: <https://github.com/o/r/issues/7|#7 &lt;!channel&gt; a &gt; b | c &amp; d>",
+		"bug 7 is current: Fix the bug",
+		"Check unit failure on o/r@0123456: <https://ci/1|details>",
+		"Released o/r <https://rel|v1.0.0>",
+		"Released x/y <|>",
+	}
+	if got := s.texts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,379 @@
+// Package relay forwards verified webhook deliveries to downstream HTTP
+// subscribers.
+//
+// Each delivery is re-signed with the secret of the subscriber, so that
+// subscribers validate it exactly like a delivery from Github. Each subscriber
+// has its own bounded queue, delivered in order by a single worker, so a slow
+// subscriber neither delays the others nor grows memory without limit. Failed
+// deliveries are retried with exponential backoff, and the outcome of every
+// delivery is logged. For example:
+//
+//	queue: 100
+//	retries: 3
+//	backoff: 2s
+//	timeout: 10s
+//	subscribers:
+//	- name: ci
+//	  url: https://ci.example.com/github
+//	  secret_env: CI_WEBHOOK_SECRET
+//	  events: [push, pull_request]
+//	- name: dashboard
+//	  url: https://dashboard.example.com/hook
+//	  secret: not-very-secret
+//	  events: [issues.opened, issues.closed]
+package relay
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/jsonl"
+)
+
+// Defaults for the Config fields.
+const (
+	DefaultRetries = 3
+	DefaultBackoff = time.Second
+	DefaultTimeout = 10 * time.Second
+	DefaultQueue   = 100
+)
+
+// Subscriber is a downstream receiver of webhook deliveries.
+type Subscriber struct {
+	// Name identifies the subscriber in the delivery log.
+	Name string `yaml:"name" json:"name"`
+	// URL receives the deliveries.
+	URL string `yaml:"url" json:"url"`
+	// Secret signs the deliveries.
+	Secret string `yaml:"secret,omitempty" json:"secret,omitempty"`
+	// SecretEnv names the environment variable holding the secret, so the
+	// secret need not be written in the configuration.
+	SecretEnv string `yaml:"secret_env,omitempty" json:"secret_env,omitempty"`
+	// Events selects the deliveries to forward by event name, e.g. "issues",
+	// or by event name and action, e.g. "issues.opened". Empty or "*"
+	// forwards every event.
+	Events []string `yaml:"events,omitempty" json:"events,omitempty"`
+}
+
+// Match reports whether the subscriber wants the event with the given action.
+func (s *Subscriber) Match(event, action string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		name := e
+		want := ""
+		if i := strings.Index(e, "."); i >= 0 {
+			name, want = e[:i], e[i+1:]
+		}
+		if (name == "*" || name == event) && (want == "" || want == action) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config is the relay configuration.
+type Config struct {
+	// Subscribers receive the deliveries.
+	Subscribers []Subscriber `yaml:"subscribers" json:"subscribers"`
+	// Retries is the number of retries after a failed delivery. Defaults to
+	// DefaultRetries. Use -1 to never retry.
+	Retries int `yaml:"retries,omitempty" json:"retries,omitempty"`
+	// Backoff is the delay before the first retry, doubled for each later
+	// retry. Defaults to DefaultBackoff.
+	Backoff time.Duration `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+	// Timeout limits each delivery attempt. Defaults to DefaultTimeout.
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// Queue is the number of deliveries queued for each subscriber. Further
+	// deliveries are dropped while the queue is full. Defaults to
+	// DefaultQueue.
+	Queue int `yaml:"queue,omitempty" json:"queue,omitempty"`
+}
+
+// Validate checks the configuration, reads secrets from the environment and
+// sets defaults.
+func (c *Config) Validate() error {
+	if len(c.Subscribers) == 0 {
+		return fmt.Errorf("subscribers must not be empty")
+	}
+	names := map[string]bool{}
+	for i := range c.Subscribers {
+		s := &c.Subscribers[i]
+		if s.Name == "" {
+			s.Name = s.URL
+		}
+		if names[s.Name] {
+			return fmt.Errorf("subscriber %q: duplicate name", s.Name)
+		}
+		names[s.Name] = true
+		if !strings.HasPrefix(s.URL, "http://") && !strings.HasPrefix(s.URL, "https://") {
+			return fmt.Errorf("subscriber %q: url must be an http or https URL", s.Name)
+		}
+		if s.SecretEnv != "" {
+			if s.Secret != "" {
+				return fmt.Errorf("subscriber %q: only one of secret and secret_env may be set", s.Name)
+			}
+			s.Secret = os.Getenv(s.SecretEnv)
+		}
+		if s.Secret == "" {
+			return fmt.Errorf("subscriber %q: secret must not be empty", s.Name)
+		}
+	}
+	if c.Retries == 0 {
+		c.Retries = DefaultRetries
+	}
+	if c.Retries < 0 {
+		c.Retries = 0
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = DefaultBackoff
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.Queue <= 0 {
+		c.Queue = DefaultQueue
+	}
+	return nil
+}
+
+// Status is the outcome of forwarding a delivery to a subscriber.
+type Status struct {
+	// Time the last attempt completed.
+	Time time.Time `json:"time"`
+	// DeliveryID is the ID of the Github delivery.
+	DeliveryID string `json:"delivery_id"`
+	// Event and Action of the delivery.
+	Event  string `json:"event"`
+	Action string `json:"action,omitempty"`
+	// Subscriber name.
+	Subscriber string `json:"subscriber"`
+	// Attempts is the number of attempts made.
+	Attempts int `json:"attempts"`
+	// Code is the HTTP status of the last response, or zero if there was
+	// none.
+	Code int `json:"code,omitempty"`
+	// Result is "ok" or the error of the last attempt.
+	Result string `json:"result"`
+}
+
+// Recorder saves delivery statuses.
+type Recorder interface {
+	Record(s *Status) error
+}
+
+// Store is a Recorder that appends statuses as JSON lines to a local file.
+type Store struct {
+	file *jsonl.File
+}
+
+// NewStore creates a new Store that saves statuses to the given file name. The
+// file is created on the first call to Record.
+func NewStore(path string) *Store {
+	return &Store{file: jsonl.New(path)}
+}
+
+// Record appends the status to the store file.
+func (s *Store) Record(st *Status) error {
+	return s.file.Append(st)
+}
+
+// Relay forwards deliveries to the configured subscribers.
+type Relay struct {
+	// Config lists the subscribers.
+	Config *Config
+	// Client sends the deliveries. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Recorder, if not nil, saves the status of every delivery.
+	Recorder Recorder
+
+	// sleep waits between retries, or returns the error of ctx. Tests
+	// replace it.
+	sleep func(ctx context.Context, d time.Duration) error
+
+	queues []chan *delivery
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+}
+
+// delivery is a queued delivery to a subscriber.
+type delivery struct {
+	*webhook.Delivery
+	action string
+}
+
+// New creates a new Relay for the given configuration, and starts a worker for
+// each subscriber. Call Close to deliver the queued deliveries and stop the
+// workers.
+func New(c *Config) *Relay {
+	r := &Relay{Config: c, Client: http.DefaultClient, sleep: sleep}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	for i := range c.Subscribers {
+		q := make(chan *delivery, c.Queue)
+		r.queues = append(r.queues, q)
+		r.wg.Add(1)
+		go r.run(&c.Subscribers[i], q)
+	}
+	return r
+}
+
+// Deliver queues the delivery for every matching subscriber. When the queue of
+// a subscriber is full, the delivery is dropped for that subscriber. Deliver
+// is meant for webhook.Handler.OnDelivery.
+func (r *Relay) Deliver(d *webhook.Delivery) {
+	var payload struct {
+		Action string `json:"action"`
+	}
+	// Payloads without an action, e.g. push events, match by event name.
+	json.Unmarshal(d.Payload, &payload)
+	for i := range r.Config.Subscribers {
+		s := &r.Config.Subscribers[i]
+		if !s.Match(d.Event, payload.Action) {
+			continue
+		}
+		select {
+		case r.queues[i] <- &delivery{Delivery: d, action: payload.Action}:
+		default:
+			log.Printf("relay: queue of %s full, dropped %s %s", s.Name, d.Event, d.ID)
+		}
+	}
+}
+
+// run sends the queued deliveries of the subscriber in order.
+func (r *Relay) run(s *Subscriber, q chan *delivery) {
+	defer r.wg.Done()
+	for d := range q {
+		st := r.Send(r.ctx, s, d.Delivery)
+		st.Action = d.action
+		r.record(st)
+	}
+}
+
+// Close sends the queued deliveries and stops the workers. If ctx is done
+// first, the remaining attempts are abandoned and Close returns the error of
+// ctx. Deliver must not be called after Close.
+func (r *Relay) Close(ctx context.Context) error {
+	r.once.Do(func() {
+		for _, q := range r.queues {
+			close(q)
+		}
+	})
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (r *Relay) record(st *Status) {
+	log.Printf("relay: %s %s to %s: %s after %d attempts", st.Event, st.DeliveryID, st.Subscriber, st.Result, st.Attempts)
+	if r.Recorder == nil {
+		return
+	}
+	if err := r.Recorder.Record(st); err != nil {
+		log.Println("relay:", err)
+	}
+}
+
+// Send forwards the delivery to the subscriber, retrying failed attempts, and
+// returns the outcome. Network errors and 429 and 5xx responses are retried.
+func (r *Relay) Send(ctx context.Context, s *Subscriber, d *webhook.Delivery) *Status {
+	st := &Status{DeliveryID: d.ID, Event: d.Event, Subscriber: s.Name}
+	backoff := r.Config.Backoff
+	for {
+		st.Attempts++
+		code, err := r.post(ctx, s, d)
+		st.Time = time.Now()
+		st.Code = code
+		if err == nil {
+			st.Result = "ok"
+			return st
+		}
+		st.Result = err.Error()
+		retry := code == 0 || code == http.StatusTooManyRequests || code >= 500
+		if !retry || st.Attempts > r.Config.Retries {
+			return st
+		}
+		if err := r.sleep(ctx, backoff); err != nil {
+			return st
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a single delivery attempt and returns the response status.
+func (r *Relay) post(ctx context.Context, s *Subscriber, d *webhook.Delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.Config.Timeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "github-webhook-receiver-relay")
+	req.Header.Set("X-GitHub-Event", d.Event)
+	req.Header.Set("X-GitHub-Delivery", d.ID)
+	req.Header.Set("X-Hub-Signature", "sha1="+sign(sha1.New, s.Secret, d.Payload))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+sign(sha256.New, s.Secret, d.Payload))
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("%s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// sign returns the hex encoded HMAC of the payload.
+func sign(h func() hash.Hash, secret string, payload []byte) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
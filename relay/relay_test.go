@@ -0,0 +1,239 @@
+package relay
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+func TestParse(t *testing.T) {
+	os.Setenv("RELAY_TEST_SECRET", "from-env")
+	defer os.Unsetenv("RELAY_TEST_SECRET")
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{
+			name:   "okay",
+			config: "subscribers:\n- {name: ci, url: 'https://ci', secret_env: RELAY_TEST_SECRET}\nbackoff: 2s\n",
+		},
+		{name: "empty", config: "retries: 1\n", wantErr: true},
+		{name: "bad-url", config: "subscribers:\n- {url: 'ftp://x', secret: s}\n", wantErr: true},
+		{name: "no-secret", config: "subscribers:\n- {url: 'https://x', secret_env: RELAY_TEST_MISSING}\n", wantErr: true},
+		{name: "two-secrets", config: "subscribers:\n- {url: 'https://x', secret: s, secret_env: RELAY_TEST_SECRET}\n", wantErr: true},
+		{name: "duplicate", config: "subscribers:\n- {url: 'https://x', secret: s}\n- {url: 'https://x', secret: s}\n", wantErr: true},
+		{name: "unknown-field", config: "subscribers:\n- {url: 'https://x', secret: s, filter: x}\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{}
+			err := configfile.Parse([]byte(tt.config), c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if s := c.Subscribers[0]; s.Secret != "from-env" || c.Backoff != 2*time.Second ||
+				c.Retries != DefaultRetries || c.Timeout != DefaultTimeout || c.Queue != DefaultQueue {
+				t.Errorf("Parse() = %+v, want secret from env and defaults", c)
+			}
+		})
+	}
+}
+
+func TestSubscriber_Match(t *testing.T) {
+	tests := []struct {
+		events []string
+		event  string
+		action string
+		want   bool
+	}{
+		{event: "push", want: true},
+		{events: []string{"*"}, event: "push", want: true},
+		{events: []string{"issues"}, event: "issues", action: "opened", want: true},
+		{events: []string{"issues"}, event: "push"},
+		{events: []string{"push", "issues.opened"}, event: "issues", action: "opened", want: true},
+		{events: []string{"issues.opened"}, event: "issues", action: "closed"},
+		{events: []string{"*.closed"}, event: "pull_request", action: "closed", want: true},
+	}
+	for _, tt := range tests {
+		s := &Subscriber{Events: tt.events}
+		if got := s.Match(tt.event, tt.action); got != tt.want {
+			t.Errorf("Match(%q, %q) with %q = %v, want %v", tt.event, tt.action, tt.events, got, tt.want)
+		}
+	}
+}
+
+// server is a subscriber that fails the first failures requests with status.
+type server struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests int
+	failures int
+	status   int
+}
+
+func newServer(t *testing.T, secret string, failures, status int) *server {
+	s := &server{failures: failures, status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := github.ValidatePayload(r, []byte(secret)); err != nil {
+			t.Errorf("ValidatePayload() error = %v", err)
+		}
+		if r.Header.Get("X-GitHub-Event") != "issues" || r.Header.Get("X-GitHub-Delivery") != "d1" {
+			t.Errorf("headers = %v, want issues delivery d1", r.Header)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if s.requests <= s.failures {
+			w.WriteHeader(s.status)
+		}
+	}))
+	return s
+}
+
+func (s *server) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func TestRelay_Send(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		wantAttempts int
+		wantResult   string
+	}{
+		{name: "ok", wantAttempts: 1, wantResult: "ok"},
+		{name: "retried", failures: 2, status: http.StatusBadGateway, wantAttempts: 3, wantResult: "ok"},
+		{name: "gave-up", failures: 5, status: http.StatusServiceUnavailable, wantAttempts: 4, wantResult: "503 Service Unavailable"},
+		{name: "not-retried", failures: 1, status: http.StatusNotFound, wantAttempts: 1, wantResult: "404 Not Found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t, "secret", tt.failures, tt.status)
+			defer srv.Close()
+			c := &Config{Subscribers: []Subscriber{{Name: "ci", URL: srv.URL, Secret: "secret"}}}
+			if err := c.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			r := New(c)
+			defer r.Close(context.Background())
+			var slept []time.Duration
+			r.sleep = func(ctx context.Context, d time.Duration) error {
+				slept = append(slept, d)
+				return nil
+			}
+			d := &webhook.Delivery{ID: "d1", Event: "issues", Payload: []byte(`{"action": "opened"}`)}
+			st := r.Send(context.Background(), &c.Subscribers[0], d)
+			if st.Attempts != tt.wantAttempts || st.Result != tt.wantResult {
+				t.Errorf("Send() = %+v, want %d attempts and %q", st, tt.wantAttempts, tt.wantResult)
+			}
+			if len(slept) > 1 && slept[1] != 2*slept[0] {
+				t.Errorf("Send() backoff = %v, want doubling", slept)
+			}
+		})
+	}
+}
+
+func TestRelay_Deliver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "relay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ci := newServer(t, "ci-secret", 0, 0)
+	defer ci.Close()
+	other := newServer(t, "other-secret", 0, 0)
+	defer other.Close()
+
+	c := &Config{Subscribers: []Subscriber{
+		{Name: "ci", URL: ci.URL, Secret: "ci-secret", Events: []string{"issues.opened"}},
+		{Name: "other", URL: other.URL, Secret: "other-secret", Events: []string{"push"}},
+	}}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	r := New(c)
+	r.Recorder = NewStore(filepath.Join(dir, "relay.jsonl"))
+	r.Deliver(&webhook.Delivery{ID: "d1", Event: "issues", Payload: []byte(`{"action": "opened"}`)})
+	if err := r.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if ci.requests != 1 || other.requests != 0 {
+		t.Errorf("Deliver() requests = %d, %d; want 1, 0", ci.requests, other.requests)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "relay.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"subscriber":"ci"`) || !strings.Contains(string(b), `"action":"opened"`) {
+		t.Errorf("status log = %q, want the ci delivery", b)
+	}
+}
+
+func TestRelay_Close(t *testing.T) {
+	// The subscriber is down, so every delivery is retried.
+	srv := newServer(t, "secret", 100, http.StatusServiceUnavailable)
+	defer srv.Close()
+	c := &Config{
+		Subscribers: []Subscriber{{Name: "ci", URL: srv.URL, Secret: "secret"}},
+		Backoff:     time.Hour,
+		Queue:       2,
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	r := New(c)
+	rec := &statuses{}
+	r.Recorder = rec
+	d := &webhook.Delivery{ID: "d1", Event: "issues", Payload: []byte(`{"action": "opened"}`)}
+	// The worker takes the first delivery, and waits to retry it. The queue
+	// holds two more, and drops the rest.
+	r.Deliver(d)
+	for srv.count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 4; i++ {
+		r.Deliver(d)
+	}
+
+	// Close abandons the retries once its context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.Close(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Close() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := len(rec.list); got != 3 {
+		t.Errorf("Close() recorded %d deliveries, want 3", got)
+	}
+}
+
+// statuses is a Recorder of statuses in memory.
+type statuses struct {
+	mu   sync.Mutex
+	list []*Status
+}
+
+func (s *statuses) Record(st *Status) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = append(s.list, st)
+	return nil
+}
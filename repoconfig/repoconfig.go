@@ -0,0 +1,273 @@
+// Package repoconfig loads per-repository receiver configuration from a file in
+// the target repository, e.g. ".github/webhook-receiver.yml".
+//
+// Organization defaults are read from the same file path in the organization's
+// ".github" repository. Settings in the repository file replace the defaults
+// section by section. Files are cached and revalidated using ETags, so an
+// unchanged file costs one conditional request, which does not count against
+// the API rate limit.
+package repoconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/projects"
+	"github.com/stephen-soltesz/github-webhook-poc/events/pulls"
+	"github.com/stephen-soltesz/github-webhook-poc/events/releases"
+	"github.com/stephen-soltesz/github-webhook-poc/events/statuses"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+	"github.com/stephen-soltesz/github-webhook-poc/iteration"
+	"github.com/stephen-soltesz/github-webhook-poc/labels"
+	"github.com/stephen-soltesz/github-webhook-poc/rules"
+	"github.com/stephen-soltesz/github-webhook-poc/stale"
+)
+
+const (
+	// DefaultPath is the default configuration file path within a repository.
+	DefaultPath = ".github/webhook-receiver.yml"
+
+	// orgRepo is the name of the repository holding organization defaults.
+	orgRepo = ".github"
+)
+
+// Config is the per-repository configuration. Nil sections are unset and
+// inherit the organization defaults or the receiver defaults.
+type Config struct {
+	// Rules replace the receiver's issue label rules.
+	Rules []*rules.Rule `yaml:"rules,omitempty"`
+
+	// Labels replaces the receiver's label catalog.
+	Labels *labels.Catalog `yaml:"label_catalog,omitempty"`
+
+	// Iteration replaces the receiver's iteration calendar.
+	Iteration *iteration.Calendar `yaml:"iteration,omitempty"`
+
+	// Stale replaces the receiver's stale workflow settings.
+	Stale *stale.Config `yaml:"stale,omitempty"`
+	// Pulls replaces the receiver's pull request automation settings.
+	Pulls *pulls.Config `yaml:"pulls,omitempty"`
+	// Projects replaces the receiver's project board sync settings.
+	Projects *projects.Config `yaml:"projects,omitempty"`
+	// Statuses replaces the receiver's commit status validators.
+	Statuses *statuses.Config `yaml:"statuses,omitempty"`
+	// Releases replaces the receiver's release notes settings.
+	Releases *releases.Config `yaml:"releases,omitempty"`
+}
+
+// Validate checks all configuration sections.
+func (c *Config) Validate() error {
+	if c.Rules != nil {
+		if err := c.RuleConfig().Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Labels != nil {
+		if err := c.Labels.Validate(); err != nil {
+			return fmt.Errorf("label_catalog: %v", err)
+		}
+	}
+	if c.Iteration != nil {
+		if err := c.Iteration.Validate(); err != nil {
+			return fmt.Errorf("iteration: %v", err)
+		}
+	}
+	if c.Stale != nil {
+		if err := c.Stale.Validate(); err != nil {
+			return fmt.Errorf("stale: %v", err)
+		}
+	}
+	if c.Pulls != nil {
+		if err := c.Pulls.Validate(); err != nil {
+			return fmt.Errorf("pulls: %v", err)
+		}
+	}
+	if c.Projects != nil {
+		if err := c.Projects.Validate(); err != nil {
+			return fmt.Errorf("projects: %v", err)
+		}
+	}
+	if c.Statuses != nil {
+		if err := c.Statuses.Validate(); err != nil {
+			return fmt.Errorf("statuses: %v", err)
+		}
+	}
+	if c.Releases != nil {
+		if err := c.Releases.Validate(); err != nil {
+			return fmt.Errorf("releases: %v", err)
+		}
+	}
+	return nil
+}
+
+// RuleConfig returns the rules section as a rule set, or nil if it is unset.
+func (c *Config) RuleConfig() *rules.Config {
+	if c == nil || c.Rules == nil {
+		return nil
+	}
+	return &rules.Config{Rules: c.Rules}
+}
+
+// Merge returns a new Config with every set section of over replacing the
+// same section in c. Either value may be nil.
+func (c *Config) Merge(over *Config) *Config {
+	m := &Config{}
+	if c != nil {
+		*m = *c
+	}
+	if over == nil {
+		return m
+	}
+	if over.Rules != nil {
+		m.Rules = over.Rules
+	}
+	if over.Labels != nil {
+		m.Labels = over.Labels
+	}
+	if over.Iteration != nil {
+		m.Iteration = over.Iteration
+	}
+	if over.Stale != nil {
+		m.Stale = over.Stale
+	}
+	if over.Pulls != nil {
+		m.Pulls = over.Pulls
+	}
+	if over.Projects != nil {
+		m.Projects = over.Projects
+	}
+	if over.Statuses != nil {
+		m.Statuses = over.Statuses
+	}
+	if over.Releases != nil {
+		m.Releases = over.Releases
+	}
+	return m
+}
+
+// entry is a cached configuration file.
+type entry struct {
+	etag   string
+	found  bool
+	config *Config
+}
+
+// Loader fetches and caches configuration files.
+type Loader struct {
+	// Path is the configuration file path within each repository.
+	Path string
+
+	mu    sync.Mutex
+	cache map[string]*entry
+}
+
+// NewLoader creates a new Loader that reads the configuration from DefaultPath.
+func NewLoader() *Loader {
+	return &Loader{Path: DefaultPath, cache: map[string]*entry{}}
+}
+
+// Load returns the merged organization and repository configuration for the
+// given owner and repo, using the given installation client. Missing files are
+// not an error; if neither file exists, Load returns an empty Config.
+func (l *Loader) Load(ctx context.Context, client *github.Client, owner, repo string) (*Config, error) {
+	org, err := l.fetch(ctx, client, owner, orgRepo)
+	if err != nil {
+		return nil, err
+	}
+	if repo == orgRepo {
+		return org.Merge(nil), nil
+	}
+	rc, err := l.fetch(ctx, client, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	return org.Merge(rc), nil
+}
+
+// Invalidate removes the cached configuration for the given owner and repo.
+func (l *Loader) Invalidate(owner, repo string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.cache, key(owner, repo))
+}
+
+// PushEvent invalidates the cached configuration when a push to the default
+// branch adds, modifies or removes the configuration file.
+func (l *Loader) PushEvent(event *github.PushEvent) error {
+	repo := event.GetRepo()
+	if event.GetRef() != "refs/heads/"+repo.GetDefaultBranch() {
+		return nil
+	}
+	for _, commit := range event.Commits {
+		for _, files := range [][]string{commit.Added, commit.Modified, commit.Removed} {
+			for _, f := range files {
+				if f == l.Path {
+					log.Println("repoconfig: invalidating", repo.GetFullName())
+					l.Invalidate(repo.GetOwner().GetName(), repo.GetName())
+					return nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fetch returns the parsed configuration file from the repository, or nil if
+// the file does not exist.
+func (l *Loader) fetch(ctx context.Context, client *github.Client, owner, repo string) (*Config, error) {
+	k := key(owner, repo)
+	l.mu.Lock()
+	cached := l.cache[k]
+	l.mu.Unlock()
+	if cached != nil && !cached.found {
+		// The file did not exist. A push adding it invalidates the entry.
+		return nil, nil
+	}
+
+	u := fmt.Sprintf("repos/%s/%s/contents/%s", owner, repo, l.Path)
+	req, err := client.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3.raw")
+	if cached != nil && cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	var buf bytes.Buffer
+	resp, err := client.Do(ctx, req, &buf)
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotModified && cached != nil:
+		return cached.config, nil
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		l.store(k, &entry{})
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := configfile.Parse(buf.Bytes(), c); err != nil {
+		return nil, fmt.Errorf("%s/%s/%s: %v", owner, repo, l.Path, err)
+	}
+	l.store(k, &entry{etag: resp.Header.Get("ETag"), found: true, config: c})
+	return c, nil
+}
+
+func (l *Loader) store(k string, e *entry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cache == nil {
+		l.cache = map[string]*entry{}
+	}
+	l.cache[k] = e
+}
+
+func key(owner, repo string) string {
+	return strings.ToLower(owner + "/" + repo)
+}
@@ -0,0 +1,164 @@
+package repoconfig
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/statuses"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/fakegithub"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+const orgConfig = `
+rules:
+- name: org-triage
+  trigger: {event: issues, actions: [opened]}
+  actions: {add_labels: [triage]}
+`
+
+const repoConfig = `
+rules:
+- name: repo-triage
+  trigger: {event: issues, actions: [opened]}
+  actions: {add_labels: [needs-review]}
+`
+
+func ruleNames(c *Config) []string {
+	names := []string{}
+	for _, r := range c.Rules {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestLoader_Load(t *testing.T) {
+	s := fakegithub.NewServer()
+	defer s.Close()
+	s.AddFile("org", ".github", DefaultPath, orgConfig)
+	s.AddFile("org", "custom", DefaultPath, repoConfig)
+	s.AddRepo("org", "plain")
+	client := s.Client()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		repo string
+		want string
+	}{
+		{name: "org-defaults", repo: "plain", want: "org-triage"},
+		{name: "repo-override", repo: "custom", want: "repo-triage"},
+		{name: "org-repo", repo: ".github", want: "org-triage"},
+	}
+	l := NewLoader()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := l.Load(ctx, client, "org", tt.repo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(ruleNames(c), ","); got != tt.want {
+				t.Errorf("Load() rules = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Neither file exists.
+	c, err := l.Load(ctx, client, "other", "repo")
+	if err != nil || c.RuleConfig() != nil {
+		t.Errorf("Load() = %v, %v; want empty config", c, err)
+	}
+}
+
+func TestLoader_Revalidate(t *testing.T) {
+	s := fakegithub.NewServer()
+	defer s.Close()
+	s.AddFile("org", "repo", DefaultPath, repoConfig)
+	client := s.Client()
+	ctx := context.Background()
+	l := NewLoader()
+
+	for i := 0; i < 3; i++ {
+		c, err := l.Load(ctx, client, "org", "repo")
+		if err != nil || len(c.Rules) != 1 {
+			t.Fatalf("Load() = %v, %v", c, err)
+		}
+	}
+	// The org file is missing and cached after the first request; the repo file
+	// is revalidated on each request.
+	if got := len(s.Requests()); got != 4 {
+		t.Errorf("got %d requests, want 4: %v", got, s.Requests())
+	}
+
+	// A push changing the file invalidates the cache; others do not.
+	s.AddFile("org", "repo", DefaultPath, orgConfig)
+	push := func(ref, file string) {
+		l.PushEvent(&github.PushEvent{
+			Ref: github.String(ref),
+			Repo: &github.PushEventRepository{
+				Name:          github.String("repo"),
+				Owner:         &github.PushEventRepoOwner{Name: github.String("org")},
+				DefaultBranch: github.String("master"),
+			},
+			Commits: []github.PushEventCommit{{Modified: []string{file}}},
+		})
+	}
+	push("refs/heads/feature", DefaultPath)
+	push("refs/heads/master", "README.md")
+	if _, ok := l.cache[key("org", "repo")]; !ok {
+		t.Errorf("PushEvent() invalidated cache for unrelated push")
+	}
+	push("refs/heads/master", DefaultPath)
+	c, err := l.Load(ctx, client, "org", "repo")
+	if err != nil || ruleNames(c)[0] != "org-triage" {
+		t.Errorf("Load() after push = %v, %v; want org-triage", c, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	parse := func(b []byte) (*Config, error) {
+		c := &Config{}
+		return c, configfile.Parse(b, c)
+	}
+	if _, err := parse([]byte("rules:\n- name: bad\n")); err == nil {
+		t.Errorf("Parse() invalid rule error = nil")
+	}
+	if _, err := parse([]byte("unknown: true\n")); err == nil {
+		t.Errorf("Parse() unknown field error = nil")
+	}
+	if _, err := parse([]byte("iteration: {kind: sprint}\n")); err == nil {
+		t.Errorf("Parse() invalid iteration error = nil")
+	}
+	c, err := parse([]byte("iteration: {kind: quarter}\n"))
+	if err != nil || c.Iteration == nil {
+		t.Errorf("Parse() iteration = %v, %v; want quarter", c, err)
+	}
+	if _, err := parse([]byte("stale: {label: old}\n")); err == nil {
+		t.Errorf("Parse() invalid stale error = nil")
+	}
+	c, err = parse([]byte("stale: {days_until_stale: 30}\n"))
+	if err != nil || c.Stale == nil || c.Stale.Label != "stale" {
+		t.Errorf("Parse() stale = %v, %v; want defaults", c, err)
+	}
+	if _, err := parse([]byte("pulls: {size_labels: [{max: 1}]}\n")); err == nil {
+		t.Errorf("Parse() invalid pulls error = nil")
+	}
+	if _, err := parse([]byte("projects: {project: 1, columns: [{name: Current}]}\n")); err == nil {
+		t.Errorf("Parse() invalid projects error = nil")
+	}
+	if _, err := parse([]byte("statuses: {validators: [{type: spelling}]}\n")); err == nil {
+		t.Errorf("Parse() invalid statuses error = nil")
+	}
+	c, err = parse([]byte("statuses: {validators: [{type: signed_off_by}]}\n"))
+	if err != nil || c.Statuses == nil || c.Statuses.Context != statuses.DefaultContext {
+		t.Errorf("Parse() statuses = %v, %v; want defaults", c, err)
+	}
+	if _, err := parse([]byte("releases: {mode: replace}\n")); err == nil {
+		t.Errorf("Parse() invalid releases error = nil")
+	}
+	c, err = parse([]byte("releases: {other: Changes}\n"))
+	if err != nil || c.Releases == nil || c.Releases.Mode != "update" {
+		t.Errorf("Parse() releases = %v, %v; want defaults", c, err)
+	}
+}
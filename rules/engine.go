@@ -0,0 +1,220 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/iteration"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
+)
+
+// TemplateData is available to label and comment templates.
+type TemplateData struct {
+	// Week and Year are the ISO 8601 week and year at the time of the event.
+	Week int
+	Year int
+	// Iteration is the current iteration of the Engine's calendar, or nil if
+	// there is none.
+	Iteration *iteration.Iteration
+	// Label is the label that triggered the event, if any.
+	Label  string
+	Issue  *github.Issue
+	Repo   *github.Repository
+	Sender *github.User
+}
+
+// Engine applies a rule set to issue events.
+type Engine struct {
+	Config *Config
+	// Calendar maps the current time to iterations. Defaults to ISO weeks.
+	Calendar *iteration.Calendar
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// NewEngine creates a new Engine for the given, validated rule set.
+func NewEngine(c *Config) *Engine {
+	return &Engine{Config: c, Calendar: iteration.Default(), Now: time.Now}
+}
+
+// WithRules returns a copy of the Engine using the given rule set.
+func (e *Engine) WithRules(c *Config) *Engine {
+	n := *e
+	n.Config = c
+	return &n
+}
+
+// WithCalendar returns a copy of the Engine using the given calendar.
+func (e *Engine) WithCalendar(c *iteration.Calendar) *Engine {
+	n := *e
+	n.Calendar = c
+	return &n
+}
+
+// Match returns the rules triggered by the event whose conditions are true for
+// the given current issue labels.
+func (e *Engine) Match(event *github.IssuesEvent, labels []string) []*Rule {
+	matched := []*Rule{}
+	for _, r := range e.Config.Rules {
+		if r.triggered("issues", event) && r.Conditions.match(event, labels) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// Apply takes the actions of every rule matching the event, in order. Apply
+// stops at the first error.
+func (e *Engine) Apply(ctx context.Context, ev *issues.Event) error {
+	for _, r := range e.Match(ev.IssuesEvent, ev.Labels()) {
+		log.Printf("IssuesEvent: applying rule %q", r.Name)
+		ev.Rule = r.Name
+		if err := e.apply(ctx, r, ev); err != nil {
+			return fmt.Errorf("rule %q: %v", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (e *Engine) apply(ctx context.Context, r *Rule, ev *issues.Event) error {
+	data := e.templateData(ev.IssuesEvent)
+	add, err := executeAll(r.Actions.addLabels, data)
+	if err != nil {
+		return err
+	}
+	remove, err := executeAll(r.Actions.removeLabels, data)
+	if err != nil {
+		return err
+	}
+	if r.Actions.Iteration {
+		current, previous, err := e.Rollover(ev.Labels())
+		if err != nil {
+			return err
+		}
+		add = append(add, current...)
+		remove = append(remove, previous...)
+	}
+	if len(add) > 0 || len(remove) > 0 {
+		result, err := ev.ReconcileLabels(ctx, add, remove)
+		if err != nil {
+			return err
+		}
+		if result.Changed() {
+			log.Printf("IssuesEvent: rule %q added %q removed %q", r.Name, result.Added, result.Removed)
+		}
+	}
+	switch {
+	case r.Actions.Close && ev.GetIssue().GetState() != "closed":
+		if _, _, err := ev.CloseIssue(ctx, nil); err != nil {
+			return err
+		}
+	case r.Actions.Reopen && ev.GetIssue().GetState() == "closed":
+		if _, _, err := ev.ReopenIssue(ctx); err != nil {
+			return err
+		}
+	}
+	if r.Actions.comment != nil {
+		body, err := execute(r.Actions.comment, data)
+		if err != nil {
+			return err
+		}
+		if _, _, err := ev.CreateComment(ctx, body); err != nil {
+			return err
+		}
+	}
+	if len(r.Actions.Assign) > 0 {
+		if _, _, err := ev.AddAssignees(ctx, r.Actions.Assign); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Engine) templateData(event *github.IssuesEvent) *TemplateData {
+	now := e.now()
+	year, week := now.ISOWeek()
+	// Named calendars may have no current iteration.
+	it, _ := e.calendar().At(now)
+	return &TemplateData{
+		Week:      week,
+		Year:      year,
+		Iteration: it,
+		Label:     event.GetLabel().GetName(),
+		Issue:     event.GetIssue(),
+		Repo:      event.GetRepo(),
+		Sender:    event.GetSender(),
+	}
+}
+
+// Rollover returns the labels of the current iteration, and the given labels of
+// other iterations to remove. See iteration.Calendar.Rollover.
+func (e *Engine) Rollover(labels []string) (add, remove []string, err error) {
+	add, remove, err = e.calendar().Rollover(e.now(), labels)
+	if err != nil {
+		return nil, nil, fmt.Errorf("iteration: %v", err)
+	}
+	return add, remove, nil
+}
+
+func (e *Engine) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+func (e *Engine) calendar() *iteration.Calendar {
+	if e.Calendar == nil {
+		return iteration.Default()
+	}
+	return e.Calendar
+}
+
+// triggered reports whether the rule trigger matches the event.
+func (r *Rule) triggered(name string, event *github.IssuesEvent) bool {
+	t := &r.Trigger
+	if t.Event != name {
+		return false
+	}
+	action := event.GetAction()
+	if len(t.Actions) > 0 && !slice.ContainsString(t.Actions, action) {
+		return false
+	}
+	label := event.GetLabel().GetName()
+	switch {
+	case t.LabelAdded != "":
+		return action == "labeled" && strings.EqualFold(label, t.LabelAdded)
+	case t.LabelRemoved != "":
+		return action == "unlabeled" && strings.EqualFold(label, t.LabelRemoved)
+	}
+	return true
+}
+
+// match reports whether all conditions are true.
+func (c *Conditions) match(event *github.IssuesEvent, labels []string) bool {
+	for _, l := range c.LabelsPresent {
+		if !slice.ContainsFold(labels, l) {
+			return false
+		}
+	}
+	for _, l := range c.LabelsAbsent {
+		if slice.ContainsFold(labels, l) {
+			return false
+		}
+	}
+	if c.Author != "" && !strings.EqualFold(c.Author, event.GetIssue().GetUser().GetLogin()) {
+		return false
+	}
+	if c.Repo != "" && !strings.EqualFold(c.Repo, event.GetRepo().GetFullName()) {
+		return false
+	}
+	if c.titleRE != nil && !c.titleRE.MatchString(event.GetIssue().GetTitle()) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,250 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface/ifacetest"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+	"github.com/stephen-soltesz/github-webhook-poc/iteration"
+)
+
+func newEvent(action, label string, labels ...string) *github.IssuesEvent {
+	event := &github.IssuesEvent{
+		Action: &action,
+		Issue: &github.Issue{
+			Title: github.String("Fix the bug"),
+			User:  &github.User{Login: github.String("alice")},
+		},
+		Repo: &github.Repository{FullName: github.String("o/r")},
+	}
+	for i := range labels {
+		event.Issue.Labels = append(event.Issue.Labels, github.Label{Name: &labels[i]})
+	}
+	if label != "" {
+		event.Label = &github.Label{Name: &label}
+	}
+	return event
+}
+
+func TestEngine_ApplyDefault(t *testing.T) {
+	now := time.Date(2019, 02, 17, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		event *github.IssuesEvent
+		want  []string
+	}{
+		{
+			name:  "opened",
+			event: newEvent("opened", ""),
+			want:  []string{`add ["review/triage"]`},
+		},
+		{
+			name:  "closed",
+			event: newEvent("closed", "", "review/triage"),
+			want:  []string{`remove "review/triage"`},
+		},
+		{
+			name:  "closed-no-op",
+			event: newEvent("closed", "", "bug"),
+			want:  nil,
+		},
+		{
+			name:  "backlog",
+			event: newEvent("labeled", "backlog", "backlog", "review/triage"),
+			want:  []string{`remove "review/triage"`},
+		},
+		{
+			name:  "current",
+			event: newEvent("labeled", "Current", "current", "backlog"),
+			want: []string{
+				`add ["Week 7" "2019"]`,
+				`remove "backlog"`,
+			},
+		},
+		{
+			name:  "current-rollover",
+			event: newEvent("labeled", "current", "current", "Week 6", "2019"),
+			want: []string{
+				`add ["Week 7"]`,
+				`remove "Week 6"`,
+			},
+		},
+		{
+			name:  "current-no-op",
+			event: newEvent("labeled", "current", "current", "Week 7", "2019"),
+			want:  nil,
+		},
+		{
+			name:  "closed-label",
+			event: newEvent("labeled", "closed", "closed"),
+			want:  []string{`edit state=closed`},
+		},
+		{
+			name:  "unlabeled",
+			event: newEvent("unlabeled", "backlog"),
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(Default())
+			e.Now = func() time.Time { return now }
+			f := &ifacetest.Issues{}
+			if err := e.Apply(context.Background(), issues.NewEvent(f, tt.event)); err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if !reflect.DeepEqual(f.Calls(), tt.want) {
+				t.Errorf("Apply() calls = %q, want %q", f.Calls(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_WeekLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{
+			name: "week 1 2019",
+			now:  time.Date(2019, 01, 1, 0, 0, 0, 0, time.UTC),
+			want: `add ["Week 1" "2019"]`,
+		},
+		{
+			name: "week 52 2019",
+			now:  time.Date(2019, 12, 29, 0, 0, 0, 0, time.UTC),
+			want: `add ["Week 52" "2019"]`,
+		},
+		{
+			name: "week 1 2020",
+			now:  time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC),
+			want: `add ["Week 1" "2020"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine(Default())
+			e.Now = func() time.Time { return tt.now }
+			f := &ifacetest.Issues{}
+			e.Apply(context.Background(), issues.NewEvent(f, newEvent("labeled", "current", "current")))
+			if len(f.Calls()) == 0 || f.Calls()[0] != tt.want {
+				t.Errorf("Apply() calls = %q, want %q first", f.Calls(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_Calendar(t *testing.T) {
+	cal := &iteration.Calendar{}
+	err := configfile.Parse([]byte("kind: sprint\nweeks: 2\nanchor: 2019-01-07\n"), cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{}
+	err = configfile.Parse([]byte(`
+rules:
+- name: current
+  trigger: {event: issues, label_added: current}
+  actions:
+    iteration: true
+    comment: "Scheduled for {{.Iteration.Name}}"
+`), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(c).WithCalendar(cal)
+	e.Now = func() time.Time { return time.Date(2019, 01, 22, 0, 0, 0, 0, time.UTC) }
+	f := &ifacetest.Issues{}
+	event := newEvent("labeled", "current", "current", "Sprint 1")
+	if err := e.Apply(context.Background(), issues.NewEvent(f, event)); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	want := []string{`add ["Sprint 2"]`, `remove "Sprint 1"`, "comment Scheduled for Sprint 2"}
+	if !reflect.DeepEqual(f.Calls(), want) {
+		t.Errorf("Apply() calls = %q, want %q", f.Calls(), want)
+	}
+}
+
+func TestEngine_CalendarNoIteration(t *testing.T) {
+	cal := &iteration.Calendar{}
+	if err := configfile.Parse([]byte("kind: named\niterations:\n- {name: Alpha, start: 2019-01-01, end: 2019-01-15}\n"), cal); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEngine(Default()).WithCalendar(cal)
+	e.Now = func() time.Time { return time.Date(2019, 01, 22, 0, 0, 0, 0, time.UTC) }
+	f := &ifacetest.Issues{}
+	event := newEvent("labeled", "current", "current", "review/triage", "Alpha")
+	if err := e.Apply(context.Background(), issues.NewEvent(f, event)); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	want := []string{`remove "review/triage"`, `remove "Alpha"`}
+	if !reflect.DeepEqual(f.Calls(), want) {
+		t.Errorf("Apply() calls = %q, want %q", f.Calls(), want)
+	}
+}
+
+func TestEngine_Conditions(t *testing.T) {
+	config := `
+rules:
+- name: greet
+  trigger: {event: issues, actions: [opened]}
+  conditions:
+    labels_absent: [wontfix]
+    labels_present: [bug]
+    author: alice
+    repo: o/r
+    title_regex: '^Fix'
+  actions:
+    comment: "Thanks {{.Issue.User.Login}}"
+    assign: [bob]
+`
+	c := &Config{}
+	if err := configfile.Parse([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		event *github.IssuesEvent
+		want  []string
+	}{
+		{
+			name:  "match",
+			event: newEvent("opened", "", "bug"),
+			want:  []string{"comment Thanks alice", `assign ["bob"]`},
+		},
+		{
+			name:  "label-absent",
+			event: newEvent("opened", "", "bug", "wontfix"),
+		},
+		{
+			name:  "label-missing",
+			event: newEvent("opened", ""),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &ifacetest.Issues{}
+			if err := NewEngine(c).Apply(context.Background(), issues.NewEvent(f, tt.event)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(f.Calls(), tt.want) {
+				t.Errorf("Apply() calls = %q, want %q", f.Calls(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_ApplyError(t *testing.T) {
+	f := &ifacetest.Issues{Err: fmt.Errorf("fake error")}
+	err := NewEngine(Default()).Apply(context.Background(), issues.NewEvent(f, newEvent("opened", "")))
+	if err == nil {
+		t.Errorf("Apply() error = nil, want error")
+	}
+}
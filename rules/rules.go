@@ -0,0 +1,262 @@
+// Package rules implements a declarative label workflow for issues.
+//
+// A rule set is loaded from a YAML or JSON file. Each rule names a trigger,
+// optional conditions and actions to take on the issue. For example:
+//
+//	rules:
+//	- name: label-current
+//	  trigger:
+//	    event: issues
+//	    label_added: current
+//	  actions:
+//	    iteration: true
+//	    remove_labels: [review/triage, backlog, closed]
+//
+// Label names and comments are Go text/templates executed with TemplateData.
+// The iteration action adds the labels of the current iteration, and removes
+// the labels of the previous one, using the Engine's iteration.Calendar.
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
+)
+
+// DefaultYAML is the default rule set, matching the original triage workflow.
+const DefaultYAML = `
+rules:
+- name: triage-opened
+  trigger:
+    event: issues
+    actions: [opened, reopened]
+  actions:
+    add_labels: [review/triage]
+
+- name: triage-closed
+  trigger:
+    event: issues
+    actions: [closed]
+  actions:
+    remove_labels: [review/triage]
+
+- name: label-backlog
+  trigger:
+    event: issues
+    label_added: backlog
+  actions:
+    remove_labels: [review/triage, current, closed]
+
+- name: label-current
+  trigger:
+    event: issues
+    label_added: current
+  actions:
+    iteration: true
+    remove_labels: [review/triage, backlog, closed]
+
+- name: label-closed
+  trigger:
+    event: issues
+    label_added: closed
+  actions:
+    close: true
+`
+
+var (
+	// supportedEvents lists the webhook event names rules may trigger on, and
+	// the actions supported for each.
+	supportedEvents = map[string][]string{
+		"issues": {
+			"opened", "edited", "deleted", "transferred", "pinned", "unpinned",
+			"closed", "reopened", "assigned", "unassigned", "labeled",
+			"unlabeled", "locked", "unlocked", "milestoned", "demilestoned",
+		},
+	}
+)
+
+// Config is a rule set.
+type Config struct {
+	Rules []*Rule `yaml:"rules" json:"rules"`
+}
+
+// Rule describes a trigger, conditions and the actions to take when both
+// match an event.
+type Rule struct {
+	Name       string     `yaml:"name" json:"name"`
+	Trigger    Trigger    `yaml:"trigger" json:"trigger"`
+	Conditions Conditions `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+	Actions    Actions    `yaml:"actions" json:"actions"`
+}
+
+// Trigger selects the events a rule applies to.
+type Trigger struct {
+	// Event is the webhook event name, e.g. "issues".
+	Event string `yaml:"event" json:"event"`
+	// Actions are the event actions, e.g. "opened". Empty matches all actions,
+	// unless LabelAdded or LabelRemoved are set.
+	Actions []string `yaml:"actions,omitempty" json:"actions,omitempty"`
+	// LabelAdded matches "labeled" events for the named label.
+	LabelAdded string `yaml:"label_added,omitempty" json:"label_added,omitempty"`
+	// LabelRemoved matches "unlabeled" events for the named label.
+	LabelRemoved string `yaml:"label_removed,omitempty" json:"label_removed,omitempty"`
+}
+
+// Conditions must all be true for a triggered rule to act.
+type Conditions struct {
+	// LabelsPresent must all be on the issue.
+	LabelsPresent []string `yaml:"labels_present,omitempty" json:"labels_present,omitempty"`
+	// LabelsAbsent must all be missing from the issue.
+	LabelsAbsent []string `yaml:"labels_absent,omitempty" json:"labels_absent,omitempty"`
+	// Author is the login of the issue author.
+	Author string `yaml:"author,omitempty" json:"author,omitempty"`
+	// Repo is the "owner/name" of the repository.
+	Repo string `yaml:"repo,omitempty" json:"repo,omitempty"`
+	// TitleRegex must match the issue title.
+	TitleRegex string `yaml:"title_regex,omitempty" json:"title_regex,omitempty"`
+
+	titleRE *regexp.Regexp
+}
+
+// Actions are taken in the order: add and remove labels, close or reopen,
+// comment and assign.
+type Actions struct {
+	AddLabels    []string `yaml:"add_labels,omitempty" json:"add_labels,omitempty"`
+	RemoveLabels []string `yaml:"remove_labels,omitempty" json:"remove_labels,omitempty"`
+	// Iteration adds the current iteration labels and removes the previous
+	// iteration labels.
+	Iteration bool     `yaml:"iteration,omitempty" json:"iteration,omitempty"`
+	Close     bool     `yaml:"close,omitempty" json:"close,omitempty"`
+	Reopen    bool     `yaml:"reopen,omitempty" json:"reopen,omitempty"`
+	Comment   string   `yaml:"comment,omitempty" json:"comment,omitempty"`
+	Assign    []string `yaml:"assign,omitempty" json:"assign,omitempty"`
+
+	addLabels    []*template.Template
+	removeLabels []*template.Template
+	comment      *template.Template
+}
+
+// Default returns the default rule set.
+func Default() *Config {
+	c := &Config{}
+	if err := configfile.Parse([]byte(DefaultYAML), c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Validate checks the rule set and compiles all expressions and templates.
+func (c *Config) Validate() error {
+	names := []string{}
+	for i, r := range c.Rules {
+		if r == nil {
+			return fmt.Errorf("rule %d: empty rule", i)
+		}
+		if r.Name == "" {
+			return fmt.Errorf("rule %d: missing name", i)
+		}
+		if slice.ContainsString(names, r.Name) {
+			return fmt.Errorf("rule %q: duplicate name", r.Name)
+		}
+		names = append(names, r.Name)
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("rule %q: %v", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) validate() error {
+	t := &r.Trigger
+	actions, ok := supportedEvents[t.Event]
+	if !ok {
+		return fmt.Errorf("unsupported trigger event %q", t.Event)
+	}
+	for _, a := range t.Actions {
+		if !slice.ContainsString(actions, a) {
+			return fmt.Errorf("unsupported %s action %q", t.Event, a)
+		}
+	}
+	if t.LabelAdded != "" && t.LabelRemoved != "" {
+		return fmt.Errorf("label_added and label_removed are mutually exclusive")
+	}
+	if t.LabelAdded != "" && len(t.Actions) > 0 && !slice.ContainsString(t.Actions, "labeled") {
+		return fmt.Errorf("label_added requires the labeled action")
+	}
+	if t.LabelRemoved != "" && len(t.Actions) > 0 && !slice.ContainsString(t.Actions, "unlabeled") {
+		return fmt.Errorf("label_removed requires the unlabeled action")
+	}
+
+	if r.Conditions.TitleRegex != "" {
+		re, err := regexp.Compile(r.Conditions.TitleRegex)
+		if err != nil {
+			return fmt.Errorf("title_regex: %v", err)
+		}
+		r.Conditions.titleRE = re
+	}
+	if r.Conditions.Repo != "" && !strings.Contains(r.Conditions.Repo, "/") {
+		return fmt.Errorf("repo %q must be owner/name", r.Conditions.Repo)
+	}
+
+	a := &r.Actions
+	if a.Close && a.Reopen {
+		return fmt.Errorf("close and reopen are mutually exclusive")
+	}
+	if len(a.AddLabels) == 0 && len(a.RemoveLabels) == 0 && !a.Iteration &&
+		!a.Close && !a.Reopen && a.Comment == "" && len(a.Assign) == 0 {
+		return fmt.Errorf("no actions")
+	}
+	var err error
+	if a.addLabels, err = parseTemplates(r.Name, a.AddLabels); err != nil {
+		return fmt.Errorf("add_labels: %v", err)
+	}
+	if a.removeLabels, err = parseTemplates(r.Name, a.RemoveLabels); err != nil {
+		return fmt.Errorf("remove_labels: %v", err)
+	}
+	if a.Comment != "" {
+		if a.comment, err = template.New(r.Name).Option("missingkey=error").Parse(a.Comment); err != nil {
+			return fmt.Errorf("comment: %v", err)
+		}
+	}
+	return nil
+}
+
+func parseTemplates(name string, values []string) ([]*template.Template, error) {
+	tmpls := []*template.Template{}
+	for _, v := range values {
+		if strings.TrimSpace(v) == "" {
+			return nil, fmt.Errorf("empty label")
+		}
+		t, err := template.New(name).Option("missingkey=error").Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		tmpls = append(tmpls, t)
+	}
+	return tmpls, nil
+}
+
+func execute(t *template.Template, data *TemplateData) (string, error) {
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func executeAll(tmpls []*template.Template, data *TemplateData) ([]string, error) {
+	values := []string{}
+	for _, t := range tmpls {
+		v, err := execute(t, data)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+func TestDefault(t *testing.T) {
+	c := Default()
+	if len(c.Rules) != 5 {
+		t.Errorf("Default() = %d rules, want 5", len(c.Rules))
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:   "yaml",
+			config: DefaultYAML,
+		},
+		{
+			name:   "json",
+			config: `{"rules": [{"name": "a", "trigger": {"event": "issues"}, "actions": {"comment": "hi"}}]}`,
+		},
+		{
+			name:    "unknown-field",
+			config:  "rules:\n- name: a\n  trigger: {event: issues}\n  actions: {close: true, explode: true}\n",
+			wantErr: "explode",
+		},
+		{
+			name:    "missing-name",
+			config:  "rules:\n- trigger: {event: issues}\n  actions: {close: true}\n",
+			wantErr: "missing name",
+		},
+		{
+			name:    "duplicate-name",
+			config:  "rules:\n- {name: a, trigger: {event: issues}, actions: {close: true}}\n- {name: a, trigger: {event: issues}, actions: {close: true}}\n",
+			wantErr: "duplicate name",
+		},
+		{
+			name:    "unsupported-event",
+			config:  "rules:\n- {name: a, trigger: {event: push}, actions: {close: true}}\n",
+			wantErr: "unsupported trigger event",
+		},
+		{
+			name:    "unsupported-action",
+			config:  "rules:\n- {name: a, trigger: {event: issues, actions: [exploded]}, actions: {close: true}}\n",
+			wantErr: "unsupported issues action",
+		},
+		{
+			name:    "label-added-action",
+			config:  "rules:\n- {name: a, trigger: {event: issues, actions: [opened], label_added: x}, actions: {close: true}}\n",
+			wantErr: "requires the labeled action",
+		},
+		{
+			name:    "bad-regex",
+			config:  "rules:\n- {name: a, trigger: {event: issues}, conditions: {title_regex: '('}, actions: {close: true}}\n",
+			wantErr: "title_regex",
+		},
+		{
+			name:    "bad-template",
+			config:  "rules:\n- {name: a, trigger: {event: issues}, actions: {add_labels: ['{{.Week']}}\n",
+			wantErr: "add_labels",
+		},
+		{
+			name:    "close-and-reopen",
+			config:  "rules:\n- {name: a, trigger: {event: issues}, actions: {close: true, reopen: true}}\n",
+			wantErr: "mutually exclusive",
+		},
+		{
+			name:    "no-actions",
+			config:  "rules:\n- {name: a, trigger: {event: issues}}\n",
+			wantErr: "no actions",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := configfile.Parse([]byte(tt.config), &Config{})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// descriptors are shorthands for common schedules.
+var descriptors = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 1",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// field is the set of allowed values of one schedule field.
+type field map[int]bool
+
+// Schedule is a parsed five field cron expression: minute, hour, day of month,
+// month and day of week. Fields accept "*", values, ranges "a-b", lists "a,b"
+// and steps "*/n" or "a-b/n". Day of week 0 and 7 are Sunday. As in cron, when
+// both day of month and day of week are restricted, a time matches if either
+// matches.
+type Schedule struct {
+	spec                          string
+	minute, hour, dom, month, dow field
+	domAny, dowAny                bool
+}
+
+// Parse parses a cron expression or one of the descriptors @hourly, @daily,
+// @weekly (Monday), @monthly or @yearly.
+func Parse(spec string) (*Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	if d, ok := descriptors[expr]; ok {
+		expr = d
+	}
+	f := strings.Fields(expr)
+	if len(f) != 5 {
+		return nil, fmt.Errorf("schedule %q: want 5 fields, got %d", spec, len(f))
+	}
+	s := &Schedule{spec: spec, domAny: f[2] == "*", dowAny: f[4] == "*"}
+	var err error
+	if s.minute, err = parseField(f[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("schedule %q: minute: %v", spec, err)
+	}
+	if s.hour, err = parseField(f[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("schedule %q: hour: %v", spec, err)
+	}
+	if s.dom, err = parseField(f[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("schedule %q: day of month: %v", spec, err)
+	}
+	if s.month, err = parseField(f[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("schedule %q: month: %v", spec, err)
+	}
+	if s.dow, err = parseField(f[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("schedule %q: day of week: %v", spec, err)
+	}
+	if s.dow[7] {
+		s.dow[0] = true
+	}
+	return s, nil
+}
+
+// String returns the original expression.
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Match reports whether the minute containing t is scheduled. Match uses the
+// location of t.
+func (s *Schedule) Match(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := s.dom[t.Day()], s.dow[int(t.Weekday())]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first scheduled minute after t, or the zero time if there
+// is none within five years, e.g. for "0 0 31 2 *".
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for ; t.Before(end); t = t.Add(time.Minute) {
+		if s.Match(t) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseField parses a comma separated list of values, ranges and steps.
+func parseField(expr string, min, max int) (field, error) {
+	f := field{}
+	for _, part := range strings.Split(expr, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			r := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(r[0])
+			hi, err2 = strconv.Atoi(r[1])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				// "a/n" means "a-max/n".
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			f[v] = true
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "* * * * *"},
+		{spec: "*/15 9-17 * * 1-5"},
+		{spec: "0 0 1,15 * *"},
+		{spec: "@weekly"},
+		{spec: "0 0 * * 7"},
+		{spec: "* * * *", wantErr: true},
+		{spec: "60 * * * *", wantErr: true},
+		{spec: "* 5-1 * * *", wantErr: true},
+		{spec: "*/0 * * * *", wantErr: true},
+		{spec: "a * * * *", wantErr: true},
+		{spec: "@fortnightly", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedule_Match(t *testing.T) {
+	// Monday, 2019-02-18.
+	monday := time.Date(2019, 02, 18, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		{spec: "* * * * *", t: monday, want: true},
+		{spec: "30 9 * * *", t: monday, want: true},
+		{spec: "31 9 * * *", t: monday, want: false},
+		{spec: "*/15 9-17 * * 1-5", t: monday, want: true},
+		{spec: "*/15 9-17 * * 1-5", t: monday.AddDate(0, 0, 5), want: false},
+		{spec: "0 0 * * 1", t: time.Date(2019, 02, 18, 0, 0, 0, 0, time.UTC), want: true},
+		{spec: "0 0 * * 7", t: time.Date(2019, 02, 17, 0, 0, 0, 0, time.UTC), want: true},
+		// Day of month or day of week.
+		{spec: "30 9 1 * 1", t: monday, want: true},
+		{spec: "30 9 1 * 2", t: monday, want: false},
+		{spec: "30 9 18 * 2", t: monday, want: true},
+		{spec: "30 9 * 3 *", t: monday, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Match(tt.t); got != tt.want {
+				t.Errorf("Match(%s) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	s, err := Parse("@weekly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := s.Next(time.Date(2019, 02, 18, 0, 0, 0, 0, time.UTC))
+	want := time.Date(2019, 02, 25, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("Next() = %s, want %s", got, want)
+	}
+	s, err = Parse("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(want); !got.IsZero() {
+		t.Errorf("Next() = %s, want zero", got)
+	}
+}
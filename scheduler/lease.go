@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Locker elects a single replica to run scheduled jobs.
+type Locker interface {
+	// Lock reports whether the caller holds the lock, acquiring or renewing
+	// it as needed.
+	Lock(ctx context.Context) (bool, error)
+}
+
+// lease is the content of a lease file.
+type lease struct {
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileLease is a Locker backed by a lease file on storage shared by all
+// replicas, e.g. a persistent volume. The holder renews the lease each time
+// it calls Lock. Other replicas take over once the lease expires.
+//
+// Replicas take turns to read and write the lease while holding an exclusive
+// flock of the file Path+".flock", so that two replicas ticking at the same
+// time cannot both take the lease.
+type FileLease struct {
+	// Path is the lease file name.
+	Path string
+	// Holder identifies this replica. Defaults to the hostname and process ID.
+	Holder string
+	// TTL is how long a lease lasts without renewal.
+	TTL time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+
+	mu sync.Mutex
+}
+
+// NewFileLease creates a new FileLease for the given file.
+func NewFileLease(path string, ttl time.Duration) *FileLease {
+	host, _ := os.Hostname()
+	return &FileLease{
+		Path:   path,
+		Holder: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		TTL:    ttl,
+		Now:    time.Now,
+	}
+}
+
+// Lock acquires the lease if it is free, expired or already held by l, and
+// reports whether l holds the lease.
+func (l *FileLease) Lock(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	unlock, err := l.flock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	now := l.now()
+	current, err := l.read()
+	if err != nil {
+		return false, err
+	}
+	if current != nil && current.Holder != l.Holder && now.Before(current.Expires) {
+		return false, nil
+	}
+	if err := l.write(&lease{Holder: l.Holder, Expires: now.Add(l.TTL)}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Unlock releases the lease if l holds it.
+func (l *FileLease) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	unlock, err := l.flock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	current, err := l.read()
+	if err != nil || current == nil || current.Holder != l.Holder {
+		return err
+	}
+	return os.Remove(l.Path)
+}
+
+// flock blocks until it holds the exclusive lock of the lease file, and
+// returns the function releasing it.
+func (l *FileLease) flock() (func(), error) {
+	f, err := os.OpenFile(l.Path+".flock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+func (l *FileLease) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// read returns the current lease, or nil if there is none.
+func (l *FileLease) read() (*lease, error) {
+	b, err := ioutil.ReadFile(l.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	current := &lease{}
+	if err := json.Unmarshal(b, current); err != nil {
+		// A corrupt lease is treated as expired.
+		return nil, nil
+	}
+	return current, nil
+}
+
+func (l *FileLease) write(v *lease) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(l.Path), filepath.Base(l.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), l.Path)
+}
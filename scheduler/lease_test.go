@@ -0,0 +1,112 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileLease(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lease")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scheduler.lock")
+
+	now := time.Date(2019, 02, 18, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	a := &FileLease{Path: path, Holder: "a", TTL: time.Minute, Now: clock}
+	b := &FileLease{Path: path, Holder: "b", TTL: time.Minute, Now: clock}
+	ctx := context.Background()
+
+	lock := func(l *FileLease, want bool) {
+		t.Helper()
+		got, err := l.Lock(ctx)
+		if err != nil {
+			t.Fatalf("Lock(%s) error = %v", l.Holder, err)
+		}
+		if got != want {
+			t.Errorf("Lock(%s) = %v, want %v", l.Holder, got, want)
+		}
+	}
+	lock(a, true)
+	lock(b, false)
+	// Renewing keeps the lease.
+	now = now.Add(50 * time.Second)
+	lock(a, true)
+	now = now.Add(50 * time.Second)
+	lock(b, false)
+	// An expired lease is taken over.
+	now = now.Add(time.Minute)
+	lock(b, true)
+	lock(a, false)
+
+	// Only the holder may unlock.
+	if err := a.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	lock(a, false)
+	if err := b.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	lock(a, true)
+}
+
+func TestFileLease_Concurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lease")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "scheduler.lock")
+
+	// Replicas ticking at the same time elect exactly one of them.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	start := make(chan struct{})
+	held := 0
+	for i := 0; i < 20; i++ {
+		l := NewFileLease(path, time.Minute)
+		l.Holder = fmt.Sprintf("replica-%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			got, err := l.Lock(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if got {
+				held++
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	if held != 1 {
+		t.Errorf("Lock() held by %d replicas, want 1", held)
+	}
+}
+
+func TestFileLease_Corrupt(t *testing.T) {
+	f, err := ioutil.TempFile("", "lease")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("not json")
+	f.Close()
+
+	l := NewFileLease(f.Name(), time.Minute)
+	if got, err := l.Lock(context.Background()); !got || err != nil {
+		t.Errorf("Lock() = %v, %v; want true", got, err)
+	}
+}
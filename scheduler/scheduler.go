@@ -0,0 +1,226 @@
+// Package scheduler runs jobs on cron-style schedules within the receiver.
+//
+// Each job has a five field cron expression evaluated once a minute in the
+// scheduler's location. When several replicas run, a Locker elects one of
+// them to run the jobs, e.g. a FileLease on a shared volume. The elected
+// replica renews the lock until its jobs return.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a named function run on a schedule.
+type Job struct {
+	Name     string
+	Schedule *Schedule
+	Run      func(ctx context.Context) error
+	// Timeout limits each run. Defaults to the scheduler's Timeout.
+	Timeout time.Duration
+
+	running bool
+}
+
+// Scheduler runs jobs on their schedules.
+type Scheduler struct {
+	// Location is used to evaluate schedules. Defaults to UTC.
+	Location *time.Location
+	// Locker, if not nil, must be held to run jobs.
+	Locker Locker
+	// Renew is how often the Locker is renewed while jobs run. It must be
+	// shorter than the lease of the Locker. Defaults to one minute.
+	Renew time.Duration
+	// Timeout is the default limit for each job run.
+	Timeout time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+
+	mu   sync.Mutex
+	jobs []*Job
+	wg   sync.WaitGroup
+}
+
+// New creates a new Scheduler that evaluates schedules in the given location.
+func New(loc *time.Location) *Scheduler {
+	return &Scheduler{Location: loc, Timeout: 30 * time.Minute, Now: time.Now}
+}
+
+// Add schedules a new job. Add returns an error if the spec is invalid or the
+// name is already in use.
+func (s *Scheduler) Add(name, spec string, run func(ctx context.Context) error) error {
+	sched, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return fmt.Errorf("job %q already exists", name)
+		}
+	}
+	s.jobs = append(s.jobs, &Job{Name: name, Schedule: sched, Run: run})
+	return nil
+}
+
+// Jobs returns the scheduled jobs.
+func (s *Scheduler) Jobs() []*Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Job(nil), s.jobs...)
+}
+
+// Run runs due jobs at the start of every minute until ctx is done, then waits
+// for running jobs to return.
+func (s *Scheduler) Run(ctx context.Context) {
+	for _, j := range s.Jobs() {
+		log.Printf("scheduler: %q runs %q, next at %s", j.Name, j.Schedule,
+			j.Schedule.Next(s.now()))
+	}
+	for {
+		now := s.now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+			return
+		case <-time.After(next.Sub(now)):
+			s.Tick(ctx, next)
+		}
+	}
+}
+
+// Tick starts every job scheduled for the minute containing t, unless it is
+// still running from an earlier tick. Jobs run in the background; use Wait to
+// wait for them. With a Locker, the lock is renewed until the jobs return, and
+// the jobs are cancelled if another replica takes it over.
+func (s *Scheduler) Tick(ctx context.Context, t time.Time) {
+	due := []*Job{}
+	for _, j := range s.Jobs() {
+		if j.Schedule.Match(t.In(s.location())) {
+			due = append(due, j)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	if s.Locker != nil {
+		held, err := s.Locker.Lock(ctx)
+		if err != nil {
+			log.Println("scheduler: lock:", err)
+			return
+		}
+		if !held {
+			log.Println("scheduler: another replica holds the lock")
+			return
+		}
+	}
+	jctx, cancel := context.WithCancel(ctx)
+	running := &sync.WaitGroup{}
+	for _, j := range due {
+		s.start(jctx, j, running)
+	}
+	s.wg.Add(1)
+	go s.hold(jctx, cancel, running)
+}
+
+// hold renews the Locker every Renew until the running jobs return, and
+// cancels them if the lock is lost.
+func (s *Scheduler) hold(ctx context.Context, cancel context.CancelFunc, running *sync.WaitGroup) {
+	defer s.wg.Done()
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+	if s.Locker == nil {
+		<-done
+		return
+	}
+	renew := s.Renew
+	if renew <= 0 {
+		renew = time.Minute
+	}
+	ticker := time.NewTicker(renew)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			held, err := s.Locker.Lock(ctx)
+			if err != nil {
+				log.Println("scheduler: renew lock:", err)
+				continue
+			}
+			if !held {
+				log.Println("scheduler: lost the lock, cancelling jobs")
+				return
+			}
+		}
+	}
+}
+
+// Wait waits for all running jobs to return.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *Scheduler) start(ctx context.Context, j *Job, running *sync.WaitGroup) {
+	s.mu.Lock()
+	if j.running {
+		s.mu.Unlock()
+		log.Printf("scheduler: %q is still running, skipping", j.Name)
+		return
+	}
+	j.running = true
+	s.mu.Unlock()
+
+	timeout := j.Timeout
+	if timeout == 0 {
+		timeout = s.Timeout
+	}
+	s.wg.Add(1)
+	running.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer running.Done()
+		defer func() {
+			s.mu.Lock()
+			j.running = false
+			s.mu.Unlock()
+		}()
+		jctx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			jctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		start := time.Now()
+		log.Printf("scheduler: %q started", j.Name)
+		if err := j.Run(jctx); err != nil {
+			log.Printf("scheduler: %q failed after %s: %v", j.Name, time.Since(start), err)
+			return
+		}
+		log.Printf("scheduler: %q finished in %s", j.Name, time.Since(start))
+	}()
+}
+
+func (s *Scheduler) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Scheduler) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
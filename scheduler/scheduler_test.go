@@ -0,0 +1,196 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeLocker struct {
+	held bool
+	err  error
+
+	mu    sync.Mutex
+	calls int
+	// lose, if not zero, is the call from which the lock is lost.
+	lose int
+}
+
+func (f *fakeLocker) Lock(ctx context.Context) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.lose > 0 && f.calls >= f.lose {
+		return false, nil
+	}
+	return f.held, f.err
+}
+
+func (f *fakeLocker) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func TestScheduler_Tick(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		locker Locker
+		at     time.Time
+		want   []string
+	}{
+		{
+			name: "due",
+			at:   time.Date(2019, 02, 18, 5, 0, 0, 0, time.UTC),
+			want: []string{"hourly", "morning"},
+		},
+		{
+			name: "not-due",
+			at:   time.Date(2019, 02, 18, 5, 1, 0, 0, time.UTC),
+		},
+		{
+			name:   "lock-held",
+			locker: &fakeLocker{held: true},
+			at:     time.Date(2019, 02, 18, 6, 0, 0, 0, time.UTC),
+			want:   []string{"hourly"},
+		},
+		{
+			name:   "lock-lost",
+			locker: &fakeLocker{held: false},
+			at:     time.Date(2019, 02, 18, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "lock-error",
+			locker: &fakeLocker{err: fmt.Errorf("fake error")},
+			at:     time.Date(2019, 02, 18, 5, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			ran := map[string]bool{}
+			run := func(name string) func(context.Context) error {
+				return func(ctx context.Context) error {
+					mu.Lock()
+					defer mu.Unlock()
+					ran[name] = true
+					return nil
+				}
+			}
+			s := New(ny)
+			s.Locker = tt.locker
+			if err := s.Add("hourly", "@hourly", run("hourly")); err != nil {
+				t.Fatal(err)
+			}
+			// 05:00 UTC is midnight in New York.
+			if err := s.Add("morning", "0 0 * * *", run("morning")); err != nil {
+				t.Fatal(err)
+			}
+			s.Tick(context.Background(), tt.at)
+			s.Wait()
+			if len(ran) != len(tt.want) {
+				t.Errorf("Tick() ran %v, want %v", ran, tt.want)
+			}
+			for _, name := range tt.want {
+				if !ran[name] {
+					t.Errorf("Tick() did not run %q", name)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduler_Add(t *testing.T) {
+	s := New(time.UTC)
+	noop := func(ctx context.Context) error { return nil }
+	if err := s.Add("a", "bad", noop); err == nil {
+		t.Errorf("Add() invalid spec error = nil")
+	}
+	if err := s.Add("a", "@daily", noop); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("a", "@daily", noop); err == nil {
+		t.Errorf("Add() duplicate error = nil")
+	}
+}
+
+func TestScheduler_SkipRunning(t *testing.T) {
+	s := New(time.UTC)
+	release := make(chan struct{})
+	count := 0
+	s.Add("slow", "* * * * *", func(ctx context.Context) error {
+		count++
+		<-release
+		return fmt.Errorf("fake error")
+	})
+	now := time.Date(2019, 02, 18, 0, 0, 0, 0, time.UTC)
+	s.Tick(context.Background(), now)
+	// Wait for the first run to start.
+	for {
+		s.mu.Lock()
+		running := s.jobs[0].running
+		s.mu.Unlock()
+		if running {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Tick(context.Background(), now.Add(time.Minute))
+	close(release)
+	s.Wait()
+	if count != 1 {
+		t.Errorf("Tick() ran slow job %d times, want 1", count)
+	}
+}
+
+func TestScheduler_Renew(t *testing.T) {
+	now := time.Date(2019, 02, 18, 0, 0, 0, 0, time.UTC)
+
+	// The lock is renewed while a job runs.
+	s := New(time.UTC)
+	locker := &fakeLocker{held: true}
+	s.Locker = locker
+	s.Renew = time.Millisecond
+	s.Add("slow", "* * * * *", func(ctx context.Context) error {
+		for locker.Calls() < 3 {
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+	s.Tick(context.Background(), now)
+	s.Wait()
+	calls := locker.Calls()
+	time.Sleep(10 * time.Millisecond)
+	if got := locker.Calls(); got != calls {
+		t.Errorf("Lock() called %d times after the job returned, want none", got-calls)
+	}
+
+	// The job is cancelled once another replica takes the lock.
+	s = New(time.UTC)
+	s.Locker = &fakeLocker{held: true, lose: 2}
+	s.Renew = time.Millisecond
+	var err error
+	s.Add("slow", "* * * * *", func(ctx context.Context) error {
+		<-ctx.Done()
+		err = ctx.Err()
+		return err
+	})
+	s.Tick(context.Background(), now)
+	s.Wait()
+	if err != context.Canceled {
+		t.Errorf("job context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestScheduler_Run(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Run returns once ctx is done.
+	New(time.UTC).Run(ctx)
+}
@@ -0,0 +1,112 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// conn is a buffered connection to a broker.
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+func dial(ctx context.Context, addr string) (*conn, error) {
+	var d net.Dialer
+	c, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetDeadline(deadline)
+	}
+	return &conn{Conn: c, r: bufio.NewReader(c), w: bufio.NewWriter(c)}, nil
+}
+
+// readLine returns the next line without the CRLF.
+func (c *conn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// flush sends the buffered request. Its errors are writeErrors.
+func (c *conn) flush() error {
+	if err := c.w.Flush(); err != nil {
+		return writeError{err}
+	}
+	return nil
+}
+
+// writeError is an error sending a request. The broker never received the
+// whole request, so it is safe to send it again.
+type writeError struct {
+	error
+}
+
+// alive reports whether the broker has not closed the idle connection. It
+// waits at most a millisecond for a pending close; a deadline in the past
+// would fail without reading at all.
+func (c *conn) alive() bool {
+	if c.r.Buffered() > 0 {
+		return true
+	}
+	c.SetReadDeadline(time.Now().Add(time.Millisecond))
+	_, err := c.r.Peek(1)
+	c.SetReadDeadline(time.Time{})
+	if err == nil {
+		return true
+	}
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
+// retry calls fn with the connection in *cp, dialing if there is none, or if
+// the broker closed the cached connection while it was idle. After an error,
+// the connection is closed. fn is tried once more on a new connection only if
+// it failed to send its request, since a request sent in full may have been
+// processed even if its reply was lost, and error replies such as "-ERR" are
+// not transient.
+func retry(ctx context.Context, cp **conn, dialFn func(context.Context) (*conn, error), fn func(*conn) error) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if *cp != nil && !(*cp).alive() {
+			closeConn(cp)
+		}
+		if *cp == nil {
+			if *cp, err = dialFn(ctx); err != nil {
+				return err
+			}
+		}
+		c := *cp
+		// A zero deadline, without a context deadline, clears it.
+		deadline, _ := ctx.Deadline()
+		c.SetDeadline(deadline)
+		if err = fn(c); err == nil {
+			return nil
+		}
+		closeConn(cp)
+		if _, ok := err.(writeError); !ok || ctx.Err() != nil {
+			break
+		}
+	}
+	if w, ok := err.(writeError); ok {
+		return w.error
+	}
+	return err
+}
+
+func closeConn(cp **conn) error {
+	if *cp == nil {
+		return nil
+	}
+	err := (*cp).Close()
+	*cp = nil
+	return err
+}
@@ -0,0 +1,91 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// NATS publishes events to a NATS server, using the text protocol. The subject
+// of each event is the prefix and the event name, e.g. "github.issues".
+type NATS struct {
+	// Addr is the "host:port" of the server.
+	Addr string
+	// Prefix is the first token of the subjects.
+	Prefix string
+
+	mu sync.Mutex
+	c  *conn
+}
+
+// Publish sends the event, connecting to the server if needed. Publish waits
+// for the server to process the message, so errors are reported.
+func (n *NATS) Publish(ctx context.Context, e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	subject := n.Prefix + "." + e.Event
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return retry(ctx, &n.c, n.dial, func(c *conn) error {
+		fmt.Fprintf(c.w, "PUB %s %d\r\n", subject, len(b))
+		c.w.Write(b)
+		c.w.WriteString("\r\nPING\r\n")
+		if err := c.flush(); err != nil {
+			return err
+		}
+		return n.pong(c)
+	})
+}
+
+// dial connects and sends the CONNECT message.
+func (n *NATS) dial(ctx context.Context) (*conn, error) {
+	c, err := dial(ctx, n.Addr)
+	if err != nil {
+		return nil, err
+	}
+	// The server starts with an INFO message.
+	line, err := c.readLine()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		c.Close()
+		return nil, fmt.Errorf("nats: unexpected greeting %q", line)
+	}
+	c.w.WriteString(`CONNECT {"verbose":false,"pedantic":false,"name":"github-webhook-receiver"}` + "\r\n")
+	return c, nil
+}
+
+// pong reads messages until the reply to PING, answering the PINGs of the
+// server.
+func (n *NATS) pong(c *conn) error {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			c.w.WriteString("PONG\r\n")
+			if err := c.w.Flush(); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// Close closes the connection, if any.
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return closeConn(&n.c)
+}
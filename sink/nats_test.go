@@ -0,0 +1,128 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// natsServer is an in-process fake of a NATS server that records published
+// messages by subject.
+type natsServer struct {
+	net.Listener
+	mu       sync.Mutex
+	messages map[string][]string
+	conns    []net.Conn
+}
+
+func newNATSServer(t *testing.T) *natsServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &natsServer{Listener: l, messages: map[string][]string{}}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, c)
+			s.mu.Unlock()
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *natsServer) serve(c net.Conn) {
+	defer c.Close()
+	fmt.Fprint(c, "INFO {\"server_id\":\"fake\"}\r\n")
+	r := bufio.NewReader(c)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "PING":
+			// Exercise the client's replies to server PINGs.
+			fmt.Fprint(c, "PING\r\nPONG\r\n")
+		case fields[0] == "PUB" && len(fields) == 3:
+			var n int
+			fmt.Sscan(fields[2], &n)
+			b := make([]byte, n+2)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return
+			}
+			if fields[1] == "github.forbidden" {
+				fmt.Fprint(c, "-ERR 'Permissions Violation'\r\n")
+				continue
+			}
+			s.mu.Lock()
+			s.messages[fields[1]] = append(s.messages[fields[1]], string(b[:n]))
+			s.mu.Unlock()
+			if fields[1] == "github.crash" {
+				// The message is processed, but the reply is lost.
+				return
+			}
+		}
+	}
+}
+
+// drop closes the open connections, as a restarted server would.
+func (s *natsServer) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func TestNATS_Publish(t *testing.T) {
+	srv := newNATSServer(t)
+	defer srv.Close()
+	n := &NATS{Addr: srv.Addr().String(), Prefix: "github"}
+	defer n.Close()
+	ctx := context.Background()
+
+	if err := n.Publish(ctx, &Event{DeliveryID: "d1", Event: "issues", Payload: json.RawMessage(`{}`)}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	// The client reconnects after the server closes the connection.
+	srv.drop()
+	for n.c.alive() {
+		time.Sleep(time.Millisecond)
+	}
+	if err := n.Publish(ctx, &Event{DeliveryID: "d2", Event: "issues", Payload: json.RawMessage(`{}`)}); err != nil {
+		t.Fatalf("Publish() after reconnect error = %v", err)
+	}
+	if err := n.Publish(ctx, &Event{DeliveryID: "d3", Event: "forbidden", Payload: json.RawMessage(`{}`)}); err == nil {
+		t.Errorf("Publish() error = nil, want server error")
+	}
+	// A message sent in full is not sent again, even if the reply is lost.
+	if err := n.Publish(ctx, &Event{DeliveryID: "d4", Event: "crash", Payload: json.RawMessage(`{}`)}); err == nil {
+		t.Errorf("Publish() error = nil, want lost reply")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	got := srv.messages["github.issues"]
+	if len(got) != 2 || !strings.Contains(got[0], `"delivery_id":"d1"`) || !strings.Contains(got[1], `"delivery_id":"d2"`) {
+		t.Errorf("messages = %q, want d1 and d2", got)
+	}
+	if got := srv.messages["github.crash"]; len(got) != 1 {
+		t.Errorf("messages = %q, want d4 once", got)
+	}
+}
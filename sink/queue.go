@@ -0,0 +1,96 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Queue is a local queue of events in a directory. Each event is written to
+// its own JSON file, named so that files sort in the order they were added.
+// Consumers read the oldest event with Pop, or process and remove the files
+// themselves.
+type Queue struct {
+	// Dir holds the event files.
+	Dir string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewQueue creates a new Queue in the directory, creating it if needed.
+func NewQueue(dir string) (*Queue, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Queue{Dir: dir}, nil
+}
+
+// Publish writes the event to a new file. Readers never see partial files,
+// since the file is renamed into place once written.
+func (q *Queue) Publish(ctx context.Context, e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	q.seq++
+	name := fmt.Sprintf("%020d-%06d.json", time.Now().UnixNano(), q.seq%1000000)
+	q.mu.Unlock()
+	tmp, err := ioutil.TempFile(q.Dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(q.Dir, name))
+}
+
+// Pop removes and returns the oldest event, or nil if the queue is empty.
+func (q *Queue) Pop() (*Event, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	files, err := ioutil.ReadDir(q.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") && !strings.HasPrefix(f.Name(), ".") {
+			names = append(names, f.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+	path := filepath.Join(q.Dir, names[0])
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	e := &Event{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return e, os.Remove(path)
+}
+
+// Close does nothing.
+func (q *Queue) Close() error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := Open("file://" + filepath.Join(dir, "queue"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := s.(*Queue)
+	ctx := context.Background()
+	for _, id := range []string{"d1", "d2", "d3"} {
+		if err := q.Publish(ctx, &Event{DeliveryID: id, Event: "push", Payload: json.RawMessage(`{}`)}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	for _, want := range []string{"d1", "d2", "d3"} {
+		e, err := q.Pop()
+		if err != nil || e == nil || e.DeliveryID != want {
+			t.Fatalf("Pop() = %+v, %v; want %s", e, err, want)
+		}
+	}
+	if e, err := q.Pop(); e != nil || err != nil {
+		t.Errorf("Pop() = %+v, %v; want empty queue", e, err)
+	}
+}
@@ -0,0 +1,109 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// Redis adds events to a Redis stream with XADD, using the RESP protocol. Each
+// entry has the fields "event", "action", "repo", "delivery_id" and "data",
+// the JSON encoded Event.
+type Redis struct {
+	// Addr is the "host:port" of the server.
+	Addr string
+	// Password, if not empty, authenticates the connection.
+	Password string
+	// Stream is the key of the stream.
+	Stream string
+	// MaxLen, if positive, trims the stream to about this many entries.
+	MaxLen int
+
+	mu sync.Mutex
+	c  *conn
+}
+
+// Publish adds the event to the stream, connecting to the server if needed.
+func (r *Redis) Publish(ctx context.Context, e *Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	args := []string{"XADD", r.Stream}
+	if r.MaxLen > 0 {
+		args = append(args, "MAXLEN", "~", strconv.Itoa(r.MaxLen))
+	}
+	args = append(args, "*",
+		"event", e.Event, "action", e.Action, "repo", e.Repo,
+		"delivery_id", e.DeliveryID, "data", string(b))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return retry(ctx, &r.c, r.dial, func(c *conn) error {
+		_, err := do(c, args...)
+		return err
+	})
+}
+
+// dial connects and authenticates.
+func (r *Redis) dial(ctx context.Context) (*conn, error) {
+	c, err := dial(ctx, r.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if r.Password != "" {
+		if _, err := do(c, "AUTH", r.Password); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close closes the connection, if any.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return closeConn(&r.c)
+}
+
+// do sends the command and returns the reply, for simple string, integer and
+// bulk string replies.
+func do(c *conn, args ...string) (string, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	c.w.Write(b.Bytes())
+	if err := c.flush(); err != nil {
+		return "", err
+	}
+	line, err := c.readLine()
+	if err != nil {
+		return "", err
+	}
+	if line == "" {
+		return "", fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return line[1:], nil
+	case '-':
+		return "", fmt.Errorf("redis: %s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("redis: unexpected reply %q", line)
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return "", err
+		}
+		return string(buf[:n]), nil
+	}
+	return "", fmt.Errorf("redis: unexpected reply %q", line)
+}
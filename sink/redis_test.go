@@ -0,0 +1,155 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// redisServer is an in-process fake of a Redis server that records XADD
+// commands.
+type redisServer struct {
+	net.Listener
+	password string
+	mu       sync.Mutex
+	commands [][]string
+}
+
+func newRedisServer(t *testing.T, password string) *redisServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &redisServer{Listener: l, password: password}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *redisServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	authed := s.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if len(args) != 2 || args[1] != s.password {
+				fmt.Fprint(c, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authed = true
+			fmt.Fprint(c, "+OK\r\n")
+		case "XADD":
+			if !authed {
+				fmt.Fprint(c, "-NOAUTH Authentication required.\r\n")
+				continue
+			}
+			s.mu.Lock()
+			s.commands = append(s.commands, args)
+			if args[1] == "string-key" {
+				s.mu.Unlock()
+				fmt.Fprint(c, "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n")
+				continue
+			}
+			id := fmt.Sprintf("1-%d", len(s.commands))
+			s.mu.Unlock()
+			fmt.Fprintf(c, "$%d\r\n%s\r\n", len(id), id)
+		default:
+			fmt.Fprintf(c, "-ERR unknown command '%s'\r\n", args[0])
+		}
+	}
+}
+
+// readCommand reads a RESP array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func TestRedis_Publish(t *testing.T) {
+	srv := newRedisServer(t, "secret")
+	defer srv.Close()
+	ctx := context.Background()
+	e := &Event{DeliveryID: "d1", Event: "issues", Action: "opened", Repo: "o/r", Payload: json.RawMessage(`{}`)}
+
+	r := &Redis{Addr: srv.Addr().String(), Password: "secret", Stream: "gh", MaxLen: 1000}
+	defer r.Close()
+	if err := r.Publish(ctx, e); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	srv.mu.Lock()
+	got := srv.commands
+	srv.mu.Unlock()
+	if len(got) != 1 {
+		t.Fatalf("XADD commands = %q, want 1", got)
+	}
+	want := []string{"XADD", "gh", "MAXLEN", "~", "1000", "*",
+		"event", "issues", "action", "opened", "repo", "o/r", "delivery_id", "d1"}
+	if !reflect.DeepEqual(got[0][:len(want)], want) {
+		t.Errorf("XADD = %q, want %q", got[0][:len(want)], want)
+	}
+	var data Event
+	if err := json.Unmarshal([]byte(got[0][len(got[0])-1]), &data); err != nil || data.DeliveryID != "d1" {
+		t.Errorf("XADD data = %q, %v; want the event", got[0][len(got[0])-1], err)
+	}
+
+	// Error replies are not retried.
+	wrong := &Redis{Addr: srv.Addr().String(), Password: "secret", Stream: "string-key"}
+	defer wrong.Close()
+	if err := wrong.Publish(ctx, e); err == nil || !strings.Contains(err.Error(), "WRONGTYPE") {
+		t.Errorf("Publish() to a string key error = %v, want WRONGTYPE", err)
+	}
+	srv.mu.Lock()
+	if len(srv.commands) != 2 {
+		t.Errorf("XADD commands = %d, want 2", len(srv.commands))
+	}
+	srv.mu.Unlock()
+
+	bad := &Redis{Addr: srv.Addr().String(), Password: "wrong", Stream: "gh"}
+	defer bad.Close()
+	if err := bad.Publish(ctx, e); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Errorf("Publish() with wrong password error = %v, want WRONGPASS", err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package sink publishes normalized webhook events to message brokers, so
+// downstream consumers can process them asynchronously.
+//
+// A Sink is opened from a URL:
+//
+//	nats://host:4222/github          publishes to subjects "github.<event>"
+//	redis://:password@host:6379/gh   adds to the Redis stream "gh"
+//	file:///var/spool/github         writes one file per event to a directory
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
+)
+
+// Event is a normalized webhook event.
+type Event struct {
+	// DeliveryID is the unique ID of the Github delivery.
+	DeliveryID string `json:"delivery_id"`
+	// Event is the event name, e.g. "issues".
+	Event string `json:"event"`
+	// Action of the event, e.g. "opened", if any.
+	Action string `json:"action,omitempty"`
+	// Repo is the full "owner/name" of the repository, if any.
+	Repo string `json:"repo,omitempty"`
+	// Actor is the login of the user that caused the event, if any.
+	Actor string `json:"actor,omitempty"`
+	// Time the delivery was received.
+	Time time.Time `json:"time"`
+	// Payload is the unmodified webhook payload.
+	Payload json.RawMessage `json:"payload"`
+}
+
+// NewEvent normalizes the delivery.
+func NewEvent(d *webhook.Delivery) *Event {
+	var p struct {
+		Action     string `json:"action"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+	}
+	// Fields missing from the payload are left empty.
+	json.Unmarshal(d.Payload, &p)
+	payload := json.RawMessage(d.Payload)
+	if !json.Valid(d.Payload) {
+		payload = json.RawMessage("null")
+	}
+	return &Event{
+		DeliveryID: d.ID,
+		Event:      d.Event,
+		Action:     p.Action,
+		Repo:       p.Repository.FullName,
+		Actor:      p.Sender.Login,
+		Time:       time.Now().UTC(),
+		Payload:    payload,
+	}
+}
+
+// Sink publishes events.
+type Sink interface {
+	// Publish sends the event to the broker.
+	Publish(ctx context.Context, e *Event) error
+	// Close releases the connection to the broker.
+	Close() error
+}
+
+// Open returns the Sink for the URL. The scheme selects the implementation:
+// "nats", "redis" or "file".
+func Open(rawurl string) (Sink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "nats":
+		if name == "" {
+			name = "github"
+		}
+		return &NATS{Addr: hostPort(u.Host, "4222"), Prefix: name}, nil
+	case "redis":
+		if name == "" {
+			name = "github"
+		}
+		r := &Redis{Addr: hostPort(u.Host, "6379"), Stream: name}
+		if u.User != nil {
+			r.Password, _ = u.User.Password()
+		}
+		return r, nil
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("%s: missing directory", rawurl)
+		}
+		return NewQueue(u.Path)
+	}
+	return nil, fmt.Errorf("%s: unsupported sink scheme %q", rawurl, u.Scheme)
+}
+
+func hostPort(host, port string) string {
+	if host == "" {
+		host = "localhost"
+	}
+	if !strings.Contains(host, ":") {
+		host += ":" + port
+	}
+	return host
+}
+
+// Async publishes deliveries to a Sink in the background, in the order they
+// are received.
+type Async struct {
+	Sink Sink
+	// Timeout limits each Publish call.
+	Timeout time.Duration
+
+	events chan *Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewAsync creates a new Async that queues up to size events for the sink.
+func NewAsync(s Sink, size int) *Async {
+	a := &Async{
+		Sink:    s,
+		Timeout: 10 * time.Second,
+		events:  make(chan *Event, size),
+		done:    make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Deliver queues the delivery for the sink. When the queue is full, the
+// delivery is dropped. Deliver is meant for webhook.Handler.OnDelivery.
+func (a *Async) Deliver(d *webhook.Delivery) {
+	select {
+	case a.events <- NewEvent(d):
+	default:
+		log.Printf("sink: queue full, dropped %s %s", d.Event, d.ID)
+	}
+}
+
+func (a *Async) run() {
+	defer close(a.done)
+	for e := range a.events {
+		ctx, cancel := context.WithTimeout(context.Background(), a.Timeout)
+		err := a.Sink.Publish(ctx, e)
+		cancel()
+		if err != nil {
+			log.Printf("sink: %s %s: %v", e.Event, e.DeliveryID, err)
+		}
+	}
+}
+
+// Close publishes the queued events and closes the sink. Deliver must not be
+// called after Close.
+func (a *Async) Close() error {
+	a.once.Do(func() { close(a.events) })
+	<-a.done
+	return a.Sink.Close()
+}
@@ -0,0 +1,85 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/webhook"
+)
+
+const issuesPayload = `{"action": "opened", "repository": {"full_name": "o/r"}, "sender": {"login": "alice"}}`
+
+func TestNewEvent(t *testing.T) {
+	e := NewEvent(&webhook.Delivery{ID: "d1", Event: "issues", Payload: []byte(issuesPayload)})
+	if e.DeliveryID != "d1" || e.Event != "issues" || e.Action != "opened" || e.Repo != "o/r" ||
+		e.Actor != "alice" || string(e.Payload) != issuesPayload || e.Time.IsZero() {
+		t.Errorf("NewEvent() = %+v, want normalized issues event", e)
+	}
+	e = NewEvent(&webhook.Delivery{ID: "d2", Event: "push", Payload: []byte("not json")})
+	if e.Action != "" || string(e.Payload) != "null" {
+		t.Errorf("NewEvent() = %+v, want empty fields for invalid payload", e)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Sink
+		wantErr bool
+	}{
+		{url: "nats://broker", want: &NATS{Addr: "broker:4222", Prefix: "github"}},
+		{url: "nats://broker:4000/events", want: &NATS{Addr: "broker:4000", Prefix: "events"}},
+		{url: "redis://:secret@cache/gh", want: &Redis{Addr: "cache:6379", Password: "secret", Stream: "gh"}},
+		{url: "redis://", want: &Redis{Addr: "localhost:6379", Stream: "github"}},
+		{url: "file://", wantErr: true},
+		{url: "kafka://broker", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := Open(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Open() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeSink records the delivery IDs of the published events.
+type fakeSink struct {
+	mu     sync.Mutex
+	ids    []string
+	closed bool
+}
+
+func (f *fakeSink) Publish(ctx context.Context, e *Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ids = append(f.ids, e.DeliveryID)
+	if e.DeliveryID == "fail" {
+		return fmt.Errorf("fake error")
+	}
+	return nil
+}
+
+func (f *fakeSink) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestAsync(t *testing.T) {
+	f := &fakeSink{}
+	a := NewAsync(f, 10)
+	for _, id := range []string{"d1", "fail", "d2"} {
+		a.Deliver(&webhook.Delivery{ID: id, Event: "push", Payload: []byte("{}")})
+	}
+	a.Close()
+	if want := []string{"d1", "fail", "d2"}; !reflect.DeepEqual(f.ids, want) || !f.closed {
+		t.Errorf("Async published %q, closed %v; want %q, closed", f.ids, f.closed, want)
+	}
+}
@@ -0,0 +1,178 @@
+package sla
+
+import (
+	"encoding/json"
+	"expvar"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Report summarizes the triage SLA of every tracked repository.
+type Report struct {
+	// Time the report was generated.
+	Time time.Time `json:"time"`
+	// SLA is how long an issue may wait for triage, in seconds.
+	SLA float64 `json:"sla_seconds"`
+	// Repos lists the repositories by name.
+	Repos []*RepoReport `json:"repos"`
+	// Overdue lists the issues waiting for triage longer than the SLA,
+	// longest wait first.
+	Overdue []*IssueReport `json:"overdue"`
+}
+
+// RepoReport summarizes the triage SLA of a repository.
+type RepoReport struct {
+	Repo string `json:"repo"`
+	// Issues is the number of tracked issues.
+	Issues int `json:"issues"`
+	// Triage is the number of issues waiting for triage.
+	Triage int `json:"triage"`
+	// Overdue is the number of issues waiting for triage longer than the SLA.
+	Overdue int `json:"overdue"`
+	// Breached is the number of issues that waited, or are waiting, for
+	// triage longer than the SLA.
+	Breached int `json:"breached"`
+	// Seconds is the total time spent by the issues in each state.
+	Seconds map[string]float64 `json:"seconds"`
+}
+
+// IssueReport describes an issue waiting for triage.
+type IssueReport struct {
+	Repo   string    `json:"repo"`
+	Number int       `json:"number"`
+	Title  string    `json:"title"`
+	URL    string    `json:"url"`
+	Since  time.Time `json:"since"`
+	// Waiting is how long the issue has been waiting for triage, in seconds.
+	Waiting float64 `json:"waiting_seconds"`
+}
+
+// Report returns the triage SLA report for the repo, or for every repo if
+// repo is empty.
+func (t *Tracker) Report(repo string) *Report {
+	now := t.now()
+	r := &Report{Time: now, SLA: t.SLA.Seconds(), Repos: []*RepoReport{}, Overdue: []*IssueReport{}}
+	repos := map[string]*RepoReport{}
+	for _, i := range t.Issues() {
+		if repo != "" && !strings.EqualFold(repo, i.Repo) {
+			continue
+		}
+		rr, ok := repos[strings.ToLower(i.Repo)]
+		if !ok {
+			rr = &RepoReport{Repo: i.Repo, Seconds: map[string]float64{}}
+			repos[strings.ToLower(i.Repo)] = rr
+			r.Repos = append(r.Repos, rr)
+		}
+		rr.Issues++
+		for _, state := range []string{StateTriage, StateOpen, StateClosed} {
+			if d := i.TimeIn(state, now); d > 0 {
+				rr.Seconds[state] += d.Seconds()
+			}
+		}
+		overdue := i.State == StateTriage && i.Waiting(now) > t.SLA
+		if i.State == StateTriage {
+			rr.Triage++
+		}
+		if overdue {
+			rr.Overdue++
+			r.Overdue = append(r.Overdue, &IssueReport{
+				Repo:    i.Repo,
+				Number:  i.Number,
+				Title:   i.Title,
+				URL:     i.URL,
+				Since:   i.Since,
+				Waiting: i.Waiting(now).Seconds(),
+			})
+		}
+		if overdue || i.LongestTriage > t.SLA {
+			rr.Breached++
+		}
+	}
+	sort.Slice(r.Repos, func(a, b int) bool {
+		return r.Repos[a].Repo < r.Repos[b].Repo
+	})
+	sort.Slice(r.Overdue, func(a, b int) bool {
+		return r.Overdue[a].Since.Before(r.Overdue[b].Since)
+	})
+	return r
+}
+
+// Metrics returns the number of issues waiting for triage, overdue and
+// breached, in total and per repository.
+func (t *Tracker) Metrics() map[string]interface{} {
+	r := t.Report("")
+	repos := map[string]interface{}{}
+	var triage, overdue, breached int
+	for _, rr := range r.Repos {
+		triage += rr.Triage
+		overdue += rr.Overdue
+		breached += rr.Breached
+		repos[rr.Repo] = map[string]int{
+			"triage":   rr.Triage,
+			"overdue":  rr.Overdue,
+			"breached": rr.Breached,
+		}
+	}
+	return map[string]interface{}{
+		"sla_seconds": r.SLA,
+		"triage":      triage,
+		"overdue":     overdue,
+		"breached":    breached,
+		"repos":       repos,
+	}
+}
+
+// Publish exports the tracker metrics as the expvar variable with the given
+// name, served at /debug/vars by expvar.Handler. Publish panics if the name is
+// already in use.
+func (t *Tracker) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return t.Metrics()
+	}))
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": func(s float64) time.Duration {
+		return (time.Duration(s) * time.Second).Round(time.Minute)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><title>Triage SLA</title></head>
+<body>
+<h1>Triage SLA</h1>
+<p>Issues waiting for triage longer than {{duration .SLA}} at {{.Time.Format "2006-01-02 15:04 MST"}}.</p>
+<table>
+<tr><th>Issue</th><th>Title</th><th>Waiting</th></tr>
+{{range .Overdue}}<tr><td><a href="{{.URL}}">{{.Repo}}#{{.Number}}</a></td><td>{{.Title}}</td><td>{{duration .Waiting}}</td></tr>
+{{else}}<tr><td colspan="3">None</td></tr>
+{{end}}</table>
+<h2>Repositories</h2>
+<table>
+<tr><th>Repo</th><th>Issues</th><th>Triage</th><th>Overdue</th><th>Breached</th></tr>
+{{range .Repos}}<tr><td>{{.Repo}}</td><td>{{.Issues}}</td><td>{{.Triage}}</td><td>{{.Overdue}}</td><td>{{.Breached}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// ServeHTTP serves the triage SLA report as HTML, or as JSON if the "format"
+// parameter is "json" or the request accepts "application/json". The "repo"
+// parameter limits the report to a single "owner/name" repository.
+func (t *Tracker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := t.Report(r.FormValue("repo"))
+	if r.FormValue("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Println("sla:", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := reportTemplate.Execute(w, report); err != nil {
+		log.Println("sla:", err)
+	}
+}
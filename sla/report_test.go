@@ -0,0 +1,81 @@
+package sla
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTracker(t *testing.T) *Tracker {
+	tr, err := NewTracker("", "review/triage", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transitions := []*Transition{
+		// Overdue.
+		{Time: start, Repo: "o/r", Issue: 1, Title: "Old", State: StateTriage},
+		// Waiting, within the SLA.
+		{Time: start.Add(90 * time.Minute), Repo: "o/r", Issue: 2, Title: "New", State: StateTriage},
+		// Triaged after breaching the SLA.
+		{Time: start, Repo: "o/r", Issue: 3, State: StateTriage},
+		{Time: start.Add(80 * time.Minute), Repo: "o/r", Issue: 3, State: StateOpen},
+		// Overdue in another repo.
+		{Time: start.Add(10 * time.Minute), Repo: "o/other", Issue: 1, Title: "<b>", State: StateTriage},
+	}
+	for _, r := range transitions {
+		tr.Record(r)
+	}
+	tr.Now = func() time.Time { return start.Add(2 * time.Hour) }
+	return tr
+}
+
+func TestTracker_Report(t *testing.T) {
+	r := newTracker(t).Report("")
+	if len(r.Repos) != 2 || r.Repos[0].Repo != "o/other" || r.Repos[1].Repo != "o/r" {
+		t.Fatalf("Report() repos = %+v, want o/other and o/r", r.Repos)
+	}
+	got := r.Repos[1]
+	if got.Issues != 3 || got.Triage != 2 || got.Overdue != 1 || got.Breached != 2 {
+		t.Errorf("Report() o/r = %+v, want 3 issues, 2 triage, 1 overdue, 2 breached", got)
+	}
+	if s := got.Seconds[StateTriage]; s != (2*time.Hour + 30*time.Minute + 80*time.Minute).Seconds() {
+		t.Errorf("Report() o/r triage seconds = %v", s)
+	}
+	if len(r.Overdue) != 2 || r.Overdue[0].Repo != "o/r" || r.Overdue[0].Waiting != 7200 {
+		t.Errorf("Report() overdue = %+v, want o/r#1 first", r.Overdue)
+	}
+
+	r = newTracker(t).Report("O/Other")
+	if len(r.Repos) != 1 || len(r.Overdue) != 1 {
+		t.Errorf("Report(o/other) = %+v, want one repo", r)
+	}
+
+	m := newTracker(t).Metrics()
+	if m["overdue"] != 2 || m["breached"] != 3 || m["triage"] != 3 {
+		t.Errorf("Metrics() = %v, want 2 overdue, 3 breached, 3 triage", m)
+	}
+}
+
+func TestTracker_ServeHTTP(t *testing.T) {
+	tr := newTracker(t)
+
+	w := httptest.NewRecorder()
+	tr.ServeHTTP(w, httptest.NewRequest("GET", "/triage?format=json&repo=o/r", nil))
+	var r Report
+	if err := json.Unmarshal(w.Body.Bytes(), &r); err != nil {
+		t.Fatalf("ServeHTTP() json = %q: %v", w.Body.String(), err)
+	}
+	if len(r.Overdue) != 1 || r.Overdue[0].Title != "Old" {
+		t.Errorf("ServeHTTP() json overdue = %+v, want o/r#1", r.Overdue)
+	}
+
+	w = httptest.NewRecorder()
+	tr.ServeHTTP(w, httptest.NewRequest("GET", "/triage", nil))
+	body := w.Body.String()
+	if !strings.Contains(w.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(body, "o/r#1") || !strings.Contains(body, "&lt;b&gt;") {
+		t.Errorf("ServeHTTP() html = %q, want escaped overdue issues", body)
+	}
+}
@@ -0,0 +1,262 @@
+// Package sla tracks how long issues spend waiting for triage.
+//
+// A Tracker records the state transitions of issues from issues events. An
+// open issue with the triage label is in the "triage" state, any other open
+// issue is "open", and a closed issue is "closed". Transitions are appended as
+// JSON lines to a local file and replayed when the Tracker is created, so the
+// time spent in each state survives restarts.
+package sla
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/jsonl"
+)
+
+// Issue states.
+const (
+	StateTriage = "triage"
+	StateOpen   = "open"
+	StateClosed = "closed"
+)
+
+// DefaultSLA is how long an issue may wait for triage when no SLA is given.
+const DefaultSLA = 72 * time.Hour
+
+// Transition records the state of an issue after an issues event.
+type Transition struct {
+	// Time the event was received.
+	Time time.Time `json:"time"`
+	// Repo is the full "owner/name" of the repository.
+	Repo string `json:"repo"`
+	// Issue number.
+	Issue int `json:"issue"`
+	// Title and URL of the issue.
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+	// Action of the issues event, e.g. "opened" or "labeled".
+	Action string `json:"action"`
+	// State of the issue after the event.
+	State string `json:"state"`
+}
+
+// Issue is the tracked state of a single issue.
+type Issue struct {
+	Repo   string
+	Number int
+	Title  string
+	URL    string
+	// State is the current state of the issue.
+	State string
+	// Since is when the issue entered the current state.
+	Since time.Time
+	// Durations is the time spent in each earlier state, excluding the
+	// current one.
+	Durations map[string]time.Duration
+	// LongestTriage is the longest completed wait for triage.
+	LongestTriage time.Duration
+}
+
+// TimeIn returns the total time the issue spent in state until now.
+func (i *Issue) TimeIn(state string, now time.Time) time.Duration {
+	d := i.Durations[state]
+	if i.State == state && now.After(i.Since) {
+		d += now.Sub(i.Since)
+	}
+	return d
+}
+
+// Waiting returns how long the issue has been in its current state.
+func (i *Issue) Waiting(now time.Time) time.Duration {
+	if now.Before(i.Since) {
+		return 0
+	}
+	return now.Sub(i.Since)
+}
+
+func (i *Issue) copy() *Issue {
+	c := *i
+	c.Durations = make(map[string]time.Duration, len(i.Durations))
+	for k, v := range i.Durations {
+		c.Durations[k] = v
+	}
+	return &c
+}
+
+// apply moves the issue to the state of the transition.
+func (i *Issue) apply(t *Transition) {
+	if t.Title != "" {
+		i.Title = t.Title
+	}
+	if t.URL != "" {
+		i.URL = t.URL
+	}
+	if t.State == i.State || t.Time.Before(i.Since) {
+		return
+	}
+	d := t.Time.Sub(i.Since)
+	i.Durations[i.State] += d
+	if i.State == StateTriage && d > i.LongestTriage {
+		i.LongestTriage = d
+	}
+	i.State = t.State
+	i.Since = t.Time
+}
+
+// Tracker records issue state transitions and reports on the triage SLA.
+type Tracker struct {
+	// TriageLabel marks issues waiting for triage.
+	TriageLabel string
+	// SLA is how long an issue may wait for triage.
+	SLA time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+
+	// file is nil if transitions are kept in memory only.
+	file   *jsonl.File
+	mu     sync.Mutex
+	issues map[string]*Issue
+}
+
+// NewTracker creates a new Tracker that saves transitions to the given file
+// name, and replays the transitions already saved there. An empty path keeps
+// transitions in memory only.
+func NewTracker(path, triageLabel string, sla time.Duration) (*Tracker, error) {
+	t := &Tracker{
+		TriageLabel: triageLabel,
+		SLA:         sla,
+		Now:         time.Now,
+		issues:      map[string]*Issue{},
+	}
+	if path != "" {
+		t.file = jsonl.New(path)
+	}
+	if err := t.replay(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return t, nil
+}
+
+func (t *Tracker) replay() error {
+	if t.file == nil {
+		return nil
+	}
+	return t.file.Read(func(line []byte) error {
+		tr := &Transition{}
+		if err := json.Unmarshal(line, tr); err != nil {
+			return err
+		}
+		t.apply(tr)
+		return nil
+	})
+}
+
+func (t *Tracker) now() time.Time {
+	if t.Now == nil {
+		return time.Now()
+	}
+	return t.Now()
+}
+
+func key(repo string, number int) string {
+	return fmt.Sprintf("%s#%d", strings.ToLower(repo), number)
+}
+
+// apply updates the tracked issue. The caller must hold t.mu, unless the
+// Tracker is not yet shared.
+func (t *Tracker) apply(tr *Transition) {
+	k := key(tr.Repo, tr.Issue)
+	i, ok := t.issues[k]
+	if !ok {
+		t.issues[k] = &Issue{
+			Repo:      tr.Repo,
+			Number:    tr.Issue,
+			Title:     tr.Title,
+			URL:       tr.URL,
+			State:     tr.State,
+			Since:     tr.Time,
+			Durations: map[string]time.Duration{},
+		}
+		return
+	}
+	i.apply(tr)
+}
+
+// Record applies the transition and appends it to the tracker file.
+func (t *Tracker) Record(tr *Transition) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.apply(tr)
+	if t.file == nil {
+		return nil
+	}
+	return t.file.Append(tr)
+}
+
+// IssuesEvent records the state of the event issue after an "opened",
+// "labeled", "unlabeled", "closed" or "reopened" event. Other events and pull
+// requests are ignored.
+func (t *Tracker) IssuesEvent(event *github.IssuesEvent) error {
+	switch event.GetAction() {
+	case "opened", "labeled", "unlabeled", "closed", "reopened":
+	default:
+		return nil
+	}
+	issue := event.GetIssue()
+	if issue == nil || issue.IsPullRequest() {
+		return nil
+	}
+	tr := &Transition{
+		Time:   t.now(),
+		Repo:   event.GetRepo().GetFullName(),
+		Issue:  issue.GetNumber(),
+		Title:  issue.GetTitle(),
+		URL:    issue.GetHTMLURL(),
+		Action: event.GetAction(),
+		State:  t.state(issue),
+	}
+	if event.GetAction() == "opened" && !issue.GetCreatedAt().IsZero() {
+		tr.Time = issue.GetCreatedAt()
+	}
+	return t.Record(tr)
+}
+
+// state returns the state of the issue.
+func (t *Tracker) state(issue *github.Issue) string {
+	if issue.GetState() == "closed" {
+		return StateClosed
+	}
+	for _, l := range issue.Labels {
+		if strings.EqualFold(l.GetName(), t.TriageLabel) {
+			return StateTriage
+		}
+	}
+	return StateOpen
+}
+
+// Issue returns a copy of the tracked issue, or nil if it is not tracked.
+func (t *Tracker) Issue(repo string, number int) *Issue {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i, ok := t.issues[key(repo, number)]
+	if !ok {
+		return nil
+	}
+	return i.copy()
+}
+
+// Issues returns a copy of every tracked issue.
+func (t *Tracker) Issues() []*Issue {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	list := make([]*Issue, 0, len(t.issues))
+	for _, i := range t.issues {
+		list = append(list, i.copy())
+	}
+	return list
+}
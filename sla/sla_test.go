@@ -0,0 +1,111 @@
+package sla
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+var start = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newEvent returns an issues event for issue 1 of o/r with the given labels.
+func newEvent(action, state string, labels ...string) *github.IssuesEvent {
+	issue := &github.Issue{
+		Number:    github.Int(1),
+		Title:     github.String("Fix the bug"),
+		HTMLURL:   github.String("https://github.com/o/r/issues/1"),
+		State:     github.String(state),
+		CreatedAt: &start,
+	}
+	for _, l := range labels {
+		issue.Labels = append(issue.Labels, github.Label{Name: github.String(l)})
+	}
+	return &github.IssuesEvent{
+		Action: github.String(action),
+		Issue:  issue,
+		Repo:   &github.Repository{FullName: github.String("o/r")},
+	}
+}
+
+func TestTracker_IssuesEvent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sla.jsonl")
+	tr, err := NewTracker(path, "review/triage", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []struct {
+		after time.Duration
+		event *github.IssuesEvent
+	}{
+		{after: time.Minute, event: newEvent("opened", "open")},
+		{after: 2 * time.Minute, event: newEvent("labeled", "open", "Review/Triage")},
+		{after: 3 * time.Minute, event: newEvent("edited", "open")},
+		{after: 4 * time.Hour, event: newEvent("unlabeled", "open", "backlog")},
+		{after: 5 * time.Hour, event: newEvent("closed", "closed", "backlog")},
+	}
+	for _, e := range events {
+		tr.Now = func() time.Time { return start.Add(e.after) }
+		if err := tr.IssuesEvent(e.event); err != nil {
+			t.Fatalf("IssuesEvent(%s) error = %v", e.event.GetAction(), err)
+		}
+	}
+	pull := newEvent("labeled", "open", "review/triage")
+	pull.Issue.Number = github.Int(2)
+	pull.Issue.PullRequestLinks = &github.PullRequestLinks{}
+	tr.IssuesEvent(pull)
+
+	// A new tracker replays the saved transitions.
+	replayed, err := NewTracker(path, "review/triage", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range []*Tracker{tr, replayed} {
+		if len(tr.Issues()) != 1 {
+			t.Errorf("Issues() = %d issues, want 1", len(tr.Issues()))
+		}
+		i := tr.Issue("O/R", 1)
+		if i == nil {
+			t.Fatalf("Issue() = nil, want issue")
+		}
+		now := start.Add(6 * time.Hour)
+		want := map[string]time.Duration{
+			StateOpen:   2*time.Minute + time.Hour,
+			StateTriage: 4*time.Hour - 2*time.Minute,
+			StateClosed: time.Hour,
+		}
+		got := map[string]time.Duration{}
+		for state := range want {
+			got[state] = i.TimeIn(state, now)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("TimeIn() = %v, want %v", got, want)
+		}
+		if i.State != StateClosed || i.LongestTriage != want[StateTriage] {
+			t.Errorf("Issue() = %+v, want closed after %v of triage", i, want[StateTriage])
+		}
+	}
+}
+
+func TestNewTracker_Error(t *testing.T) {
+	f, err := ioutil.TempFile("", "sla")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("not json\n")
+	f.Close()
+	if _, err := NewTracker(f.Name(), "review/triage", time.Hour); err == nil {
+		t.Errorf("NewTracker() error = nil, want error")
+	}
+}
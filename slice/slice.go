@@ -1,5 +1,7 @@
 package slice
 
+import "strings"
+
 // ContainsString looks for value in the given slice
 func ContainsString(slice []string, value string) bool {
 	for i := range slice {
@@ -10,6 +12,16 @@ func ContainsString(slice []string, value string) bool {
 	return false
 }
 
+// ContainsFold looks for value in the given slice, ignoring case.
+func ContainsFold(slice []string, value string) bool {
+	for i := range slice {
+		if strings.EqualFold(slice[i], value) {
+			return true
+		}
+	}
+	return false
+}
+
 // IndexString returns the index of value in slice, or -1 if value is not present.
 func IndexString(slice []string, value string) int {
 	for i := range slice {
@@ -30,3 +30,32 @@ func TestContainsString(t *testing.T) {
 		})
 	}
 }
+
+func TestContainsFold(t *testing.T) {
+	tests := []struct {
+		name  string
+		slice []string
+		value string
+		want  bool
+	}{
+		{
+			name:  "value-found",
+			slice: []string{"a", "B", "c"},
+			value: "b",
+			want:  true,
+		},
+		{
+			name:  "value-missing",
+			slice: []string{"a", "B", "c"},
+			value: "f",
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ContainsFold(tt.slice, tt.value); got != tt.want {
+				t.Errorf("ContainsFold() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
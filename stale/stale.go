@@ -0,0 +1,170 @@
+// Package stale marks inactive issues and pull requests as stale, and closes
+// them if they remain inactive.
+//
+// An issue with no activity for days_until_stale days is labeled and receives
+// a comment. If there is still no activity days_until_close days later, it is
+// closed. Any activity, such as a comment or an edit, removes the label. For
+// example:
+//
+//	days_until_stale: 60
+//	days_until_close: 7
+//	label: stale
+//	exempt_labels: [pinned, security]
+//	exempt_milestones: ["*"]
+//	exempt_assignees: [alice]
+package stale
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/slice"
+)
+
+const (
+	// Marker identifies comments added by this package, so they are not
+	// counted as activity.
+	Marker = "<!-- stale -->"
+
+	// DefaultComment is the comment added when marking an issue stale.
+	DefaultComment = "This has been automatically marked as stale because it has not had " +
+		"recent activity. It will be closed if no further activity occurs."
+
+	// DefaultCloseComment is the comment added when closing a stale issue.
+	DefaultCloseComment = "Closing due to inactivity."
+
+	day = 24 * time.Hour
+)
+
+// Action is the change made by Config.Check.
+type Action string
+
+// Actions taken by Config.Check.
+const (
+	None  Action = ""
+	Mark  Action = "mark"
+	Close Action = "close"
+)
+
+// Config is the stale workflow configuration.
+type Config struct {
+	// DaysUntilStale is the number of days without activity before an issue is
+	// marked stale.
+	DaysUntilStale int `yaml:"days_until_stale" json:"days_until_stale"`
+	// DaysUntilClose is the number of days without activity before a stale
+	// issue is closed. Zero never closes issues.
+	DaysUntilClose int `yaml:"days_until_close,omitempty" json:"days_until_close,omitempty"`
+	// Label marks stale issues. Defaults to "stale".
+	Label string `yaml:"label,omitempty" json:"label,omitempty"`
+	// Comment is added when marking an issue stale. Defaults to DefaultComment.
+	Comment string `yaml:"comment,omitempty" json:"comment,omitempty"`
+	// CloseComment is added when closing an issue. Defaults to
+	// DefaultCloseComment.
+	CloseComment string `yaml:"close_comment,omitempty" json:"close_comment,omitempty"`
+	// SkipPullRequests excludes pull requests.
+	SkipPullRequests bool `yaml:"skip_pull_requests,omitempty" json:"skip_pull_requests,omitempty"`
+
+	// ExemptLabels exempt issues with any of these labels.
+	ExemptLabels []string `yaml:"exempt_labels,omitempty" json:"exempt_labels,omitempty"`
+	// ExemptMilestones exempt issues in any of these milestones, by title. "*"
+	// exempts issues in any milestone.
+	ExemptMilestones []string `yaml:"exempt_milestones,omitempty" json:"exempt_milestones,omitempty"`
+	// ExemptAssignees exempt issues assigned to any of these users. "*"
+	// exempts all assigned issues.
+	ExemptAssignees []string `yaml:"exempt_assignees,omitempty" json:"exempt_assignees,omitempty"`
+}
+
+// Validate checks the configuration and sets defaults.
+func (c *Config) Validate() error {
+	if c.DaysUntilStale <= 0 {
+		return fmt.Errorf("days_until_stale must be positive")
+	}
+	if c.DaysUntilClose < 0 {
+		return fmt.Errorf("days_until_close must not be negative")
+	}
+	if c.Label == "" {
+		c.Label = "stale"
+	}
+	if c.Comment == "" {
+		c.Comment = DefaultComment
+	}
+	if c.CloseComment == "" {
+		c.CloseComment = DefaultCloseComment
+	}
+	return nil
+}
+
+// Exempt reports whether the event issue is never marked stale.
+func (c *Config) Exempt(ev *issues.Event) bool {
+	issue := ev.GetIssue()
+	if c.SkipPullRequests && issue.IsPullRequest() {
+		return true
+	}
+	for _, l := range c.ExemptLabels {
+		if slice.ContainsFold(ev.Labels(), l) {
+			return true
+		}
+	}
+	if m := issue.GetMilestone(); m != nil {
+		for _, title := range c.ExemptMilestones {
+			if title == "*" || strings.EqualFold(title, m.GetTitle()) {
+				return true
+			}
+		}
+	}
+	for _, a := range issue.Assignees {
+		for _, login := range c.ExemptAssignees {
+			if login == "*" || strings.EqualFold(login, a.GetLogin()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check marks the event issue stale or closes it, depending on the time of the
+// last update. Because marking an issue updates it, the close delay counts
+// from the time the issue was marked.
+func (c *Config) Check(ctx context.Context, ev *issues.Event, now time.Time) (Action, error) {
+	if ev.GetIssue().GetState() != "open" || c.Exempt(ev) {
+		return None, nil
+	}
+	idle := now.Sub(ev.GetIssue().GetUpdatedAt())
+	if slice.ContainsFold(ev.Labels(), c.Label) {
+		if c.DaysUntilClose == 0 || idle < time.Duration(c.DaysUntilClose)*day {
+			return None, nil
+		}
+		if _, _, err := ev.CreateComment(ctx, c.CloseComment+"\n"+Marker); err != nil {
+			return None, err
+		}
+		if _, _, err := ev.CloseIssue(ctx, nil); err != nil {
+			return None, err
+		}
+		return Close, nil
+	}
+	if idle < time.Duration(c.DaysUntilStale)*day {
+		return None, nil
+	}
+	if _, _, err := ev.AddIssueLabels(ctx, []string{c.Label}); err != nil {
+		return None, err
+	}
+	if _, _, err := ev.CreateComment(ctx, c.Comment+"\n"+Marker); err != nil {
+		return None, err
+	}
+	return Mark, nil
+}
+
+// Unmark removes the stale label from the event issue, if present. Call Unmark
+// on activity, e.g. a new comment that does not contain Marker.
+func (c *Config) Unmark(ctx context.Context, ev *issues.Event) (bool, error) {
+	if !slice.ContainsFold(ev.Labels(), c.Label) {
+		return false, nil
+	}
+	if _, err := ev.RemoveIssueLabels(ctx, []string{c.Label}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,239 @@
+package stale
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues"
+	"github.com/stephen-soltesz/github-webhook-poc/events/issues/iface"
+	"github.com/stephen-soltesz/github-webhook-poc/githubx/fakegithub"
+	"github.com/stephen-soltesz/github-webhook-poc/internal/configfile"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    *Config
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			config: "days_until_stale: 30\n",
+			want: &Config{
+				DaysUntilStale: 30,
+				Label:          "stale",
+				Comment:        DefaultComment,
+				CloseComment:   DefaultCloseComment,
+			},
+		},
+		{
+			name:   "json",
+			config: `{"days_until_stale": 60, "days_until_close": 7, "label": "inactive", "exempt_labels": ["pinned"]}`,
+			want: &Config{
+				DaysUntilStale: 60,
+				DaysUntilClose: 7,
+				Label:          "inactive",
+				Comment:        DefaultComment,
+				CloseComment:   DefaultCloseComment,
+				ExemptLabels:   []string{"pinned"},
+			},
+		},
+		{
+			name:    "error-missing-days",
+			config:  "label: stale\n",
+			wantErr: true,
+		},
+		{
+			name:    "error-negative-close",
+			config:  "days_until_stale: 1\ndays_until_close: -1\n",
+			wantErr: true,
+		},
+		{
+			name:    "error-unknown-field",
+			config:  "days_until_stale: 1\ndays: 1\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &Config{}
+			err := configfile.Parse([]byte(tt.config), got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newEvent(client *github.Client, issue *github.Issue) *issues.Event {
+	return issues.NewEvent(iface.NewIssues(client.Issues), &github.IssuesEvent{
+		Issue: issue,
+		Repo: &github.Repository{
+			Name:  github.String("repo"),
+			Owner: &github.User{Login: github.String("owner")},
+		},
+	})
+}
+
+func TestConfig_Exempt(t *testing.T) {
+	c := &Config{
+		DaysUntilStale:   1,
+		SkipPullRequests: true,
+		ExemptLabels:     []string{"Pinned"},
+		ExemptMilestones: []string{"v1"},
+		ExemptAssignees:  []string{"alice"},
+	}
+	tests := []struct {
+		name  string
+		issue *github.Issue
+		want  bool
+	}{
+		{
+			name:  "not-exempt",
+			issue: &github.Issue{},
+		},
+		{
+			name:  "pull-request",
+			issue: &github.Issue{PullRequestLinks: &github.PullRequestLinks{}},
+			want:  true,
+		},
+		{
+			name:  "label",
+			issue: &github.Issue{Labels: []github.Label{{Name: github.String("pinned")}}},
+			want:  true,
+		},
+		{
+			name:  "milestone",
+			issue: &github.Issue{Milestone: &github.Milestone{Title: github.String("v1")}},
+			want:  true,
+		},
+		{
+			name:  "other-milestone",
+			issue: &github.Issue{Milestone: &github.Milestone{Title: github.String("v2")}},
+		},
+		{
+			name:  "assignee",
+			issue: &github.Issue{Assignees: []*github.User{{Login: github.String("Alice")}}},
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.Exempt(newEvent(github.NewClient(nil), tt.issue)); got != tt.want {
+				t.Errorf("Config.Exempt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Check(t *testing.T) {
+	now := time.Now()
+	ago := func(days int) *time.Time {
+		t := now.Add(-time.Duration(days) * day)
+		return &t
+	}
+	c := &Config{}
+	if err := configfile.Parse([]byte("days_until_stale: 30\ndays_until_close: 7\n"), c); err != nil {
+		t.Fatal(err)
+	}
+	stale := []github.Label{{Name: github.String("stale")}}
+	tests := []struct {
+		name       string
+		issue      *github.Issue
+		want       Action
+		wantLabels []string
+		wantState  string
+	}{
+		{
+			name:       "active",
+			issue:      &github.Issue{UpdatedAt: ago(29)},
+			want:       None,
+			wantLabels: []string{},
+			wantState:  "open",
+		},
+		{
+			name:       "mark",
+			issue:      &github.Issue{UpdatedAt: ago(31)},
+			want:       Mark,
+			wantLabels: []string{"stale"},
+			wantState:  "open",
+		},
+		{
+			name:       "stale-not-yet-closed",
+			issue:      &github.Issue{UpdatedAt: ago(6), Labels: stale},
+			want:       None,
+			wantLabels: []string{"stale"},
+			wantState:  "open",
+		},
+		{
+			name:       "close",
+			issue:      &github.Issue{UpdatedAt: ago(8), Labels: stale},
+			want:       Close,
+			wantLabels: []string{"stale"},
+			wantState:  "closed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fakegithub.NewServer()
+			defer s.Close()
+			tt.issue.Number = github.Int(1)
+			tt.issue.State = github.String("open")
+			s.AddIssue("owner", "repo", tt.issue)
+
+			got, err := c.Check(context.Background(), newEvent(s.Client(), tt.issue), now)
+			if err != nil {
+				t.Fatalf("Config.Check() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Config.Check() = %q, want %q", got, tt.want)
+			}
+			if labels := s.IssueLabels("owner", "repo", 1); !reflect.DeepEqual(labels, tt.wantLabels) {
+				t.Errorf("IssueLabels() = %v, want %v", labels, tt.wantLabels)
+			}
+			if state := s.Issue("owner", "repo", 1).GetState(); state != tt.wantState {
+				t.Errorf("Issue().State = %q, want %q", state, tt.wantState)
+			}
+			comments := s.Comments("owner", "repo", 1)
+			if (len(comments) > 0) != (tt.want != None) {
+				t.Errorf("Comments() = %q, want comments %v", comments, tt.want != None)
+			}
+			for _, body := range comments {
+				if !strings.Contains(body, Marker) {
+					t.Errorf("comment %q missing marker", body)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_Unmark(t *testing.T) {
+	s := fakegithub.NewServer()
+	defer s.Close()
+	issue := &github.Issue{
+		Number: github.Int(1),
+		Labels: []github.Label{{Name: github.String("stale")}, {Name: github.String("bug")}},
+	}
+	s.AddIssue("owner", "repo", issue)
+	c := &Config{DaysUntilStale: 1, Label: "stale"}
+
+	removed, err := c.Unmark(context.Background(), newEvent(s.Client(), issue))
+	if err != nil || !removed {
+		t.Fatalf("Config.Unmark() = %v, %v; want true, nil", removed, err)
+	}
+	if got, want := s.IssueLabels("owner", "repo", 1), []string{"bug"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueLabels() = %v, want %v", got, want)
+	}
+	removed, err = c.Unmark(context.Background(), newEvent(s.Client(), s.Issue("owner", "repo", 1)))
+	if err != nil || removed {
+		t.Errorf("Config.Unmark() = %v, %v; want false, nil", removed, err)
+	}
+}